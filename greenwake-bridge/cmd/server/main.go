@@ -5,23 +5,29 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"greenwake-bridge/internal/api"
 	"greenwake-bridge/internal/config"
 )
 
-func main() {
-	// 添加命令行参数
-	configFile := flag.String("config", "", "配置文件路径（如果不存在，会从示例配置创建）")
-	flag.Parse()
+// setFlags 可重复的 -set 参数
+type setFlags []string
+
+func (f *setFlags) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *setFlags) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
 
-	// 获取配置文件路径
-	configPath := *configFile
+// absConfigPath 未指定配置文件时使用默认路径，相对路径相对于当前工作目录
+func absConfigPath(configPath string) string {
 	if configPath == "" {
 		configPath = config.GetConfigPath()
 	}
-
-	// 如果没有指定绝对路径，则使用相对于当前工作目录的路径
 	if configPath != "" && !filepath.IsAbs(configPath) {
 		workDir, err := os.Getwd()
 		if err != nil {
@@ -29,17 +35,62 @@ func main() {
 		}
 		configPath = filepath.Join(workDir, configPath)
 	}
+	return configPath
+}
+
+func main() {
+	// 子命令
+	if len(os.Args) > 1 && os.Args[1] == "hash-password" {
+		hashPassword(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "validate" {
+		validateConfig(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "print-config" {
+		printConfig(os.Args[2:])
+		return
+	}
+
+	// 添加命令行参数
+	configFile := flag.String("config", "", "配置文件路径（如果不存在，会从示例配置创建）")
+	lenient := flag.Bool("lenient", false, "配置校验不通过时仍然启动，只输出警告")
+	var sets setFlags
+	flag.Var(&sets, "set", "覆盖配置项，格式为 路径=值，如 -set http.port=8080 -set hosts[0].mac=AA:BB:CC:DD:EE:FF，可重复")
+	flag.Parse()
+
+	configPath := absConfigPath(*configFile)
+	overrides, err := config.LoadOverrides(sets)
+	if err != nil {
+		log.Fatalf("读取配置覆盖失败: %v", err)
+	}
 
 	log.Printf("使用配置文件: %s", configPath)
+	for _, o := range overrides {
+		log.Printf("配置覆盖: %s <- %s", o.Path, o.Source)
+	}
 
 	// 加载配置
-	cfg, err := config.Load(configPath)
+	cfg, err := config.Load(configPath, overrides...)
 	if err != nil {
 		log.Fatalf("加载配置失败: %v", err)
 	}
+	if err := cfg.Validate(); err != nil {
+		if !*lenient {
+			log.Fatalf("%v\n请修正配置后重新启动，或使用 -lenient 参数忽略校验错误", err)
+		}
+		log.Printf("警告: %v", err)
+	}
 
 	// 启动服务器
-	server := api.NewServer(cfg)
+	server, err := api.NewServer(cfg)
+	if err != nil {
+		log.Fatalf("初始化服务器失败: %v", err)
+	}
+	if err := server.WatchConfig(); err != nil {
+		log.Printf("配置文件自动重新加载不可用: %v", err)
+	}
 	if err := server.Run(); err != nil {
 		log.Fatalf("服务器启动失败: %v", err)
 	}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"greenwake-bridge/internal/auth"
+)
+
+// hashPassword 生成可直接填入 http.password 的 bcrypt 哈希
+// 用法: greenwake-bridge hash-password [密码]，不带参数时从标准输入读取
+func hashPassword(args []string) {
+	var password string
+	if len(args) > 0 {
+		password = args[0]
+	} else {
+		fmt.Fprint(os.Stderr, "请输入密码: ")
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			fmt.Fprintf(os.Stderr, "读取密码失败: %v\n", err)
+			os.Exit(1)
+		}
+		password = strings.TrimRight(line, "\r\n")
+	}
+
+	if password == "" {
+		fmt.Fprintln(os.Stderr, "密码不能为空")
+		os.Exit(1)
+	}
+
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "生成密码哈希失败: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(hash)
+}
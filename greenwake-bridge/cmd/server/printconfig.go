@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"greenwake-bridge/internal/config"
+
+	"gopkg.in/yaml.v3"
+)
+
+// printConfig 输出合并配置文件、环境变量和 -set 参数后的最终配置，密码和令牌已隐藏
+// 用法: greenwake-bridge print-config [-config config.yaml] [-set 路径=值 ...]
+func printConfig(args []string) {
+	fs := flag.NewFlagSet("print-config", flag.ExitOnError)
+	configFile := fs.String("config", "", "配置文件路径，不存在时只使用环境变量和 -set 参数")
+	var sets setFlags
+	fs.Var(&sets, "set", "覆盖配置项，格式为 路径=值，可重复")
+	fs.Parse(args)
+
+	configPath := absConfigPath(*configFile)
+	overrides, err := config.LoadOverrides(sets)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "读取配置覆盖失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil && !os.IsNotExist(err) {
+		fmt.Fprintf(os.Stderr, "读取配置失败: %v\n", err)
+		os.Exit(1)
+	}
+	cfg, err := config.Parse(data, configPath, overrides...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "解析配置失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	if data == nil {
+		fmt.Printf("# 配置文件 %s 不存在\n", configPath)
+	} else {
+		fmt.Printf("# 配置文件: %s\n", configPath)
+	}
+	for _, o := range overrides {
+		fmt.Printf("# %s <- %s\n", o.Path, o.Source)
+	}
+	enc := yaml.NewEncoder(os.Stdout)
+	enc.SetIndent(2)
+	if err := enc.Encode(cfg.Redacted()); err != nil {
+		fmt.Fprintf(os.Stderr, "序列化配置失败: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"greenwake-bridge/internal/config"
+)
+
+// validateConfig 校验配置文件并输出全部问题，供 CI 使用，配置无效时退出码为1
+// 用法: greenwake-bridge validate -config config.yaml
+func validateConfig(args []string) {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	configFile := fs.String("config", "config.yaml", "要校验的配置文件路径")
+	var sets setFlags
+	fs.Var(&sets, "set", "覆盖配置项，格式为 路径=值，可重复")
+	fs.Parse(args)
+
+	// 与启动时一致，校验应用环境变量和 -set 参数后的配置
+	overrides, err := config.LoadOverrides(sets)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "读取配置覆盖失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	data, err := os.ReadFile(*configFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "读取配置失败: %v\n", err)
+		os.Exit(1)
+	}
+	cfg, err := config.Parse(data, *configFile, overrides...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: 解析配置失败: %v\n", *configFile, err)
+		os.Exit(1)
+	}
+
+	var invalid *config.ValidationError
+	if err := cfg.Validate(); errors.As(err, &invalid) {
+		for _, p := range invalid.Problems {
+			if p.Line > 0 {
+				fmt.Fprintf(os.Stderr, "%s:%d: %s: %s\n", *configFile, p.Line, p.Path, p.Message)
+			} else {
+				fmt.Fprintf(os.Stderr, "%s: %s: %s\n", *configFile, p.Path, p.Message)
+			}
+		}
+		fmt.Fprintf(os.Stderr, "发现 %d 个问题\n", len(invalid.Problems))
+		os.Exit(1)
+	}
+	fmt.Printf("%s: 配置有效\n", *configFile)
+}
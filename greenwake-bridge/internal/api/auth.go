@@ -0,0 +1,257 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"greenwake-bridge/internal/auth"
+	"greenwake-bridge/internal/config"
+	"greenwake-bridge/internal/model"
+	"greenwake-bridge/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	sessionCookieName = "greenwake_session"
+	principalKey      = "principal"
+)
+
+type Authenticator struct {
+	cfg      *config.Config
+	sessions *auth.SessionManager
+	tokens   *auth.TokenStore
+	events   *service.EventBus
+}
+
+func NewAuthenticator(cfg *config.Config, events *service.EventBus) (*Authenticator, error) {
+	if !cfg.HTTP.Anonymous {
+		if cfg.HTTP.User == "" || cfg.HTTP.Password == "" {
+			return nil, fmt.Errorf("未配置 http.user/http.password，如需关闭认证请显式设置 http.anonymous: true")
+		}
+		if !auth.IsHashed(cfg.HTTP.Password) {
+			log.Printf("警告: http.password 为明文，请使用 hash-password 子命令生成哈希后替换")
+		}
+	} else {
+		log.Printf("警告: 已开启匿名访问模式，Web界面和API无需认证")
+	}
+
+	sessions, err := auth.NewSessionManager(cfg.HTTP.SessionSecret, time.Duration(cfg.HTTP.SessionTTL)*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := auth.NewTokenStore(cfg.Tokens, cfg.DataDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Authenticator{
+		cfg:      cfg,
+		sessions: sessions,
+		tokens:   tokens,
+		events:   events,
+	}, nil
+}
+
+// Middleware 校验会话Cookie或HTTP Basic认证，并将调用者写入上下文
+func (a *Authenticator) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := a.authenticate(c)
+		if principal == nil {
+			// 网页请求不返回 WWW-Authenticate，避免浏览器弹出原生登录框
+			if c.GetHeader("X-Requested-With") != "XMLHttpRequest" {
+				c.Header("WWW-Authenticate", `Basic realm="greenwake-bridge"`)
+			}
+			c.AbortWithStatusJSON(http.StatusUnauthorized, model.Response{
+				Success: false,
+				Error:   "unauthorized",
+			})
+			return
+		}
+
+		c.Set(principalKey, principal)
+		c.Next()
+	}
+}
+
+func (a *Authenticator) authenticate(c *gin.Context) *auth.Principal {
+	// 携带了 Bearer 令牌时只按令牌认证，匿名模式下也用于记录调用者
+	if header := c.GetHeader("Authorization"); strings.HasPrefix(header, "Bearer ") {
+		raw := strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))
+		if token, ok := a.tokens.Authenticate(raw); ok {
+			return &auth.Principal{Kind: auth.KindToken, Name: token.Name, Token: token}
+		}
+		log.Printf("令牌认证失败: IP=%s", c.ClientIP())
+		return nil
+	}
+
+	if a.cfg.HTTP.Anonymous {
+		return &auth.Principal{Kind: auth.KindAnonymous}
+	}
+
+	if cookie, err := c.Cookie(sessionCookieName); err == nil && cookie != "" {
+		if user, ok := a.sessions.Verify(cookie); ok && user == a.cfg.HTTP.User {
+			return &auth.Principal{Kind: auth.KindUser, Name: user}
+		}
+	}
+
+	if user, password, ok := c.Request.BasicAuth(); ok {
+		if a.checkCredentials(user, password) {
+			return &auth.Principal{Kind: auth.KindUser, Name: user}
+		}
+		log.Printf("Basic认证失败: 用户=%s, IP=%s", user, c.ClientIP())
+	}
+
+	return nil
+}
+
+func (a *Authenticator) checkCredentials(user, password string) bool {
+	return user == a.cfg.HTTP.User && auth.CheckPassword(a.cfg.HTTP.Password, password)
+}
+
+// ProxyCredentials 返回 SOCKS5/CONNECT 代理的认证函数，匿名模式下返回 nil 表示不需要认证
+// 可使用 http.user/http.password，或以任意用户名加 API 令牌作为密码
+func (a *Authenticator) ProxyCredentials() service.Credentials {
+	if a.cfg.HTTP.Anonymous {
+		return nil
+	}
+	return func(user, password string) *auth.Principal {
+		if a.checkCredentials(user, password) {
+			return &auth.Principal{Kind: auth.KindUser, Name: user}
+		}
+		if token, ok := a.tokens.Authenticate(password); ok {
+			return &auth.Principal{Kind: auth.KindToken, Name: token.Name, Token: token}
+		}
+		return nil
+	}
+}
+
+// RequireAction 校验令牌是否具有对路径中主机执行指定操作的权限
+func RequireAction(action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := principalFrom(c)
+		if !principal.Allows(c.Param("hostName"), action) {
+			log.Printf("拒绝越权操作: 调用者=%s, 主机=%s, 操作=%s", principal, c.Param("hostName"), action)
+			c.AbortWithStatusJSON(http.StatusForbidden, model.Response{
+				Success: false,
+				Error:   "forbidden",
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireAllHosts 校验令牌是否具有对所有主机执行指定操作的权限，限定了主机的令牌不能访问
+func RequireAllHosts(action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := principalFrom(c)
+		if !principal.AllowsAllHosts(action) {
+			log.Printf("拒绝越权操作: 调用者=%s, 路径=%s, 操作=%s（令牌限定了主机）", principal, c.Request.URL.Path, action)
+			c.AbortWithStatusJSON(http.StatusForbidden, model.Response{
+				Success: false,
+				Error:   "forbidden",
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireAdmin 只允许登录用户（或匿名模式）访问，令牌不能访问管理接口
+func RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !principalFrom(c).IsAdmin() {
+			c.AbortWithStatusJSON(http.StatusForbidden, model.Response{
+				Success: false,
+				Error:   "forbidden",
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+type loginRequest struct {
+	User     string `json:"user" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+func (a *Authenticator) Login(c *gin.Context) {
+	if a.cfg.HTTP.Anonymous {
+		c.JSON(http.StatusOK, model.Response{
+			Success: true,
+			Data:    gin.H{"user": "", "anonymous": true},
+		})
+		return
+	}
+
+	var req loginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if !a.checkCredentials(req.User, req.Password) {
+		log.Printf("登录失败: 用户=%s, IP=%s", req.User, c.ClientIP())
+		a.events.Publish(service.EventLogin, "", loginEvent(req.User, c.ClientIP(), false))
+		c.JSON(http.StatusUnauthorized, model.Response{
+			Success: false,
+			Error:   "用户名或密码错误",
+		})
+		return
+	}
+
+	log.Printf("登录成功: 用户=%s, IP=%s", req.User, c.ClientIP())
+	a.events.Publish(service.EventLogin, "", loginEvent(req.User, c.ClientIP(), true))
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(sessionCookieName, a.sessions.Issue(req.User), int(a.sessions.TTL().Seconds()), "/", "", c.Request.TLS != nil, true)
+	c.JSON(http.StatusOK, model.Response{
+		Success: true,
+		Data:    gin.H{"user": req.User, "anonymous": false},
+	})
+}
+
+// loginEvent 登录事件的内容，发起者为尝试登录的用户名
+func loginEvent(user, clientIP string, success bool) map[string]interface{} {
+	return map[string]interface{}{
+		"actor":    auth.KindUser + ":" + user,
+		"clientIp": clientIP,
+		"success":  success,
+	}
+}
+
+func (a *Authenticator) Logout(c *gin.Context) {
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(sessionCookieName, "", -1, "/", "", c.Request.TLS != nil, true)
+	c.JSON(http.StatusOK, model.Response{Success: true})
+}
+
+func (a *Authenticator) Me(c *gin.Context) {
+	principal := principalFrom(c)
+	c.JSON(http.StatusOK, model.Response{
+		Success: true,
+		Data: gin.H{
+			"user":      principal.Name,
+			"anonymous": principal.Kind == auth.KindAnonymous,
+			"token":     principal.Kind == auth.KindToken,
+		},
+	})
+}
+
+// principalFrom 获取当前请求的调用者
+func principalFrom(c *gin.Context) *auth.Principal {
+	if v, ok := c.Get(principalKey); ok {
+		if p, ok := v.(*auth.Principal); ok {
+			return p
+		}
+	}
+	return &auth.Principal{Kind: auth.KindAnonymous}
+}
@@ -0,0 +1,154 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"greenwake-bridge/internal/auth"
+	"greenwake-bridge/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testToken = "gwb_test-token"
+
+// newTestAuthenticator 创建用户为 admin/greenwake、令牌 ha 只能查看 desktop 状态的认证器
+func newTestAuthenticator(t *testing.T, anonymous bool) *Authenticator {
+	t.Helper()
+	hash, err := auth.HashPassword("greenwake")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := NewAuthenticator(&config.Config{
+		HTTP: config.HTTPConfig{User: "admin", Password: hash, Anonymous: anonymous, SessionTTL: 1},
+		Tokens: []config.TokenConfig{{
+			Name: "ha", Token: testToken, Hosts: []string{"desktop"}, Actions: []string{auth.ActionStatus},
+		}},
+		DataDir: t.TempDir(),
+	}, nil)
+	if err != nil {
+		t.Fatalf("创建认证器失败: %v", err)
+	}
+	return a
+}
+
+// whoami 经过认证中间件后返回调用者
+func whoami(a *Authenticator, setup func(r *http.Request)) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/whoami", a.Middleware(), func(c *gin.Context) {
+		c.String(http.StatusOK, principalFrom(c).String())
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+	setup(req)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuthenticatorMiddleware(t *testing.T) {
+	a := newTestAuthenticator(t, false)
+	session := a.sessions.Issue("admin")
+	otherSession := a.sessions.Issue("other")
+
+	tests := []struct {
+		name  string
+		setup func(r *http.Request)
+		want  string // 为空表示期望 401
+	}{
+		{"未认证", func(r *http.Request) {}, ""},
+		{"Basic认证", func(r *http.Request) { r.SetBasicAuth("admin", "greenwake") }, "user:admin"},
+		{"Basic密码错误", func(r *http.Request) { r.SetBasicAuth("admin", "wrong") }, ""},
+		{"Basic用户名错误", func(r *http.Request) { r.SetBasicAuth("root", "greenwake") }, ""},
+		{"会话Cookie", func(r *http.Request) { r.AddCookie(&http.Cookie{Name: sessionCookieName, Value: session}) }, "user:admin"},
+		{"其他用户的会话", func(r *http.Request) { r.AddCookie(&http.Cookie{Name: sessionCookieName, Value: otherSession}) }, ""},
+		{"伪造的会话", func(r *http.Request) { r.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "x.y"}) }, ""},
+		{"Bearer令牌", func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+testToken) }, "token:ha"},
+		{"Bearer令牌错误", func(r *http.Request) { r.Header.Set("Authorization", "Bearer gwb_wrong") }, ""},
+		{"携带令牌时不再使用会话", func(r *http.Request) {
+			r.Header.Set("Authorization", "Bearer gwb_wrong")
+			r.AddCookie(&http.Cookie{Name: sessionCookieName, Value: session})
+		}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := whoami(a, tt.setup)
+			if tt.want == "" {
+				if w.Code != http.StatusUnauthorized {
+					t.Fatalf("状态码为 %d，期望 401", w.Code)
+				}
+				if w.Header().Get("WWW-Authenticate") == "" {
+					t.Error("非网页请求应返回 WWW-Authenticate")
+				}
+				return
+			}
+			if w.Code != http.StatusOK || w.Body.String() != tt.want {
+				t.Errorf("状态码为 %d，调用者为 %q，期望 %q", w.Code, w.Body.String(), tt.want)
+			}
+		})
+	}
+
+	// 网页请求不返回 WWW-Authenticate，避免浏览器弹出登录框
+	w := whoami(a, func(r *http.Request) { r.Header.Set("X-Requested-With", "XMLHttpRequest") })
+	if w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") != "" {
+		t.Errorf("网页请求状态码为 %d，WWW-Authenticate 为 %q，期望 401 且不返回该头", w.Code, w.Header().Get("WWW-Authenticate"))
+	}
+}
+
+func TestAuthenticatorAnonymous(t *testing.T) {
+	a := newTestAuthenticator(t, true)
+
+	if w := whoami(a, func(r *http.Request) {}); w.Code != http.StatusOK || w.Body.String() != auth.KindAnonymous {
+		t.Errorf("匿名模式状态码为 %d，调用者为 %q，期望 anonymous", w.Code, w.Body.String())
+	}
+	// 匿名模式下携带令牌时按令牌记录调用者和权限
+	if w := whoami(a, func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+testToken) }); w.Body.String() != "token:ha" {
+		t.Errorf("匿名模式携带令牌时调用者为 %q，期望 token:ha", w.Body.String())
+	}
+	if w := whoami(a, func(r *http.Request) { r.Header.Set("Authorization", "Bearer gwb_wrong") }); w.Code != http.StatusUnauthorized {
+		t.Errorf("匿名模式携带错误令牌时状态码为 %d，期望 401", w.Code)
+	}
+
+	if a.ProxyCredentials() != nil {
+		t.Error("匿名模式下代理不需要认证")
+	}
+}
+
+func TestNewAuthenticatorRequiresCredentials(t *testing.T) {
+	_, err := NewAuthenticator(&config.Config{DataDir: t.TempDir()}, nil)
+	if err == nil {
+		t.Fatal("未配置用户名密码且未开启匿名模式时应返回错误")
+	}
+}
+
+func TestProxyCredentials(t *testing.T) {
+	check := newTestAuthenticator(t, false).ProxyCredentials()
+
+	tests := []struct {
+		name, user, password string
+		want                 string // 为空表示认证失败
+	}{
+		{"用户名密码", "admin", "greenwake", "user:admin"},
+		{"密码错误", "admin", "wrong", ""},
+		{"任意用户名加令牌", "anything", testToken, "token:ha"},
+		{"令牌错误", "anything", "gwb_wrong", ""},
+		{"空密码", "admin", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal := check(tt.user, tt.password)
+			if tt.want == "" {
+				if principal != nil {
+					t.Errorf("认证应失败，得到 %s", principal)
+				}
+				return
+			}
+			if principal == nil || principal.String() != tt.want {
+				t.Errorf("调用者为 %v，期望 %s", principal, tt.want)
+			}
+		})
+	}
+}
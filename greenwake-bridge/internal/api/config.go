@@ -0,0 +1,431 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"greenwake-bridge/internal/auth"
+	"greenwake-bridge/internal/config"
+	"greenwake-bridge/internal/model"
+	"greenwake-bridge/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// invalidConfigError 修改后的配置校验不通过
+type invalidConfigError struct {
+	err error
+}
+
+func (e *invalidConfigError) Error() string {
+	return e.err.Error()
+}
+
+// overrideConflictError 要修改的配置项有按下标设置的环境变量或 -set 覆盖
+type overrideConflictError struct {
+	section   string
+	overrides []config.Override
+}
+
+func (e *overrideConflictError) Error() string {
+	sources := make([]string, 0, len(e.overrides))
+	for _, o := range e.overrides {
+		sources = append(sources, o.Source)
+	}
+	return fmt.Sprintf("%s 有按下标设置的配置覆盖（%s），修改后下标对应的条目会改变，请先去掉这些覆盖或直接编辑配置文件",
+		e.section, strings.Join(sources, ", "))
+}
+
+// editConfig 修改配置文件中 section 的条目并立即生效，修改后的配置校验不通过时不写入文件
+func (s *Server) editConfig(section string, edit func(doc *config.Document) error) error {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	if overrides := s.cfg.IndexedOverrides(section); len(overrides) > 0 {
+		return &overrideConflictError{section: section, overrides: overrides}
+	}
+
+	doc, err := config.OpenDocument(s.cfg.Path())
+	if err != nil {
+		return fmt.Errorf("读取配置失败: %v", err)
+	}
+	if err := edit(doc); err != nil {
+		return err
+	}
+
+	cfg, err := config.Parse(doc.Bytes(), s.cfg.Path(), s.cfg.Overrides()...)
+	if err != nil {
+		return &invalidConfigError{err: err}
+	}
+	if err := cfg.Validate(); err != nil {
+		return &invalidConfigError{err: err}
+	}
+
+	if err := doc.Save(); err != nil {
+		return err
+	}
+	s.apply(cfg, doc.Bytes())
+	return nil
+}
+
+// writeConfigError 按错误类型返回 404、400、409 或 500
+func writeConfigError(c *gin.Context, err error) {
+	var invalid *invalidConfigError
+	var conflict *overrideConflictError
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, config.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.As(err, &invalid):
+		status = http.StatusBadRequest
+	case errors.As(err, &conflict):
+		status = http.StatusConflict
+	}
+	c.JSON(status, model.Response{
+		Success: false,
+		Error:   err.Error(),
+	})
+}
+
+// ListHostConfigs 获取配置文件中的主机配置
+func (s *Server) ListHostConfigs(c *gin.Context) {
+	doc, err := config.OpenDocument(s.cfg.Path())
+	if err != nil {
+		writeConfigError(c, err)
+		return
+	}
+	hosts, err := doc.Hosts()
+	if err != nil {
+		writeConfigError(c, err)
+		return
+	}
+	redacted := make([]config.PCHostConfig, 0, len(hosts))
+	for _, host := range hosts {
+		redacted = append(redacted, host.Redacted())
+	}
+	c.JSON(http.StatusOK, model.Response{
+		Success: true,
+		Data:    redacted,
+	})
+}
+
+// CreateHost 新增主机
+func (s *Server) CreateHost(c *gin.Context) {
+	s.saveHost(c, "")
+}
+
+// UpdateHost 替换主机配置，可以修改主机名
+func (s *Server) UpdateHost(c *gin.Context) {
+	s.saveHost(c, c.Param("hostName"))
+}
+
+func (s *Server) saveHost(c *gin.Context, name string) {
+	var host config.PCHostConfig
+	if err := c.ShouldBindJSON(&host); err != nil || host.Name == "" {
+		c.JSON(http.StatusBadRequest, model.Response{
+			Success: false,
+			Error:   "请求格式错误，name 不能为空",
+		})
+		return
+	}
+
+	// 未填写 MAC 时使用主机上已注册的 guard 上报的网卡地址
+	if host.MAC == "" {
+		if mac := s.handler.pcService.Guards().MAC(host.Name, host.IP); mac != "" {
+			log.Printf("主机 %s 使用 guard 上报的 MAC 地址: %s", host.Name, mac)
+			host.MAC = mac
+		}
+	}
+
+	var before *config.PCHostConfig
+	err := s.editConfig("hosts", func(doc *config.Document) error {
+		if name != "" {
+			hosts, err := doc.Hosts()
+			if err != nil {
+				return err
+			}
+			for i := range hosts {
+				if hosts[i].Name == name {
+					before = &hosts[i]
+				}
+			}
+			// 读取到的是隐藏后的配置，未修改的密码和密钥保留原值
+			if before != nil {
+				host.RestoreRedacted(*before)
+			}
+		}
+		return doc.SetHost(name, host)
+	})
+	if err != nil {
+		writeConfigError(c, err)
+		return
+	}
+
+	action := "host.create"
+	if name != "" {
+		action = "host.update"
+	}
+	redacted := host.Redacted()
+	s.audit.Record(principalFrom(c).String(), action, host.Name, host.Name, redactedHost(before), redacted)
+	c.JSON(http.StatusOK, model.Response{
+		Success: true,
+		Data:    redacted,
+	})
+}
+
+// DeleteHost 删除主机，主机仍被转发或反向代理引用时拒绝删除
+func (s *Server) DeleteHost(c *gin.Context) {
+	name := c.Param("hostName")
+
+	var before *config.PCHostConfig
+	err := s.editConfig("hosts", func(doc *config.Document) error {
+		hosts, err := doc.Hosts()
+		if err != nil {
+			return err
+		}
+		for i := range hosts {
+			if hosts[i].Name == name {
+				before = &hosts[i]
+			}
+		}
+		return doc.DeleteHost(name)
+	})
+	if err != nil {
+		writeConfigError(c, err)
+		return
+	}
+
+	s.audit.Record(principalFrom(c).String(), "host.delete", name, name, redactedHost(before), nil)
+	c.JSON(http.StatusOK, model.Response{Success: true})
+}
+
+// ListForwardConfigs 获取配置文件中调用者可访问主机的转发配置
+func (s *Server) ListForwardConfigs(c *gin.Context) {
+	doc, err := config.OpenDocument(s.cfg.Path())
+	if err != nil {
+		writeConfigError(c, err)
+		return
+	}
+	forwards, err := doc.Forwards()
+	if err != nil {
+		writeConfigError(c, err)
+		return
+	}
+
+	principal := principalFrom(c)
+	result := make([]config.ForwardConfig, 0, len(forwards))
+	for _, fc := range forwards {
+		if principal.Allows(fc.TargetHost, auth.ActionForwards) {
+			result = append(result, fc)
+		}
+	}
+	c.JSON(http.StatusOK, model.Response{
+		Success: true,
+		Data:    result,
+	})
+}
+
+// CreateForward 新增端口转发
+func (s *Server) CreateForward(c *gin.Context) {
+	s.saveForward(c, 0, "")
+}
+
+// UpdateForward 替换端口转发配置，路径参数为监听端口，UDP 转发需指定 ?protocol=udp
+func (s *Server) UpdateForward(c *gin.Context) {
+	port, protocol, ok := forwardKey(c)
+	if !ok {
+		return
+	}
+	s.saveForward(c, port, protocol)
+}
+
+func (s *Server) saveForward(c *gin.Context, port int, protocol string) {
+	var fc config.ForwardConfig
+	if err := c.ShouldBindJSON(&fc); err != nil {
+		c.JSON(http.StatusBadRequest, model.Response{
+			Success: false,
+			Error:   "请求格式错误",
+		})
+		return
+	}
+
+	principal := principalFrom(c)
+	if !principal.Allows(fc.TargetHost, auth.ActionForwards) {
+		forbidden(c, principal, fc.TargetHost)
+		return
+	}
+
+	var before *config.ForwardConfig
+	err := s.editConfig("forwards", func(doc *config.Document) error {
+		if port != 0 {
+			before = findForward(doc, port, protocol)
+			if before != nil && !principal.Allows(before.TargetHost, auth.ActionForwards) {
+				return errForbidden
+			}
+		}
+		return doc.SetForward(port, protocol, fc)
+	})
+	if errors.Is(err, errForbidden) {
+		forbidden(c, principal, before.TargetHost)
+		return
+	}
+	if err != nil {
+		writeConfigError(c, err)
+		return
+	}
+
+	action := "forward.create"
+	if port != 0 {
+		action = "forward.update"
+	}
+	var auditBefore interface{}
+	if before != nil {
+		auditBefore = before
+	}
+	s.audit.Record(principal.String(), action, forwardTarget(fc.ServicePort, fc.Protocol), fc.TargetHost, auditBefore, fc)
+	c.JSON(http.StatusOK, model.Response{
+		Success: true,
+		Data:    fc,
+	})
+}
+
+// DeleteForward 删除端口转发，已建立的连接继续转发直到结束
+func (s *Server) DeleteForward(c *gin.Context) {
+	port, protocol, ok := forwardKey(c)
+	if !ok {
+		return
+	}
+
+	principal := principalFrom(c)
+	var before *config.ForwardConfig
+	err := s.editConfig("forwards", func(doc *config.Document) error {
+		before = findForward(doc, port, protocol)
+		if before != nil && !principal.Allows(before.TargetHost, auth.ActionForwards) {
+			return errForbidden
+		}
+		return doc.DeleteForward(port, protocol)
+	})
+	if errors.Is(err, errForbidden) {
+		forbidden(c, principal, before.TargetHost)
+		return
+	}
+	if err != nil {
+		writeConfigError(c, err)
+		return
+	}
+
+	s.audit.Record(principal.String(), "forward.delete", forwardTarget(port, protocol), before.TargetHost, before, nil)
+	c.JSON(http.StatusOK, model.Response{Success: true})
+}
+
+// redactedHost 审计记录中隐藏敏感配置项的主机配置，新增主机时返回 nil 而不是带类型的空指针
+func redactedHost(host *config.PCHostConfig) interface{} {
+	if host == nil {
+		return nil
+	}
+	return host.Redacted()
+}
+
+var errForbidden = errors.New("forbidden")
+
+func forbidden(c *gin.Context, principal *auth.Principal, host string) {
+	log.Printf("拒绝越权操作: 调用者=%s, 主机=%s, 操作=%s", principal, host, auth.ActionForwards)
+	c.JSON(http.StatusForbidden, model.Response{
+		Success: false,
+		Error:   "forbidden",
+	})
+}
+
+// forwardKey 解析路径中的监听端口和查询参数中的协议
+func forwardKey(c *gin.Context) (int, string, bool) {
+	port, err := strconv.Atoi(c.Param("port"))
+	protocol := c.DefaultQuery("protocol", config.ProtocolTCP)
+	if err != nil || port <= 0 || (protocol != config.ProtocolTCP && protocol != config.ProtocolUDP) {
+		c.JSON(http.StatusBadRequest, model.Response{
+			Success: false,
+			Error:   "端口或协议无效",
+		})
+		return 0, "", false
+	}
+	return port, protocol, true
+}
+
+func findForward(doc *config.Document, port int, protocol string) *config.ForwardConfig {
+	forwards, err := doc.Forwards()
+	if err != nil {
+		return nil
+	}
+	for i := range forwards {
+		p := forwards[i].Protocol
+		if p == "" {
+			p = config.ProtocolTCP
+		}
+		if forwards[i].ServicePort == port && p == protocol {
+			return &forwards[i]
+		}
+	}
+	return nil
+}
+
+// forwardTarget 审计记录中的转发标识，如 13389/tcp
+func forwardTarget(port int, protocol string) string {
+	if protocol == "" {
+		protocol = config.ProtocolTCP
+	}
+	return fmt.Sprintf("%d/%s", port, protocol)
+}
+
+// GetAudit 查询配置修改审计记录
+// 参数: action, target, from, to（RFC3339）, offset, limit
+func (s *Server) GetAudit(c *gin.Context) {
+	filter := service.AuditFilter{
+		Action: c.Query("action"),
+		Target: c.Query("target"),
+		Limit:  defaultSessionLimit,
+	}
+
+	for name, dst := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		if v := c.Query(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, model.Response{
+					Success: false,
+					Error:   name + " 格式错误: " + err.Error(),
+				})
+				return
+			}
+			*dst = t
+		}
+	}
+
+	for name, dst := range map[string]*int{"offset": &filter.Offset, "limit": &filter.Limit} {
+		if v := c.Query(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				c.JSON(http.StatusBadRequest, model.Response{
+					Success: false,
+					Error:   name + " 必须是非负整数",
+				})
+				return
+			}
+			*dst = n
+		}
+	}
+	if filter.Limit == 0 || filter.Limit > maxSessionLimit {
+		filter.Limit = maxSessionLimit
+	}
+
+	entries, total := s.audit.Query(filter)
+	c.JSON(http.StatusOK, model.Response{
+		Success: true,
+		Data: gin.H{
+			"total":   total,
+			"entries": entries,
+		},
+	})
+}
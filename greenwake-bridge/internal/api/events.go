@@ -0,0 +1,131 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"greenwake-bridge/internal/auth"
+	"greenwake-bridge/internal/model"
+	"greenwake-bridge/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+const eventKeepAlive = 15 * time.Second // SSE 心跳间隔，避免代理关闭空闲连接
+
+// StreamEvents 以 SSE 推送主机状态、唤醒、转发会话和保持唤醒租约的变化，只包含调用者可查看状态的主机
+// 参数: host（只推送指定主机的事件）；断线重连时携带 Last-Event-ID 可补发错过的事件
+func (h *Handler) StreamEvents(c *gin.Context) {
+	lastID, _ := strconv.ParseInt(c.GetHeader("Last-Event-ID"), 10, 64)
+	hostName := c.Query("host")
+	principal := principalFrom(c)
+
+	sub := h.pcService.Events().Subscribe(lastID)
+	defer sub.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no") // 关闭 nginx 的响应缓冲
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(eventKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(c.Writer, ": keep-alive\n\n")
+		case ev, ok := <-sub.C:
+			if !ok {
+				// 消费过慢被断开，客户端重连后补发
+				return
+			}
+			if hostName != "" && ev.Host != hostName {
+				continue
+			}
+			if !eventVisible(principal, ev.Host) {
+				continue
+			}
+			data, err := json.Marshal(ev)
+			if err != nil {
+				continue
+			}
+			// 只推送不保存的事件没有事件ID，不改变客户端重连时携带的 Last-Event-ID
+			if ev.ID > 0 {
+				fmt.Fprintf(c.Writer, "id: %d\n", ev.ID)
+			}
+			fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", ev.Type, data)
+		}
+		c.Writer.Flush()
+	}
+}
+
+// eventVisible 调用者是否可以查看事件，不属于主机的事件（如登录、未匹配主机的 guard）只有管理员可以查看
+func eventVisible(principal *auth.Principal, host string) bool {
+	if host == "" {
+		return principal.IsAdmin()
+	}
+	return principal.Allows(host, auth.ActionStatus)
+}
+
+// GetEvents 查询保存的事件记录，只包含调用者可查看状态的主机
+// 参数: host, type（逗号分隔，可以是类型前缀，如 wake）, actor（发起者或其前缀，如 forward）, from, to（RFC3339）, offset, limit
+func (h *Handler) GetEvents(c *gin.Context) {
+	principal := principalFrom(c)
+	filter := service.EventFilter{
+		Host:  c.Query("host"),
+		Actor: c.Query("actor"),
+		Allow: func(host string) bool { return eventVisible(principal, host) },
+		Limit: defaultSessionLimit,
+	}
+	if v := c.Query("type"); v != "" {
+		filter.Types = strings.Split(v, ",")
+	}
+
+	for name, dst := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		if v := c.Query(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, model.Response{
+					Success: false,
+					Error:   name + " 格式错误: " + err.Error(),
+				})
+				return
+			}
+			*dst = t
+		}
+	}
+
+	for name, dst := range map[string]*int{"offset": &filter.Offset, "limit": &filter.Limit} {
+		if v := c.Query(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				c.JSON(http.StatusBadRequest, model.Response{
+					Success: false,
+					Error:   name + " 必须是非负整数",
+				})
+				return
+			}
+			*dst = n
+		}
+	}
+	if filter.Limit == 0 || filter.Limit > maxSessionLimit {
+		filter.Limit = maxSessionLimit
+	}
+
+	events, total := h.pcService.Events().Query(filter)
+	c.JSON(http.StatusOK, model.Response{
+		Success: true,
+		Data: gin.H{
+			"total":  total,
+			"events": events,
+		},
+	})
+}
@@ -0,0 +1,218 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"greenwake-bridge/internal/auth"
+	"greenwake-bridge/internal/config"
+	"greenwake-bridge/internal/model"
+	"greenwake-bridge/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestEventVisible(t *testing.T) {
+	user := &auth.Principal{Kind: auth.KindUser, Name: "admin"}
+	anonymous := &auth.Principal{Kind: auth.KindAnonymous}
+	status := &auth.Principal{Kind: auth.KindToken, Name: "ha", Token: &auth.Token{
+		Hosts:   []string{"desktop"},
+		Actions: []string{auth.ActionStatus},
+	}}
+	wakeOnly := &auth.Principal{Kind: auth.KindToken, Name: "wake", Token: &auth.Token{
+		Hosts:   []string{"*"},
+		Actions: []string{auth.ActionWake},
+	}}
+
+	tests := []struct {
+		name      string
+		principal *auth.Principal
+		host      string
+		want      bool
+	}{
+		{"用户查看主机事件", user, "desktop", true},
+		{"用户查看不属于主机的事件", user, "", true},
+		{"匿名模式查看不属于主机的事件", anonymous, "", true},
+		{"令牌查看授权主机", status, "desktop", true},
+		{"令牌查看未授权主机", status, "nas", false},
+		{"令牌查看不属于主机的事件", status, "", false},
+		{"令牌没有 status 权限", wakeOnly, "desktop", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := eventVisible(tt.principal, tt.host); got != tt.want {
+				t.Errorf("eventVisible(%s, %q) = %v，期望 %v", tt.principal, tt.host, got, tt.want)
+			}
+		})
+	}
+}
+
+// sseEvent 解析后的 SSE 事件
+type sseEvent struct {
+	id    string
+	event string
+	host  string
+}
+
+// openEventStream 以 principal 连接事件流，返回解析后的事件，连接在测试结束时关闭
+func openEventStream(t *testing.T, h *Handler, principal *auth.Principal, query, lastID string) <-chan sseEvent {
+	t.Helper()
+	r := gin.New()
+	r.GET("/events/stream", withPrincipal(principal), h.StreamEvents)
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/events/stream"+query, nil)
+	if lastID != "" {
+		req.Header.Set("Last-Event-ID", lastID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("连接事件流失败: %v", err)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type 为 %s，期望 text/event-stream", ct)
+	}
+
+	events := make(chan sseEvent, 16)
+	go func() {
+		defer resp.Body.Close()
+		defer close(events)
+		var ev sseEvent
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case strings.HasPrefix(line, "id: "):
+				ev.id = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "event: "):
+				ev.event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				var data model.Event
+				json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &data)
+				ev.host = data.Host
+			case line == "" && ev.event != "":
+				events <- ev
+				ev = sseEvent{}
+			}
+		}
+	}()
+	return events
+}
+
+// readUntil 读取事件直到收到 last 类型的事件（不包含在结果中）
+func readUntil(t *testing.T, events <-chan sseEvent, last string) []sseEvent {
+	t.Helper()
+	var got []sseEvent
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				t.Fatalf("事件流已关闭，收到 %v", got)
+			}
+			if ev.event == last {
+				return got
+			}
+			got = append(got, ev)
+		case <-timeout:
+			t.Fatalf("等待 %s 超时，收到 %v", last, got)
+		}
+	}
+}
+
+func TestStreamEvents(t *testing.T) {
+	user := &auth.Principal{Kind: auth.KindUser, Name: "admin"}
+	status := &auth.Principal{Kind: auth.KindToken, Name: "ha", Token: &auth.Token{
+		Hosts:   []string{"desktop"},
+		Actions: []string{auth.ActionStatus},
+	}}
+
+	tests := []struct {
+		name      string
+		principal *auth.Principal
+		query     string
+		want      []sseEvent
+	}{
+		{"用户接收全部事件", user, "", []sseEvent{
+			{"1", service.EventWakeSent, "desktop"},
+			{"2", service.EventWakeSent, "nas"},
+			{"3", service.EventLogin, ""},
+			{"", service.EventLeaseRenewed, "desktop"},
+		}},
+		{"只接收指定主机的事件", user, "?host=nas", []sseEvent{
+			{"2", service.EventWakeSent, "nas"},
+		}},
+		{"令牌只接收授权主机的事件", status, "", []sseEvent{
+			{"1", service.EventWakeSent, "desktop"},
+			{"", service.EventLeaseRenewed, "desktop"},
+		}},
+	}
+
+	gin.SetMode(gin.TestMode)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{DataDir: t.TempDir()}
+			pcService, err := service.NewPCService(cfg)
+			if err != nil {
+				t.Fatalf("创建主机服务失败: %v", err)
+			}
+			defer pcService.Close()
+			h := NewHandler(pcService, nil, nil, nil, cfg)
+
+			events := openEventStream(t, h, tt.principal, tt.query, "")
+			bus := pcService.Events()
+			bus.Publish(service.EventWakeSent, "desktop", nil)
+			bus.Publish(service.EventWakeSent, "nas", nil)
+			bus.Publish(service.EventLogin, "", nil)
+			bus.Publish(service.EventLeaseRenewed, "desktop", nil)
+			// 结束标记，每种情况都能收到其中一个
+			bus.Publish(service.EventConfigChanged, "desktop", nil)
+			bus.Publish(service.EventConfigChanged, "nas", nil)
+
+			if got := readUntil(t, events, service.EventConfigChanged); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("收到的事件为 %v，期望 %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStreamEventsReplay(t *testing.T) {
+	cfg := &config.Config{DataDir: t.TempDir()}
+	pcService, err := service.NewPCService(cfg)
+	if err != nil {
+		t.Fatalf("创建主机服务失败: %v", err)
+	}
+	defer pcService.Close()
+	h := NewHandler(pcService, nil, nil, nil, cfg)
+
+	bus := pcService.Events()
+	bus.Publish(service.EventWakeSent, "desktop", nil)
+	bus.Publish(service.EventWakeSent, "nas", nil)
+	bus.Publish(service.EventHostState, "desktop", nil)
+	bus.Publish(service.EventLeaseRenewed, "desktop", nil)
+
+	// 重连时补发 Last-Event-ID 之后的事件，同样按调用者的权限过滤，只推送的事件不补发
+	status := &auth.Principal{Kind: auth.KindToken, Name: "ha", Token: &auth.Token{
+		Hosts:   []string{"desktop"},
+		Actions: []string{auth.ActionStatus},
+	}}
+	gin.SetMode(gin.TestMode)
+	events := openEventStream(t, h, status, "", "1")
+	bus.Publish(service.EventConfigChanged, "desktop", nil)
+
+	want := []sseEvent{{"3", service.EventHostState, "desktop"}}
+	if got := readUntil(t, events, service.EventConfigChanged); !reflect.DeepEqual(got, want) {
+		t.Errorf("补发的事件为 %v，期望 %v", got, want)
+	}
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+
+	"greenwake-bridge/internal/auth"
+	"greenwake-bridge/internal/guard"
+	"greenwake-bridge/internal/model"
+	"greenwake-bridge/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxGuardBody = 64 << 10
+
+// RegisterGuard 接收 guard 注册，返回匹配的主机和心跳间隔
+func (h *Handler) RegisterGuard(c *gin.Context) {
+	hb, ok := h.readHeartbeat(c, guard.RegisterPath)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, model.Response{
+		Success: true,
+		Data:    h.pcService.Guards().Register(hb, remoteHost(c)),
+	})
+}
+
+// GuardHeartbeat 接收 guard 心跳，guard 未注册时返回404，guard 收到后重新注册
+func (h *Handler) GuardHeartbeat(c *gin.Context) {
+	hb, ok := h.readHeartbeat(c, guard.HeartbeatPath)
+	if !ok {
+		return
+	}
+	host, err := h.pcService.Guards().Heartbeat(hb, remoteHost(c))
+	if errors.Is(err, service.ErrGuardNotRegistered) {
+		writeGuardError(c, http.StatusNotFound, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, model.Response{
+		Success: true,
+		Data:    gin.H{"host": host},
+	})
+}
+
+func writeGuardError(c *gin.Context, status int, message string) {
+	c.JSON(status, model.Response{
+		Success: false,
+		Error:   message,
+	})
+}
+
+// readHeartbeat 读取并校验 guard 的签名请求，失败时写入错误响应
+func (h *Handler) readHeartbeat(c *gin.Context, path string) (guard.Heartbeat, bool) {
+	var hb guard.Heartbeat
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxGuardBody))
+	if err != nil {
+		writeGuardError(c, http.StatusBadRequest, "读取请求失败")
+		return hb, false
+	}
+
+	if err := h.pcService.Guards().Verify(c.Request.Method, path, c.Request.Header, body); err != nil {
+		status := http.StatusUnauthorized
+		if errors.Is(err, service.ErrGuardsDisabled) {
+			status = http.StatusForbidden
+		}
+		log.Printf("拒绝 guard 请求: %s, 来源: %s, 原因: %v", path, c.ClientIP(), err)
+		writeGuardError(c, status, err.Error())
+		return hb, false
+	}
+
+	if err := json.Unmarshal(body, &hb); err != nil || hb.Hostname == "" {
+		writeGuardError(c, http.StatusBadRequest, "请求格式错误，hostname 不能为空")
+		return hb, false
+	}
+	if hb.Version != guard.ProtocolVersion {
+		writeGuardError(c, http.StatusBadRequest, fmt.Sprintf("不支持的协议版本 %d，bridge 支持版本 %d", hb.Version, guard.ProtocolVersion))
+		return hb, false
+	}
+	return hb, true
+}
+
+// remoteHost 返回请求的来源地址，不使用代理请求头，guard 与 bridge 直接连接
+func remoteHost(c *gin.Context) string {
+	host, _, err := net.SplitHostPort(c.Request.RemoteAddr)
+	if err != nil {
+		return c.Request.RemoteAddr
+	}
+	return host
+}
+
+// GetGuards 获取已注册的 guard，管理员可查看全部（包括未匹配主机的），其他调用者只能查看可访问主机上的
+func (h *Handler) GetGuards(c *gin.Context) {
+	principal := principalFrom(c)
+	guards := make([]*model.GuardStatus, 0)
+	for _, g := range h.pcService.Guards().GetGuards() {
+		if principal.IsAdmin() || (g.Host != "" && principal.Allows(g.Host, auth.ActionStatus)) {
+			guards = append(guards, g)
+		}
+	}
+	c.JSON(http.StatusOK, model.Response{
+		Success: true,
+		Data:    guards,
+	})
+}
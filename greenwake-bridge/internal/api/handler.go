@@ -1,55 +1,65 @@
 package api
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"sync"
 
 	"greenwake-bridge/internal/model"
 	"greenwake-bridge/internal/service"
 
-	"crypto/md5"
-	"encoding/hex"
+	"log"
+	"time"
 
+	"greenwake-bridge/internal/auth"
 	"greenwake-bridge/internal/config"
+	"greenwake-bridge/internal/guard"
 
 	"github.com/gin-gonic/gin"
 )
 
+const (
+	defaultKeepAwakeTTL   = 10 * time.Minute
+	maxKeepAwakeTTL       = 24 * time.Hour
+	defaultWaitOnlineTime = 60 * time.Second
+	maxWaitOnlineTime     = 10 * time.Minute
+)
+
 type Handler struct {
-	pcService      *service.PCService
-	clientService  *service.ClientService
-	forwardService *service.ForwardService
-	config         *config.Config
+	pcService        *service.PCService
+	forwardService   *service.ForwardService
+	keepAwakeService *service.KeepAwakeService
+	sessionService   *service.SessionService
+	mu               sync.RWMutex // 保护 config，配置重新加载时会替换
+	config           *config.Config
 }
 
-func NewHandler(pcService *service.PCService, clientService *service.ClientService, forwardService *service.ForwardService, config *config.Config) *Handler {
+func NewHandler(pcService *service.PCService, forwardService *service.ForwardService, keepAwakeService *service.KeepAwakeService, sessionService *service.SessionService, config *config.Config) *Handler {
 	return &Handler{
-		pcService:      pcService,
-		clientService:  clientService,
-		forwardService: forwardService,
-		config:         config,
+		pcService:        pcService,
+		forwardService:   forwardService,
+		keepAwakeService: keepAwakeService,
+		sessionService:   sessionService,
+		config:           config,
 	}
 }
 
-func (h *Handler) generateClientID(c *gin.Context) string {
-	// 获取各种可用信息
-	ip := c.ClientIP()
-	userAgent := c.GetHeader("User-Agent")
-	pageID := c.GetHeader("X-Page-ID")
-	// X-Forwarded-For 可能包含多个IP，取最后一个
-	forwardedFor := c.GetHeader("X-Forwarded-For")
-	// X-Real-IP 通常是真实客户端IP
-	realIP := c.GetHeader("X-Real-IP")
-
-	// 组合所有信息生成唯一ID（不包含端口）
-	idStr := fmt.Sprintf("%s|%s|%s|%s|%s", ip, pageID, forwardedFor, realIP, userAgent)
-	// 使用 MD5 生成固定长度的 ID
-	hash := md5.Sum([]byte(idStr))
-	return hex.EncodeToString(hash[:])
+// UpdateConfig 替换重新加载后的配置
+func (h *Handler) UpdateConfig(cfg *config.Config) {
+	h.mu.Lock()
+	h.config = cfg
+	h.mu.Unlock()
 }
 
 func (h *Handler) GetHosts(c *gin.Context) {
-	hosts := h.pcService.GetHosts()
+	principal := principalFrom(c)
+	hosts := make([]*model.PCHostInfo, 0)
+	for _, host := range h.pcService.GetHosts() {
+		if principal.Allows(host.Name, auth.ActionStatus) {
+			hosts = append(hosts, host)
+		}
+	}
 	c.JSON(http.StatusOK, model.Response{
 		Success: true,
 		Data:    hosts,
@@ -58,21 +68,8 @@ func (h *Handler) GetHosts(c *gin.Context) {
 
 func (h *Handler) GetHostStatus(c *gin.Context) {
 	hostName := c.Param("hostName")
-	keepAwake := c.Query("keepAwake") == "true"
-
-	// 只有在保持唤醒的请求中才更新客户端状态
-	if keepAwake {
-		clientId := h.generateClientID(c)
-		h.clientService.UpdateClient(
-			clientId,
-			c.GetHeader("User-Agent"),
-			c.ClientIP(),
-			"",
-			hostName,
-		)
-	}
 
-	status, err := h.pcService.GetHostStatus(hostName, keepAwake)
+	status, err := h.pcService.GetHostStatus(hostName)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, model.Response{
 			Success: false,
@@ -80,6 +77,7 @@ func (h *Handler) GetHostStatus(c *gin.Context) {
 		})
 		return
 	}
+	status.KeepAwake = h.keepAwakeService.IsKeptAwake(hostName)
 
 	c.JSON(http.StatusOK, model.Response{
 		Success: true,
@@ -87,12 +85,358 @@ func (h *Handler) GetHostStatus(c *gin.Context) {
 	})
 }
 
-func (h *Handler) GetHostClients(c *gin.Context) {
+// WakeHost 发送一次唤醒包
+func (h *Handler) WakeHost(c *gin.Context) {
+	hostName := c.Param("hostName")
+	if err := h.pcService.Wake(c.Request.Context(), hostName, principalFrom(c).String()); err != nil {
+		c.JSON(http.StatusInternalServerError, model.Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, model.Response{
+		Success: true,
+		Data: gin.H{
+			"name":   hostName,
+			"sentAt": time.Now().Format(time.RFC3339),
+		},
+	})
+}
+
+type sleepRequest struct {
+	Action string `json:"action"` // sleep（默认）、shutdown 或 sleep_when_idle
+	Force  bool   `json:"force"`  // 有活动的转发会话或保持唤醒租约时仍然执行，并结束这些租约
+}
+
+// SleepHost 通过主机上的 guard 让主机睡眠或关机
+// 有活动的转发会话或保持唤醒租约时返回 409，除非指定 force
+func (h *Handler) SleepHost(c *gin.Context) {
+	hostName := c.Param("hostName")
+
+	var req sleepRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, model.Response{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+	}
+	if req.Action == "" {
+		req.Action = guard.SleepNow
+	}
+	if req.Action != guard.SleepNow && req.Action != guard.SleepShutdown && req.Action != guard.SleepWhenIdle {
+		c.JSON(http.StatusBadRequest, model.Response{
+			Success: false,
+			Error:   "未知的操作: " + req.Action,
+		})
+		return
+	}
+
+	sessions := h.forwardService.HostActiveSessions(hostName)
+	leases := h.keepAwakeService.GetHostLeases(hostName)
+	if (sessions > 0 || len(leases) > 0) && !req.Force {
+		c.JSON(http.StatusConflict, model.Response{
+			Success: false,
+			Error:   fmt.Sprintf("主机正在使用中: %d 个活动会话, %d 个保持唤醒租约", sessions, len(leases)),
+			Data: gin.H{
+				"activeSessions": sessions,
+				"leases":         leases,
+			},
+		})
+		return
+	}
+
+	actor := principalFrom(c).String()
+	resp, err := h.pcService.Sleep(c.Request.Context(), hostName, req.Action, actor)
+	if err != nil {
+		status := http.StatusBadGateway
+		switch {
+		case errors.Is(err, service.ErrHostNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, service.ErrNoGuard):
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, model.Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	// 强制睡眠时结束租约并停止转发通道保持唤醒，否则主机睡眠后会被立即唤醒
+	if req.Force {
+		h.pcService.ForceSleep(hostName)
+		for _, lease := range leases {
+			if err := h.keepAwakeService.AdminRelease(hostName, lease.ID); err == nil {
+				log.Printf("强制睡眠，结束保持唤醒租约: %s, 主机: %s, 操作者: %s", lease.ID, hostName, actor)
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, model.Response{
+		Success: true,
+		Data: gin.H{
+			"name":      hostName,
+			"action":    resp.Action,
+			"delaySecs": resp.DelaySecs,
+			"sentAt":    time.Now().Format(time.RFC3339),
+		},
+	})
+}
+
+type keepAwakeRequest struct {
+	TTL    int    `json:"ttl"`    // 租约有效期（秒），默认600秒
+	Reason string `json:"reason"` // 保持唤醒的原因
+	EndAt  string `json:"endAt"`  // 最晚结束时间（RFC3339），续期不会超过该时间
+}
+
+// keepAwakeTTL 计算租约有效期，未指定时使用默认值
+func keepAwakeTTL(seconds int) time.Duration {
+	ttl := defaultKeepAwakeTTL
+	if seconds > 0 {
+		ttl = time.Duration(seconds) * time.Second
+	}
+	if ttl > maxKeepAwakeTTL {
+		ttl = maxKeepAwakeTTL
+	}
+	return ttl
+}
+
+// StartKeepAwake 创建保持唤醒租约，租约有效期间主机保持唤醒
+func (h *Handler) StartKeepAwake(c *gin.Context) {
+	hostName := c.Param("hostName")
+
+	var req keepAwakeRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, model.Response{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+	}
+
+	var endAt time.Time
+	if req.EndAt != "" {
+		t, err := time.Parse(time.RFC3339, req.EndAt)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.Response{
+				Success: false,
+				Error:   "endAt 格式错误: " + err.Error(),
+			})
+			return
+		}
+		endAt = t
+	}
+
+	lease, err := h.keepAwakeService.Acquire(hostName, service.LeaseRequest{
+		Owner:     principalFrom(c).String(),
+		Reason:    req.Reason,
+		ClientIP:  c.ClientIP(),
+		UserAgent: c.GetHeader("User-Agent"),
+		TTL:       keepAwakeTTL(req.TTL),
+		EndAt:     endAt,
+	})
+	if err != nil {
+		c.JSON(http.StatusNotFound, model.Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, model.Response{
+		Success: true,
+		Data:    lease,
+	})
+}
+
+// renewLease 管理员可以续期任意租约，其他调用者只能续期自己创建的租约
+func (h *Handler) renewLease(principal *auth.Principal, hostName, leaseId string, ttl time.Duration) (*model.KeepAwakeLease, error) {
+	if principal.IsAdmin() {
+		return h.keepAwakeService.AdminRenew(hostName, leaseId, ttl)
+	}
+	return h.keepAwakeService.Renew(hostName, leaseId, principal.String(), ttl)
+}
+
+// releaseLease 管理员可以结束任意租约，其他调用者只能结束自己创建的租约
+func (h *Handler) releaseLease(principal *auth.Principal, hostName, leaseId string) error {
+	if principal.IsAdmin() {
+		return h.keepAwakeService.AdminRelease(hostName, leaseId)
+	}
+	return h.keepAwakeService.Release(hostName, leaseId, principal.String())
+}
+
+// leaseErrorStatus 租约属于其他调用者时返回 403，否则为租约不存在
+func leaseErrorStatus(err error) int {
+	if errors.Is(err, service.ErrLeaseNotOwned) {
+		return http.StatusForbidden
+	}
+	return http.StatusNotFound
+}
+
+type renewLeaseRequest struct {
+	TTL int `json:"ttl"` // 新的有效期（秒），为0时沿用原有效期
+}
+
+// RenewKeepAwake 续期保持唤醒租约
+func (h *Handler) RenewKeepAwake(c *gin.Context) {
+	hostName := c.Param("hostName")
+	leaseId := c.Param("leaseId")
+
+	var req renewLeaseRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, model.Response{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+	}
+
+	var ttl time.Duration
+	if req.TTL > 0 {
+		ttl = keepAwakeTTL(req.TTL)
+	}
+	lease, err := h.renewLease(principalFrom(c), hostName, leaseId, ttl)
+	if err != nil {
+		c.JSON(leaseErrorStatus(err), model.Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, model.Response{
+		Success: true,
+		Data:    lease,
+	})
+}
+
+// GetHostLeases 获取主机的保持唤醒租约及其持有者
+func (h *Handler) GetHostLeases(c *gin.Context) {
+	hostName := c.Param("hostName")
+	c.JSON(http.StatusOK, model.Response{
+		Success: true,
+		Data:    h.keepAwakeService.GetHostLeases(hostName),
+	})
+}
+
+// GetHostHistory 获取主机最近的状态变化记录
+func (h *Handler) GetHostHistory(c *gin.Context) {
+	hostName := c.Param("hostName")
+	history, ok := h.pcService.GetHostHistory(hostName)
+	if !ok {
+		c.JSON(http.StatusNotFound, model.Response{
+			Success: false,
+			Error:   "host not found: " + hostName,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, model.Response{
+		Success: true,
+		Data:    history,
+	})
+}
+
+// GetRelays 获取唤醒包中继的可达状态，只包含调用者可查看状态的主机使用的中继
+func (h *Handler) GetRelays(c *gin.Context) {
+	principal := principalFrom(c)
+	used := make(map[string]bool)
+	for _, host := range h.pcService.GetHosts() {
+		if host.Relay != "" && principal.Allows(host.Name, auth.ActionStatus) {
+			used[host.Relay] = true
+		}
+	}
+
+	relays := make([]*model.RelayStatus, 0)
+	for _, relay := range h.pcService.Relays().GetRelays() {
+		if principal.IsAdmin() || used[relay.Name] {
+			relays = append(relays, relay)
+		}
+	}
+	c.JSON(http.StatusOK, model.Response{
+		Success: true,
+		Data:    relays,
+	})
+}
+
+// StopKeepAwake 结束保持唤醒租约
+func (h *Handler) StopKeepAwake(c *gin.Context) {
+	hostName := c.Param("hostName")
+	leaseId := c.Param("leaseId")
+	if err := h.releaseLease(principalFrom(c), hostName, leaseId); err != nil {
+		c.JSON(leaseErrorStatus(err), model.Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	log.Printf("结束保持唤醒: 主机=%s, 租约=%s, 操作者=%s", hostName, leaseId, principalFrom(c))
+	c.JSON(http.StatusOK, model.Response{Success: true})
+}
+
+type waitOnlineRequest struct {
+	Timeout int `json:"timeout"` // 最长等待时间（秒），默认60秒
+}
+
+// WaitOnline 阻塞直到主机上线或超时，超时返回 504
+func (h *Handler) WaitOnline(c *gin.Context) {
 	hostName := c.Param("hostName")
-	clients := h.clientService.GetHostClients(hostName)
+
+	var req waitOnlineRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, model.Response{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+	}
+
+	timeout := defaultWaitOnlineTime
+	if req.Timeout > 0 {
+		timeout = time.Duration(req.Timeout) * time.Second
+	}
+	if timeout > maxWaitOnlineTime {
+		timeout = maxWaitOnlineTime
+	}
+
+	start := time.Now()
+	online, err := h.pcService.WaitOnline(c.Request.Context(), hostName, timeout)
+	if err != nil {
+		c.JSON(http.StatusNotFound, model.Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	data := gin.H{
+		"name":     hostName,
+		"isOnline": online,
+		"waited":   time.Since(start).Seconds(),
+	}
+	if !online {
+		c.JSON(http.StatusGatewayTimeout, model.Response{
+			Success: false,
+			Error:   "等待主机上线超时",
+			Data:    data,
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, model.Response{
 		Success: true,
-		Data:    clients,
+		Data:    data,
 	})
 }
 
@@ -106,7 +450,9 @@ func (h *Handler) GetHostChannels(c *gin.Context) {
 }
 
 func (h *Handler) GetConfig(c *gin.Context) {
+	h.mu.RLock()
 	refreshInterval := h.config.HTTP.RefreshInterval
+	h.mu.RUnlock()
 	if refreshInterval <= 0 {
 		refreshInterval = 30
 	}
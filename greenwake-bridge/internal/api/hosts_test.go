@@ -0,0 +1,115 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+
+	"greenwake-bridge/internal/auth"
+	"greenwake-bridge/internal/config"
+	"greenwake-bridge/internal/model"
+	"greenwake-bridge/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// withPrincipal 以指定调用者处理请求，代替认证中间件
+func withPrincipal(p *auth.Principal) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(principalKey, p)
+		c.Next()
+	}
+}
+
+func TestGetHostsFiltersByStatus(t *testing.T) {
+	cfg := &config.Config{
+		DataDir: t.TempDir(),
+		Hosts: []config.PCHostConfig{
+			{Name: "desktop", IP: "127.0.0.1", MAC: "00:11:22:33:44:55", MonitorPort: 1},
+			{Name: "nas", IP: "127.0.0.1", MAC: "00:11:22:33:44:66", MonitorPort: 1},
+		},
+	}
+	pcService, err := service.NewPCService(cfg)
+	if err != nil {
+		t.Fatalf("创建主机服务失败: %v", err)
+	}
+	defer pcService.Close()
+	h := NewHandler(pcService, nil, nil, nil, cfg)
+
+	tests := []struct {
+		name      string
+		principal *auth.Principal
+		want      []string
+	}{
+		{"用户查看全部主机", &auth.Principal{Kind: auth.KindUser, Name: "admin"}, []string{"desktop", "nas"}},
+		{"令牌只能查看授权的主机", &auth.Principal{Kind: auth.KindToken, Name: "ha", Token: &auth.Token{
+			Hosts: []string{"desktop"}, Actions: []string{auth.ActionStatus},
+		}}, []string{"desktop"}},
+		{"没有 status 权限的令牌", &auth.Principal{Kind: auth.KindToken, Name: "wake", Token: &auth.Token{
+			Hosts: []string{"*"}, Actions: []string{auth.ActionWake},
+		}}, []string{}},
+	}
+
+	gin.SetMode(gin.TestMode)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.GET("/hosts", withPrincipal(tt.principal), h.GetHosts)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/hosts", nil))
+
+			var resp struct {
+				model.Response
+				Data []model.PCHostInfo `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("解析响应失败: %v", err)
+			}
+			names := make([]string, 0, len(resp.Data))
+			for _, host := range resp.Data {
+				names = append(names, host.Name)
+			}
+			sort.Strings(names)
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("返回的主机为 %v，期望 %v", names, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequireAllHosts(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal *auth.Principal
+		want      int
+	}{
+		{"用户", &auth.Principal{Kind: auth.KindUser, Name: "admin"}, http.StatusOK},
+		{"不限定主机的令牌", &auth.Principal{Kind: auth.KindToken, Name: "prometheus", Token: &auth.Token{
+			Hosts: []string{"*"}, Actions: []string{auth.ActionStatus},
+		}}, http.StatusOK},
+		{"限定了主机的令牌", &auth.Principal{Kind: auth.KindToken, Name: "ha", Token: &auth.Token{
+			Hosts: []string{"desktop"}, Actions: []string{auth.ActionStatus},
+		}}, http.StatusForbidden},
+		{"没有 status 权限的令牌", &auth.Principal{Kind: auth.KindToken, Name: "wake", Token: &auth.Token{
+			Actions: []string{auth.ActionWake},
+		}}, http.StatusForbidden},
+	}
+
+	gin.SetMode(gin.TestMode)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.GET("/metrics", withPrincipal(tt.principal), RequireAllHosts(auth.ActionStatus), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+			if w.Code != tt.want {
+				t.Errorf("状态码为 %d，期望 %d", w.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package api
+
+import (
+	"strconv"
+	"time"
+
+	"greenwake-bridge/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// streamRoutes 长连接的路由，耗时为连接时长，不记录到请求耗时中
+var streamRoutes = map[string]bool{
+	"/api/events/stream": true,
+}
+
+// metricsMiddleware 按路由记录请求耗时，未匹配任何路由的请求归为 unmatched
+func metricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if streamRoutes[route] {
+			return
+		}
+		if route == "" {
+			route = "unmatched"
+		}
+		metrics.HTTPDuration.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).
+			Observe(time.Since(start).Seconds())
+	}
+}
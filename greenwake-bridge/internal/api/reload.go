@@ -0,0 +1,157 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"syscall"
+	"time"
+
+	"greenwake-bridge/internal/config"
+	"greenwake-bridge/internal/service"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const reloadDebounce = 500 * time.Millisecond // 编辑器保存时可能连续触发多个文件事件
+
+// Reload 重新加载配置文件，校验通过后更新主机、端口转发和反向代理
+// 配置无效时返回错误并继续使用当前配置
+func (s *Server) Reload() error {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	data, err := os.ReadFile(s.cfg.Path())
+	if err != nil {
+		return fmt.Errorf("读取配置失败: %v", err)
+	}
+	if bytes.Equal(data, s.cfgData) {
+		return nil
+	}
+
+	cfg, err := config.Parse(data, s.cfg.Path(), s.cfg.Overrides()...)
+	if err != nil {
+		return fmt.Errorf("解析配置失败: %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	s.apply(cfg, data)
+	log.Printf("配置已重新加载: 主机 %d 台，端口转发 %d 个，反向代理 %d 个", len(cfg.Hosts), len(cfg.Forwards), len(cfg.Proxies))
+	s.handler.pcService.Events().Publish(service.EventConfigReloaded, "", map[string]int{
+		"hosts":    len(cfg.Hosts),
+		"forwards": len(cfg.Forwards),
+		"proxies":  len(cfg.Proxies),
+	})
+	return nil
+}
+
+// apply 将已校验的配置应用到各服务，调用方需持有 reloadMu
+func (s *Server) apply(cfg *config.Config, data []byte) {
+	for _, section := range restartRequired(s.cfg, cfg) {
+		log.Printf("配置 %s 的修改需要重启后生效", section)
+	}
+
+	if err := s.handler.pcService.Relays().UpdateRelays(cfg.Relays); err != nil {
+		log.Printf("更新唤醒包中继失败: %v", err)
+	}
+	s.handler.pcService.Guards().UpdateConfig(cfg.Guards)
+	if err := s.handler.pcService.UpdateConfig(cfg); err != nil {
+		log.Printf("更新主机失败: %v", err)
+	}
+	s.handler.keepAwakeService.DropRemovedHosts()
+	if err := s.proxyService.UpdateProxies(cfg.Proxies); err != nil {
+		log.Printf("更新反向代理失败: %v", err)
+	}
+	s.handler.forwardService.UpdateForwards(cfg.Forwards)
+	s.handler.UpdateConfig(cfg)
+
+	s.cfg = cfg
+	s.cfgData = data
+}
+
+// restartRequired 返回不支持重新加载且发生了修改的配置项
+func restartRequired(old, cfg *config.Config) []string {
+	sections := []struct {
+		name     string
+		old, new interface{}
+	}{
+		{"log", old.Log, cfg.Log},
+		{"http", old.HTTP, cfg.HTTP},
+		{"monitor", old.Monitor, cfg.Monitor},
+		{"session_log", old.SessionLog, cfg.SessionLog},
+		{"audit_log", old.AuditLog, cfg.AuditLog},
+		{"event_log", old.EventLog, cfg.EventLog},
+		{"data_dir", old.DataDir, cfg.DataDir},
+		{"tokens", old.Tokens, cfg.Tokens},
+		{"socks", old.Socks, cfg.Socks},
+	}
+
+	var changed []string
+	for _, section := range sections {
+		if !reflect.DeepEqual(section.old, section.new) {
+			changed = append(changed, section.name)
+		}
+	}
+	return changed
+}
+
+// WatchConfig 监听配置文件修改和 SIGHUP 信号，自动重新加载配置
+func (s *Server) WatchConfig() error {
+	path := s.cfg.Path()
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("创建配置文件监听失败: %v", err)
+	}
+	// 监听所在目录，编辑器通常通过替换文件的方式保存
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		watcher.Close()
+		return fmt.Errorf("监听配置目录失败: %v", err)
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	go func() {
+		defer watcher.Close()
+
+		debounce := time.NewTimer(reloadDebounce)
+		debounce.Stop()
+
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) == path && event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) != 0 {
+					debounce.Reset(reloadDebounce)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Printf("配置文件监听错误: %v", err)
+			case <-debounce.C:
+				s.reload("配置文件已修改")
+			case <-hup:
+				s.reload("收到 SIGHUP")
+			}
+		}
+	}()
+
+	log.Printf("监听配置文件修改: %s（也可发送 SIGHUP 重新加载）", path)
+	return nil
+}
+
+func (s *Server) reload(reason string) {
+	log.Printf("%s，重新加载配置", reason)
+	if err := s.Reload(); err != nil {
+		log.Printf("重新加载配置失败，继续使用当前配置: %v", err)
+	}
+}
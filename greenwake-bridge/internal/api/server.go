@@ -4,20 +4,30 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"sync"
 
+	"greenwake-bridge/internal/auth"
 	"greenwake-bridge/internal/config"
+	"greenwake-bridge/internal/metrics"
 	"greenwake-bridge/internal/service"
 
 	"github.com/gin-gonic/gin"
 )
 
 type Server struct {
-	cfg     *config.Config
-	handler *Handler
-	engine  *gin.Engine
+	cfg          *config.Config
+	cfgData      []byte     // 当前配置文件内容，内容未变化时跳过重新加载
+	reloadMu     sync.Mutex // 保护 cfg 和 cfgData
+	handler      *Handler
+	proxyService *service.ProxyService
+	socksService *service.SocksService
+	demand       *service.DemandService
+	audit        *service.AuditService
+	metrics      *service.MetricsCollector
+	engine       *gin.Engine
 }
 
-func NewServer(cfg *config.Config) *Server {
+func NewServer(cfg *config.Config) (*Server, error) {
 	gin.SetMode(gin.ReleaseMode)
 
 	// 设置日志级别
@@ -53,34 +63,126 @@ func NewServer(cfg *config.Config) *Server {
 		},
 	}))
 	r.Use(gin.Recovery())
+	r.Use(metricsMiddleware())
 
 	// 添加前端静态文件支持
 	r.Static("/assets", "./web/dist/assets")
 	r.StaticFile("/", "./web/dist/index.html")
 	r.StaticFile("/favicon.ico", "./web/dist/favicon.ico")
 
-	pcService := service.NewPCService(cfg)
-	clientService := service.NewClientService()
-	forwardService := service.NewForwardService(cfg, pcService)
-	handler := NewHandler(pcService, clientService, forwardService, cfg)
+	pcService, err := service.NewPCService(cfg)
+	if err != nil {
+		return nil, err
+	}
+	authenticator, err := NewAuthenticator(cfg, pcService.Events())
+	if err != nil {
+		return nil, err
+	}
+	sessionService, err := service.NewSessionService(cfg)
+	if err != nil {
+		return nil, err
+	}
+	forwardService := service.NewForwardService(cfg, pcService, sessionService)
+	keepAwakeService, err := service.NewKeepAwakeService(pcService, cfg.DataDir)
+	if err != nil {
+		return nil, err
+	}
+	proxyService, err := service.NewProxyService(cfg, pcService)
+	if err != nil {
+		return nil, err
+	}
+	socksService, err := service.NewSocksService(cfg, pcService, forwardService, authenticator.ProxyCredentials())
+	if err != nil {
+		return nil, err
+	}
+	auditService, err := service.NewAuditService(cfg, pcService.Events())
+	if err != nil {
+		return nil, err
+	}
+	demandService := service.NewDemandService(pcService, forwardService, keepAwakeService)
+	handler := NewHandler(pcService, forwardService, keepAwakeService, sessionService, cfg)
+	metricsCollector := service.NewMetricsCollector(pcService, forwardService, keepAwakeService)
+	if err := metrics.Register(metricsCollector); err != nil {
+		return nil, err
+	}
+
+	// 记录启动时的配置内容，配置文件监听通过比较内容判断是否需要重新加载
+	cfgData, _ := os.ReadFile(cfg.Path())
+
+	s := &Server{
+		cfg:          cfg,
+		cfgData:      cfgData,
+		handler:      handler,
+		proxyService: proxyService,
+		socksService: socksService,
+		demand:       demandService,
+		audit:        auditService,
+		metrics:      metricsCollector,
+		engine:       r,
+	}
+
+	// Prometheus 抓取时可使用具有 status 权限且不限定主机的API令牌，指标包含所有主机的数据
+	r.GET("/metrics", authenticator.Middleware(), RequireAllHosts(auth.ActionStatus), gin.WrapH(metrics.Handler()))
 
 	api := r.Group("/api")
 	{
-		pc := api.Group("/pc")
+		api.POST("/auth/login", authenticator.Login)
+		api.POST("/auth/logout", authenticator.Logout)
+
+		// guard 注册和心跳使用共享密钥签名，不使用登录会话
+		api.POST("/guard/v1/register", handler.RegisterGuard)
+		api.POST("/guard/v1/heartbeat", handler.GuardHeartbeat)
+	}
+
+	protected := api.Group("", authenticator.Middleware())
+	{
+		protected.GET("/auth/me", authenticator.Me)
+
+		pc := protected.Group("/pc")
 		{
 			pc.GET("/hosts", handler.GetHosts)
 			pc.GET("/config", handler.GetConfig)
-			pc.GET("/:hostName/status", handler.GetHostStatus)
-			pc.GET("/:hostName/client_info", handler.GetHostClients)
-			pc.GET("/:hostName/forward_channels", handler.GetHostChannels)
+			pc.GET("/:hostName/status", RequireAction(auth.ActionStatus), handler.GetHostStatus)
+			pc.GET("/:hostName/history", RequireAction(auth.ActionStatus), handler.GetHostHistory)
+			pc.GET("/:hostName/leases", RequireAction(auth.ActionStatus), handler.GetHostLeases)
+			pc.GET("/:hostName/forward_channels", RequireAction(auth.ActionStatus), handler.GetHostChannels)
+			pc.POST("/:hostName/wake", RequireAction(auth.ActionWake), handler.WakeHost)
+			pc.POST("/:hostName/sleep", RequireAction(auth.ActionSleep), handler.SleepHost)
+			pc.POST("/:hostName/keep-awake", RequireAction(auth.ActionKeepAwake), handler.StartKeepAwake)
+			pc.POST("/:hostName/keep-awake/:leaseId/renew", RequireAction(auth.ActionKeepAwake), handler.RenewKeepAwake)
+			pc.DELETE("/:hostName/keep-awake/:leaseId", RequireAction(auth.ActionKeepAwake), handler.StopKeepAwake)
+			pc.POST("/:hostName/wait-online", RequireAction(auth.ActionStatus), handler.WaitOnline)
 		}
-	}
 
-	return &Server{
-		cfg:     cfg,
-		handler: handler,
-		engine:  r,
+		protected.GET("/sessions", RequireAction(auth.ActionStatus), handler.GetSessions)
+		protected.GET("/events", RequireAction(auth.ActionStatus), handler.GetEvents)
+		protected.GET("/events/stream", RequireAction(auth.ActionStatus), handler.StreamEvents)
+		protected.GET("/relays", RequireAction(auth.ActionStatus), handler.GetRelays)
+		protected.GET("/guards", RequireAction(auth.ActionStatus), handler.GetGuards)
+
+		tokens := protected.Group("/tokens", RequireAdmin())
+		{
+			tokens.GET("", authenticator.ListTokens)
+			tokens.POST("", authenticator.CreateToken)
+			tokens.DELETE("/:name", authenticator.DeleteToken)
+		}
+
+		cfgEdit := protected.Group("/config")
+		{
+			cfgEdit.GET("/hosts", RequireAdmin(), s.ListHostConfigs)
+			cfgEdit.POST("/hosts", RequireAdmin(), s.CreateHost)
+			cfgEdit.PUT("/hosts/:hostName", RequireAdmin(), s.UpdateHost)
+			cfgEdit.DELETE("/hosts/:hostName", RequireAdmin(), s.DeleteHost)
+			cfgEdit.GET("/forwards", RequireAction(auth.ActionForwards), s.ListForwardConfigs)
+			cfgEdit.POST("/forwards", RequireAction(auth.ActionForwards), s.CreateForward)
+			cfgEdit.PUT("/forwards/:port", RequireAction(auth.ActionForwards), s.UpdateForward)
+			cfgEdit.DELETE("/forwards/:port", RequireAction(auth.ActionForwards), s.DeleteForward)
+		}
+
+		protected.GET("/audit", RequireAdmin(), s.GetAudit)
 	}
+
+	return s, nil
 }
 
 func (s *Server) Run() error {
@@ -89,6 +191,13 @@ func (s *Server) Run() error {
 }
 
 func (s *Server) Close() {
-	s.handler.clientService.Close()
+	s.proxyService.Close()
+	s.socksService.Close()
+	s.demand.Close()
 	s.handler.forwardService.Close()
+	s.handler.keepAwakeService.Close()
+	s.handler.pcService.Close()
+	s.handler.sessionService.Close()
+	s.audit.Close()
+	metrics.Unregister(s.metrics)
 }
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"greenwake-bridge/internal/model"
+	"greenwake-bridge/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultSessionLimit = 50
+	maxSessionLimit     = 1000
+)
+
+// GetSessions 查询转发会话记录
+// 参数: host, channel（通道ID或服务端口）, from, to（RFC3339）, offset, limit
+func (h *Handler) GetSessions(c *gin.Context) {
+	principal := principalFrom(c)
+	filter := service.SessionFilter{
+		Host:    c.Query("host"),
+		Channel: c.Query("channel"),
+		Allow:   principal.AllowsHost,
+		Limit:   defaultSessionLimit,
+	}
+
+	for name, dst := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		if v := c.Query(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, model.Response{
+					Success: false,
+					Error:   name + " 格式错误: " + err.Error(),
+				})
+				return
+			}
+			*dst = t
+		}
+	}
+
+	for name, dst := range map[string]*int{"offset": &filter.Offset, "limit": &filter.Limit} {
+		if v := c.Query(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				c.JSON(http.StatusBadRequest, model.Response{
+					Success: false,
+					Error:   name + " 必须是非负整数",
+				})
+				return
+			}
+			*dst = n
+		}
+	}
+	if filter.Limit == 0 || filter.Limit > maxSessionLimit {
+		filter.Limit = maxSessionLimit
+	}
+
+	sessions, total := h.sessionService.Query(filter)
+	c.JSON(http.StatusOK, model.Response{
+		Success: true,
+		Data: gin.H{
+			"total":    total,
+			"sessions": sessions,
+		},
+	})
+}
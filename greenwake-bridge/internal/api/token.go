@@ -0,0 +1,81 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"greenwake-bridge/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+type createTokenRequest struct {
+	Name      string   `json:"name" binding:"required"`
+	Hosts     []string `json:"hosts"`
+	Actions   []string `json:"actions" binding:"required"`
+	ExpiresAt string   `json:"expiresAt"` // RFC3339，为空表示永不过期
+}
+
+func (a *Authenticator) ListTokens(c *gin.Context) {
+	c.JSON(http.StatusOK, model.Response{
+		Success: true,
+		Data:    a.tokens.List(),
+	})
+}
+
+func (a *Authenticator) CreateToken(c *gin.Context) {
+	var req createTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	var expiresAt *time.Time
+	if req.ExpiresAt != "" {
+		t, err := time.Parse(time.RFC3339, req.ExpiresAt)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.Response{
+				Success: false,
+				Error:   "expiresAt 格式错误: " + err.Error(),
+			})
+			return
+		}
+		expiresAt = &t
+	}
+
+	raw, token, err := a.tokens.Create(req.Name, req.Hosts, req.Actions, expiresAt)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	log.Printf("创建API令牌: 名称=%s, 主机=%v, 操作=%v, 操作者=%s", token.Name, token.Hosts, token.Actions, principalFrom(c))
+	c.JSON(http.StatusOK, model.Response{
+		Success: true,
+		Data: gin.H{
+			"token": raw, // 令牌明文只在创建时返回一次
+			"info":  token,
+		},
+	})
+}
+
+func (a *Authenticator) DeleteToken(c *gin.Context) {
+	name := c.Param("name")
+	if err := a.tokens.Delete(name); err != nil {
+		c.JSON(http.StatusBadRequest, model.Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	log.Printf("删除API令牌: 名称=%s, 操作者=%s", name, principalFrom(c))
+	c.JSON(http.StatusOK, model.Response{Success: true})
+}
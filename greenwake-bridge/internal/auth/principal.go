@@ -0,0 +1,55 @@
+package auth
+
+// 调用者类型
+const (
+	KindAnonymous = "anonymous" // 匿名模式
+	KindUser      = "user"      // 用户名密码登录（会话或 Basic）
+	KindToken     = "token"     // API令牌
+)
+
+// Principal 已认证的调用者
+type Principal struct {
+	Kind  string
+	Name  string
+	Token *Token // 仅当 Kind 为 token 时有值
+}
+
+// Allows 判断调用者是否可以对主机执行操作，用户和匿名模式不受限制
+func (p *Principal) Allows(host, action string) bool {
+	if p.Kind != KindToken {
+		return true
+	}
+	return p.Token != nil && p.Token.Allows(host, action)
+}
+
+// AllowsAllHosts 判断调用者是否可以对所有主机执行操作，用于包含全部主机数据的接口
+func (p *Principal) AllowsAllHosts(action string) bool {
+	if p.Kind != KindToken {
+		return true
+	}
+	return p.Token != nil && p.Token.Allows("", action) && p.Token.AllowsAllHosts()
+}
+
+// AllowsHost 判断调用者是否可以访问主机
+func (p *Principal) AllowsHost(host string) bool {
+	if p.Kind != KindToken {
+		return true
+	}
+	return p.Token != nil && p.Token.AllowsHost(host)
+}
+
+// IsAdmin 令牌不具备管理权限，只有登录用户（或匿名模式）可以管理令牌等资源
+func (p *Principal) IsAdmin() bool {
+	return p.Kind != KindToken
+}
+
+// String 返回用于日志记录的调用者标识，如 user:admin
+func (p *Principal) String() string {
+	if p == nil {
+		return KindAnonymous
+	}
+	if p.Name == "" {
+		return p.Kind
+	}
+	return p.Kind + ":" + p.Name
+}
@@ -0,0 +1,89 @@
+package auth
+
+import "testing"
+
+func TestPrincipalAllows(t *testing.T) {
+	user := &Principal{Kind: KindUser, Name: "admin"}
+	anonymous := &Principal{Kind: KindAnonymous}
+	desktop := &Principal{Kind: KindToken, Name: "ha", Token: &Token{
+		Hosts:   []string{"desktop"},
+		Actions: []string{ActionStatus, ActionWake},
+	}}
+	all := &Principal{Kind: KindToken, Name: "prometheus", Token: &Token{
+		Hosts:   []string{"*"},
+		Actions: []string{ActionStatus},
+	}}
+	unrestricted := &Principal{Kind: KindToken, Name: "script", Token: &Token{
+		Actions: []string{ActionKeepAwake},
+	}}
+	missing := &Principal{Kind: KindToken, Name: "broken"}
+
+	tests := []struct {
+		name      string
+		principal *Principal
+		host      string
+		action    string
+		want      bool
+	}{
+		{"用户不受限制", user, "nas", ActionSleep, true},
+		{"匿名模式不受限制", anonymous, "nas", ActionForwards, true},
+		{"授权主机和操作", desktop, "desktop", ActionWake, true},
+		{"未授权主机", desktop, "nas", ActionStatus, false},
+		{"未授权操作", desktop, "desktop", ActionSleep, false},
+		{"不限定主机的操作", desktop, "", ActionStatus, true},
+		{"不限定主机的未授权操作", desktop, "", ActionForwards, false},
+		{"通配主机", all, "nas", ActionStatus, true},
+		{"未配置主机表示全部", unrestricted, "nas", ActionKeepAwake, true},
+		{"缺少令牌信息", missing, "desktop", ActionStatus, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.principal.Allows(tt.host, tt.action); got != tt.want {
+				t.Errorf("%s.Allows(%q, %q) = %v，期望 %v", tt.principal, tt.host, tt.action, got, tt.want)
+			}
+		})
+	}
+
+	hostTests := []struct {
+		principal *Principal
+		host      string
+		want      bool
+	}{
+		{user, "nas", true},
+		{desktop, "desktop", true},
+		{desktop, "nas", false},
+		{all, "nas", true},
+		{missing, "desktop", false},
+	}
+	for _, tt := range hostTests {
+		if got := tt.principal.AllowsHost(tt.host); got != tt.want {
+			t.Errorf("%s.AllowsHost(%q) = %v，期望 %v", tt.principal, tt.host, got, tt.want)
+		}
+	}
+
+	allTests := []struct {
+		principal *Principal
+		action    string
+		want      bool
+	}{
+		{user, ActionStatus, true},
+		{anonymous, ActionStatus, true},
+		{desktop, ActionStatus, false},
+		{all, ActionStatus, true},
+		{all, ActionWake, false},
+		{unrestricted, ActionStatus, false},
+		{missing, ActionStatus, false},
+	}
+	for _, tt := range allTests {
+		if got := tt.principal.AllowsAllHosts(tt.action); got != tt.want {
+			t.Errorf("%s.AllowsAllHosts(%q) = %v，期望 %v", tt.principal, tt.action, got, tt.want)
+		}
+	}
+
+	if !user.IsAdmin() || !anonymous.IsAdmin() || desktop.IsAdmin() {
+		t.Error("只有登录用户和匿名模式具有管理权限")
+	}
+	if user.String() != "user:admin" || desktop.String() != "token:ha" || anonymous.String() != KindAnonymous {
+		t.Errorf("调用者标识为 %s、%s、%s", user, desktop, anonymous)
+	}
+}
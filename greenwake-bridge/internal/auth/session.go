@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SessionManager 签发和校验基于 HMAC 签名的会话令牌
+type SessionManager struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+// NewSessionManager 创建会话管理器，secret 为空时随机生成（重启后会话失效）
+func NewSessionManager(secret string, ttl time.Duration) (*SessionManager, error) {
+	key := []byte(secret)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("生成会话密钥失败: %v", err)
+		}
+	}
+	return &SessionManager{secret: key, ttl: ttl}, nil
+}
+
+// TTL 返回会话有效期
+func (m *SessionManager) TTL() time.Duration {
+	return m.ttl
+}
+
+// Issue 为用户签发会话令牌，格式为 base64(user|expiry).base64(signature)
+func (m *SessionManager) Issue(user string) string {
+	expiry := time.Now().Add(m.ttl).Unix()
+	payload := base64.RawURLEncoding.EncodeToString([]byte(user + "|" + strconv.FormatInt(expiry, 10)))
+	return payload + "." + m.sign(payload)
+}
+
+// Verify 校验会话令牌，返回其中的用户名
+func (m *SessionManager) Verify(token string) (string, bool) {
+	payload, sig, found := strings.Cut(token, ".")
+	if !found {
+		return "", false
+	}
+	if !hmac.Equal([]byte(sig), []byte(m.sign(payload))) {
+		return "", false
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return "", false
+	}
+	user, expiryStr, found := strings.Cut(string(raw), "|")
+	if !found {
+		return "", false
+	}
+	expiry, err := strconv.ParseInt(expiryStr, 10, 64)
+	if err != nil || time.Now().Unix() > expiry {
+		return "", false
+	}
+	return user, true
+}
+
+func (m *SessionManager) sign(payload string) string {
+	mac := hmac.New(sha256.New, m.secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
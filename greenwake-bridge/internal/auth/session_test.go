@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSessionManager(t *testing.T) {
+	m, err := NewSessionManager("session-secret", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := m.Issue("admin")
+
+	if user, ok := m.Verify(token); !ok || user != "admin" {
+		t.Errorf("校验结果为 %q %v，期望 admin", user, ok)
+	}
+
+	payload, sig, _ := strings.Cut(token, ".")
+	otherPayload, _, _ := strings.Cut(m.Issue("other"), ".")
+	other, _ := NewSessionManager("other-secret", time.Hour)
+	expired, _ := NewSessionManager("session-secret", -time.Minute)
+	tests := []struct {
+		name    string
+		manager *SessionManager
+		token   string
+	}{
+		{"空令牌", m, ""},
+		{"缺少签名", m, payload},
+		{"签名错误", m, payload + "." + sig[1:]},
+		{"修改用户名", m, otherPayload + "." + sig},
+		{"其他密钥签发", m, other.Issue("admin")},
+		{"已过期", expired, expired.Issue("admin")},
+		{"载荷格式错误", m, "bm90LWEtc2Vzc2lvbg." + m.sign("bm90LWEtc2Vzc2lvbg")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if user, ok := tt.manager.Verify(tt.token); ok {
+				t.Errorf("校验应失败，得到用户 %q", user)
+			}
+		})
+	}
+}
+
+func TestSessionManagerRandomSecret(t *testing.T) {
+	a, err := NewSessionManager("", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := NewSessionManager("", time.Hour)
+	if _, ok := a.Verify(a.Issue("admin")); !ok {
+		t.Error("随机密钥签发的会话应能校验")
+	}
+	// 未配置密钥时每次启动随机生成，重启前的会话失效
+	if _, ok := b.Verify(a.Issue("admin")); ok {
+		t.Error("不同的随机密钥不应校验通过")
+	}
+}
+
+func TestCheckPassword(t *testing.T) {
+	hash, err := HashPassword("greenwake")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsHashed(hash) || IsHashed("greenwake") {
+		t.Errorf("IsHashed 判断错误: %q", hash)
+	}
+
+	tests := []struct {
+		name, stored, password string
+		want                   bool
+	}{
+		{"哈希匹配", hash, "greenwake", true},
+		{"哈希不匹配", hash, "wrong", false},
+		{"兼容明文密码", "plain", "plain", true},
+		{"明文不匹配", "plain", "Plain", false},
+		{"未配置密码", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CheckPassword(tt.stored, tt.password); got != tt.want {
+				t.Errorf("CheckPassword = %v，期望 %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,321 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"greenwake-bridge/internal/config"
+)
+
+// 令牌可授权的操作
+const (
+	ActionStatus    = "status"     // 查看主机状态、客户端和转发通道
+	ActionWake      = "wake"       // 发送唤醒包
+	ActionKeepAwake = "keep_awake" // 保持主机唤醒
+	ActionForwards  = "forwards"   // 管理转发通道
+	ActionSleep     = "sleep"      // 通过 guard 让主机睡眠或关机
+)
+
+var validActions = map[string]bool{
+	ActionStatus:    true,
+	ActionWake:      true,
+	ActionKeepAwake: true,
+	ActionForwards:  true,
+	ActionSleep:     true,
+}
+
+// 令牌来源
+const (
+	TokenSourceConfig = "config" // 配置文件中定义
+	TokenSourceAPI    = "api"    // 通过管理接口创建
+)
+
+// Token API令牌（不含令牌明文）
+type Token struct {
+	Name       string     `json:"name"`
+	Hosts      []string   `json:"hosts"`
+	Actions    []string   `json:"actions"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	CreatedAt  *time.Time `json:"createdAt,omitempty"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"` // 接口创建的令牌会持久化，配置文件中的令牌重启后清空
+	Source     string     `json:"source"`
+	Hash       string     `json:"-"`
+}
+
+// storedToken 令牌文件中的记录，额外保存令牌哈希
+type storedToken struct {
+	Token
+	Hash string `json:"hash"`
+}
+
+// Expired 判断令牌是否已过期
+func (t *Token) Expired() bool {
+	return t.ExpiresAt != nil && time.Now().After(*t.ExpiresAt)
+}
+
+// Allows 判断令牌是否允许对指定主机执行指定操作，host 为空表示不限定主机的操作
+func (t *Token) Allows(host, action string) bool {
+	if !contains(t.Actions, action) {
+		return false
+	}
+	return host == "" || t.AllowsHost(host)
+}
+
+// AllowsHost 判断令牌是否可以访问指定主机
+func (t *Token) AllowsHost(host string) bool {
+	return t.AllowsAllHosts() || contains(t.Hosts, host)
+}
+
+// AllowsAllHosts 判断令牌是否不限定主机
+func (t *Token) AllowsAllHosts() bool {
+	return len(t.Hosts) == 0 || contains(t.Hosts, "*")
+}
+
+func contains(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
+
+// TokenStore 管理配置文件中定义的令牌和通过接口创建的令牌
+type TokenStore struct {
+	mu     sync.Mutex
+	path   string            // 接口创建的令牌持久化文件
+	tokens map[string]*Token // key: 令牌名称
+}
+
+// NewTokenStore 加载配置中的令牌和数据目录中持久化的令牌
+func NewTokenStore(cfgTokens []config.TokenConfig, dataDir string) (*TokenStore, error) {
+	s := &TokenStore{
+		path:   filepath.Join(dataDir, "tokens.json"),
+		tokens: make(map[string]*Token),
+	}
+
+	for _, tc := range cfgTokens {
+		token, err := tokenFromConfig(tc)
+		if err != nil {
+			return nil, fmt.Errorf("令牌配置错误 [%s]: %v", tc.Name, err)
+		}
+		if _, exists := s.tokens[token.Name]; exists {
+			return nil, fmt.Errorf("令牌名称重复: %s", token.Name)
+		}
+		s.tokens[token.Name] = token
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("读取令牌文件失败: %v", err)
+	}
+	if len(data) > 0 {
+		var stored []storedToken
+		if err := json.Unmarshal(data, &stored); err != nil {
+			return nil, fmt.Errorf("解析令牌文件失败: %v", err)
+		}
+		for _, st := range stored {
+			if _, exists := s.tokens[st.Name]; exists {
+				continue // 配置文件中的同名令牌优先
+			}
+			token := st.Token
+			token.Source = TokenSourceAPI
+			token.Hash = st.Hash
+			s.tokens[token.Name] = &token
+		}
+	}
+
+	return s, nil
+}
+
+func tokenFromConfig(tc config.TokenConfig) (*Token, error) {
+	if tc.Name == "" {
+		return nil, fmt.Errorf("缺少 name")
+	}
+	if tc.Token == "" {
+		return nil, fmt.Errorf("缺少 token")
+	}
+	if err := validateActions(tc.Actions); err != nil {
+		return nil, err
+	}
+
+	hash := hashToken(tc.Token)
+	if strings.HasPrefix(tc.Token, "sha256:") {
+		hash = strings.ToLower(strings.TrimPrefix(tc.Token, "sha256:"))
+	}
+
+	token := &Token{
+		Name:    tc.Name,
+		Hosts:   tc.Hosts,
+		Actions: tc.Actions,
+		Source:  TokenSourceConfig,
+		Hash:    hash,
+	}
+	if tc.ExpiresAt != "" {
+		expiresAt, err := time.Parse(time.RFC3339, tc.ExpiresAt)
+		if err != nil {
+			return nil, fmt.Errorf("expires_at 格式错误: %v", err)
+		}
+		token.ExpiresAt = &expiresAt
+	}
+	return token, nil
+}
+
+func validateActions(actions []string) error {
+	if len(actions) == 0 {
+		return fmt.Errorf("缺少 actions")
+	}
+	for _, action := range actions {
+		if !validActions[action] {
+			return fmt.Errorf("未知的操作: %s", action)
+		}
+	}
+	return nil
+}
+
+func hashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// lastUsedSaveInterval 接口创建的令牌最后使用时间的持久化间隔，避免每个请求都写文件
+const lastUsedSaveInterval = 10 * time.Minute
+
+// Authenticate 校验令牌明文，返回对应令牌的副本
+func (s *TokenStore) Authenticate(raw string) (*Token, bool) {
+	hash := hashToken(raw)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, token := range s.tokens {
+		if subtle.ConstantTimeCompare([]byte(token.Hash), []byte(hash)) == 1 {
+			if token.Expired() {
+				return nil, false
+			}
+			now := time.Now()
+			persist := token.Source == TokenSourceAPI &&
+				(token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= lastUsedSaveInterval)
+			token.LastUsedAt = &now
+			if persist {
+				if err := s.save(); err != nil {
+					log.Printf("保存令牌最后使用时间失败: %v", err)
+				}
+			}
+			copied := *token
+			return &copied, true
+		}
+	}
+	return nil, false
+}
+
+// List 返回所有令牌的副本
+func (s *TokenStore) List() []*Token {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tokens := make([]*Token, 0, len(s.tokens))
+	for _, token := range s.tokens {
+		copied := *token
+		tokens = append(tokens, &copied)
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].Name < tokens[j].Name
+	})
+	return tokens
+}
+
+// Create 创建新令牌并持久化，返回只展示一次的令牌明文
+func (s *TokenStore) Create(name string, hosts, actions []string, expiresAt *time.Time) (string, *Token, error) {
+	if name == "" {
+		return "", nil, fmt.Errorf("令牌名称不能为空")
+	}
+	if err := validateActions(actions); err != nil {
+		return "", nil, err
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", nil, fmt.Errorf("生成令牌失败: %v", err)
+	}
+	raw := "gwb_" + base64.RawURLEncoding.EncodeToString(buf)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.tokens[name]; exists {
+		return "", nil, fmt.Errorf("令牌已存在: %s", name)
+	}
+
+	now := time.Now()
+	token := &Token{
+		Name:      name,
+		Hosts:     hosts,
+		Actions:   actions,
+		ExpiresAt: expiresAt,
+		CreatedAt: &now,
+		Source:    TokenSourceAPI,
+		Hash:      hashToken(raw),
+	}
+	s.tokens[name] = token
+
+	if err := s.save(); err != nil {
+		delete(s.tokens, name)
+		return "", nil, err
+	}
+	copied := *token
+	return raw, &copied, nil
+}
+
+// Delete 删除通过接口创建的令牌，配置文件中的令牌只能修改配置删除
+func (s *TokenStore) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, exists := s.tokens[name]
+	if !exists {
+		return fmt.Errorf("令牌不存在: %s", name)
+	}
+	if token.Source == TokenSourceConfig {
+		return fmt.Errorf("令牌定义在配置文件中，无法通过接口删除: %s", name)
+	}
+
+	delete(s.tokens, name)
+	return s.save()
+}
+
+// save 持久化接口创建的令牌，调用方需持有锁
+func (s *TokenStore) save() error {
+	stored := make([]storedToken, 0, len(s.tokens))
+	for _, token := range s.tokens {
+		if token.Source == TokenSourceAPI {
+			stored = append(stored, storedToken{Token: *token, Hash: token.Hash})
+		}
+	}
+
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化令牌失败: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("创建数据目录失败: %v", err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("写入令牌文件失败: %v", err)
+	}
+	return os.Rename(tmp, s.path)
+}
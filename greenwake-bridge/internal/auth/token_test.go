@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"greenwake-bridge/internal/config"
+)
+
+func TestTokenStoreAuthenticate(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewTokenStore([]config.TokenConfig{
+		{Name: "ha", Token: "plain-token", Actions: []string{ActionStatus}},
+		// echo -n hashed-token | sha256sum
+		{Name: "hashed", Token: "sha256:" + hashToken("hashed-token"), Actions: []string{ActionWake}},
+		{Name: "expired", Token: "old-token", Actions: []string{ActionStatus}, ExpiresAt: "2020-01-01T00:00:00Z"},
+	}, dir)
+	if err != nil {
+		t.Fatalf("加载令牌失败: %v", err)
+	}
+
+	tests := []struct {
+		raw  string
+		want string // 为空表示认证失败
+	}{
+		{"plain-token", "ha"},
+		{"hashed-token", "hashed"},
+		{"old-token", ""},
+		{"wrong", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		token, ok := s.Authenticate(tt.raw)
+		if tt.want == "" {
+			if ok {
+				t.Errorf("令牌 %q 认证应失败，得到 %s", tt.raw, token.Name)
+			}
+			continue
+		}
+		if !ok || token.Name != tt.want {
+			t.Errorf("令牌 %q 认证结果为 %v，期望 %s", tt.raw, token, tt.want)
+		}
+	}
+
+	// 返回副本，修改不影响保存的令牌
+	token, _ := s.Authenticate("plain-token")
+	token.Actions = []string{ActionSleep}
+	token.LastUsedAt = nil
+	for _, listed := range s.List() {
+		if listed.Name == "ha" && (listed.LastUsedAt == nil || listed.Actions[0] != ActionStatus) {
+			t.Errorf("令牌列表应为副本，得到 %+v", listed)
+		}
+	}
+}
+
+func TestTokenStoreLastUsedPersisted(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewTokenStore(nil, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, created, err := s.Create("script", nil, []string{ActionStatus}, nil)
+	if err != nil {
+		t.Fatalf("创建令牌失败: %v", err)
+	}
+	if created.LastUsedAt != nil {
+		t.Error("新建的令牌不应有最后使用时间")
+	}
+
+	// 并发认证和列出令牌不应产生数据竞争（go test -race）
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			s.Authenticate(raw)
+		}()
+		go func() {
+			defer wg.Done()
+			for _, token := range s.List() {
+				_ = token.LastUsedAt
+			}
+		}()
+	}
+	wg.Wait()
+
+	// 重新加载后保留接口创建的令牌的最后使用时间
+	reloaded, err := NewTokenStore(nil, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens := reloaded.List()
+	if len(tokens) != 1 || tokens[0].LastUsedAt == nil || time.Since(*tokens[0].LastUsedAt) > time.Minute {
+		t.Errorf("重新加载后的令牌为 %+v，期望保留最后使用时间", tokens)
+	}
+}
@@ -1,31 +1,237 @@
 package config
 
 import (
+	"crypto/rand"
+	"encoding/base64"
 	"fmt"
+	"log"
+	"net"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"golang.org/x/crypto/bcrypt"
 	"gopkg.in/yaml.v3"
 )
 
 const (
 	// 默认配置值
-	DefaultLogLevel        = "info" // 默认日志级别
-	DefaultHTTPPort        = "8055" // 默认HTTP端口
-	DefaultRefreshInterval = 30     // 默认刷新间隔（秒）
-	DefaultWakeTimeout     = 10     // 默认唤醒超时时间（秒）
-	DefaultRetryCount      = 1      // 默认重试次数
-	DefaultWakeInterval    = 5      // 默认唤醒间隔（秒）
+	DefaultLogLevel           = "info" // 默认日志级别
+	DefaultHTTPPort           = "8055" // 默认HTTP端口
+	DefaultRefreshInterval    = 30     // 默认刷新间隔（秒）
+	DefaultWakeTimeout        = 10     // 默认唤醒超时时间（秒）
+	DefaultRetryCount         = 1      // 默认重试次数
+	DefaultWakeInterval       = 5      // 默认唤醒间隔（秒）
+	DefaultSessionTTL         = 168    // 默认登录会话有效期（小时）
+	DefaultMonitorInterval    = 10     // 默认主机状态检测间隔（秒）
+	DefaultMonitorMaxInterval = 120    // 默认主机离线时的最大检测间隔（秒）
+	DefaultProbeTimeout       = 5      // 默认在线检测超时时间（秒）
+	DefaultRetentionDays      = 30     // 默认转发会话记录保留天数
+	DefaultMaxRecords         = 10000  // 默认最多保留的转发会话记录数
+	DefaultUDPIdleTimeout     = 60     // 默认UDP转发会话空闲超时时间（秒）
+	DefaultAuditRetentionDays = 365    // 默认配置修改审计记录保留天数
+	DefaultEventRetentionDays = 90     // 默认事件记录保留天数
+	DefaultEventMaxRecords    = 50000  // 默认最多保留的事件记录数
+	DefaultWOLPort            = 9      // 默认唤醒包端口
+	DefaultWOLCount           = 1      // 默认每次唤醒发送的包数
+	DefaultWOLInterval        = 100    // 默认连续发送唤醒包的间隔（毫秒）
+	DefaultWakeMethodTimeout  = 10     // 默认单个唤醒方式的超时时间（秒）
+	DefaultGuardHeartbeat     = 15     // 默认 guard 心跳间隔（秒）
 )
 
 type PCHostConfig struct {
-	Name         string `yaml:"name"`
-	IP           string `yaml:"ip"`
-	MAC          string `yaml:"mac"`
-	MonitorPort  int    `yaml:"monitor_port"`
-	WakeTimeout  int    `yaml:"wake_timeout"`
-	RetryCount   int    `yaml:"retry_count"`
-	WakeInterval int    `yaml:"wake_interval"`
+	Name         string       `yaml:"name" json:"name"`
+	IP           string       `yaml:"ip" json:"ip"`
+	MAC          string       `yaml:"mac" json:"mac"`
+	MonitorPort  int          `yaml:"monitor_port,omitempty" json:"monitor_port,omitempty"`
+	Probe        *ProbeConfig `yaml:"probe,omitempty" json:"probe,omitempty"` // 在线检测方式，未配置时检测 monitor_port 的TCP连接
+	WakeTimeout  int          `yaml:"wake_timeout,omitempty" json:"wake_timeout,omitempty"`
+	RetryCount   int          `yaml:"retry_count,omitempty" json:"retry_count,omitempty"`
+	WakeInterval int          `yaml:"wake_interval,omitempty" json:"wake_interval,omitempty"`
+	AllowedPorts []int        `yaml:"allowed_ports,omitempty" json:"allowed_ports,omitempty"` // 允许通过 SOCKS5/CONNECT 代理访问的端口，为空表示不允许
+	Aliases      []string     `yaml:"aliases,omitempty" json:"aliases,omitempty"`             // 代理请求中指向该主机的其他域名
+	WOL          *WOLConfig   `yaml:"wol,omitempty" json:"wol,omitempty"`                     // 唤醒包发送方式，未配置时向 255.255.255.255:9 广播一次
+	Relay        string       `yaml:"relay,omitempty" json:"relay,omitempty"`                 // 通过 relays 中的 greenwake-guard 在主机所在网段发送唤醒包
+	Wake         []WakeMethod `yaml:"wake,omitempty" json:"wake,omitempty"`                   // 按顺序使用的唤醒方式，未配置时只发送唤醒包
+	Guard        *GuardConfig `yaml:"guard,omitempty" json:"guard,omitempty"`                 // 主机上运行的 greenwake-guard，用于远程睡眠或关机
+}
+
+// GuardConfig 主机上运行的 greenwake-guard 控制接口，远程睡眠需在 guard 配置中设置 control.allow_sleep
+type GuardConfig struct {
+	URL    string `yaml:"url" json:"url"`       // guard 控制接口地址，如 http://192.168.1.100:8056
+	Secret string `yaml:"secret" json:"secret"` // 与 guard 的 control.secret 相同的签名密钥
+	// 向 guard 报告主机是否在使用（转发会话、保持唤醒租约），代替重发唤醒包保持唤醒，
+	// 最后一个会话结束后由 guard 按 control.demand_timeout 睡眠
+	Demand bool `yaml:"demand,omitempty" json:"demand,omitempty"`
+}
+
+// RelayConfig 作为唤醒包中继的 greenwake-guard，用于 bridge 广播不能到达的网段
+type RelayConfig struct {
+	Name   string `yaml:"name" json:"name"`
+	URL    string `yaml:"url" json:"url"`       // guard 控制接口地址，如 http://192.168.2.10:8056
+	Secret string `yaml:"secret" json:"secret"` // 与 guard 的 control.secret 相同的签名密钥
+}
+
+// GuardsConfig 接受主机上的 greenwake-guard 注册和心跳
+type GuardsConfig struct {
+	Secret            string `yaml:"secret"`             // 签名密钥，与 guard 的 bridges[].secret 相同，为空时不接受注册
+	HeartbeatInterval int    `yaml:"heartbeat_interval"` // guard 发送心跳的间隔（秒），超过3倍间隔没有心跳视为离线
+}
+
+// 唤醒包发送方式
+const (
+	WOLTransportUDP = "udp" // UDP 广播或单播
+	WOLTransportRaw = "raw" // 以太网帧（EtherType 0x0842），仅 Linux，需要 CAP_NET_RAW
+)
+
+// WOLConfig 唤醒包的目标地址、端口、网卡、发送次数和 SecureOn 密码
+type WOLConfig struct {
+	Transport  string `yaml:"transport,omitempty" json:"transport,omitempty"`     // udp（默认）或 raw，raw 需要指定 interface，无权限时改用UDP
+	Address    string `yaml:"address,omitempty" json:"address,omitempty"`         // 定向广播（如 192.168.1.255）或单播地址，默认 255.255.255.255，指定网卡时默认为网卡所在子网的广播地址
+	Port       int    `yaml:"port,omitempty" json:"port,omitempty"`               // UDP端口，默认9
+	Interface  string `yaml:"interface,omitempty" json:"interface,omitempty"`     // 从指定网卡发送，如 eth0
+	Count      int    `yaml:"count,omitempty" json:"count,omitempty"`             // 每次唤醒连续发送的包数，默认1
+	IntervalMs int    `yaml:"interval_ms,omitempty" json:"interval_ms,omitempty"` // 连续发送的间隔（毫秒），默认100
+	Password   string `yaml:"password,omitempty" json:"password,omitempty"`       // SecureOn 密码，6字节（如 00:11:22:33:44:55）或4字节（如 192.168.1.1）
+}
+
+// SecureOn 解析 SecureOn 密码，未配置时返回空
+func (w *WOLConfig) SecureOn() ([]byte, error) {
+	if w == nil || w.Password == "" {
+		return nil, nil
+	}
+	if ip := net.ParseIP(w.Password); ip != nil && ip.To4() != nil && !strings.Contains(w.Password, ":") {
+		return []byte(ip.To4()), nil
+	}
+	if hw, err := net.ParseMAC(w.Password); err == nil && len(hw) == 6 {
+		return []byte(hw), nil
+	}
+	return nil, fmt.Errorf("SecureOn 密码应为6字节（如 00:11:22:33:44:55）或4字节（如 192.168.1.1）")
+}
+
+// 唤醒方式
+const (
+	WakeWOL     = "wol"     // 按 wol 和 relay 配置发送唤醒包
+	WakeRedfish = "redfish" // 通过 BMC 的 Redfish 接口开机（ComputerSystem.Reset）
+	WakeHTTP    = "http"    // 调用HTTP接口，如智能插座或 Home Assistant 服务
+	WakeCommand = "command" // 执行自定义命令
+)
+
+// WakeMethod 一种唤醒方式，前一种失败，或主机仍未上线时再次唤醒，会使用下一种
+type WakeMethod struct {
+	Type      string            `yaml:"type" json:"type"`                                 // wol, redfish, http, command
+	URL       string            `yaml:"url,omitempty" json:"url,omitempty"`               // redfish: BMC 地址，如 https://10.0.0.5；http: 请求地址
+	System    string            `yaml:"system,omitempty" json:"system,omitempty"`         // redfish: 计算机系统路径，如 /redfish/v1/Systems/1，默认使用第一个
+	ResetType string            `yaml:"reset_type,omitempty" json:"reset_type,omitempty"` // redfish: 开机方式，默认 On
+	User      string            `yaml:"user,omitempty" json:"user,omitempty"`             // redfish, http: Basic 认证用户名
+	Password  string            `yaml:"password,omitempty" json:"password,omitempty"`     // redfish, http: Basic 认证密码
+	Method    string            `yaml:"method,omitempty" json:"method,omitempty"`         // http: 请求方法，默认 POST
+	Headers   map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`       // http: 请求头，如 Authorization: Bearer <令牌>
+	Body      string            `yaml:"body,omitempty" json:"body,omitempty"`             // http: 请求内容
+	Status    int               `yaml:"status,omitempty" json:"status,omitempty"`         // http: 期望的状态码，默认任意2xx
+	Insecure  bool              `yaml:"insecure,omitempty" json:"insecure,omitempty"`     // redfish, http: 跳过TLS证书校验，BMC 通常使用自签名证书
+	Command   []string          `yaml:"command,omitempty" json:"command,omitempty"`       // command: 命令及参数
+	Timeout   int               `yaml:"timeout,omitempty" json:"timeout,omitempty"`       // 超时时间（秒），默认10秒
+}
+
+// 在线检测类型
+const (
+	ProbeTCP     = "tcp"     // TCP连接任一端口成功
+	ProbeICMP    = "icmp"    // ICMP Echo（非特权ping）
+	ProbeHTTP    = "http"    // HTTP(S) GET 返回期望的状态码或内容
+	ProbeARP     = "arp"     // ARP/邻居表中存在主机
+	ProbeCommand = "command" // 自定义命令退出码为0
+)
+
+// ProbeConfig 主机在线检测配置，可组合多个检测
+type ProbeConfig struct {
+	Mode    string       `yaml:"mode,omitempty" json:"mode,omitempty"`       // any: 任一检测成功即在线（默认），all: 全部成功才在线
+	Timeout int          `yaml:"timeout,omitempty" json:"timeout,omitempty"` // 单个检测的默认超时时间（秒），默认5秒
+	Checks  []ProbeCheck `yaml:"checks,omitempty" json:"checks,omitempty"`
+}
+
+// ProbeCheck 单个在线检测
+type ProbeCheck struct {
+	Type     string   `yaml:"type" json:"type"`                             // tcp, icmp, http, arp, command
+	Ports    []int    `yaml:"ports,omitempty" json:"ports,omitempty"`       // tcp: 检测的端口，默认 monitor_port
+	URL      string   `yaml:"url,omitempty" json:"url,omitempty"`           // http: 请求地址
+	Status   int      `yaml:"status,omitempty" json:"status,omitempty"`     // http: 期望的状态码，默认任意2xx
+	Body     string   `yaml:"body,omitempty" json:"body,omitempty"`         // http: 响应内容需包含的字符串
+	Insecure bool     `yaml:"insecure,omitempty" json:"insecure,omitempty"` // http: 跳过TLS证书校验
+	Command  []string `yaml:"command,omitempty" json:"command,omitempty"`   // command: 命令及参数
+	Timeout  int      `yaml:"timeout,omitempty" json:"timeout,omitempty"`   // 超时时间（秒），默认使用 probe.timeout
+}
+
+// 转发协议
+const (
+	ProtocolTCP = "tcp"
+	ProtocolUDP = "udp"
+)
+
+// ForwardConfig 端口转发配置
+type ForwardConfig struct {
+	ServicePort int    `yaml:"service_port" json:"service_port"`
+	TargetHost  string `yaml:"target_host" json:"target_host"`
+	TargetPort  int    `yaml:"target_port" json:"target_port"`
+	Protocol    string `yaml:"protocol,omitempty" json:"protocol,omitempty"`         // tcp（默认）或 udp
+	IdleTimeout int    `yaml:"idle_timeout,omitempty" json:"idle_timeout,omitempty"` // udp: 客户端无数据往来多久后结束会话（秒），默认60秒
+}
+
+// ProxyConfig HTTP反向代理监听，按 Host 头或路径前缀路由到目标主机
+type ProxyConfig struct {
+	ServicePort int          `yaml:"service_port"`
+	Routes      []ProxyRoute `yaml:"routes"`
+}
+
+// ProxyRoute 反向代理路由
+type ProxyRoute struct {
+	Host         string `yaml:"host"`          // 匹配的 Host 头（不含端口），为空匹配任意域名
+	PathPrefix   string `yaml:"path_prefix"`   // 匹配的路径前缀，为空匹配全部路径
+	StripPrefix  bool   `yaml:"strip_prefix"`  // 转发时去掉路径前缀
+	TargetHost   string `yaml:"target_host"`   // 目标主机名，关联 hosts 中的配置
+	TargetPort   int    `yaml:"target_port"`   // 目标端口
+	Scheme       string `yaml:"scheme"`        // http（默认）或 https
+	Insecure     bool   `yaml:"insecure"`      // https: 跳过证书校验
+	PreserveHost bool   `yaml:"preserve_host"` // 转发时保留原始 Host 头
+	HoldTimeout  int    `yaml:"hold_timeout"`  // 非浏览器请求等待主机上线的最长时间（秒），默认为唤醒超时×(重试次数+1)
+}
+
+// SocksConfig SOCKS5 和 HTTP CONNECT 代理监听，按目标地址匹配 hosts 并在需要时唤醒
+type SocksConfig struct {
+	ServicePort int `yaml:"service_port"` // SOCKS5 监听端口，0 表示不启用
+	ConnectPort int `yaml:"connect_port"` // HTTP CONNECT 监听端口，0 表示不启用
+}
+
+// HTTPConfig Web界面和API的监听与认证配置
+type HTTPConfig struct {
+	Port            string `yaml:"port"`
+	User            string `yaml:"user"`
+	Password        string `yaml:"password"`  // bcrypt 哈希，可用 hash-password 子命令生成
+	Anonymous       bool   `yaml:"anonymous"` // 显式开启匿名访问，关闭所有认证
+	SessionSecret   string `yaml:"session_secret"`
+	SessionTTL      int    `yaml:"session_ttl"` // 登录会话有效期（小时）
+	RefreshInterval int    `yaml:"refresh_interval"`
+}
+
+// TokenConfig 自动化脚本使用的API令牌
+type TokenConfig struct {
+	Name      string   `yaml:"name"`
+	Token     string   `yaml:"token"`      // 令牌明文，或 sha256:<十六进制哈希>
+	Hosts     []string `yaml:"hosts"`      // 允许访问的主机，为空或 "*" 表示全部
+	Actions   []string `yaml:"actions"`    // 允许的操作：status, wake, keep_awake, forwards, sleep
+	ExpiresAt string   `yaml:"expires_at"` // 过期时间（RFC3339），为空表示永不过期
+}
+
+// MonitorConfig 后台主机状态检测配置
+type MonitorConfig struct {
+	Interval    int `yaml:"interval"`     // 主机在线时的检测间隔（秒）
+	MaxInterval int `yaml:"max_interval"` // 主机离线时检测间隔逐步加倍，最大不超过该值（秒）
+}
+
+// RetentionConfig 本地记录的保留策略
+type RetentionConfig struct {
+	RetentionDays int `yaml:"retention_days"` // 记录保留天数
+	MaxRecords    int `yaml:"max_records"`    // 最多保留的记录数
 }
 
 type Config struct {
@@ -33,24 +239,98 @@ type Config struct {
 		Level string `yaml:"level"`
 	} `yaml:"log"`
 
-	HTTP struct {
-		Port            string `yaml:"port"`
-		User            string `yaml:"user"`
-		Password        string `yaml:"password"`
-		RefreshInterval int    `yaml:"refresh_interval"`
-	} `yaml:"http"`
+	HTTP HTTPConfig `yaml:"http"`
+
+	Monitor MonitorConfig `yaml:"monitor"`
+
+	SessionLog RetentionConfig `yaml:"session_log"` // 转发会话记录
+
+	AuditLog RetentionConfig `yaml:"audit_log"` // 配置修改审计记录
+
+	EventLog RetentionConfig `yaml:"event_log"` // 事件记录（唤醒、状态变化、会话、租约、登录和配置修改）
+
+	DataDir string `yaml:"data_dir"` // 运行数据目录（令牌等），默认为配置文件所在目录下的 data
+
+	Tokens []TokenConfig `yaml:"tokens"`
+
+	Relays []RelayConfig `yaml:"relays"`
+
+	Guards GuardsConfig `yaml:"guards"`
 
 	Hosts []PCHostConfig `yaml:"hosts"`
 
-	Forwards []struct {
-		ServicePort int    `yaml:"service_port"`
-		TargetHost  string `yaml:"target_host"`
-		TargetPort  int    `yaml:"target_port"`
-	} `yaml:"forwards"`
+	Forwards []ForwardConfig `yaml:"forwards"`
+
+	Proxies []ProxyConfig `yaml:"proxies"`
+
+	Socks SocksConfig `yaml:"socks"`
+
+	path      string     // 配置文件路径
+	node      *yaml.Node // 配置文件的根节点，用于校验时定位行号
+	overrides []Override // 环境变量和命令行参数的配置覆盖
+}
+
+// Path 返回加载配置的文件路径
+func (c *Config) Path() string {
+	return c.path
+}
+
+// DefaultHTTPUser 自动创建的默认配置的登录用户
+const DefaultHTTPUser = "admin"
+
+// generatePassword 生成随机密码及其 bcrypt 哈希
+func generatePassword() (password, hash string, err error) {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	password = base64.RawURLEncoding.EncodeToString(buf)
+	h, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", "", err
+	}
+	return password, string(h), nil
 }
 
-func Load(path string) (*Config, error) {
-	if _, err := os.Stat(path); os.IsNotExist(err) {
+// exampleSections 从示例配置创建时注释掉的顶级配置项，避免使用公开的令牌或监听示例端口
+var exampleSections = map[string]bool{"proxies": true, "socks": true, "tokens": true}
+
+// fromExample 由示例配置生成首次启动的配置：使用默认用户名和生成的密码哈希，
+// 注释掉 exampleSections 中的配置项，其余内容和注释保持原样
+func fromExample(data []byte, passwordHash string) []byte {
+	lines := strings.Split(string(data), "\n")
+	section := ""
+	for i, line := range lines {
+		switch {
+		case line == "" || line[0] == '#':
+			// 空行或顶级注释之后属于下一个配置项
+			section = ""
+		case line[0] != ' ':
+			section, _, _ = strings.Cut(line, ":")
+		}
+
+		trimmed := strings.TrimLeft(line, " ")
+		indent := line[:len(line)-len(trimmed)]
+		switch {
+		case exampleSections[section]:
+			lines[i] = "# " + line
+		case section == "http" && indent == "  " && strings.HasPrefix(trimmed, "user:"):
+			lines[i] = indent + "user: " + DefaultHTTPUser
+		case section == "http" && indent == "  " && strings.HasPrefix(trimmed, "password:"):
+			lines[i] = indent + `password: "` + passwordHash + `"  # 首次启动时生成，密码只在日志中显示一次`
+		case section == "http" && strings.Contains(trimmed, "示例密码"):
+			lines[i] = indent + "# 密码的 bcrypt 哈希，使用 `greenwake-bridge hash-password` 生成"
+		}
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
+
+// Load 加载配置文件并应用配置覆盖，配置文件不存在时：
+// 有配置覆盖则只使用覆盖和默认值，否则从示例配置创建
+func Load(path string, overrides ...Override) (*Config, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) && len(overrides) > 0 {
+		return Parse(nil, path, overrides...)
+	} else if os.IsNotExist(err) {
 		exePath, err := os.Executable()
 		if err != nil {
 			return nil, fmt.Errorf("获取可执行文件路径失败: %v", err)
@@ -74,6 +354,12 @@ func Load(path string) (*Config, error) {
 			}
 		}
 
+		// 默认配置不开启匿名访问，生成管理员密码，只在首次启动时输出一次
+		password, hash, err := generatePassword()
+		if err != nil {
+			return nil, fmt.Errorf("生成默认密码失败: %v", err)
+		}
+
 		if !foundExample {
 			cfg := &Config{
 				Log: struct {
@@ -81,13 +367,11 @@ func Load(path string) (*Config, error) {
 				}{
 					Level: DefaultLogLevel,
 				},
-				HTTP: struct {
-					Port            string `yaml:"port"`
-					User            string `yaml:"user"`
-					Password        string `yaml:"password"`
-					RefreshInterval int    `yaml:"refresh_interval"`
-				}{
+				HTTP: HTTPConfig{
 					Port:            DefaultHTTPPort,
+					User:            DefaultHTTPUser,
+					Password:        hash,
+					SessionTTL:      DefaultSessionTTL,
 					RefreshInterval: DefaultRefreshInterval,
 				},
 			}
@@ -102,10 +386,13 @@ func Load(path string) (*Config, error) {
 				return nil, fmt.Errorf("序列化默认配置失败: %v", err)
 			}
 
-			if err := os.WriteFile(path, data, 0644); err != nil {
+			if err := os.WriteFile(path, data, 0600); err != nil {
 				return nil, fmt.Errorf("写入默认配置失败: %v", err)
 			}
+			log.Printf("已创建默认配置 %s，登录用户: %s，密码: %s（只显示一次，可用 hash-password 子命令生成新密码替换 http.password）",
+				path, DefaultHTTPUser, password)
 
+			cfg.applyDefaults(path)
 			return cfg, nil
 		}
 
@@ -114,45 +401,122 @@ func Load(path string) (*Config, error) {
 			return nil, fmt.Errorf("创建配置目录失败: %v", err)
 		}
 
-		if err := os.WriteFile(path, exampleConfig, 0644); err != nil {
+		if err := os.WriteFile(path, fromExample(exampleConfig, hash), 0600); err != nil {
 			return nil, fmt.Errorf("复制示例配置失败: %v", err)
 		}
+		log.Printf("已从示例配置创建 %s，登录用户: %s，密码: %s（只显示一次，可用 hash-password 子命令生成新密码替换 http.password）",
+			path, DefaultHTTPUser, password)
 	}
 
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
+	return Parse(data, path, overrides...)
+}
 
-	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
+// Parse 解析配置内容，应用配置覆盖并设置默认值，path 为配置文件路径，用于确定数据目录
+func Parse(data []byte, path string, overrides ...Override) (*Config, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
 		return nil, err
 	}
 
-	// 设置默认值
-	if cfg.Log.Level == "" {
-		cfg.Log.Level = DefaultLogLevel
+	cfg := Config{overrides: overrides}
+	if len(doc.Content) > 0 {
+		cfg.node = doc.Content[0]
+	} else if len(overrides) > 0 {
+		cfg.node = &yaml.Node{Kind: yaml.MappingNode}
 	}
-	if cfg.HTTP.Port == "" {
-		cfg.HTTP.Port = DefaultHTTPPort
+	if cfg.node != nil {
+		if err := applyOverrides(cfg.node, overrides); err != nil {
+			return nil, err
+		}
+		if err := cfg.node.Decode(&cfg); err != nil {
+			return nil, err
+		}
 	}
-	if cfg.HTTP.RefreshInterval == 0 {
-		cfg.HTTP.RefreshInterval = DefaultRefreshInterval
+
+	cfg.applyDefaults(path)
+
+	return &cfg, nil
+}
+
+// applyDefaults 为未配置的字段设置默认值，path 为配置文件路径
+func (c *Config) applyDefaults(path string) {
+	c.path = path
+	if c.Log.Level == "" {
+		c.Log.Level = DefaultLogLevel
+	}
+	if c.HTTP.Port == "" {
+		c.HTTP.Port = DefaultHTTPPort
+	}
+	if c.HTTP.RefreshInterval == 0 {
+		c.HTTP.RefreshInterval = DefaultRefreshInterval
+	}
+	if c.HTTP.SessionTTL == 0 {
+		c.HTTP.SessionTTL = DefaultSessionTTL
+	}
+	if c.Monitor.Interval == 0 {
+		c.Monitor.Interval = DefaultMonitorInterval
+	}
+	if c.Monitor.MaxInterval == 0 {
+		c.Monitor.MaxInterval = DefaultMonitorMaxInterval
+	}
+	if c.Guards.HeartbeatInterval == 0 {
+		c.Guards.HeartbeatInterval = DefaultGuardHeartbeat
+	}
+	if c.SessionLog.RetentionDays == 0 {
+		c.SessionLog.RetentionDays = DefaultRetentionDays
+	}
+	if c.SessionLog.MaxRecords == 0 {
+		c.SessionLog.MaxRecords = DefaultMaxRecords
+	}
+	if c.AuditLog.RetentionDays == 0 {
+		c.AuditLog.RetentionDays = DefaultAuditRetentionDays
+	}
+	if c.AuditLog.MaxRecords == 0 {
+		c.AuditLog.MaxRecords = DefaultMaxRecords
+	}
+	if c.EventLog.RetentionDays == 0 {
+		c.EventLog.RetentionDays = DefaultEventRetentionDays
+	}
+	if c.EventLog.MaxRecords == 0 {
+		c.EventLog.MaxRecords = DefaultEventMaxRecords
+	}
+	if c.DataDir == "" {
+		c.DataDir = "data"
+	}
+	if !filepath.IsAbs(c.DataDir) {
+		c.DataDir = filepath.Join(filepath.Dir(path), c.DataDir)
+	}
+	for i := range c.Forwards {
+		if c.Forwards[i].Protocol == "" {
+			c.Forwards[i].Protocol = ProtocolTCP
+		}
+		if c.Forwards[i].Protocol == ProtocolUDP && c.Forwards[i].IdleTimeout == 0 {
+			c.Forwards[i].IdleTimeout = DefaultUDPIdleTimeout
+		}
+	}
+	for i := range c.Proxies {
+		for j := range c.Proxies[i].Routes {
+			if c.Proxies[i].Routes[j].Scheme == "" {
+				c.Proxies[i].Routes[j].Scheme = "http"
+			}
+		}
 	}
 	// 设置主机配置的默认值
-	for i := range cfg.Hosts {
-		if cfg.Hosts[i].WakeTimeout == 0 {
-			cfg.Hosts[i].WakeTimeout = DefaultWakeTimeout
+	for i := range c.Hosts {
+		if c.Hosts[i].WakeTimeout == 0 {
+			c.Hosts[i].WakeTimeout = DefaultWakeTimeout
 		}
-		if cfg.Hosts[i].RetryCount == 0 {
-			cfg.Hosts[i].RetryCount = DefaultRetryCount
+		if c.Hosts[i].RetryCount == 0 {
+			c.Hosts[i].RetryCount = DefaultRetryCount
 		}
-		if cfg.Hosts[i].WakeInterval == 0 {
-			cfg.Hosts[i].WakeInterval = DefaultWakeInterval
+		if c.Hosts[i].WakeInterval == 0 {
+			c.Hosts[i].WakeInterval = DefaultWakeInterval
 		}
 	}
-
-	return &cfg, nil
 }
 
 // GetConfigPath 获取配置文件路径
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestFromExample(t *testing.T) {
+	example, err := os.ReadFile("../../config.example.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, hash, err := generatePassword()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := fromExample(example, hash)
+	cfg, err := Parse(data, "config.yaml")
+	if err != nil {
+		t.Fatalf("解析由示例生成的配置失败: %v\n%s", err, data)
+	}
+
+	if cfg.HTTP.User != DefaultHTTPUser || cfg.HTTP.Password != hash {
+		t.Errorf("登录用户为 %s，密码为 %s，期望 %s 和生成的密码哈希", cfg.HTTP.User, cfg.HTTP.Password, DefaultHTTPUser)
+	}
+	if strings.Contains(string(data), "greenwake）") || strings.Contains(string(data), "user: test") {
+		t.Error("生成的配置中仍有示例用户名或密码")
+	}
+	if len(cfg.Tokens) != 0 || len(cfg.Proxies) != 0 || cfg.Socks.ServicePort != 0 || cfg.Socks.ConnectPort != 0 {
+		t.Errorf("示例的令牌、反向代理和 SOCKS 监听应被注释掉，得到 %d 个令牌、%d 个反向代理、SOCKS 端口 %d/%d",
+			len(cfg.Tokens), len(cfg.Proxies), cfg.Socks.ServicePort, cfg.Socks.ConnectPort)
+	}
+	// 其余配置保持不变
+	if len(cfg.Hosts) == 0 || cfg.Monitor.MaxInterval != 120 {
+		t.Errorf("示例的主机和检测配置应保留，得到 %d 台主机，最大检测间隔 %d", len(cfg.Hosts), cfg.Monitor.MaxInterval)
+	}
+	if !strings.Contains(string(data), "# tokens:") || !strings.Contains(string(data), "#   - name: home-assistant") {
+		t.Error("注释掉的配置项应保留为注释，便于参考")
+	}
+}
+
+func TestGeneratePassword(t *testing.T) {
+	password, hash, err := generatePassword()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(password) < 16 {
+		t.Errorf("生成的密码为 %d 个字符，期望至少 16 个", len(password))
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+		t.Error("密码哈希与密码不匹配")
+	}
+	if other, _, _ := generatePassword(); other == password {
+		t.Error("每次生成的密码应不同")
+	}
+}
+
+func TestLoadCreatesDefaultConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "conf", "config.yaml")
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("创建默认配置失败: %v", err)
+	}
+	if cfg.HTTP.User != DefaultHTTPUser || !strings.HasPrefix(cfg.HTTP.Password, "$2") || cfg.HTTP.Anonymous {
+		t.Errorf("默认配置的登录用户为 %q，密码为 %q，期望生成的用户名和密码哈希", cfg.HTTP.User, cfg.HTTP.Password)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("配置文件权限为 %o，期望 600", perm)
+	}
+
+	// 再次加载使用已创建的配置
+	again, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.HTTP.Password != cfg.HTTP.Password {
+		t.Error("再次加载时不应重新生成密码")
+	}
+}
@@ -0,0 +1,480 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrNotFound 要修改或删除的配置条目不存在
+var ErrNotFound = errors.New("配置条目不存在")
+
+// Document 可编辑的配置文件
+// 修改单个主机或转发时只重写该条目对应的文本，其余内容（注释、空行、对齐）保持不变
+type Document struct {
+	path string
+	data []byte
+	doc  *yaml.Node
+	root *yaml.Node // 顶层 mapping
+}
+
+// OpenDocument 读取配置文件用于编辑
+func OpenDocument(path string) (*Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	d := &Document{path: path}
+	if err := d.setData(data); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *Document) setData(data []byte) error {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	if len(doc.Content) == 0 {
+		// 空文件
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	if doc.Content[0].Kind != yaml.MappingNode {
+		return errors.New("配置文件顶层必须是 mapping")
+	}
+	d.data = data
+	d.doc = &doc
+	d.root = doc.Content[0]
+	return nil
+}
+
+// Bytes 返回编辑后的配置内容
+func (d *Document) Bytes() []byte {
+	return d.data
+}
+
+// Save 原子地写回配置文件：先写入同目录的临时文件再重命名
+func (d *Document) Save() error {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(d.path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(d.path), "."+filepath.Base(d.path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("创建临时文件失败: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(d.data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("写入临时文件失败: %v", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("写入临时文件失败: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("写入临时文件失败: %v", err)
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return fmt.Errorf("设置文件权限失败: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), d.path); err != nil {
+		// 以单文件方式挂载到容器中时无法替换，直接写入
+		if werr := os.WriteFile(d.path, d.data, mode); werr != nil {
+			return fmt.Errorf("写入配置文件失败: %v", werr)
+		}
+	}
+	return nil
+}
+
+// Hosts 返回配置文件中的主机配置（未设置默认值）
+func (d *Document) Hosts() ([]PCHostConfig, error) {
+	var hosts []PCHostConfig
+	if _, seq, _ := d.section("hosts"); seq != nil {
+		if err := seq.Decode(&hosts); err != nil {
+			return nil, err
+		}
+	}
+	return hosts, nil
+}
+
+// Forwards 返回配置文件中的端口转发配置（未设置默认值）
+func (d *Document) Forwards() ([]ForwardConfig, error) {
+	var forwards []ForwardConfig
+	if _, seq, _ := d.section("forwards"); seq != nil {
+		if err := seq.Decode(&forwards); err != nil {
+			return nil, err
+		}
+	}
+	return forwards, nil
+}
+
+// SetHost 新增（name 为空）或替换名为 name 的主机配置
+func (d *Document) SetHost(name string, host PCHostConfig) error {
+	return d.setItem("hosts", hostMatcher(name), name == "", host)
+}
+
+// DeleteHost 删除名为 name 的主机配置
+func (d *Document) DeleteHost(name string) error {
+	return d.deleteItem("hosts", hostMatcher(name))
+}
+
+// SetForward 新增（port 为 0）或替换监听 port/protocol 的转发配置
+func (d *Document) SetForward(port int, protocol string, fc ForwardConfig) error {
+	return d.setItem("forwards", forwardMatcher(port, protocol), port == 0, fc)
+}
+
+// DeleteForward 删除监听 port/protocol 的转发配置
+func (d *Document) DeleteForward(port int, protocol string) error {
+	return d.deleteItem("forwards", forwardMatcher(port, protocol))
+}
+
+func hostMatcher(name string) func(*yaml.Node) bool {
+	return func(item *yaml.Node) bool {
+		var host PCHostConfig
+		return item.Decode(&host) == nil && host.Name == name
+	}
+}
+
+func forwardMatcher(port int, protocol string) func(*yaml.Node) bool {
+	return func(item *yaml.Node) bool {
+		var fc ForwardConfig
+		if item.Decode(&fc) != nil {
+			return false
+		}
+		if fc.Protocol == "" {
+			fc.Protocol = ProtocolTCP
+		}
+		return fc.ServicePort == port && fc.Protocol == protocol
+	}
+}
+
+// section 查找顶层配置项，返回值节点和下一个顶层配置项所在行（从1开始，不存在时为文件末尾之后）
+func (d *Document) section(key string) (*yaml.Node, *yaml.Node, int) {
+	content := d.root.Content
+	for i := 0; i+1 < len(content); i += 2 {
+		if content[i].Value != key {
+			continue
+		}
+		next := len(splitLines(d.data)) + 1
+		if i+2 < len(content) {
+			next = content[i+2].Line
+		}
+		return content[i], content[i+1], next
+	}
+	return nil, nil, 0
+}
+
+// blockSequence 判断是否为可以按行编辑的非空块序列
+func blockSequence(node *yaml.Node) bool {
+	return node != nil && node.Kind == yaml.SequenceNode && len(node.Content) > 0 && node.Style&yaml.FlowStyle == 0
+}
+
+func (d *Document) setItem(key string, match func(*yaml.Node) bool, create bool, v interface{}) error {
+	keyNode, seq, next := d.section(key)
+	if !blockSequence(seq) {
+		if !create {
+			return ErrNotFound
+		}
+		return d.appendToSection(key, keyNode, seq, v)
+	}
+
+	lines := splitLines(d.data)
+	dash := indentOf(lines[seq.Content[0].Line-1])
+
+	if create {
+		text, err := renderItem(nil, v, dash)
+		if err != nil {
+			return err
+		}
+		// 已有条目之间有空行时，新条目前也加一个空行
+		_, end := itemSpan(lines, seq, len(seq.Content)-1, next)
+		if len(seq.Content) > 1 {
+			if start, _ := itemSpan(lines, seq, 1, next); start > 0 && isBlank(lines[start-1]) {
+				text = "\n" + text
+			}
+		}
+		return d.splice(lines, end, end, text)
+	}
+
+	idx := findItem(seq, match)
+	if idx < 0 {
+		return ErrNotFound
+	}
+	text, err := renderItem(seq.Content[idx], v, dash)
+	if err != nil {
+		return err
+	}
+
+	// 条目前的注释保留，条目后的空行保留
+	start := seq.Content[idx].Line - 1
+	_, end := itemSpan(lines, seq, idx, next)
+	for end > start+1 && isBlank(lines[end-1]) {
+		end--
+	}
+	return d.splice(lines, start, end, text)
+}
+
+func (d *Document) deleteItem(key string, match func(*yaml.Node) bool) error {
+	_, seq, next := d.section(key)
+	if !blockSequence(seq) {
+		if seq != nil && seq.Kind == yaml.SequenceNode {
+			// 流式序列如 [a, b] 无法按行编辑，重新生成整个文件
+			if idx := findItem(seq, match); idx >= 0 {
+				seq.Content = append(seq.Content[:idx], seq.Content[idx+1:]...)
+				return d.reencode()
+			}
+		}
+		return ErrNotFound
+	}
+
+	idx := findItem(seq, match)
+	if idx < 0 {
+		return ErrNotFound
+	}
+
+	lines := splitLines(d.data)
+	start, end := itemSpan(lines, seq, idx, next)
+	if idx == len(seq.Content)-1 {
+		// 删除最后一个条目时一并删除它前面的空行
+		for start > 0 && isBlank(lines[start-1]) {
+			start--
+		}
+	}
+	return d.splice(lines, start, end, "")
+}
+
+// appendToSection 配置项不存在、为空或为流式序列时添加条目
+func (d *Document) appendToSection(key string, keyNode, seq *yaml.Node, v interface{}) error {
+	if keyNode == nil {
+		text, err := renderItem(nil, v, 2)
+		if err != nil {
+			return err
+		}
+		data := d.data
+		if len(data) > 0 && !bytes.HasSuffix(data, []byte("\n")) {
+			data = append(data, '\n')
+		}
+		data = append(data, []byte("\n"+key+":\n"+text)...)
+		return d.setData(data)
+	}
+
+	var item yaml.Node
+	if err := item.Encode(v); err != nil {
+		return err
+	}
+	if seq.Kind == yaml.SequenceNode {
+		seq.Content = append(seq.Content, &item)
+		seq.Style = 0
+	} else {
+		*seq = yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Content: []*yaml.Node{&item}}
+	}
+	return d.reencode()
+}
+
+// reencode 重新生成整个文件，注释会保留但空行和对齐可能改变
+func (d *Document) reencode() error {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(d.doc); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	return d.setData(buf.Bytes())
+}
+
+// splice 用 text 替换第 start 到 end-1 行（从0开始）
+func (d *Document) splice(lines []string, start, end int, text string) error {
+	var buf strings.Builder
+	for _, line := range lines[:start] {
+		buf.WriteString(line)
+	}
+	if start > 0 && !strings.HasSuffix(lines[start-1], "\n") {
+		buf.WriteString("\n")
+	}
+	buf.WriteString(text)
+	for _, line := range lines[end:] {
+		buf.WriteString(line)
+	}
+	return d.setData([]byte(buf.String()))
+}
+
+func findItem(seq *yaml.Node, match func(*yaml.Node) bool) int {
+	for i, item := range seq.Content {
+		if match(item) {
+			return i
+		}
+	}
+	return -1
+}
+
+// itemSpan 返回序列第 i 个条目占用的行范围 [start, end)（从0开始），包括条目前的注释
+func itemSpan(lines []string, seq *yaml.Node, i int, next int) (int, int) {
+	line := seq.Content[i].Line - 1
+	dash := indentOf(lines[line])
+	start := commentStart(lines, line, dash)
+
+	if i+1 < len(seq.Content) {
+		nextLine := seq.Content[i+1].Line - 1
+		return start, commentStart(lines, nextLine, indentOf(lines[nextLine]))
+	}
+
+	// 最后一个条目：之后的空行和缩进不超过条目的注释属于下一个配置项
+	end := next - 1
+	if end > len(lines) {
+		end = len(lines)
+	}
+	for end > line+1 && (isBlank(lines[end-1]) || isComment(lines[end-1]) && indentOf(lines[end-1]) <= dash) {
+		end--
+	}
+	return start, end
+}
+
+// commentStart 返回紧挨在第 line 行之前、缩进不小于 indent 的注释的起始行
+func commentStart(lines []string, line, indent int) int {
+	for line > 0 && isComment(lines[line-1]) && indentOf(lines[line-1]) >= indent {
+		line--
+	}
+	return line
+}
+
+// renderItem 生成序列条目的文本；old 不为空时保留原条目中各字段的注释、顺序和格式
+func renderItem(old *yaml.Node, v interface{}, indent int) (string, error) {
+	var item yaml.Node
+	if err := item.Encode(v); err != nil {
+		return "", err
+	}
+	flowScalarSequences(&item)
+	if old != nil {
+		mergeMapping(old, &item)
+		item = *old
+	}
+
+	// 条目前的注释不在替换范围内，避免重复
+	item.HeadComment = ""
+	if len(item.Content) > 0 {
+		item.Content[0].HeadComment = ""
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&yaml.Node{Kind: yaml.SequenceNode, Content: []*yaml.Node{&item}}); err != nil {
+		return "", err
+	}
+	if err := enc.Close(); err != nil {
+		return "", err
+	}
+
+	prefix := strings.Repeat(" ", indent)
+	var out strings.Builder
+	for _, line := range splitLines(buf.Bytes()) {
+		if !isBlank(line) {
+			out.WriteString(prefix)
+		}
+		out.WriteString(line)
+	}
+	return out.String(), nil
+}
+
+// mergeMapping 用 src 的字段更新 dst：保留 dst 中仍存在字段的顺序和注释，删除 src 中没有的字段，追加新字段
+func mergeMapping(dst, src *yaml.Node) {
+	values := make(map[string]*yaml.Node, len(src.Content)/2)
+	var order []*yaml.Node
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		values[src.Content[i].Value] = src.Content[i+1]
+		order = append(order, src.Content[i])
+	}
+
+	merged := make([]*yaml.Node, 0, len(src.Content))
+	seen := make(map[string]bool, len(values))
+	for i := 0; i+1 < len(dst.Content); i += 2 {
+		key, old := dst.Content[i], dst.Content[i+1]
+		v, exists := values[key.Value]
+		if !exists {
+			continue
+		}
+		seen[key.Value] = true
+		merged = append(merged, key, mergeNode(old, v))
+	}
+	for _, key := range order {
+		if !seen[key.Value] {
+			merged = append(merged, key, values[key.Value])
+		}
+	}
+	dst.Content = merged
+}
+
+// mergeNode 返回合并后的节点，mapping 和序列逐项合并，其他节点保留原来的注释和格式
+func mergeNode(old, v *yaml.Node) *yaml.Node {
+	if old.Kind == yaml.MappingNode && v.Kind == yaml.MappingNode {
+		mergeMapping(old, v)
+		return old
+	}
+	if old.Kind == yaml.SequenceNode && v.Kind == yaml.SequenceNode {
+		for i := range v.Content {
+			if i < len(old.Content) {
+				v.Content[i] = mergeNode(old.Content[i], v.Content[i])
+			}
+		}
+	}
+	v.HeadComment, v.LineComment, v.FootComment = old.HeadComment, old.LineComment, old.FootComment
+	if old.Kind == v.Kind && old.Tag == v.Tag {
+		v.Style = old.Style
+	}
+	return v
+}
+
+// flowScalarSequences 新增的端口、别名等简单列表使用 [a, b] 格式
+func flowScalarSequences(node *yaml.Node) {
+	if node.Kind == yaml.SequenceNode && len(node.Content) > 0 {
+		flow := true
+		for _, item := range node.Content {
+			if item.Kind != yaml.ScalarNode {
+				flow = false
+			}
+		}
+		if flow {
+			node.Style = yaml.FlowStyle
+			return
+		}
+	}
+	for _, child := range node.Content {
+		flowScalarSequences(child)
+	}
+}
+
+// splitLines 按行拆分，每行保留换行符
+func splitLines(data []byte) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func indentOf(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+func isBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+func isComment(line string) bool {
+	return strings.HasPrefix(strings.TrimSpace(line), "#")
+}
@@ -0,0 +1,323 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// openTestDocument 将 content 写入临时配置文件并打开
+func openTestDocument(t *testing.T, content string) *Document {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := OpenDocument(path)
+	if err != nil {
+		t.Fatalf("打开配置失败: %v", err)
+	}
+	return doc
+}
+
+const testConfig = `# GreenWake 配置
+http:
+  port: 8055 # 管理端口
+
+hosts:
+  # 书房台式机
+  - name: desktop
+    ip: 192.168.1.10   # 固定地址
+    mac: "00:11:22:33:44:55"
+    aliases: [pc, desktop.lan]
+
+  # NAS
+  - name: nas
+    ip: 192.168.1.20
+    mac: "00:11:22:33:44:66"
+
+# 端口转发
+forwards:
+  - service_port: 13389
+    target_host: desktop
+    target_port: 3389
+`
+
+func TestDocumentSetItem(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		edit    func(d *Document) error
+		want    string
+	}{
+		{
+			name:    "替换条目保留注释和对齐",
+			content: testConfig,
+			edit: func(d *Document) error {
+				return d.SetHost("desktop", PCHostConfig{Name: "desktop", IP: "192.168.1.11", MAC: "00:11:22:33:44:55", Aliases: []string{"pc"}})
+			},
+			want: `# GreenWake 配置
+http:
+  port: 8055 # 管理端口
+
+hosts:
+  # 书房台式机
+  - name: desktop
+    ip: 192.168.1.11 # 固定地址
+    mac: "00:11:22:33:44:55"
+    aliases: [pc]
+
+  # NAS
+  - name: nas
+    ip: 192.168.1.20
+    mac: "00:11:22:33:44:66"
+
+# 端口转发
+forwards:
+  - service_port: 13389
+    target_host: desktop
+    target_port: 3389
+`,
+		},
+		{
+			name:    "替换最后一个条目不影响下一个配置项的注释",
+			content: testConfig,
+			edit: func(d *Document) error {
+				return d.SetHost("nas", PCHostConfig{Name: "nas2", IP: "192.168.1.21", MAC: "00:11:22:33:44:66"})
+			},
+			want: `# GreenWake 配置
+http:
+  port: 8055 # 管理端口
+
+hosts:
+  # 书房台式机
+  - name: desktop
+    ip: 192.168.1.10   # 固定地址
+    mac: "00:11:22:33:44:55"
+    aliases: [pc, desktop.lan]
+
+  # NAS
+  - name: nas2
+    ip: 192.168.1.21
+    mac: "00:11:22:33:44:66"
+
+# 端口转发
+forwards:
+  - service_port: 13389
+    target_host: desktop
+    target_port: 3389
+`,
+		},
+		{
+			name:    "新增条目沿用空行分隔",
+			content: testConfig,
+			edit: func(d *Document) error {
+				return d.SetHost("", PCHostConfig{Name: "laptop", IP: "192.168.1.30", MAC: "00:11:22:33:44:77"})
+			},
+			want: `# GreenWake 配置
+http:
+  port: 8055 # 管理端口
+
+hosts:
+  # 书房台式机
+  - name: desktop
+    ip: 192.168.1.10   # 固定地址
+    mac: "00:11:22:33:44:55"
+    aliases: [pc, desktop.lan]
+
+  # NAS
+  - name: nas
+    ip: 192.168.1.20
+    mac: "00:11:22:33:44:66"
+
+  - name: laptop
+    ip: 192.168.1.30
+    mac: 00:11:22:33:44:77
+
+# 端口转发
+forwards:
+  - service_port: 13389
+    target_host: desktop
+    target_port: 3389
+`,
+		},
+		{
+			name:    "唯一条目位于文件末尾且缩进为0",
+			content: "forwards:\n- service_port: 13389\n  target_host: desktop\n  target_port: 3389\n",
+			edit: func(d *Document) error {
+				return d.SetForward(13389, ProtocolTCP, ForwardConfig{ServicePort: 13389, TargetHost: "desktop", TargetPort: 3390})
+			},
+			want: "forwards:\n- service_port: 13389\n  target_host: desktop\n  target_port: 3390\n",
+		},
+		{
+			name:    "配置项不存在时追加到文件末尾",
+			content: "http:\n  port: 8055",
+			edit: func(d *Document) error {
+				return d.SetForward(0, "", ForwardConfig{ServicePort: 13389, TargetHost: "desktop", TargetPort: 3389})
+			},
+			want: "http:\n  port: 8055\n\nforwards:\n  - service_port: 13389\n    target_host: desktop\n    target_port: 3389\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := openTestDocument(t, tt.content)
+			if err := tt.edit(doc); err != nil {
+				t.Fatalf("修改失败: %v", err)
+			}
+			if got := string(doc.Bytes()); got != tt.want {
+				t.Errorf("修改后的配置不符合预期\n得到:\n%s\n期望:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDocumentDeleteItem(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		edit    func(d *Document) error
+		want    string
+	}{
+		{
+			name:    "删除第一个条目连同它的注释",
+			content: testConfig,
+			edit:    func(d *Document) error { return d.DeleteHost("desktop") },
+			want: `# GreenWake 配置
+http:
+  port: 8055 # 管理端口
+
+hosts:
+  # NAS
+  - name: nas
+    ip: 192.168.1.20
+    mac: "00:11:22:33:44:66"
+
+# 端口转发
+forwards:
+  - service_port: 13389
+    target_host: desktop
+    target_port: 3389
+`,
+		},
+		{
+			name:    "删除最后一个条目连同前面的空行",
+			content: testConfig,
+			edit:    func(d *Document) error { return d.DeleteHost("nas") },
+			want: `# GreenWake 配置
+http:
+  port: 8055 # 管理端口
+
+hosts:
+  # 书房台式机
+  - name: desktop
+    ip: 192.168.1.10   # 固定地址
+    mac: "00:11:22:33:44:55"
+    aliases: [pc, desktop.lan]
+
+# 端口转发
+forwards:
+  - service_port: 13389
+    target_host: desktop
+    target_port: 3389
+`,
+		},
+		{
+			name:    "删除文件末尾的唯一条目",
+			content: testConfig,
+			edit:    func(d *Document) error { return d.DeleteForward(13389, ProtocolTCP) },
+			want: `# GreenWake 配置
+http:
+  port: 8055 # 管理端口
+
+hosts:
+  # 书房台式机
+  - name: desktop
+    ip: 192.168.1.10   # 固定地址
+    mac: "00:11:22:33:44:55"
+    aliases: [pc, desktop.lan]
+
+  # NAS
+  - name: nas
+    ip: 192.168.1.20
+    mac: "00:11:22:33:44:66"
+
+# 端口转发
+forwards:
+`,
+		},
+		{
+			name:    "流式序列重新生成整个文件",
+			content: "# 转发\nforwards: [{service_port: 13389, target_host: desktop, target_port: 3389}, {service_port: 2222, target_host: nas, target_port: 22}]\n",
+			edit:    func(d *Document) error { return d.DeleteForward(2222, ProtocolTCP) },
+			want:    "# 转发\nforwards: [{service_port: 13389, target_host: desktop, target_port: 3389}]\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := openTestDocument(t, tt.content)
+			if err := tt.edit(doc); err != nil {
+				t.Fatalf("删除失败: %v", err)
+			}
+			if got := string(doc.Bytes()); got != tt.want {
+				t.Errorf("删除后的配置不符合预期\n得到:\n%s\n期望:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDocumentNotFound(t *testing.T) {
+	doc := openTestDocument(t, testConfig)
+	if err := doc.SetHost("laptop", PCHostConfig{Name: "laptop"}); err != ErrNotFound {
+		t.Errorf("替换不存在的主机应返回 ErrNotFound，得到 %v", err)
+	}
+	if err := doc.DeleteForward(13389, ProtocolUDP); err != ErrNotFound {
+		t.Errorf("删除不存在的转发应返回 ErrNotFound，得到 %v", err)
+	}
+	if string(doc.Bytes()) != testConfig {
+		t.Error("操作失败时不应修改配置")
+	}
+}
+
+func TestItemSpan(t *testing.T) {
+	doc := openTestDocument(t, testConfig)
+	_, seq, next := doc.section("hosts")
+	lines := splitLines(doc.Bytes())
+
+	// 第一个条目从注释开始，到下一个条目的注释之前结束（包含中间的空行）
+	if start, end := itemSpan(lines, seq, 0, next); start != 5 || end != 11 {
+		t.Errorf("第一个条目的范围为 [%d, %d)，期望 [5, 11)", start, end)
+	}
+	// 最后一个条目不包含之后的空行和下一个配置项的注释
+	if start, end := itemSpan(lines, seq, 1, next); start != 11 || end != 15 {
+		t.Errorf("最后一个条目的范围为 [%d, %d)，期望 [11, 15)", start, end)
+	}
+}
+
+func TestDocumentEditReparses(t *testing.T) {
+	doc := openTestDocument(t, testConfig)
+	if err := doc.SetHost("", PCHostConfig{Name: "laptop", IP: "192.168.1.30", MAC: "00:11:22:33:44:77"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.DeleteHost("desktop"); err != nil {
+		t.Fatal(err)
+	}
+	hosts, err := doc.Hosts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hosts) != 2 || hosts[0].Name != "nas" || hosts[1].Name != "laptop" {
+		t.Errorf("连续修改后的主机为 %+v，期望 nas 和 laptop", hosts)
+	}
+	if err := doc.Save(); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := os.ReadFile(doc.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(saved) != string(doc.Bytes()) {
+		t.Error("保存的文件与编辑后的内容不一致")
+	}
+}
@@ -0,0 +1,412 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix 覆盖配置项的环境变量前缀
+const EnvPrefix = "GREENWAKE_"
+
+// Override 覆盖配置文件中的一个配置项，优先级: 命令行参数 > 环境变量 > 配置文件
+type Override struct {
+	Source string // 来源，如 GREENWAKE_HTTP_PORT 或 -set http.port，不含值以免日志中出现密码
+	Path   string // 配置项路径，如 hosts[0].mac
+	Value  string
+
+	path yamlPath
+	kind reflect.Type // 配置项的类型
+}
+
+// EnvOverrides 从环境变量读取配置覆盖，变量名为 GREENWAKE_ 加大写的配置项路径，
+// 以下划线连接，序列下标直接写数字，如 GREENWAKE_HTTP_PORT、GREENWAKE_HOSTS_0_MAC
+func EnvOverrides(environ []string) ([]Override, error) {
+	var overrides []Override
+	for _, kv := range environ {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(name, EnvPrefix) {
+			continue
+		}
+		tokens := strings.Split(strings.ToLower(strings.TrimPrefix(name, EnvPrefix)), "_")
+		path, kind, ok := resolvePath(reflect.TypeOf(Config{}), tokens, "_", nil)
+		if !ok {
+			return nil, fmt.Errorf("环境变量 %s 不对应任何配置项", name)
+		}
+		overrides = append(overrides, Override{Source: name, Path: path.String(), Value: value, path: path, kind: kind})
+	}
+	// 先设置序列中靠前的条目，保证 HOSTS_2 在 HOSTS_10 之前
+	sort.SliceStable(overrides, func(i, j int) bool {
+		return lessPath(overrides[i].path, overrides[j].path)
+	})
+	return overrides, nil
+}
+
+// ParseOverride 解析命令行参数 -set 的值，格式为 路径=值，路径如 http.port、hosts[0].mac 或 hosts.0.mac
+func ParseOverride(s string) (Override, error) {
+	key, value, ok := strings.Cut(s, "=")
+	if !ok || key == "" {
+		return Override{}, fmt.Errorf("格式应为 路径=值: %s", s)
+	}
+	normalized := strings.NewReplacer("[", ".", "]", "").Replace(key)
+	path, kind, ok := resolvePath(reflect.TypeOf(Config{}), strings.Split(normalized, "."), ".", nil)
+	if !ok {
+		return Override{}, fmt.Errorf("配置项不存在: %s", key)
+	}
+	return Override{Source: "-set " + key, Path: path.String(), Value: value, path: path, kind: kind}, nil
+}
+
+// resolvePath 按配置结构解析路径，tokens 为以 sep 分隔的路径片段，
+// 字段名本身包含 sep 时（如环境变量中的 service_port）会尝试各种组合
+func resolvePath(t reflect.Type, tokens []string, sep string, path yamlPath) (yamlPath, reflect.Type, bool) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if len(tokens) == 0 {
+		return path, t, true
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+			if name == "" || name == "-" {
+				continue
+			}
+			parts := strings.Split(name, sep)
+			if len(parts) > len(tokens) || strings.Join(tokens[:len(parts)], sep) != name {
+				continue
+			}
+			if p, kind, ok := resolvePath(t.Field(i).Type, tokens[len(parts):], sep, path.child(name)); ok {
+				return p, kind, true
+			}
+		}
+	case reflect.Slice:
+		index, err := strconv.Atoi(tokens[0])
+		if err != nil || index < 0 {
+			return nil, nil, false
+		}
+		return resolvePath(t.Elem(), tokens[1:], sep, path.child(index))
+	}
+	return nil, nil, false
+}
+
+func lessPath(a, b yamlPath) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] == b[i] {
+			continue
+		}
+		ai, aIsIndex := a[i].(int)
+		bi, bIsIndex := b[i].(int)
+		if aIsIndex && bIsIndex {
+			return ai < bi
+		}
+		return fmt.Sprint(a[i]) < fmt.Sprint(b[i])
+	}
+	return len(a) < len(b)
+}
+
+// applyOverrides 将覆盖写入配置文件的根映射，缺少的映射和序列条目会自动创建
+func applyOverrides(root *yaml.Node, overrides []Override) error {
+	for _, o := range overrides {
+		value, err := o.node()
+		if err != nil {
+			return fmt.Errorf("%s: %v", o.Source, err)
+		}
+		if err := setPath(root, o.path, value); err != nil {
+			return fmt.Errorf("%s: %v", o.Source, err)
+		}
+	}
+	return nil
+}
+
+// node 将覆盖值转换为 YAML 节点，标量序列（如 allowed_ports）使用逗号分隔
+func (o Override) node() (*yaml.Node, error) {
+	switch o.kind.Kind() {
+	case reflect.Slice:
+		if o.kind.Elem().Kind() == reflect.Struct {
+			return nil, fmt.Errorf("%s 需要按下标逐项设置，如 %s.0.name", o.Path, o.Path)
+		}
+		seq := &yaml.Node{Kind: yaml.SequenceNode}
+		for _, item := range strings.Split(o.Value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				seq.Content = append(seq.Content, scalarNode(item, o.kind.Elem()))
+			}
+		}
+		return seq, nil
+	case reflect.Struct:
+		return nil, fmt.Errorf("%s 需要按字段逐项设置，如 %s.<字段>", o.Path, o.Path)
+	case reflect.Map:
+		return nil, fmt.Errorf("%s 不支持覆盖，请在配置文件中设置", o.Path)
+	default:
+		return scalarNode(o.Value, o.kind), nil
+	}
+}
+
+func scalarNode(value string, t reflect.Type) *yaml.Node {
+	n := &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+	if t.Kind() == reflect.String {
+		// 避免 "0123"、"true" 等值被解析为其他类型
+		n.Tag = "!!str"
+	}
+	return n
+}
+
+func setPath(node *yaml.Node, path yamlPath, value *yaml.Node) error {
+	for i, elem := range path {
+		last := i == len(path)-1
+		if node.Kind == yaml.ScalarNode && node.ShortTag() == "!!null" {
+			// 配置文件中为空（如 hosts:）时替换为映射或序列
+			*node = *emptyNode(elem)
+		}
+		switch elem := elem.(type) {
+		case string:
+			if node.Kind != yaml.MappingNode {
+				return fmt.Errorf("%s 不是映射", path[:i])
+			}
+			var next *yaml.Node
+			for j := 0; j+1 < len(node.Content); j += 2 {
+				if node.Content[j].Value == elem {
+					if last {
+						// 值不再来自配置文件，校验时不显示原来的行号
+						node.Content[j].Line = 0
+						node.Content[j+1] = value
+						return nil
+					}
+					next = node.Content[j+1]
+					break
+				}
+			}
+			if next == nil {
+				next = value
+				if !last {
+					next = emptyNode(path[i+1])
+				}
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: elem}, next)
+			}
+			node = next
+		case int:
+			if node.Kind != yaml.SequenceNode {
+				return fmt.Errorf("%s 不是序列", path[:i])
+			}
+			for len(node.Content) <= elem {
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.MappingNode})
+			}
+			if last {
+				node.Content[elem] = value
+				return nil
+			}
+			node = node.Content[elem]
+		}
+	}
+	return nil
+}
+
+func emptyNode(next interface{}) *yaml.Node {
+	if _, ok := next.(int); ok {
+		return &yaml.Node{Kind: yaml.SequenceNode}
+	}
+	return &yaml.Node{Kind: yaml.MappingNode}
+}
+
+// Overrides 返回加载配置时使用的覆盖，重新加载配置文件时需要再次应用
+func (c *Config) Overrides() []Override {
+	return c.overrides
+}
+
+// redactedMask 隐藏后的敏感配置项
+const redactedMask = "******"
+
+// ChildEnviron 返回执行唤醒和检测命令时传递的环境变量，去掉 GREENWAKE_ 开头的配置覆盖，避免密码等配置泄露给命令
+func ChildEnviron() []string {
+	var env []string
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, EnvPrefix) {
+			env = append(env, kv)
+		}
+	}
+	return env
+}
+
+// IndexedOverrides 返回按下标覆盖 section（如 hosts）中条目的配置覆盖，
+// 通过接口增删条目后下标对应的条目会改变
+func (c *Config) IndexedOverrides(section string) []Override {
+	var indexed []Override
+	for _, o := range c.overrides {
+		if len(o.path) > 1 && o.path[0] == section {
+			indexed = append(indexed, o)
+		}
+	}
+	return indexed
+}
+
+// Redacted 返回隐藏了密码、会话密钥、令牌、guard 签名密钥、SecureOn 密码和唤醒方式认证信息的配置副本，用于输出展示
+func (c *Config) Redacted() *Config {
+	redacted := *c
+	if redacted.HTTP.Password != "" {
+		redacted.HTTP.Password = redactedMask
+	}
+	if redacted.HTTP.SessionSecret != "" {
+		redacted.HTTP.SessionSecret = redactedMask
+	}
+	redacted.Tokens = make([]TokenConfig, len(c.Tokens))
+	for i, tc := range c.Tokens {
+		if tc.Token != "" {
+			tc.Token = redactedMask
+		}
+		redacted.Tokens[i] = tc
+	}
+	redacted.Relays = make([]RelayConfig, len(c.Relays))
+	for i, rc := range c.Relays {
+		if rc.Secret != "" {
+			rc.Secret = redactedMask
+		}
+		rc.URL = redactURL(rc.URL)
+		redacted.Relays[i] = rc
+	}
+	if redacted.Guards.Secret != "" {
+		redacted.Guards.Secret = redactedMask
+	}
+	redacted.Hosts = make([]PCHostConfig, len(c.Hosts))
+	for i, host := range c.Hosts {
+		redacted.Hosts[i] = host.Redacted()
+	}
+	return &redacted
+}
+
+// Redacted 返回隐藏了 guard 签名密钥、SecureOn 密码、唤醒方式认证信息和地址中用户信息的主机配置副本，用于接口返回和审计记录
+func (h PCHostConfig) Redacted() PCHostConfig {
+	if h.WOL != nil && h.WOL.Password != "" {
+		wol := *h.WOL
+		wol.Password = redactedMask
+		h.WOL = &wol
+	}
+	if h.Guard != nil && h.Guard.Secret != "" {
+		guard := *h.Guard
+		guard.Secret = redactedMask
+		h.Guard = &guard
+	}
+	if h.Guard != nil {
+		guard := *h.Guard
+		guard.URL = redactURL(guard.URL)
+		h.Guard = &guard
+	}
+	if h.Probe != nil && len(h.Probe.Checks) > 0 {
+		probe := *h.Probe
+		probe.Checks = make([]ProbeCheck, len(h.Probe.Checks))
+		for j, check := range h.Probe.Checks {
+			check.URL = redactURL(check.URL)
+			probe.Checks[j] = check
+		}
+		h.Probe = &probe
+	}
+	if len(h.Wake) > 0 {
+		wake := make([]WakeMethod, len(h.Wake))
+		for j, m := range h.Wake {
+			if m.Password != "" {
+				m.Password = redactedMask
+			}
+			// 请求内容中可能有令牌或密码
+			if m.Body != "" {
+				m.Body = redactedMask
+			}
+			m.URL = redactURL(m.URL)
+			// 请求头中通常是访问令牌
+			if len(m.Headers) > 0 {
+				headers := make(map[string]string, len(m.Headers))
+				for k := range m.Headers {
+					headers[k] = redactedMask
+				}
+				m.Headers = headers
+			}
+			wake[j] = m
+		}
+		h.Wake = wake
+	}
+	return h
+}
+
+// RestoreRedacted 将提交的主机配置中仍为隐藏值的敏感配置项恢复为 prev 中的原值，
+// 客户端读取主机配置后原样提交时不会覆盖密码和密钥，唤醒方式按顺序对应
+func (h *PCHostConfig) RestoreRedacted(prev PCHostConfig) {
+	if h.WOL != nil && h.WOL.Password == redactedMask && prev.WOL != nil {
+		h.WOL.Password = prev.WOL.Password
+	}
+	if h.Guard != nil && prev.Guard != nil {
+		if h.Guard.Secret == redactedMask {
+			h.Guard.Secret = prev.Guard.Secret
+		}
+		h.Guard.URL = restoreURL(h.Guard.URL, prev.Guard.URL)
+	}
+	if h.Probe != nil && prev.Probe != nil {
+		for j := range h.Probe.Checks {
+			if j < len(prev.Probe.Checks) {
+				h.Probe.Checks[j].URL = restoreURL(h.Probe.Checks[j].URL, prev.Probe.Checks[j].URL)
+			}
+		}
+	}
+	for j := range h.Wake {
+		if j >= len(prev.Wake) {
+			break
+		}
+		m, old := &h.Wake[j], prev.Wake[j]
+		if m.Password == redactedMask {
+			m.Password = old.Password
+		}
+		if m.Body == redactedMask {
+			m.Body = old.Body
+		}
+		m.URL = restoreURL(m.URL, old.URL)
+		for k, v := range m.Headers {
+			if v == redactedMask {
+				m.Headers[k] = old.Headers[k]
+			}
+		}
+	}
+}
+
+// redactURL 隐藏地址中的用户信息，有密码时只隐藏密码，只有用户名时用户名通常是令牌
+func redactURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || u.User == nil {
+		return s
+	}
+	userinfo := redactedMask
+	if _, ok := u.User.Password(); ok {
+		userinfo = u.User.Username() + ":" + redactedMask
+	}
+	// url.URL 会转义用户信息中的 *，直接拼接
+	u.User = nil
+	scheme, rest, _ := strings.Cut(u.String(), "://")
+	return scheme + "://" + userinfo + "@" + rest
+}
+
+// restoreURL 提交的地址与原地址隐藏后相同时恢复为原地址
+func restoreURL(s, prev string) string {
+	if prev != "" && s != prev && s == redactURL(prev) {
+		return prev
+	}
+	return s
+}
+
+// LoadOverrides 合并环境变量和命令行参数 -set 的配置覆盖，命令行参数优先
+func LoadOverrides(sets []string) ([]Override, error) {
+	overrides, err := EnvOverrides(os.Environ())
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range sets {
+		o, err := ParseOverride(s)
+		if err != nil {
+			return nil, fmt.Errorf("-set 参数错误: %v", err)
+		}
+		overrides = append(overrides, o)
+	}
+	return overrides, nil
+}
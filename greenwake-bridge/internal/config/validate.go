@@ -0,0 +1,539 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// minGuardSecret guard 控制接口签名密钥的最短长度，与 guard 的要求一致
+const minGuardSecret = 16
+
+// Problem 配置中的一处问题
+type Problem struct {
+	Path    string `json:"path"`           // YAML 路径，如 forwards[2].target_host
+	Line    int    `json:"line,omitempty"` // 所在行号，无法定位时为0
+	Message string `json:"message"`
+}
+
+func (p Problem) String() string {
+	if p.Line > 0 {
+		return fmt.Sprintf("第%d行 %s: %s", p.Line, p.Path, p.Message)
+	}
+	return fmt.Sprintf("%s: %s", p.Path, p.Message)
+}
+
+// ValidationError 配置校验失败，包含发现的全部问题
+type ValidationError struct {
+	File     string
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, 0, len(e.Problems)+1)
+	lines = append(lines, fmt.Sprintf("配置 %s 校验发现 %d 个问题:", e.File, len(e.Problems)))
+	for _, p := range e.Problems {
+		lines = append(lines, "  "+p.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Validate 检查全部配置项，返回的 *ValidationError 包含每个问题的 YAML 路径和行号
+func (c *Config) Validate() error {
+	v := &validator{root: c.node}
+	v.unknownKeys(c.node, reflect.TypeOf(*c), nil)
+
+	relays := v.relays(c.Relays)
+	hosts := v.hosts(c.Hosts, relays)
+	v.http(c.HTTP)
+	v.monitor(c.Monitor)
+	v.guards(c.Guards)
+	v.retention(yamlPath{"session_log"}, c.SessionLog)
+	v.retention(yamlPath{"audit_log"}, c.AuditLog)
+	v.retention(yamlPath{"event_log"}, c.EventLog)
+	v.tokens(c.Tokens, hosts)
+
+	// 所有 TCP 监听端口，用于检查转发、反向代理、SOCKS 和 HTTP 端口冲突
+	ports := make(map[string]string)
+	if port, err := strconv.Atoi(c.HTTP.Port); err == nil {
+		ports[fmt.Sprintf("%d/%s", port, ProtocolTCP)] = "http.port"
+	}
+	v.forwards(c.Forwards, hosts, ports)
+	v.proxies(c.Proxies, hosts, ports)
+	v.socks(c.Socks, ports)
+
+	if len(v.problems) == 0 {
+		return nil
+	}
+	return &ValidationError{File: c.path, Problems: v.problems}
+}
+
+// yamlPath 配置项路径，元素为映射键（string）或序列下标（int）
+type yamlPath []interface{}
+
+func (p yamlPath) child(elem interface{}) yamlPath {
+	return append(p[:len(p):len(p)], elem)
+}
+
+func (p yamlPath) String() string {
+	var b strings.Builder
+	for _, elem := range p {
+		switch elem := elem.(type) {
+		case int:
+			fmt.Fprintf(&b, "[%d]", elem)
+		case string:
+			if b.Len() > 0 {
+				b.WriteByte('.')
+			}
+			b.WriteString(elem)
+		}
+	}
+	return b.String()
+}
+
+type validator struct {
+	root     *yaml.Node // 配置文件的根映射，未从文件解析时为 nil
+	problems []Problem
+}
+
+func (v *validator) addf(path yamlPath, format string, args ...interface{}) {
+	v.problems = append(v.problems, Problem{
+		Path:    path.String(),
+		Line:    v.line(path),
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// line 返回路径所在行号，配置项未出现在文件中（使用默认值）时返回最近的上级配置项所在行
+func (v *validator) line(path yamlPath) int {
+	node, line := v.root, 0
+	for _, elem := range path {
+		if node == nil {
+			break
+		}
+		var next *yaml.Node
+		switch elem := elem.(type) {
+		case string:
+			if node.Kind == yaml.MappingNode {
+				for i := 0; i+1 < len(node.Content); i += 2 {
+					if node.Content[i].Value == elem {
+						line = node.Content[i].Line
+						next = node.Content[i+1]
+						break
+					}
+				}
+			}
+		case int:
+			if node.Kind == yaml.SequenceNode && elem < len(node.Content) {
+				next = node.Content[elem]
+				line = next.Line
+			}
+		}
+		node = next
+	}
+	return line
+}
+
+// unknownKeys 检查文件中无法对应到配置字段的键，通常是拼写错误
+func (v *validator) unknownKeys(node *yaml.Node, t reflect.Type, path yamlPath) {
+	if node == nil {
+		return
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t.Kind() == reflect.Struct && node.Kind == yaml.MappingNode:
+		fields := make(map[string]reflect.Type, t.NumField())
+		for i := 0; i < t.NumField(); i++ {
+			name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+			if name != "" && name != "-" {
+				fields[name] = t.Field(i).Type
+			}
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			ft, ok := fields[key]
+			if !ok {
+				v.problems = append(v.problems, Problem{
+					Path:    path.child(key).String(),
+					Line:    node.Content[i].Line,
+					Message: "未知的配置项",
+				})
+				continue
+			}
+			v.unknownKeys(node.Content[i+1], ft, path.child(key))
+		}
+	case t.Kind() == reflect.Slice && node.Kind == yaml.SequenceNode:
+		for i, item := range node.Content {
+			v.unknownKeys(item, t.Elem(), path.child(i))
+		}
+	}
+}
+
+func validPort(port int) bool {
+	return port > 0 && port <= 65535
+}
+
+// nonNegative 检查可省略（0 表示使用默认值）但不能为负数的配置项
+func (v *validator) nonNegative(p yamlPath, value int) {
+	if value < 0 {
+		v.addf(p, "不能为负数: %d", value)
+	}
+}
+
+// hosts 检查主机配置，返回主机名集合
+func (v *validator) hosts(hosts []PCHostConfig, relays map[string]bool) map[string]bool {
+	names := make(map[string]bool, len(hosts))
+	// 主机名、IP 和别名都用于代理请求匹配主机，不能重复
+	addresses := make(map[string]string)
+
+	for i, host := range hosts {
+		p := yamlPath{"hosts", i}
+
+		if host.Name == "" {
+			v.addf(p.child("name"), "缺少主机名")
+		} else if names[host.Name] {
+			v.addf(p.child("name"), "主机名称重复: %s", host.Name)
+		}
+		names[host.Name] = true
+
+		if host.IP == "" {
+			v.addf(p.child("ip"), "缺少 ip")
+		} else if net.ParseIP(host.IP) == nil {
+			v.addf(p.child("ip"), "IP 地址格式错误: %s", host.IP)
+		}
+		if host.MAC == "" {
+			v.addf(p.child("mac"), "缺少 mac")
+		} else if hw, err := net.ParseMAC(host.MAC); err != nil || len(hw) != 6 {
+			v.addf(p.child("mac"), "MAC 地址格式错误: %s", host.MAC)
+		}
+
+		if host.MonitorPort != 0 && !validPort(host.MonitorPort) {
+			v.addf(p.child("monitor_port"), "端口无效: %d", host.MonitorPort)
+		}
+		v.nonNegative(p.child("wake_timeout"), host.WakeTimeout)
+		v.nonNegative(p.child("retry_count"), host.RetryCount)
+		v.nonNegative(p.child("wake_interval"), host.WakeInterval)
+		for j, port := range host.AllowedPorts {
+			if !validPort(port) {
+				v.addf(p.child("allowed_ports").child(j), "端口无效: %d", port)
+			}
+		}
+
+		for j, alias := range append([]string{host.Name, host.IP}, host.Aliases...) {
+			if alias == "" {
+				continue
+			}
+			key := strings.ToLower(alias)
+			if owner, ok := addresses[key]; ok && owner != host.Name {
+				if j >= 2 {
+					v.addf(p.child("aliases").child(j-2), "别名 %s 与主机 %s 冲突", alias, owner)
+				}
+				continue
+			}
+			addresses[key] = host.Name
+		}
+
+		v.probe(p, host)
+		v.wol(p.child("wol"), host.WOL)
+		v.wake(p.child("wake"), host.Wake)
+		if host.Guard != nil {
+			v.guard(p.child("guard"), host.Guard.URL, host.Guard.Secret)
+		}
+		if host.Relay != "" {
+			if !relays[host.Relay] {
+				v.addf(p.child("relay"), "中继不存在: %s", host.Relay)
+			}
+			// 中继在 guard 所在网段发送UDP唤醒包，不能指定 bridge 的网卡
+			if host.WOL != nil && (host.WOL.Interface != "" || host.WOL.Transport == WOLTransportRaw) {
+				v.addf(p.child("relay"), "使用中继时不支持 wol.interface 和 wol.transport: raw")
+			}
+		}
+	}
+	return names
+}
+
+// relays 检查唤醒包中继配置，返回中继名称集合
+func (v *validator) relays(relays []RelayConfig) map[string]bool {
+	names := make(map[string]bool, len(relays))
+	for i, rc := range relays {
+		p := yamlPath{"relays", i}
+
+		if rc.Name == "" {
+			v.addf(p.child("name"), "缺少中继名称")
+		} else if names[rc.Name] {
+			v.addf(p.child("name"), "中继名称重复: %s", rc.Name)
+		}
+		names[rc.Name] = true
+
+		v.guard(p, rc.URL, rc.Secret)
+	}
+	return names
+}
+
+// guard 检查 greenwake-guard 控制接口的地址和签名密钥
+func (v *validator) guard(p yamlPath, address, secret string) {
+	if u, err := url.Parse(address); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		v.addf(p.child("url"), "地址格式错误，应为 http(s)://主机:端口: %s", address)
+	}
+	if secret == "" {
+		v.addf(p.child("secret"), "缺少签名密钥")
+	} else if len(secret) < minGuardSecret {
+		v.addf(p.child("secret"), "签名密钥至少 %d 个字符", minGuardSecret)
+	}
+}
+
+// guards 检查 guard 注册配置，未设置密钥时不接受注册
+func (v *validator) guards(cfg GuardsConfig) {
+	if cfg.Secret != "" && len(cfg.Secret) < minGuardSecret {
+		v.addf(yamlPath{"guards", "secret"}, "签名密钥至少 %d 个字符", minGuardSecret)
+	}
+	v.nonNegative(yamlPath{"guards", "heartbeat_interval"}, cfg.HeartbeatInterval)
+}
+
+// wol 检查唤醒包发送配置，网卡是否存在在发送时检查，以便在其他机器上校验配置
+func (v *validator) wol(p yamlPath, cfg *WOLConfig) {
+	if cfg == nil {
+		return
+	}
+	switch cfg.Transport {
+	case "", WOLTransportUDP:
+	case WOLTransportRaw:
+		if cfg.Interface == "" {
+			v.addf(p.child("transport"), "raw 方式需要指定 interface")
+		}
+	default:
+		v.addf(p.child("transport"), "未知的发送方式: %s", cfg.Transport)
+	}
+	if cfg.Port != 0 && !validPort(cfg.Port) {
+		v.addf(p.child("port"), "端口无效: %d", cfg.Port)
+	}
+	v.nonNegative(p.child("count"), cfg.Count)
+	v.nonNegative(p.child("interval_ms"), cfg.IntervalMs)
+	if _, err := cfg.SecureOn(); err != nil {
+		v.addf(p.child("password"), "%v", err)
+	}
+}
+
+// wake 检查唤醒方式配置
+func (v *validator) wake(p yamlPath, methods []WakeMethod) {
+	for i, m := range methods {
+		mp := p.child(i)
+		v.nonNegative(mp.child("timeout"), m.Timeout)
+		switch m.Type {
+		case WakeRedfish, WakeHTTP:
+			if m.URL == "" {
+				v.addf(mp, "%s 唤醒缺少 url", m.Type)
+			} else if u, err := url.Parse(m.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				v.addf(mp.child("url"), "URL 格式错误: %s", m.URL)
+			}
+			if m.Type == WakeRedfish && m.System != "" && !strings.HasPrefix(m.System, "/") {
+				v.addf(mp.child("system"), "系统路径应以 / 开头，如 /redfish/v1/Systems/1: %s", m.System)
+			}
+			if m.Status != 0 && (m.Status < 100 || m.Status > 599) {
+				v.addf(mp.child("status"), "状态码无效: %d", m.Status)
+			}
+		case WakeCommand:
+			if len(m.Command) == 0 {
+				v.addf(mp, "command 唤醒缺少 command")
+			}
+		case WakeWOL:
+		default:
+			v.addf(mp.child("type"), "未知的唤醒方式: %s", m.Type)
+		}
+	}
+}
+
+// probe 检查主机的在线检测配置
+func (v *validator) probe(host yamlPath, cfg PCHostConfig) {
+	if cfg.Probe == nil || len(cfg.Probe.Checks) == 0 {
+		if cfg.MonitorPort == 0 {
+			v.addf(host, "未配置 monitor_port 或 probe")
+		}
+		return
+	}
+
+	p := host.child("probe")
+	// 组合方式与 probe 包的 any/all 一致
+	if mode := cfg.Probe.Mode; mode != "" && mode != "any" && mode != "all" {
+		v.addf(p.child("mode"), "未知的组合方式: %s", mode)
+	}
+	v.nonNegative(p.child("timeout"), cfg.Probe.Timeout)
+
+	for i, check := range cfg.Probe.Checks {
+		cp := p.child("checks").child(i)
+		v.nonNegative(cp.child("timeout"), check.Timeout)
+		switch check.Type {
+		case ProbeTCP:
+			if len(check.Ports) == 0 && cfg.MonitorPort == 0 {
+				v.addf(cp, "tcp 检测缺少 ports")
+			}
+			for j, port := range check.Ports {
+				if !validPort(port) {
+					v.addf(cp.child("ports").child(j), "端口无效: %d", port)
+				}
+			}
+		case ProbeHTTP:
+			if check.URL == "" {
+				v.addf(cp, "http 检测缺少 url")
+			} else if u, err := url.Parse(check.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				v.addf(cp.child("url"), "URL 格式错误: %s", check.URL)
+			}
+		case ProbeCommand:
+			if len(check.Command) == 0 {
+				v.addf(cp, "command 检测缺少 command")
+			}
+		case ProbeICMP, ProbeARP:
+		default:
+			v.addf(cp.child("type"), "未知的检测类型: %s", check.Type)
+		}
+	}
+}
+
+func (v *validator) http(cfg HTTPConfig) {
+	if port, err := strconv.Atoi(cfg.Port); err != nil || !validPort(port) {
+		v.addf(yamlPath{"http", "port"}, "端口无效: %s", cfg.Port)
+	}
+	v.nonNegative(yamlPath{"http", "session_ttl"}, cfg.SessionTTL)
+	v.nonNegative(yamlPath{"http", "refresh_interval"}, cfg.RefreshInterval)
+}
+
+func (v *validator) monitor(cfg MonitorConfig) {
+	v.nonNegative(yamlPath{"monitor", "interval"}, cfg.Interval)
+	if cfg.MaxInterval < cfg.Interval {
+		v.addf(yamlPath{"monitor", "max_interval"}, "不能小于 interval（%d）: %d", cfg.Interval, cfg.MaxInterval)
+	}
+}
+
+func (v *validator) retention(p yamlPath, cfg RetentionConfig) {
+	v.nonNegative(p.child("retention_days"), cfg.RetentionDays)
+	v.nonNegative(p.child("max_records"), cfg.MaxRecords)
+}
+
+// tokens 检查配置文件中的令牌，操作名称由 auth 包在加载令牌时检查
+func (v *validator) tokens(tokens []TokenConfig, hosts map[string]bool) {
+	names := make(map[string]bool, len(tokens))
+	for i, tc := range tokens {
+		p := yamlPath{"tokens", i}
+		if tc.Name == "" {
+			v.addf(p.child("name"), "缺少令牌名称")
+		} else if names[tc.Name] {
+			v.addf(p.child("name"), "令牌名称重复: %s", tc.Name)
+		}
+		names[tc.Name] = true
+
+		if tc.Token == "" {
+			v.addf(p.child("token"), "缺少 token")
+		}
+		if len(tc.Actions) == 0 {
+			v.addf(p.child("actions"), "缺少 actions")
+		}
+		for j, host := range tc.Hosts {
+			if host != "*" && !hosts[host] {
+				v.addf(p.child("hosts").child(j), "主机不存在: %s", host)
+			}
+		}
+		if tc.ExpiresAt != "" {
+			if _, err := time.Parse(time.RFC3339, tc.ExpiresAt); err != nil {
+				v.addf(p.child("expires_at"), "时间格式错误，应为 RFC3339: %s", tc.ExpiresAt)
+			}
+		}
+	}
+}
+
+// listen 登记监听端口，与已登记的端口冲突时报告问题
+func (v *validator) listen(ports map[string]string, p yamlPath, port int, protocol string) {
+	key := fmt.Sprintf("%d/%s", port, protocol)
+	if owner, ok := ports[key]; ok {
+		v.addf(p, "监听端口 %s 与 %s 冲突", key, owner)
+		return
+	}
+	ports[key] = p.String()
+}
+
+func (v *validator) forwards(forwards []ForwardConfig, hosts map[string]bool, ports map[string]string) {
+	for i, fc := range forwards {
+		p := yamlPath{"forwards", i}
+
+		protocolOK := fc.Protocol == ProtocolTCP || fc.Protocol == ProtocolUDP
+		if !protocolOK {
+			v.addf(p.child("protocol"), "未知的协议: %s", fc.Protocol)
+		}
+		if !validPort(fc.ServicePort) {
+			v.addf(p.child("service_port"), "端口无效: %d", fc.ServicePort)
+		} else if protocolOK {
+			v.listen(ports, p.child("service_port"), fc.ServicePort, fc.Protocol)
+		}
+		if !validPort(fc.TargetPort) {
+			v.addf(p.child("target_port"), "端口无效: %d", fc.TargetPort)
+		}
+		if fc.TargetHost == "" {
+			v.addf(p.child("target_host"), "缺少目标主机")
+		} else if !hosts[fc.TargetHost] {
+			v.addf(p.child("target_host"), "目标主机不存在: %s", fc.TargetHost)
+		}
+		v.nonNegative(p.child("idle_timeout"), fc.IdleTimeout)
+	}
+}
+
+func (v *validator) proxies(proxies []ProxyConfig, hosts map[string]bool, ports map[string]string) {
+	for i, pc := range proxies {
+		p := yamlPath{"proxies", i}
+
+		if !validPort(pc.ServicePort) {
+			v.addf(p.child("service_port"), "端口无效: %d", pc.ServicePort)
+		} else {
+			v.listen(ports, p.child("service_port"), pc.ServicePort, ProtocolTCP)
+		}
+		if len(pc.Routes) == 0 {
+			v.addf(p, "缺少 routes")
+		}
+
+		for j, rc := range pc.Routes {
+			rp := p.child("routes").child(j)
+			if rc.TargetHost == "" {
+				v.addf(rp.child("target_host"), "缺少目标主机")
+			} else if !hosts[rc.TargetHost] {
+				v.addf(rp.child("target_host"), "目标主机不存在: %s", rc.TargetHost)
+			}
+			if !validPort(rc.TargetPort) {
+				v.addf(rp.child("target_port"), "端口无效: %d", rc.TargetPort)
+			}
+			if rc.Scheme != "http" && rc.Scheme != "https" {
+				v.addf(rp.child("scheme"), "未知的 scheme: %s", rc.Scheme)
+			}
+			if rc.PathPrefix != "" && !strings.HasPrefix(rc.PathPrefix, "/") {
+				v.addf(rp.child("path_prefix"), "路径前缀需以 / 开头: %s", rc.PathPrefix)
+			}
+			v.nonNegative(rp.child("hold_timeout"), rc.HoldTimeout)
+		}
+	}
+}
+
+func (v *validator) socks(cfg SocksConfig, ports map[string]string) {
+	listeners := []struct {
+		key  string
+		port int
+	}{
+		{"service_port", cfg.ServicePort},
+		{"connect_port", cfg.ConnectPort},
+	}
+	for _, l := range listeners {
+		p, port := yamlPath{"socks", l.key}, l.port
+		switch {
+		case port == 0:
+		case !validPort(port):
+			v.addf(p, "端口无效: %d", port)
+		default:
+			v.listen(ports, p, port, ProtocolTCP)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package config
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const validateTestConfig = `http:
+  port: "8055"
+  user: admin
+  password: secret
+hosts:
+  - name: desktop
+    ip: 192.168.1.10
+    mac: "00:11:22:33:44:55"
+    wol:
+      prot: 9
+forwards:
+  - service_port: 8055
+    target_host: desktop
+    target_port: 3389
+  - service_port: 13389
+    protocol: udp
+    target_host: desktop
+    target_port: 3389
+  - service_port: 13389
+    target_host: nas
+    target_port: 3389
+proxies:
+  - service_port: 13389
+    routes:
+      - target_host: desktop
+        target_port: 80
+socks:
+  service_port: 1080
+  conect_port: 1081
+`
+
+func TestYAMLPathString(t *testing.T) {
+	tests := []struct {
+		path yamlPath
+		want string
+	}{
+		{yamlPath{}, ""},
+		{yamlPath{"http", "port"}, "http.port"},
+		{yamlPath{"hosts", 0, "wake", 1, "url"}, "hosts[0].wake[1].url"},
+		{yamlPath{"proxies", 2, "routes", 0}, "proxies[2].routes[0]"},
+	}
+	for _, tt := range tests {
+		if got := tt.path.String(); got != tt.want {
+			t.Errorf("路径为 %q，期望 %q", got, tt.want)
+		}
+	}
+
+	// child 不修改上级路径
+	parent := make(yamlPath, 1, 4)
+	parent[0] = "hosts"
+	a, b := parent.child(0), parent.child(1)
+	if a.String() != "hosts[0]" || b.String() != "hosts[1]" {
+		t.Errorf("子路径为 %s 和 %s，期望 hosts[0] 和 hosts[1]", a, b)
+	}
+}
+
+func TestValidatorLine(t *testing.T) {
+	cfg, err := Parse([]byte(validateTestConfig), "")
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	v := &validator{root: cfg.node}
+
+	tests := []struct {
+		name string
+		path yamlPath
+		want int
+	}{
+		{"映射键", yamlPath{"http", "password"}, 4},
+		{"序列项", yamlPath{"forwards", 2}, 19},
+		{"序列项的键", yamlPath{"forwards", 2, "target_host"}, 20},
+		{"嵌套序列", yamlPath{"proxies", 0, "routes", 0, "target_port"}, 26},
+		{"未配置的项使用上级所在行", yamlPath{"hosts", 0, "wol", "port"}, 9},
+		{"下标超出时使用上级所在行", yamlPath{"forwards", 5, "target_host"}, 11},
+		{"未出现的顶级配置项", yamlPath{"monitor", "interval"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := v.line(tt.path); got != tt.want {
+				t.Errorf("%s 所在行为 %d，期望 %d", tt.path, got, tt.want)
+			}
+		})
+	}
+
+	// 未从文件解析的配置无法定位
+	if got := (&validator{}).line(yamlPath{"http", "port"}); got != 0 {
+		t.Errorf("没有配置文件时行号为 %d，期望 0", got)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cfg, err := Parse([]byte(validateTestConfig), "config.yaml")
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+
+	var verr *ValidationError
+	if err := cfg.Validate(); !errors.As(err, &verr) {
+		t.Fatalf("校验结果为 %v，期望 *ValidationError", err)
+	}
+	if verr.File != "config.yaml" {
+		t.Errorf("配置文件为 %q，期望 config.yaml", verr.File)
+	}
+
+	want := []Problem{
+		// 拼写错误的配置项
+		{Path: "hosts[0].wol.prot", Line: 10, Message: "未知的配置项"},
+		{Path: "socks.conect_port", Line: 29, Message: "未知的配置项"},
+		{Path: "hosts[0]", Line: 6, Message: "未配置 monitor_port 或 probe"},
+		// 转发与 HTTP 端口冲突，UDP 转发与 TCP 监听同一端口不冲突
+		{Path: "forwards[0].service_port", Line: 12, Message: "监听端口 8055/tcp 与 http.port 冲突"},
+		{Path: "forwards[2].target_host", Line: 20, Message: "目标主机不存在: nas"},
+		{Path: "proxies[0].service_port", Line: 23, Message: "监听端口 13389/tcp 与 forwards[2].service_port 冲突"},
+	}
+	if !reflect.DeepEqual(verr.Problems, want) {
+		t.Errorf("校验发现的问题为:\n%v\n期望:\n%v", verr, &ValidationError{File: "config.yaml", Problems: want})
+	}
+
+	if got, want := want[3].String(), "第12行 forwards[0].service_port: 监听端口 8055/tcp 与 http.port 冲突"; got != want {
+		t.Errorf("问题描述为 %q，期望 %q", got, want)
+	}
+	if got, want := (Problem{Path: "http.port", Message: "端口无效"}).String(), "http.port: 端口无效"; got != want {
+		t.Errorf("没有行号的问题描述为 %q，期望 %q", got, want)
+	}
+}
+
+func TestValidateSocksPortConflict(t *testing.T) {
+	cfg, err := Parse([]byte(`http:
+  port: "1080"
+socks:
+  service_port: 1080
+  connect_port: 1080
+`), "")
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+
+	var verr *ValidationError
+	if err := cfg.Validate(); !errors.As(err, &verr) {
+		t.Fatalf("校验结果为 %v，期望 *ValidationError", err)
+	}
+	want := []Problem{
+		{Path: "socks.service_port", Line: 4, Message: "监听端口 1080/tcp 与 http.port 冲突"},
+		{Path: "socks.connect_port", Line: 5, Message: "监听端口 1080/tcp 与 http.port 冲突"},
+	}
+	var got []Problem
+	for _, p := range verr.Problems {
+		if p.Path == "socks.service_port" || p.Path == "socks.connect_port" {
+			got = append(got, p)
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("端口冲突为 %v，期望 %v", got, want)
+	}
+}
@@ -0,0 +1,179 @@
+package guard
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const requestTimeout = 10 * time.Second
+
+// Client greenwake-guard 控制接口客户端
+type Client struct {
+	baseURL string
+	secret  string
+	http    *http.Client
+}
+
+// PingResponse guard 的基本信息
+type PingResponse struct {
+	Version  int    `json:"version"`   // 协议版本
+	Hostname string `json:"hostname"`  // guard 所在主机名
+	WOLRelay bool   `json:"wol_relay"` // 是否启用了唤醒包中继
+	Sleep    bool   `json:"sleep"`     // 是否允许远程睡眠
+	Demand   bool   `json:"demand"`    // 是否接受主机使用状态
+}
+
+// WakeRequest 请求 guard 在所在网段发送唤醒包
+type WakeRequest struct {
+	MAC        string `json:"mac"`
+	Address    string `json:"address,omitempty"`     // 目标地址，默认 255.255.255.255
+	Port       int    `json:"port,omitempty"`        // UDP端口，默认9
+	Count      int    `json:"count,omitempty"`       // 连续发送的包数，默认1
+	IntervalMs int    `json:"interval_ms,omitempty"` // 连续发送的间隔（毫秒）
+	Password   string `json:"password,omitempty"`    // 十六进制的 SecureOn 密码
+}
+
+// 睡眠请求的操作
+const (
+	SleepNow      = "sleep"           // 立即睡眠
+	SleepShutdown = "shutdown"        // 关机
+	SleepWhenIdle = "sleep_when_idle" // 结束 guard 的临时唤醒状态，空闲时睡眠
+)
+
+// SleepRequest 请求 guard 让所在主机睡眠或关机
+type SleepRequest struct {
+	Action      string `json:"action"`
+	RequestedBy string `json:"requested_by"` // 发起者，如 user:admin，记录在 guard 日志中
+}
+
+// SleepResponse guard 接受睡眠请求后的响应
+type SleepResponse struct {
+	Action    string `json:"action"`
+	DelaySecs int    `json:"delay_secs"` // sleep_when_idle: 预计睡眠前的等待时间（秒）
+}
+
+// DemandRequest 向 guard 报告主机是否在使用
+type DemandRequest struct {
+	InUse     bool   `json:"in_use"`
+	IdleSince string `json:"idle_since,omitempty"` // 不再使用的开始时间（RFC3339）
+	Sessions  int    `json:"sessions"`             // 活跃的转发会话数
+	Leases    int    `json:"leases"`               // 保持唤醒租约数
+}
+
+// DemandResponse guard 接受使用状态后的响应
+type DemandResponse struct {
+	InUse bool   `json:"in_use"`
+	Until string `json:"until"` // guard 保持唤醒的截止时间（RFC3339）
+}
+
+// errorResponse guard 返回的错误
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// NewClient 创建客户端，baseURL 如 http://192.168.2.10:8056
+func NewClient(baseURL, secret string) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("地址格式错误: %s", baseURL)
+	}
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		secret:  secret,
+		http:    &http.Client{Timeout: requestTimeout},
+	}, nil
+}
+
+// Ping 检查 guard 是否可达并获取基本信息
+func (c *Client) Ping(ctx context.Context) (*PingResponse, error) {
+	var resp PingResponse
+	if err := c.do(ctx, http.MethodGet, "/v1/ping", nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Wake 请求 guard 发送唤醒包
+func (c *Client) Wake(ctx context.Context, req WakeRequest) error {
+	return c.do(ctx, http.MethodPost, "/v1/wake", req, nil)
+}
+
+// Sleep 请求 guard 让所在主机睡眠、关机或空闲时睡眠
+func (c *Client) Sleep(ctx context.Context, req SleepRequest) (*SleepResponse, error) {
+	var resp SleepResponse
+	if err := c.do(ctx, http.MethodPost, "/v1/sleep", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Demand 报告主机的使用状态，guard 将其作为唤醒来源，主机不再使用一段时间后睡眠
+func (c *Client) Demand(ctx context.Context, req DemandRequest) (*DemandResponse, error) {
+	var resp DemandResponse
+	if err := c.do(ctx, http.MethodPost, "/v1/demand", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// do 发送签名的请求，in 和 out 为 JSON 请求体和响应体，可以为空
+func (c *Client) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderNonce, hex.EncodeToString(nonce))
+	req.Header.Set(HeaderSignature, Sign(c.secret, method, path, timestamp, hex.EncodeToString(nonce), body))
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var e errorResponse
+		if json.Unmarshal(data, &e) == nil && e.Error != "" {
+			return fmt.Errorf("%s (%d)", e.Error, resp.StatusCode)
+		}
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("解析响应失败: %v", err)
+		}
+	}
+	return nil
+}
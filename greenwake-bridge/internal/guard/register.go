@@ -0,0 +1,51 @@
+package guard
+
+// guard 向 bridge 注册和发送心跳的接口路径，签名时使用该路径
+const (
+	RegisterPath  = "/api/guard/v1/register"
+	HeartbeatPath = "/api/guard/v1/heartbeat"
+)
+
+// 心跳中的主机状态
+const (
+	StateAwake    = "awake"         // 正常运行
+	StateSleeping = "sleeping"      // 即将睡眠，guard 在睡眠前发送
+	StateShutdown = "shutting_down" // 即将关机
+	StateStopped  = "stopped"       // guard 退出，主机状态未知
+)
+
+// Heartbeat guard 注册和心跳的请求体，注册时与心跳内容相同
+type Heartbeat struct {
+	Version       int         `json:"version"`
+	Hostname      string      `json:"hostname"`       // guard 所在主机名，用于区分不同的 guard
+	Host          string      `json:"host,omitempty"` // guard 配置的 bridge 主机名，为空时按 MAC 和 IP 匹配
+	OS            string      `json:"os"`
+	State         string      `json:"state"`
+	Strategy      string      `json:"strategy"`   // 唤醒策略：external_wake, permanent, timed
+	SleepMode     string      `json:"sleep_mode"` // 睡眠模式：system, program
+	TemporaryWake bool        `json:"temporary_wake"`
+	SleepInSecs   *int        `json:"sleep_in_secs,omitempty"` // 程序控制睡眠模式下距离睡眠的时间（秒），没有睡眠定时器时为空
+	LastWake      *WakeEvent  `json:"last_wake,omitempty"`
+	Interfaces    []Interface `json:"interfaces"`
+}
+
+// WakeEvent guard 最后一次收到的唤醒事件
+type WakeEvent struct {
+	Type   string `json:"type"`   // wol, device, bridge
+	Source string `json:"source"` // 如唤醒包的源地址
+	Time   string `json:"time"`   // RFC3339
+}
+
+// Interface guard 所在主机的网卡
+type Interface struct {
+	Name string   `json:"name"`
+	MAC  string   `json:"mac"`
+	IPs  []string `json:"ips"`
+}
+
+// RegisterResponse 注册成功后 bridge 返回的匹配主机和心跳间隔，位于响应的 data 中
+type RegisterResponse struct {
+	Version           int    `json:"version"`
+	Host              string `json:"host,omitempty"` // 匹配的主机，未匹配时为空
+	HeartbeatInterval int    `json:"heartbeat_interval"`
+}
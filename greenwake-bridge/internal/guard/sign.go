@@ -0,0 +1,87 @@
+// Package guard 调用 greenwake-guard 的控制接口，并接收 guard 的注册和心跳，请求使用共享密钥签名
+package guard
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProtocolVersion 控制接口和注册协议版本，接口路径包含 /v1
+const ProtocolVersion = 1
+
+// 签名相关的请求头
+const (
+	HeaderTimestamp = "X-Greenwake-Timestamp" // Unix 时间戳（秒），拒绝偏差超过5分钟的请求
+	HeaderNonce     = "X-Greenwake-Nonce"     // 随机数，拒绝重复的请求
+	HeaderSignature = "X-Greenwake-Signature" // 十六进制的 HMAC-SHA256 签名
+)
+
+// Sign 计算请求签名: HMAC-SHA256(密钥, 方法\n路径\n时间戳\n随机数\n请求体)
+func Sign(secret, method, path, timestamp, nonce string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strings.Join([]string{method, path, timestamp, nonce}, "\n")))
+	mac.Write([]byte("\n"))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// maxClockSkew 允许的请求时间偏差，与 guard 一致
+const maxClockSkew = 5 * time.Minute
+
+// Verifier 校验 guard 发送的签名请求（注册和心跳），记录最近使用过的随机数防止重放
+type Verifier struct {
+	mu     sync.Mutex
+	nonces map[string]time.Time
+}
+
+func NewVerifier() *Verifier {
+	return &Verifier{nonces: make(map[string]time.Time)}
+}
+
+// Verify 校验请求的时间戳、签名和随机数，path 为协议中的接口路径
+func (v *Verifier) Verify(secret, method, path string, header http.Header, body []byte) error {
+	timestamp := header.Get(HeaderTimestamp)
+	nonce := header.Get(HeaderNonce)
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || nonce == "" {
+		return errors.New("缺少时间戳或随机数")
+	}
+	if skew := time.Since(time.Unix(ts, 0)); skew > maxClockSkew || skew < -maxClockSkew {
+		return errors.New("请求时间偏差过大，请检查两台机器的时钟")
+	}
+
+	want, _ := hex.DecodeString(Sign(secret, method, path, timestamp, nonce, body))
+	got, err := hex.DecodeString(header.Get(HeaderSignature))
+	if err != nil || !hmac.Equal(got, want) {
+		return errors.New("签名错误")
+	}
+	if !v.useNonce(nonce) {
+		return errors.New("重复的请求")
+	}
+	return nil
+}
+
+// useNonce 记录随机数，已使用过时返回 false，超过时间偏差的记录会被清理
+func (v *Verifier) useNonce(nonce string) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	now := time.Now()
+	for n, at := range v.nonces {
+		if now.Sub(at) > 2*maxClockSkew {
+			delete(v.nonces, n)
+		}
+	}
+	if _, used := v.nonces[nonce]; used {
+		return false
+	}
+	v.nonces[nonce] = now
+	return true
+}
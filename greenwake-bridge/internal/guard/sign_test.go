@@ -0,0 +1,127 @@
+package guard
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSecret = "0123456789abcdef"
+
+// signedHeader 生成带签名的请求头
+func signedHeader(secret, method, path string, at time.Time, nonce string, body []byte) http.Header {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	header := http.Header{}
+	header.Set(HeaderTimestamp, timestamp)
+	header.Set(HeaderNonce, nonce)
+	header.Set(HeaderSignature, Sign(secret, method, path, timestamp, nonce, body))
+	return header
+}
+
+func TestSign(t *testing.T) {
+	// 与 guard 的签名方式一致: HMAC-SHA256(密钥, 方法\n路径\n时间戳\n随机数\n请求体)
+	want := "d6d7f399ea417d0667c499b8e0ddda3fa6be2e0bcdac117a6bfef5b54fe7bcfb"
+	if got := Sign(testSecret, http.MethodPost, "/v1/wake", "1700000000", "n1", []byte(`{"mac":"00:11:22:33:44:55"}`)); got != want {
+		t.Fatalf("签名为 %s，期望 %s", got, want)
+	}
+
+	body := []byte(`{"version":1}`)
+	sig := Sign(testSecret, http.MethodPost, "/api/guard/v1/heartbeat", "1700000000", "n1", body)
+
+	// 任一签名字段变化时签名都应变化
+	changed := map[string]string{
+		"密钥":  Sign("fedcba9876543210", http.MethodPost, "/api/guard/v1/heartbeat", "1700000000", "n1", body),
+		"方法":  Sign(testSecret, http.MethodGet, "/api/guard/v1/heartbeat", "1700000000", "n1", body),
+		"路径":  Sign(testSecret, http.MethodPost, "/api/guard/v1/register", "1700000000", "n1", body),
+		"时间戳": Sign(testSecret, http.MethodPost, "/api/guard/v1/heartbeat", "1700000001", "n1", body),
+		"随机数": Sign(testSecret, http.MethodPost, "/api/guard/v1/heartbeat", "1700000000", "n2", body),
+		"请求体": Sign(testSecret, http.MethodPost, "/api/guard/v1/heartbeat", "1700000000", "n1", []byte(`{"version":2}`)),
+	}
+	for name, other := range changed {
+		if other == sig {
+			t.Errorf("%s变化后签名未变化", name)
+		}
+	}
+}
+
+func TestVerifierVerify(t *testing.T) {
+	const path = "/api/guard/v1/register"
+	body := []byte(`{"version":1,"hostname":"desktop"}`)
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		header  http.Header
+		body    []byte
+		wantErr string
+	}{
+		{"正确签名", signedHeader(testSecret, http.MethodPost, path, now, "ok", body), body, ""},
+		{"允许时间偏差内的请求", signedHeader(testSecret, http.MethodPost, path, now.Add(-4*time.Minute), "past", body), body, ""},
+		{"允许时钟略快的请求", signedHeader(testSecret, http.MethodPost, path, now.Add(4*time.Minute), "future", body), body, ""},
+		{"缺少时间戳", http.Header{HeaderNonce: {"x"}}, body, "缺少时间戳或随机数"},
+		{"缺少随机数", signedHeader(testSecret, http.MethodPost, path, now, "", body), body, "缺少时间戳或随机数"},
+		{"时间戳过旧", signedHeader(testSecret, http.MethodPost, path, now.Add(-6*time.Minute), "old", body), body, "时间偏差过大"},
+		{"时间戳超前", signedHeader(testSecret, http.MethodPost, path, now.Add(6*time.Minute), "new", body), body, "时间偏差过大"},
+		{"密钥错误", signedHeader("fedcba9876543210", http.MethodPost, path, now, "key", body), body, "签名错误"},
+		{"请求体被修改", signedHeader(testSecret, http.MethodPost, path, now, "body", body), []byte(`{"version":1}`), "签名错误"},
+		{"签名不是十六进制", http.Header{
+			HeaderTimestamp: {strconv.FormatInt(now.Unix(), 10)},
+			HeaderNonce:     {"hex"},
+			HeaderSignature: {"not-hex"},
+		}, body, "签名错误"},
+	}
+
+	v := NewVerifier()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.Verify(testSecret, http.MethodPost, path, tt.header, tt.body)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("校验失败: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("错误为 %v，期望包含 %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifierRejectsReplay(t *testing.T) {
+	const path = "/api/guard/v1/heartbeat"
+	body := []byte(`{"version":1}`)
+	header := signedHeader(testSecret, http.MethodPost, path, time.Now(), "replay", body)
+
+	v := NewVerifier()
+	if err := v.Verify(testSecret, http.MethodPost, path, header, body); err != nil {
+		t.Fatalf("首次请求校验失败: %v", err)
+	}
+	if err := v.Verify(testSecret, http.MethodPost, path, header, body); err == nil || !strings.Contains(err.Error(), "重复的请求") {
+		t.Errorf("重放的请求应返回重复的请求，得到 %v", err)
+	}
+
+	// 签名错误的请求不占用随机数
+	bad := signedHeader("fedcba9876543210", http.MethodPost, path, time.Now(), "unused", body)
+	v.Verify(testSecret, http.MethodPost, path, bad, body)
+	good := signedHeader(testSecret, http.MethodPost, path, time.Now(), "unused", body)
+	if err := v.Verify(testSecret, http.MethodPost, path, good, body); err != nil {
+		t.Errorf("签名错误的请求不应占用随机数，得到 %v", err)
+	}
+
+	// 超过两倍时间偏差的随机数被清理
+	v.mu.Lock()
+	v.nonces["replay"] = time.Now().Add(-2*maxClockSkew - time.Second)
+	v.mu.Unlock()
+	if !v.useNonce("other") {
+		t.Fatal("新的随机数应可使用")
+	}
+	v.mu.Lock()
+	_, kept := v.nonces["replay"]
+	v.mu.Unlock()
+	if kept {
+		t.Error("过期的随机数未被清理")
+	}
+}
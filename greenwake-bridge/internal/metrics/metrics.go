@@ -0,0 +1,91 @@
+// Package metrics 定义 Prometheus 指标，通过 /metrics 暴露
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "greenwake"
+
+var (
+	// WakePackets 成功发送的唤醒请求数，使用唤醒包以外的唤醒方式时也计入
+	WakePackets = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "wake_packets_sent_total",
+		Help:      "Number of successful wake requests (Wake-on-LAN or another wake method), by host.",
+	}, []string{"host"})
+
+	// WakePacketErrors 全部唤醒方式都失败的次数
+	WakePacketErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "wake_packet_errors_total",
+		Help:      "Number of wake requests where every wake method failed, by host.",
+	}, []string{"host"})
+
+	// Wakes 唤醒结果，主机在等待时间内上线为 success，否则为 timeout
+	Wakes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "wakes_total",
+		Help:      "Number of completed wake attempts, by host and result (success or timeout).",
+	}, []string{"host", "result"})
+
+	// WakeDuration 从发送唤醒包到检测到主机上线的耗时
+	WakeDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "wake_duration_seconds",
+		Help:      "Time from the first wake packet until the host was detected online.",
+		Buckets:   []float64{1, 2, 5, 10, 15, 20, 30, 45, 60, 90, 120, 180, 300},
+	}, []string{"host"})
+
+	// HTTPDuration Web界面和API请求耗时
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of HTTP API requests, by method, route and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+)
+
+var registry = prometheus.NewRegistry()
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		WakePackets,
+		WakePacketErrors,
+		Wakes,
+		WakeDuration,
+		HTTPDuration,
+	)
+}
+
+// Register 注册抓取时才计算的指标，如主机状态和转发通道统计
+func Register(c prometheus.Collector) error {
+	return registry.Register(c)
+}
+
+// Unregister 取消注册，服务关闭时调用
+func Unregister(c prometheus.Collector) {
+	registry.Unregister(c)
+}
+
+// Handler 返回 Prometheus 文本格式的指标
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// WakeFinished 记录一次唤醒的结果，online 为 false 表示等待超时仍未上线
+func WakeFinished(host string, online bool, elapsed time.Duration) {
+	if !online {
+		Wakes.WithLabelValues(host, "timeout").Inc()
+		return
+	}
+	Wakes.WithLabelValues(host, "success").Inc()
+	WakeDuration.WithLabelValues(host).Observe(elapsed.Seconds())
+}
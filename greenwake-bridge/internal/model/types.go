@@ -1,35 +1,46 @@
 package model
 
+import "encoding/json"
+
 type PCHostInfo struct {
 	Name        string `json:"name"`
 	IP          string `json:"ip"`
 	MAC         string `json:"mac"`
 	MonitorPort int    `json:"monitorPort"`
+	Relay       string `json:"relay,omitempty"` // 发送唤醒包的中继
+	Sleep       bool   `json:"sleep,omitempty"` // 配置了 guard，可以远程睡眠或关机
 }
 
 type PCHostStatus struct {
-	Name         string `json:"name"`
-	IsOnline     bool   `json:"isOnline"`
-	KeepAwake    bool   `json:"keepAwake"`
-	LastUpdate   string `json:"lastUpdate,omitempty"`
-	LastWakeTime string `json:"lastWakeTime,omitempty"`
+	Name         string       `json:"name"`
+	IsOnline     bool         `json:"isOnline"`
+	State        string       `json:"state"`      // unknown, online, offline, waking
+	StateSince   string       `json:"stateSince"` // 进入当前状态的时间
+	KeepAwake    bool         `json:"keepAwake"`
+	LastUpdate   string       `json:"lastUpdate,omitempty"` // 最后一次检测时间
+	LastWakeTime string       `json:"lastWakeTime,omitempty"`
+	LastWakeBy   string       `json:"lastWakeBy,omitempty"` // 最后一次唤醒的发起者
+	Guard        *GuardStatus `json:"guard,omitempty"`      // 主机上的 greenwake-guard 最近一次心跳
 }
 
-type ClientInfo struct {
-	ID         string `json:"id"`
-	UserAgent  string `json:"userAgent"`
-	IP         string `json:"ip"`
-	Port       string `json:"port,omitempty"`
-	LastSeen   string `json:"lastSeen"`
-	TargetHost string `json:"targetHost"`
+// StatusTransition 主机状态变化记录
+type StatusTransition struct {
+	State string `json:"state"`
+	At    string `json:"at"`
 }
 
-type ChannelClient struct {
-	ID         string `json:"id"`
-	IP         string `json:"ip"`
-	Port       string `json:"port"`
-	Status     string `json:"status"`
-	LastActive string `json:"lastActive"`
+type KeepAwakeLease struct {
+	ID        string `json:"id"`
+	Host      string `json:"host"`
+	Owner     string `json:"owner"`
+	Reason    string `json:"reason,omitempty"`
+	ClientIP  string `json:"clientIp,omitempty"`
+	UserAgent string `json:"userAgent,omitempty"`
+	TTL       int    `json:"ttl"`
+	CreatedAt string `json:"createdAt"`
+	RenewedAt string `json:"renewedAt"`
+	ExpiresAt string `json:"expiresAt"`
+	EndAt     string `json:"endAt,omitempty"`
 }
 
 type AggregatedClient struct {
@@ -41,6 +52,7 @@ type AggregatedClient struct {
 
 type ForwardChannel struct {
 	ID          string              `json:"id"`
+	Protocol    string              `json:"protocol"`
 	ServicePort int                 `json:"service_port"`
 	TargetHost  string              `json:"target_host"`
 	TargetPort  int                 `json:"target_port"`
@@ -48,6 +60,35 @@ type ForwardChannel struct {
 	LastActive  string              `json:"last_active"`
 	Clients     []*AggregatedClient `json:"clients"`
 	ActiveCount int                 `json:"active_count"`
+	IdleTimeout int                 `json:"idle_timeout,omitempty"` // udp 会话空闲超时（秒）
+
+	TotalSessions    int64 `json:"total_sessions"`
+	FailedSessions   int64 `json:"failed_sessions"`
+	WakeTimeouts     int64 `json:"wake_timeouts"`       // 唤醒超时导致的失败连接数
+	DialFailures     int64 `json:"dial_failures"`       // 连接目标失败的连接数
+	BytesToTarget    int64 `json:"bytes_to_target"`     // 客户端 -> 目标主机的字节数
+	BytesToClient    int64 `json:"bytes_to_client"`     // 目标主机 -> 客户端的字节数
+	Wakes            int64 `json:"wakes"`               // 需要唤醒且唤醒成功的连接数
+	AvgWakeLatencyMs int64 `json:"avg_wake_latency_ms"` // 平均唤醒耗时（毫秒）
+}
+
+// ForwardSession 一次转发连接的记录
+type ForwardSession struct {
+	ID             string `json:"id"`
+	Channel        string `json:"channel"`
+	Protocol       string `json:"protocol"`
+	ServicePort    int    `json:"service_port"`
+	TargetHost     string `json:"target_host"`
+	TargetPort     int    `json:"target_port"`
+	ClientIP       string `json:"client_ip"`
+	ClientPort     int    `json:"client_port"`
+	StartTime      string `json:"start_time"`
+	EndTime        string `json:"end_time"`
+	WakeNeeded     bool   `json:"wake_needed"`      // 连接时主机是否离线需要唤醒
+	WakeDurationMs int64  `json:"wake_duration_ms"` // 唤醒耗时（毫秒）
+	BytesToTarget  int64  `json:"bytes_to_target"`
+	BytesToClient  int64  `json:"bytes_to_client"`
+	CloseReason    string `json:"close_reason"`
 }
 
 type Response struct {
@@ -55,3 +96,70 @@ type Response struct {
 	Error   string      `json:"error,omitempty"`
 	Data    interface{} `json:"data,omitempty"`
 }
+
+// AuditEntry 配置修改审计记录
+type AuditEntry struct {
+	ID     string          `json:"id"`
+	Time   string          `json:"time"`
+	Actor  string          `json:"actor"`            // 调用者，如 user:admin、token:ha
+	Action string          `json:"action"`           // 操作，如 host.create、forward.delete
+	Target string          `json:"target"`           // 操作对象，如主机名、转发端口
+	Before json.RawMessage `json:"before,omitempty"` // 修改前的配置
+	After  json.RawMessage `json:"after,omitempty"`  // 修改后的配置
+}
+
+// Event 实时事件，通过 SSE 推送给前端，并保存为事件记录
+type Event struct {
+	ID    int64       `json:"id,omitempty"`    // 事件ID，只推送不保存的事件（如 lease.renewed）为空
+	Type  string      `json:"type"`            // 事件类型，如 host.state、wake.sent、session.opened
+	Host  string      `json:"host,omitempty"`  // 相关主机
+	Actor string      `json:"actor,omitempty"` // 发起者，如 user:admin、token:ha、forward:<通道>、keep-awake:<租约持有者>
+	Time  string      `json:"time"`
+	Data  interface{} `json:"data,omitempty"`
+}
+
+// RelayStatus 唤醒包中继（greenwake-guard）的可达状态
+type RelayStatus struct {
+	Name      string `json:"name"`
+	URL       string `json:"url"`
+	Reachable bool   `json:"reachable"`
+	WOLRelay  bool   `json:"wolRelay"` // guard 是否启用了唤醒包中继
+	Hostname  string `json:"hostname,omitempty"`
+	Version   int    `json:"version,omitempty"` // guard 控制接口协议版本
+	LatencyMs int64  `json:"latencyMs,omitempty"`
+	LastCheck string `json:"lastCheck,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// GuardStatus 向 bridge 注册的 greenwake-guard 及其最近一次心跳
+type GuardStatus struct {
+	Hostname      string           `json:"hostname"`
+	Host          string           `json:"host,omitempty"` // 匹配的主机，未匹配时为空
+	OS            string           `json:"os"`
+	Version       int              `json:"version"`
+	Online        bool             `json:"online"` // 超过3倍心跳间隔没有心跳时为 false
+	State         string           `json:"state"`  // awake, sleeping, shutting_down, stopped
+	Strategy      string           `json:"strategy"`
+	SleepMode     string           `json:"sleepMode"`
+	TemporaryWake bool             `json:"temporaryWake"`
+	SleepAt       string           `json:"sleepAt,omitempty"` // 程序控制睡眠模式下预计睡眠的时间
+	LastWake      *GuardWakeEvent  `json:"lastWake,omitempty"`
+	Interfaces    []GuardInterface `json:"interfaces"`
+	Address       string           `json:"address"` // 心跳的来源地址
+	RegisteredAt  string           `json:"registeredAt"`
+	LastHeartbeat string           `json:"lastHeartbeat"`
+}
+
+// GuardWakeEvent guard 最后一次收到的唤醒事件
+type GuardWakeEvent struct {
+	Type   string `json:"type"`
+	Source string `json:"source"`
+	Time   string `json:"time"`
+}
+
+// GuardInterface guard 所在主机的网卡
+type GuardInterface struct {
+	Name string   `json:"name"`
+	MAC  string   `json:"mac"`
+	IPs  []string `json:"ips"`
+}
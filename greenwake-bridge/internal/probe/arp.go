@@ -0,0 +1,38 @@
+package probe
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// arpProber 主机地址存在于本机ARP/邻居表中时认为在线，只适用于同一网段的主机
+// 检测前先发送一个UDP包触发地址解析，邻居表项失效存在数秒延迟
+type arpProber struct {
+	host string
+}
+
+func (p *arpProber) Probe(ctx context.Context) bool {
+	ip, err := resolveIP(ctx, p.host)
+	if err != nil {
+		return false
+	}
+
+	// 发送到 discard 端口，促使内核重新解析邻居地址
+	if conn, err := net.Dial("udp", net.JoinHostPort(ip.String(), "9")); err == nil {
+		conn.Write([]byte{0})
+		conn.Close()
+	}
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	return neighborPresent(ctx, ip)
+}
+
+func (p *arpProber) String() string {
+	return "arp"
+}
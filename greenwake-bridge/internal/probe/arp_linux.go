@@ -0,0 +1,43 @@
+package probe
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// neighborPresent 检查邻居表中是否存在已解析的主机地址
+// IPv4 读取 /proc/net/arp，IPv6 使用 ip -6 neigh
+func neighborPresent(ctx context.Context, ip net.IP) bool {
+	if ip.To4() == nil {
+		out, err := exec.CommandContext(ctx, "ip", "-6", "neigh", "show", ip.String()).Output()
+		if err != nil {
+			return false
+		}
+		line := string(out)
+		return strings.Contains(line, "lladdr") && !strings.Contains(line, "FAILED") && !strings.Contains(line, "INCOMPLETE")
+	}
+
+	f, err := os.Open("/proc/net/arp")
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	// 格式: IP address  HW type  Flags  HW address  Mask  Device
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || fields[0] != ip.String() {
+			continue
+		}
+		// Flags 为 0x0 表示解析未完成
+		if fields[2] != "0x0" && fields[3] != "00:00:00:00:00:00" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,27 @@
+//go:build !linux
+
+package probe
+
+import (
+	"context"
+	"net"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+var macPattern = regexp.MustCompile(`(?i)([0-9a-f]{1,2}[:-]){5}[0-9a-f]{1,2}`)
+
+// neighborPresent 通过 arp 命令检查主机地址是否已解析
+func neighborPresent(ctx context.Context, ip net.IP) bool {
+	out, err := exec.CommandContext(ctx, "arp", "-n", ip.String()).Output()
+	if err != nil {
+		// Windows 的 arp 不支持 -n
+		out, err = exec.CommandContext(ctx, "arp", "-a", ip.String()).Output()
+		if err != nil {
+			return false
+		}
+	}
+	line := strings.ToLower(string(out))
+	return !strings.Contains(line, "incomplete") && macPattern.MatchString(line)
+}
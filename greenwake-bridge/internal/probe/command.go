@@ -0,0 +1,30 @@
+package probe
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+
+	"greenwake-bridge/internal/config"
+)
+
+// commandProber 执行自定义命令，退出码为0时认为在线
+// 命令可以通过环境变量 GREENWAKE_HOST、GREENWAKE_IP、GREENWAKE_MAC 获取主机信息，bridge 的其他 GREENWAKE_ 环境变量不会传递
+type commandProber struct {
+	host    config.PCHostConfig
+	command []string
+}
+
+func (p *commandProber) Probe(ctx context.Context) bool {
+	cmd := exec.CommandContext(ctx, p.command[0], p.command[1:]...)
+	cmd.Env = append(config.ChildEnviron(),
+		"GREENWAKE_HOST="+p.host.Name,
+		"GREENWAKE_IP="+p.host.IP,
+		"GREENWAKE_MAC="+p.host.MAC,
+	)
+	return cmd.Run() == nil
+}
+
+func (p *commandProber) String() string {
+	return "command:" + strings.Join(p.command, " ")
+}
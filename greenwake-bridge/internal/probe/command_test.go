@@ -0,0 +1,50 @@
+package probe
+
+import (
+	"context"
+	"runtime"
+	"testing"
+
+	"greenwake-bridge/internal/config"
+)
+
+func TestCommandProber(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("需要 sh")
+	}
+	t.Setenv("GREENWAKE_HTTP_PASSWORD", "secret")
+	host := config.PCHostConfig{Name: "desktop", IP: "192.168.1.10", MAC: "00:11:22:33:44:55"}
+
+	tests := []struct {
+		name    string
+		command []string
+		want    bool
+	}{
+		{"退出码为0", []string{"sh", "-c", "exit 0"}, true},
+		{"退出码非0", []string{"sh", "-c", "exit 3"}, false},
+		{"命令不存在", []string{"greenwake-probe-not-exist"}, false},
+		{"传递主机信息", []string{"sh", "-c", `test "$GREENWAKE_HOST/$GREENWAKE_IP/$GREENWAKE_MAC" = "desktop/192.168.1.10/00:11:22:33:44:55"`}, true},
+		{"不传递配置覆盖", []string{"sh", "-c", `test -z "$GREENWAKE_HTTP_PASSWORD"`}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &commandProber{host: host, command: tt.command}
+			if got := p.Probe(context.Background()); got != tt.want {
+				t.Errorf("检测结果为 %v，期望 %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommandProberTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("需要 sh")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	p := &commandProber{command: []string{"sh", "-c", "sleep 10"}}
+	if p.Probe(ctx) {
+		t.Error("ctx 已取消时检测结果为在线")
+	}
+}
@@ -0,0 +1,67 @@
+package probe
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"strings"
+
+	"greenwake-bridge/internal/config"
+)
+
+const maxHTTPProbeBody = 1 << 20 // 校验响应内容时最多读取1MB
+
+// httpProber 发送 GET 请求，返回期望的状态码（默认任意2xx）且包含期望内容时认为在线
+type httpProber struct {
+	url    string
+	status int
+	body   string
+	client *http.Client
+}
+
+func newHTTPProber(check config.ProbeCheck) *httpProber {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if check.Insecure {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	return &httpProber{
+		url:    check.URL,
+		status: check.Status,
+		body:   check.Body,
+		client: &http.Client{Transport: transport},
+	}
+}
+
+func (p *httpProber) Probe(ctx context.Context) bool {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.url, nil)
+	if err != nil {
+		return false
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+
+	if p.status != 0 {
+		if resp.StatusCode != p.status {
+			return false
+		}
+	} else if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return false
+	}
+
+	if p.body == "" {
+		return true
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxHTTPProbeBody))
+	if err != nil {
+		return false
+	}
+	return strings.Contains(string(data), p.body)
+}
+
+func (p *httpProber) String() string {
+	return "http:" + p.url
+}
@@ -0,0 +1,65 @@
+package probe
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"greenwake-bridge/internal/config"
+)
+
+func TestHTTPProber(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			w.Write([]byte(`{"status":"ready"}`))
+		case "/redirect":
+			http.Redirect(w, r, "/ok", http.StatusFound)
+		case "/unauthorized":
+			w.WriteHeader(http.StatusUnauthorized)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name  string
+		check config.ProbeCheck
+		want  bool
+	}{
+		{"2xx 状态码", config.ProbeCheck{URL: srv.URL + "/ok"}, true},
+		{"跟随重定向", config.ProbeCheck{URL: srv.URL + "/redirect"}, true},
+		{"非 2xx 状态码", config.ProbeCheck{URL: srv.URL + "/missing"}, false},
+		{"期望的状态码", config.ProbeCheck{URL: srv.URL + "/unauthorized", Status: http.StatusUnauthorized}, true},
+		{"状态码不符", config.ProbeCheck{URL: srv.URL + "/ok", Status: http.StatusNoContent}, false},
+		{"包含期望内容", config.ProbeCheck{URL: srv.URL + "/ok", Body: `"ready"`}, true},
+		{"不包含期望内容", config.ProbeCheck{URL: srv.URL + "/ok", Body: "starting"}, false},
+		{"无法连接", config.ProbeCheck{URL: "http://127.0.0.1:1/"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newHTTPProber(tt.check).Probe(context.Background()); got != tt.want {
+				t.Errorf("检测结果为 %v，期望 %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTPProberInsecure(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0) // 证书校验失败时服务端的握手错误
+	srv.StartTLS()
+	defer srv.Close()
+
+	if newHTTPProber(config.ProbeCheck{URL: srv.URL}).Probe(context.Background()) {
+		t.Error("自签名证书未校验失败")
+	}
+	if !newHTTPProber(config.ProbeCheck{URL: srv.URL, Insecure: true}).Probe(context.Background()) {
+		t.Error("设置 insecure 后仍校验证书")
+	}
+}
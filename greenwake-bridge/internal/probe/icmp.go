@@ -0,0 +1,97 @@
+package probe
+
+import (
+	"context"
+	"log"
+	"math/rand"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// icmpProber 使用非特权ping套接字发送ICMP Echo
+// Linux 下需要 net.ipv4.ping_group_range 包含运行用户的组
+type icmpProber struct {
+	host string
+	warn sync.Once
+}
+
+func (p *icmpProber) Probe(ctx context.Context) bool {
+	ip, err := resolveIP(ctx, p.host)
+	if err != nil {
+		return false
+	}
+
+	network, address := "udp4", "0.0.0.0"
+	var reqType, replyType icmp.Type = ipv4.ICMPTypeEcho, ipv4.ICMPTypeEchoReply
+	proto := 1 // ICMPv4
+	if ip.To4() == nil {
+		network, address = "udp6", "::"
+		reqType, replyType = ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply
+		proto = 58 // ICMPv6
+	}
+
+	conn, err := icmp.ListenPacket(network, address)
+	if err != nil {
+		p.warn.Do(func() {
+			log.Printf("创建ICMP套接字失败，请检查 net.ipv4.ping_group_range: %v", err)
+		})
+		return false
+	}
+	defer conn.Close()
+
+	// 非特权套接字的 ID 由内核分配，只按序号匹配应答
+	seq := rand.Intn(0xffff)
+	msg := icmp.Message{
+		Type: reqType,
+		Body: &icmp.Echo{
+			ID:   os.Getpid() & 0xffff,
+			Seq:  seq,
+			Data: []byte("greenwake"),
+		},
+	}
+	data, err := msg.Marshal(nil)
+	if err != nil {
+		return false
+	}
+	if _, err := conn.WriteTo(data, &net.UDPAddr{IP: ip}); err != nil {
+		return false
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(5 * time.Second)
+	}
+	conn.SetReadDeadline(deadline)
+
+	// ctx 取消时关闭套接字，结束读取
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	buf := make([]byte, 1500)
+	for {
+		n, peer, err := conn.ReadFrom(buf)
+		if err != nil {
+			return false
+		}
+		if udpAddr, ok := peer.(*net.UDPAddr); !ok || !udpAddr.IP.Equal(ip) {
+			continue
+		}
+		reply, err := icmp.ParseMessage(proto, buf[:n])
+		if err != nil || reply.Type != replyType {
+			continue
+		}
+		if echo, ok := reply.Body.(*icmp.Echo); ok && echo.Seq == seq {
+			return true
+		}
+	}
+}
+
+func (p *icmpProber) String() string {
+	return "icmp"
+}
@@ -0,0 +1,168 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"greenwake-bridge/internal/config"
+)
+
+// 多个检测的组合方式
+const (
+	ModeAny = "any" // 任一检测成功即认为在线
+	ModeAll = "all" // 全部检测成功才认为在线
+)
+
+// Prober 主机在线检测
+type Prober interface {
+	// Probe 检测主机是否在线，ctx 取消或超时时返回 false
+	Probe(ctx context.Context) bool
+	// String 返回用于日志展示的检测描述
+	String() string
+}
+
+// New 根据主机配置创建在线检测，未配置 probe 时检测 monitor_port 的TCP连接
+func New(host config.PCHostConfig) (Prober, error) {
+	cfg := host.Probe
+	if cfg == nil || len(cfg.Checks) == 0 {
+		if host.MonitorPort == 0 {
+			return nil, fmt.Errorf("未配置 monitor_port 或 probe")
+		}
+		cfg = &config.ProbeConfig{
+			Checks: []config.ProbeCheck{{Type: config.ProbeTCP}},
+		}
+		if host.Probe != nil {
+			cfg.Mode = host.Probe.Mode
+			cfg.Timeout = host.Probe.Timeout
+		}
+	}
+
+	mode := cfg.Mode
+	if mode == "" {
+		mode = ModeAny
+	}
+	if mode != ModeAny && mode != ModeAll {
+		return nil, fmt.Errorf("未知的 probe.mode: %s", mode)
+	}
+
+	defaultTimeout := cfg.Timeout
+	if defaultTimeout <= 0 {
+		defaultTimeout = config.DefaultProbeTimeout
+	}
+
+	probers := make([]Prober, 0, len(cfg.Checks))
+	for i, check := range cfg.Checks {
+		p, err := newCheck(host, check)
+		if err != nil {
+			return nil, fmt.Errorf("probe.checks[%d]: %v", i, err)
+		}
+		timeout := check.Timeout
+		if timeout <= 0 {
+			timeout = defaultTimeout
+		}
+		probers = append(probers, &timeoutProber{Prober: p, timeout: time.Duration(timeout) * time.Second})
+	}
+
+	if len(probers) == 1 {
+		return probers[0], nil
+	}
+	return &multiProber{mode: mode, probers: probers}, nil
+}
+
+func newCheck(host config.PCHostConfig, check config.ProbeCheck) (Prober, error) {
+	switch check.Type {
+	case config.ProbeTCP:
+		ports := check.Ports
+		if len(ports) == 0 && host.MonitorPort > 0 {
+			ports = []int{host.MonitorPort}
+		}
+		if len(ports) == 0 {
+			return nil, fmt.Errorf("tcp 检测缺少 ports")
+		}
+		return &tcpProber{host: host.IP, ports: ports}, nil
+	case config.ProbeICMP:
+		return &icmpProber{host: host.IP}, nil
+	case config.ProbeHTTP:
+		if check.URL == "" {
+			return nil, fmt.Errorf("http 检测缺少 url")
+		}
+		return newHTTPProber(check), nil
+	case config.ProbeARP:
+		return &arpProber{host: host.IP}, nil
+	case config.ProbeCommand:
+		if len(check.Command) == 0 {
+			return nil, fmt.Errorf("command 检测缺少 command")
+		}
+		return &commandProber{host: host, command: check.Command}, nil
+	default:
+		return nil, fmt.Errorf("未知的检测类型: %s", check.Type)
+	}
+}
+
+// timeoutProber 为单个检测设置超时时间
+type timeoutProber struct {
+	Prober
+	timeout time.Duration
+}
+
+func (p *timeoutProber) Probe(ctx context.Context) bool {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	return p.Prober.Probe(ctx)
+}
+
+// multiProber 并发执行多个检测，按 any/all 组合结果
+type multiProber struct {
+	mode    string
+	probers []Prober
+}
+
+func (p *multiProber) Probe(ctx context.Context) bool {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan bool, len(p.probers))
+	for _, prober := range p.probers {
+		go func(prober Prober) {
+			results <- prober.Probe(ctx)
+		}(prober)
+	}
+
+	// 结果已能确定时立即返回，并取消其余检测
+	for range p.probers {
+		online := <-results
+		if p.mode == ModeAny && online {
+			return true
+		}
+		if p.mode == ModeAll && !online {
+			return false
+		}
+	}
+	return p.mode == ModeAll
+}
+
+func (p *multiProber) String() string {
+	names := make([]string, 0, len(p.probers))
+	for _, prober := range p.probers {
+		names = append(names, prober.String())
+	}
+	return fmt.Sprintf("%s(%s)", p.mode, strings.Join(names, ", "))
+}
+
+// resolveIP 解析主机地址，配置中的 ip 也可以是主机名
+func resolveIP(ctx context.Context, host string) (net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return ip, nil
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("无法解析主机地址: %s", host)
+	}
+	return addrs[0].IP, nil
+}
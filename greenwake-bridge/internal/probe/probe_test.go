@@ -0,0 +1,157 @@
+package probe
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"greenwake-bridge/internal/config"
+)
+
+// stubProber 返回固定结果，block 为 true 时一直等待到 ctx 取消
+type stubProber struct {
+	online   bool
+	block    bool
+	canceled chan struct{} // block 为 true 时，ctx 取消后关闭
+}
+
+func (p *stubProber) Probe(ctx context.Context) bool {
+	if p.block {
+		<-ctx.Done()
+		close(p.canceled)
+		return false
+	}
+	return p.online
+}
+
+func (p *stubProber) String() string {
+	return "stub"
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		host    config.PCHostConfig
+		want    string
+		wantErr string
+	}{
+		{"默认检测 monitor_port", config.PCHostConfig{IP: "127.0.0.1", MonitorPort: 3389}, "tcp:3389", ""},
+		{"只配置超时时间", config.PCHostConfig{IP: "127.0.0.1", MonitorPort: 3389, Probe: &config.ProbeConfig{Timeout: 2}}, "tcp:3389", ""},
+		{"多个检测默认任一成功", config.PCHostConfig{IP: "127.0.0.1", MonitorPort: 3389, Probe: &config.ProbeConfig{
+			Checks: []config.ProbeCheck{{Type: config.ProbeTCP, Ports: []int{22, 445}}, {Type: config.ProbeHTTP, URL: "http://127.0.0.1/"}},
+		}}, "any(tcp:22,445, http:http://127.0.0.1/)", ""},
+		{"全部成功", config.PCHostConfig{IP: "127.0.0.1", Probe: &config.ProbeConfig{
+			Mode:   ModeAll,
+			Checks: []config.ProbeCheck{{Type: config.ProbeICMP}, {Type: config.ProbeCommand, Command: []string{"true"}}},
+		}}, "all(icmp, command:true)", ""},
+		{"缺少 monitor_port", config.PCHostConfig{IP: "127.0.0.1"}, "", "未配置 monitor_port 或 probe"},
+		{"未知的组合方式", config.PCHostConfig{IP: "127.0.0.1", MonitorPort: 3389, Probe: &config.ProbeConfig{Mode: "most"}}, "", "未知的 probe.mode"},
+		{"未知的检测类型", config.PCHostConfig{IP: "127.0.0.1", Probe: &config.ProbeConfig{
+			Checks: []config.ProbeCheck{{Type: "snmp"}},
+		}}, "", "probe.checks[0]: 未知的检测类型"},
+		{"tcp 缺少端口", config.PCHostConfig{IP: "127.0.0.1", Probe: &config.ProbeConfig{
+			Checks: []config.ProbeCheck{{Type: config.ProbeTCP}},
+		}}, "", "tcp 检测缺少 ports"},
+		{"http 缺少地址", config.PCHostConfig{IP: "127.0.0.1", Probe: &config.ProbeConfig{
+			Checks: []config.ProbeCheck{{Type: config.ProbeHTTP}},
+		}}, "", "http 检测缺少 url"},
+		{"command 缺少命令", config.PCHostConfig{IP: "127.0.0.1", Probe: &config.ProbeConfig{
+			Checks: []config.ProbeCheck{{Type: config.ProbeICMP}, {Type: config.ProbeCommand}},
+		}}, "", "probe.checks[1]: command 检测缺少 command"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := New(tt.host)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("错误为 %v，期望包含 %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("创建检测失败: %v", err)
+			}
+			if got := p.String(); got != tt.want {
+				t.Errorf("检测为 %s，期望 %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMultiProber(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    string
+		results []bool
+		want    bool
+	}{
+		{"any 全部成功", ModeAny, []bool{true, true}, true},
+		{"any 部分成功", ModeAny, []bool{false, true}, true},
+		{"any 全部失败", ModeAny, []bool{false, false}, false},
+		{"all 全部成功", ModeAll, []bool{true, true}, true},
+		{"all 部分成功", ModeAll, []bool{true, false}, false},
+		{"all 全部失败", ModeAll, []bool{false, false}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &multiProber{mode: tt.mode}
+			for _, online := range tt.results {
+				p.probers = append(p.probers, &stubProber{online: online})
+			}
+			if got := p.Probe(context.Background()); got != tt.want {
+				t.Errorf("检测结果为 %v，期望 %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMultiProberReturnsEarly(t *testing.T) {
+	// 结果已能确定时不等待其余检测，并取消它们
+	tests := []struct {
+		name   string
+		mode   string
+		online bool
+	}{
+		{"any 任一成功", ModeAny, true},
+		{"all 任一失败", ModeAll, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pending := &stubProber{block: true, canceled: make(chan struct{})}
+			p := &multiProber{mode: tt.mode, probers: []Prober{pending, &stubProber{online: tt.online}}}
+
+			done := make(chan bool, 1)
+			go func() { done <- p.Probe(context.Background()) }()
+			select {
+			case got := <-done:
+				if got != tt.online {
+					t.Errorf("检测结果为 %v，期望 %v", got, tt.online)
+				}
+			case <-time.After(3 * time.Second):
+				t.Fatal("结果确定后仍在等待其余检测")
+			}
+			select {
+			case <-pending.canceled:
+			case <-time.After(3 * time.Second):
+				t.Error("其余检测未被取消")
+			}
+		})
+	}
+}
+
+func TestTimeoutProber(t *testing.T) {
+	pending := &stubProber{block: true, canceled: make(chan struct{})}
+	p := &timeoutProber{Prober: pending, timeout: 50 * time.Millisecond}
+
+	start := time.Now()
+	if p.Probe(context.Background()) {
+		t.Error("超时的检测结果为在线")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("检测耗时 %v，期望在超时后返回", elapsed)
+	}
+}
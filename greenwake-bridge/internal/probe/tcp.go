@@ -0,0 +1,48 @@
+package probe
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// tcpProber 任一端口可以建立TCP连接即认为在线
+type tcpProber struct {
+	host  string
+	ports []int
+}
+
+func (p *tcpProber) Probe(ctx context.Context) bool {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan bool, len(p.ports))
+	for _, port := range p.ports {
+		go func(port int) {
+			var d net.Dialer
+			conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(p.host, strconv.Itoa(port)))
+			if err != nil {
+				results <- false
+				return
+			}
+			conn.Close()
+			results <- true
+		}(port)
+	}
+
+	for range p.ports {
+		if <-results {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *tcpProber) String() string {
+	ports := make([]string, 0, len(p.ports))
+	for _, port := range p.ports {
+		ports = append(ports, strconv.Itoa(port))
+	}
+	return "tcp:" + strings.Join(ports, ",")
+}
@@ -0,0 +1,71 @@
+package probe
+
+import (
+	"context"
+	"net"
+	"testing"
+)
+
+// listenPort 在本地监听一个端口，测试结束后关闭
+func listenPort(t *testing.T) int {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听本地端口失败: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+// closedPort 返回一个没有监听的本地端口
+func closedPort(t *testing.T) int {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听本地端口失败: %v", err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+	return port
+}
+
+func TestTCPProber(t *testing.T) {
+	open, closed := listenPort(t), closedPort(t)
+
+	tests := []struct {
+		name  string
+		ports []int
+		want  bool
+	}{
+		{"端口可以连接", []int{open}, true},
+		{"端口没有监听", []int{closed}, false},
+		{"任一端口可以连接", []int{closed, open}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &tcpProber{host: "127.0.0.1", ports: tt.ports}
+			if got := p.Probe(context.Background()); got != tt.want {
+				t.Errorf("检测结果为 %v，期望 %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTCPProberCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	p := &tcpProber{host: "127.0.0.1", ports: []int{listenPort(t)}}
+	if p.Probe(ctx) {
+		t.Error("ctx 已取消时检测结果为在线")
+	}
+}
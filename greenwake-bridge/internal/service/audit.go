@@ -0,0 +1,138 @@
+package service
+
+import (
+	"encoding/json"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"greenwake-bridge/internal/config"
+	"greenwake-bridge/internal/model"
+	"greenwake-bridge/internal/store"
+)
+
+// auditRecord 配置修改审计记录
+type auditRecord struct {
+	ID     string          `json:"id"`
+	Time   time.Time       `json:"time"`
+	Actor  string          `json:"actor"`
+	Action string          `json:"action"`
+	Target string          `json:"target"`
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+}
+
+func (r auditRecord) toModel() *model.AuditEntry {
+	entry := &model.AuditEntry{
+		ID:     r.ID,
+		Time:   r.Time.Format(time.RFC3339),
+		Actor:  r.Actor,
+		Action: r.Action,
+		Target: r.Target,
+		Before: r.Before,
+		After:  r.After,
+	}
+	// 旧版本保存的主机配置中可能有密码和密钥，返回前再隐藏一次
+	if strings.HasPrefix(r.Action, "host.") {
+		entry.Before = redactHostJSON(r.Before)
+		entry.After = redactHostJSON(r.After)
+	}
+	return entry
+}
+
+// redactHostJSON 隐藏审计记录中主机配置的敏感配置项
+func redactHostJSON(raw json.RawMessage) json.RawMessage {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	var host config.PCHostConfig
+	if err := json.Unmarshal(raw, &host); err != nil {
+		return nil
+	}
+	redacted, _ := json.Marshal(host.Redacted())
+	return redacted
+}
+
+// AuditFilter 审计记录查询条件，零值表示不限制
+type AuditFilter struct {
+	Action string
+	Target string
+	From   time.Time
+	To     time.Time
+	Offset int
+	Limit  int
+}
+
+func (f *AuditFilter) match(r auditRecord) bool {
+	if f.Action != "" && r.Action != f.Action {
+		return false
+	}
+	if f.Target != "" && r.Target != f.Target {
+		return false
+	}
+	if !f.From.IsZero() && r.Time.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && r.Time.After(f.To) {
+		return false
+	}
+	return true
+}
+
+// AuditService 持久化配置修改审计记录，并发布配置修改事件
+type AuditService struct {
+	log    *store.Log[auditRecord]
+	events *EventBus
+}
+
+func NewAuditService(cfg *config.Config, events *EventBus) (*AuditService, error) {
+	l, err := store.Open(filepath.Join(cfg.DataDir, "audit.jsonl"), store.Options{
+		MaxAge:     time.Duration(cfg.AuditLog.RetentionDays) * 24 * time.Hour,
+		MaxRecords: cfg.AuditLog.MaxRecords,
+	}, func(r auditRecord) time.Time {
+		return r.Time
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &AuditService{log: l, events: events}, nil
+}
+
+// Record 保存一条审计记录，host 为修改涉及的主机，before/after 为修改前后的配置，新增或删除时对应一项为 nil，
+// 调用方需先隐藏其中的密码和密钥
+func (s *AuditService) Record(actor, action, target, host string, before, after interface{}) {
+	r := auditRecord{
+		ID:     newSessionID(),
+		Time:   time.Now(),
+		Actor:  actor,
+		Action: action,
+		Target: target,
+	}
+	if before != nil {
+		r.Before, _ = json.Marshal(before)
+	}
+	if after != nil {
+		r.After, _ = json.Marshal(after)
+	}
+
+	log.Printf("配置修改: %s %s %s", actor, action, target)
+	if err := s.log.Append(r); err != nil {
+		log.Printf("保存审计记录失败: %v", err)
+	}
+	s.events.Publish(EventConfigChanged, host, map[string]string{"actor": actor, "action": action, "target": target})
+}
+
+// Query 查询审计记录，返回按时间倒序的记录和匹配总数
+func (s *AuditService) Query(filter AuditFilter) ([]*model.AuditEntry, int) {
+	records, total := s.log.Query(filter.match, filter.Offset, filter.Limit)
+	entries := make([]*model.AuditEntry, 0, len(records))
+	for _, r := range records {
+		entries = append(entries, r.toModel())
+	}
+	return entries, total
+}
+
+func (s *AuditService) Close() {
+	s.log.Close()
+}
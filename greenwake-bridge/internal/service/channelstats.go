@@ -0,0 +1,113 @@
+package service
+
+import (
+	"io"
+	"sync/atomic"
+	"time"
+
+	"greenwake-bridge/internal/model"
+)
+
+// channelStats 单个转发通道的连接统计
+type channelStats struct {
+	active         atomic.Int64
+	totalSessions  atomic.Int64
+	failedSessions atomic.Int64
+	wakeTimeouts   atomic.Int64 // 主机唤醒超时导致的失败
+	dialFailures   atomic.Int64 // 连接目标端口失败
+	bytesToTarget  atomic.Int64 // 客户端 -> 目标主机
+	bytesToClient  atomic.Int64 // 目标主机 -> 客户端
+	wakes          atomic.Int64 // 需要唤醒且唤醒成功的连接数
+	wakeLatency    atomic.Int64 // 唤醒耗时累计（纳秒）
+	lastActive     atomic.Int64 // 最后活跃时间（Unix纳秒）
+}
+
+// channelClient 转发通道的客户端连接，最后活跃时间由转发协程并发更新
+type channelClient struct {
+	id         string
+	ip         string
+	port       string
+	lastActive atomic.Int64 // 最后活跃时间（Unix纳秒）
+}
+
+func newChannelClient(id, ip, port string) *channelClient {
+	c := &channelClient{id: id, ip: ip, port: port}
+	c.touch(time.Now())
+	return c
+}
+
+func (c *channelClient) touch(at time.Time) {
+	c.lastActive.Store(at.UnixNano())
+}
+
+func (c *channelClient) lastActiveTime() time.Time {
+	return time.Unix(0, c.lastActive.Load())
+}
+
+func (st *channelStats) sessionStarted() {
+	st.active.Add(1)
+	st.totalSessions.Add(1)
+	st.touch()
+}
+
+func (st *channelStats) sessionEnded() {
+	st.active.Add(-1)
+	st.touch()
+}
+
+func (st *channelStats) touch() {
+	st.lastActive.Store(time.Now().UnixNano())
+}
+
+func (st *channelStats) wakeSucceeded(latency time.Duration) {
+	st.wakes.Add(1)
+	st.wakeLatency.Add(int64(latency))
+}
+
+func (st *channelStats) wakeTimedOut() {
+	st.failedSessions.Add(1)
+	st.wakeTimeouts.Add(1)
+}
+
+func (st *channelStats) dialFailed() {
+	st.failedSessions.Add(1)
+	st.dialFailures.Add(1)
+}
+
+// fill 将统计信息写入通道信息
+func (st *channelStats) fill(channel *model.ForwardChannel) {
+	channel.ActiveCount = int(st.active.Load())
+	channel.Status = "inactive"
+	if channel.ActiveCount > 0 {
+		channel.Status = "active"
+	}
+	if lastActive := st.lastActive.Load(); lastActive > 0 {
+		channel.LastActive = time.Unix(0, lastActive).Format(time.RFC3339)
+	}
+	channel.TotalSessions = st.totalSessions.Load()
+	channel.FailedSessions = st.failedSessions.Load()
+	channel.WakeTimeouts = st.wakeTimeouts.Load()
+	channel.DialFailures = st.dialFailures.Load()
+	channel.BytesToTarget = st.bytesToTarget.Load()
+	channel.BytesToClient = st.bytesToClient.Load()
+	channel.Wakes = st.wakes.Load()
+	if wakes := st.wakes.Load(); wakes > 0 {
+		channel.AvgWakeLatencyMs = time.Duration(st.wakeLatency.Load() / wakes).Milliseconds()
+	}
+}
+
+// countingWriter 转发数据时实时累计通道和会话的字节数
+type countingWriter struct {
+	w        io.Writer
+	stats    *channelStats
+	counters []*atomic.Int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	for _, counter := range cw.counters {
+		counter.Add(int64(n))
+	}
+	cw.stats.touch()
+	return n, err
+}
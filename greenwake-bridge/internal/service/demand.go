@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"greenwake-bridge/internal/guard"
+)
+
+const (
+	demandRefreshInterval = 30 * time.Second // 定期刷新使用状态的间隔，guard 超过 demand_timeout 没有收到时结束 bridge 唤醒
+	demandRequestTimeout  = 5 * time.Second
+)
+
+// hostDemand 一台主机向 guard 报告的使用状态
+type hostDemand struct {
+	inUse     bool
+	idleSince time.Time // 不再使用的开始时间
+	reported  bool      // 当前状态已被 guard 接受
+	failing   bool      // 上一次报告失败，恢复时记录日志
+	sending   bool      // 正在向 guard 发送报告
+	pending   bool      // 发送期间又有变化，发送完成后再报告一次
+	refresh   bool      // 待报告的是定期刷新
+}
+
+// DemandService 向配置了 guard.demand 的主机上的 guard 报告主机是否在使用（转发会话、保持唤醒租约）
+// 会话或租约变化时立即报告，之后定期刷新；guard 接受使用中的状态后，bridge 不再重发唤醒包保持唤醒，
+// 最后一个会话结束后由 guard 按自己的超时时间睡眠。每台主机单独发送报告，一台主机的 guard 不可达不影响其他主机
+type DemandService struct {
+	pcService        *PCService
+	forwardService   *ForwardService
+	keepAwakeService *KeepAwakeService
+	mu               sync.Mutex
+	hosts            map[string]*hostDemand
+	done             chan struct{}
+}
+
+func NewDemandService(pcService *PCService, forwardService *ForwardService, keepAwakeService *KeepAwakeService) *DemandService {
+	s := &DemandService{
+		pcService:        pcService,
+		forwardService:   forwardService,
+		keepAwakeService: keepAwakeService,
+		hosts:            make(map[string]*hostDemand),
+		done:             make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+// run 订阅会话、租约和主机状态事件立即报告，并定期刷新全部主机，直到服务关闭
+func (s *DemandService) run() {
+	ticker := time.NewTicker(demandRefreshInterval)
+	defer ticker.Stop()
+	sub := s.pcService.events.Subscribe(0)
+	defer func() { sub.Close() }()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			for _, host := range s.pcService.GetHosts() {
+				s.schedule(host.Name, true)
+			}
+		case ev, ok := <-sub.C:
+			if !ok {
+				// 消费过慢被断开，重新订阅，错过的变化在下次刷新时报告
+				sub = s.pcService.events.Subscribe(0)
+				continue
+			}
+			switch ev.Type {
+			case EventSessionOpened, EventSessionClosed, EventLeaseAcquired, EventLeaseReleased, EventLeaseExpired, EventHostState:
+				s.schedule(ev.Host, false)
+			}
+		}
+	}
+}
+
+// schedule 在主机自己的 goroutine 中报告，正在发送时合并到发送完成后的下一次报告
+func (s *DemandService) schedule(hostName string, refresh bool) {
+	s.mu.Lock()
+	d, ok := s.hosts[hostName]
+	if !ok {
+		d = &hostDemand{idleSince: time.Now()}
+		s.hosts[hostName] = d
+	}
+	if d.sending {
+		d.pending = true
+		d.refresh = d.refresh || refresh
+		s.mu.Unlock()
+		return
+	}
+	d.sending = true
+	s.mu.Unlock()
+
+	go func() {
+		for {
+			select {
+			case <-s.done:
+			default:
+				s.report(hostName, refresh)
+			}
+
+			s.mu.Lock()
+			if !d.pending {
+				d.sending = false
+				s.mu.Unlock()
+				return
+			}
+			refresh = d.refresh
+			d.pending = false
+			d.refresh = false
+			s.mu.Unlock()
+		}
+	}()
+}
+
+// report 统计主机的会话和租约，状态变化或 refresh 时报告给 guard，主机不在线时等待上线后报告
+func (s *DemandService) report(hostName string, refresh bool) {
+	cfgHost, exists := s.pcService.hostConfig(hostName)
+	if !exists || cfgHost.Guard == nil || !cfgHost.Guard.Demand {
+		return
+	}
+
+	sessions := s.forwardService.HostActiveSessions(hostName)
+	leases := len(s.keepAwakeService.GetHostLeases(hostName))
+	inUse := sessions+leases > 0
+	now := time.Now()
+
+	s.mu.Lock()
+	d, ok := s.hosts[hostName]
+	if !ok {
+		d = &hostDemand{idleSince: now}
+		s.hosts[hostName] = d
+	}
+	changed := inUse != d.inUse || !d.reported
+	if d.inUse && !inUse {
+		d.idleSince = now
+	}
+	d.inUse = inUse
+	idleSince := d.idleSince
+	s.mu.Unlock()
+
+	if !changed && !refresh {
+		return
+	}
+	if s.pcService.hostState(hostName) != HostStateOnline {
+		s.setReported(hostName, d, false)
+		return
+	}
+
+	// 唤醒后还没有使用时，从唤醒时间开始计算
+	if lastWake, ok := s.pcService.lastWakeTime(hostName); ok && lastWake.After(idleSince) {
+		idleSince = lastWake
+	}
+	req := guard.DemandRequest{InUse: inUse, Sessions: sessions, Leases: leases}
+	if !inUse {
+		req.IdleSince = idleSince.Format(time.RFC3339)
+	}
+
+	client, err := guard.NewClient(cfgHost.Guard.URL, cfgHost.Guard.Secret)
+	if err == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), demandRequestTimeout)
+		_, err = client.Demand(ctx, req)
+		cancel()
+	}
+
+	s.mu.Lock()
+	wasFailing := d.failing
+	d.failing = err != nil
+	s.mu.Unlock()
+	if err != nil {
+		if !wasFailing {
+			log.Printf("向主机 %s 的 guard 报告使用状态失败，恢复重发唤醒包保持唤醒: %v", hostName, err)
+		}
+		s.setReported(hostName, d, false)
+		return
+	}
+
+	if wasFailing {
+		log.Printf("向主机 %s 的 guard 报告使用状态恢复", hostName)
+	}
+	if changed {
+		log.Printf("主机 %s 使用状态: 使用中=%v, 转发会话 %d 个, 保持唤醒租约 %d 个", hostName, inUse, sessions, leases)
+		data := map[string]interface{}{"inUse": inUse, "sessions": sessions, "leases": leases}
+		if !inUse {
+			data["idleSince"] = req.IdleSince
+		}
+		s.pcService.events.Publish(EventGuardDemand, hostName, data)
+	}
+	s.setReported(hostName, d, true)
+}
+
+// setReported 记录报告结果，guard 接受了使用中的状态时由 guard 保持主机唤醒
+func (s *DemandService) setReported(hostName string, d *hostDemand, reported bool) {
+	s.mu.Lock()
+	d.reported = reported
+	keepsAwake := reported && d.inUse
+	s.mu.Unlock()
+	s.pcService.guardKeepsAwake.Store(hostName, keepsAwake)
+}
+
+// Close 停止报告
+func (s *DemandService) Close() {
+	close(s.done)
+}
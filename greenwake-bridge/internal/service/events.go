@@ -0,0 +1,296 @@
+package service
+
+import (
+	"encoding/json"
+	"log"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"greenwake-bridge/internal/config"
+	"greenwake-bridge/internal/model"
+	"greenwake-bridge/internal/store"
+)
+
+// 实时事件类型
+const (
+	EventHostState      = "host.state"      // 主机状态变化
+	EventWakeSent       = "wake.sent"       // 发送唤醒包
+	EventWakeFinished   = "wake.finished"   // 唤醒结束，主机上线或等待超时
+	EventSleepSent      = "sleep.sent"      // guard 接受了睡眠或关机请求
+	EventGuardState     = "guard.state"     // guard 注册、状态变化或心跳超时
+	EventGuardDemand    = "guard.demand"    // 向 guard 报告的主机使用状态变化
+	EventSessionOpened  = "session.opened"  // 转发会话开始
+	EventSessionClosed  = "session.closed"  // 转发会话结束
+	EventLeaseAcquired  = "lease.acquired"  // 创建保持唤醒租约
+	EventLeaseRenewed   = "lease.renewed"   // 续期保持唤醒租约
+	EventLeaseReleased  = "lease.released"  // 结束保持唤醒租约
+	EventLeaseExpired   = "lease.expired"   // 保持唤醒租约过期
+	EventLogin          = "auth.login"      // 登录成功或失败
+	EventConfigChanged  = "config.changed"  // 通过接口修改主机或端口转发配置
+	EventConfigReloaded = "config.reloaded" // 重新加载配置文件
+)
+
+// transientEvents 只推送不保存的事件，客户端定期续期租约，保存会淹没其他记录
+// 这些事件没有事件ID，也不会在断线重连时补发，保证重启后继续递增的事件ID不会重复
+var transientEvents = map[string]bool{
+	EventLeaseRenewed: true,
+}
+
+const (
+	eventHistory    = 256  // 保留的最近事件数，客户端断线重连时补发
+	subscriberQueue = 64   // 每个订阅者的事件缓冲，消费过慢时断开订阅
+	eventWriteQueue = 1024 // 等待写入事件记录的缓冲，写入过慢时丢弃
+)
+
+// eventRecord 保存的事件记录
+type eventRecord struct {
+	ID    int64           `json:"id"`
+	Type  string          `json:"type"`
+	Host  string          `json:"host,omitempty"`
+	Actor string          `json:"actor,omitempty"`
+	Time  time.Time       `json:"time"`
+	Data  json.RawMessage `json:"data,omitempty"`
+}
+
+func (r eventRecord) toModel() *model.Event {
+	ev := &model.Event{
+		ID:    r.ID,
+		Type:  r.Type,
+		Host:  r.Host,
+		Actor: r.Actor,
+		Time:  r.Time.Format(time.RFC3339),
+	}
+	if len(r.Data) > 0 {
+		ev.Data = r.Data
+	}
+	return ev
+}
+
+// EventFilter 事件记录查询条件，零值表示不限制
+type EventFilter struct {
+	Host   string
+	Types  []string // 事件类型，如 wake.sent，也可以是类型前缀，如 wake
+	Actor  string   // 发起者，如 user:admin，也可以是前缀，如 forward、token
+	From   time.Time
+	To     time.Time
+	Allow  func(host string) bool // 调用者可查看的主机，不属于主机的事件（如登录）传入空字符串
+	Offset int
+	Limit  int
+}
+
+func (f *EventFilter) match(r eventRecord) bool {
+	if f.Host != "" && r.Host != f.Host {
+		return false
+	}
+	if len(f.Types) > 0 {
+		matched := false
+		for _, t := range f.Types {
+			if r.Type == t || strings.HasPrefix(r.Type, t+".") {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if f.Actor != "" && r.Actor != f.Actor && !strings.HasPrefix(r.Actor, f.Actor+":") {
+		return false
+	}
+	if !f.From.IsZero() && r.Time.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && r.Time.After(f.To) {
+		return false
+	}
+	if f.Allow != nil && !f.Allow(r.Host) {
+		return false
+	}
+	return true
+}
+
+// eventActor 从事件内容中获取发起者
+func eventActor(data interface{}) string {
+	switch d := data.(type) {
+	case map[string]string:
+		return d["actor"]
+	case map[string]interface{}:
+		actor, _ := d["actor"].(string)
+		return actor
+	case *model.KeepAwakeLease:
+		return d.Owner
+	case *model.ForwardSession:
+		return "forward:" + d.Channel
+	}
+	return ""
+}
+
+// EventBus 将主机、唤醒、会话和租约的变化推送给订阅者，并保存到本地事件记录
+type EventBus struct {
+	mu          sync.Mutex
+	nextID      int64
+	history     []*model.Event
+	subscribers map[*Subscription]struct{}
+	log         *store.Log[eventRecord]
+	records     chan eventRecord // 由 writeRecords 按事件ID的顺序写入
+	written     chan struct{}    // writeRecords 退出时关闭
+}
+
+// Subscription 事件订阅，订阅被断开时 C 会被关闭
+type Subscription struct {
+	C   <-chan *model.Event
+	ch  chan *model.Event
+	bus *EventBus
+}
+
+func NewEventBus(cfg *config.Config) (*EventBus, error) {
+	l, err := store.Open(filepath.Join(cfg.DataDir, "events.jsonl"), store.Options{
+		MaxAge:     time.Duration(cfg.EventLog.RetentionDays) * 24 * time.Hour,
+		MaxRecords: cfg.EventLog.MaxRecords,
+	}, func(r eventRecord) time.Time {
+		return r.Time
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	b := &EventBus{
+		subscribers: make(map[*Subscription]struct{}),
+		log:         l,
+		records:     make(chan eventRecord, eventWriteQueue),
+		written:     make(chan struct{}),
+	}
+	// 重启后事件ID继续递增，客户端断线重连时仍可补发重启前保存的事件
+	recent, _ := l.Query(nil, 0, eventHistory)
+	for i := len(recent) - 1; i >= 0; i-- {
+		b.history = append(b.history, recent[i].toModel())
+	}
+	if len(recent) > 0 {
+		b.nextID = recent[0].ID
+	}
+	go b.writeRecords(b.records)
+	return b, nil
+}
+
+// Publish 发布事件，不会阻塞调用方，事件记录由后台协程写入
+func (b *EventBus) Publish(eventType, host string, data interface{}) {
+	now := time.Now()
+	ev := &model.Event{
+		Type:  eventType,
+		Host:  host,
+		Actor: eventActor(data),
+		Time:  now.Format(time.RFC3339),
+		Data:  data,
+	}
+	transient := transientEvents[eventType]
+	var raw json.RawMessage
+	if !transient && data != nil {
+		raw, _ = json.Marshal(data)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !transient {
+		b.nextID++
+		ev.ID = b.nextID
+		b.save(eventRecord{ID: ev.ID, Type: ev.Type, Host: ev.Host, Actor: ev.Actor, Time: now, Data: raw})
+		b.history = append(b.history, ev)
+		if len(b.history) > eventHistory {
+			b.history = b.history[len(b.history)-eventHistory:]
+		}
+	}
+
+	for sub := range b.subscribers {
+		select {
+		case sub.ch <- ev:
+		default:
+			// 订阅者消费过慢，断开后由客户端重连补发
+			log.Printf("事件订阅者消费过慢，断开订阅")
+			delete(b.subscribers, sub)
+			close(sub.ch)
+		}
+	}
+}
+
+// save 将事件记录交给后台协程写入，调用方需持有锁，保证记录按事件ID的顺序写入
+func (b *EventBus) save(r eventRecord) {
+	if b.records == nil {
+		return
+	}
+	select {
+	case b.records <- r:
+	default:
+		log.Printf("事件记录写入过慢，丢弃事件: %d %s", r.ID, r.Type)
+	}
+}
+
+// writeRecords 写入事件记录，直到事件总线关闭
+func (b *EventBus) writeRecords(records <-chan eventRecord) {
+	defer close(b.written)
+	for r := range records {
+		if err := b.log.Append(r); err != nil {
+			log.Printf("保存事件记录失败: %v", err)
+		}
+	}
+}
+
+// Query 查询保存的事件记录，返回按时间倒序的事件和匹配总数
+func (b *EventBus) Query(filter EventFilter) ([]*model.Event, int) {
+	records, total := b.log.Query(filter.match, filter.Offset, filter.Limit)
+	events := make([]*model.Event, 0, len(records))
+	for _, r := range records {
+		events = append(events, r.toModel())
+	}
+	return events, total
+}
+
+// Close 写入剩余的事件记录后关闭事件记录文件，之后发布的事件只推送不保存
+func (b *EventBus) Close() {
+	b.mu.Lock()
+	records := b.records
+	b.records = nil
+	b.mu.Unlock()
+	if records == nil {
+		return
+	}
+	close(records)
+	<-b.written
+	b.log.Close()
+}
+
+// Subscribe 订阅事件，lastID 大于0时先补发该事件之后仍保留的事件
+func (b *EventBus) Subscribe(lastID int64) *Subscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var missed []*model.Event
+	if lastID > 0 {
+		for _, ev := range b.history {
+			if ev.ID > lastID {
+				missed = append(missed, ev)
+			}
+		}
+	}
+
+	ch := make(chan *model.Event, subscriberQueue+len(missed))
+	for _, ev := range missed {
+		ch <- ev
+	}
+	sub := &Subscription{C: ch, ch: ch, bus: b}
+	b.subscribers[sub] = struct{}{}
+	return sub
+}
+
+// Close 取消订阅
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+
+	if _, ok := s.bus.subscribers[s]; ok {
+		delete(s.bus.subscribers, s)
+		close(s.ch)
+	}
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"greenwake-bridge/internal/config"
+	"greenwake-bridge/internal/model"
+)
+
+func TestEventFilterMatch(t *testing.T) {
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	record := eventRecord{ID: 1, Type: EventWakeSent, Host: "desktop", Actor: "forward:13389-desktop:3389", Time: at}
+
+	tests := []struct {
+		name   string
+		filter EventFilter
+		want   bool
+	}{
+		{"零值不限制", EventFilter{}, true},
+		{"主机匹配", EventFilter{Host: "desktop"}, true},
+		{"主机不匹配", EventFilter{Host: "nas"}, false},
+		{"完整类型", EventFilter{Types: []string{EventHostState, EventWakeSent}}, true},
+		{"类型前缀", EventFilter{Types: []string{"wake"}}, true},
+		{"前缀需按点分隔", EventFilter{Types: []string{"wak"}}, false},
+		{"类型不匹配", EventFilter{Types: []string{"lease"}}, false},
+		{"完整发起者", EventFilter{Actor: "forward:13389-desktop:3389"}, true},
+		{"发起者前缀", EventFilter{Actor: "forward"}, true},
+		{"发起者前缀需按冒号分隔", EventFilter{Actor: "for"}, false},
+		{"发起者不匹配", EventFilter{Actor: "user:admin"}, false},
+		{"开始时间包含边界", EventFilter{From: at}, true},
+		{"开始时间之前", EventFilter{From: at.Add(time.Second)}, false},
+		{"结束时间包含边界", EventFilter{To: at}, true},
+		{"结束时间之后", EventFilter{To: at.Add(-time.Second)}, false},
+		{"可查看的主机", EventFilter{Allow: func(host string) bool { return host == "desktop" }}, true},
+		{"不可查看的主机", EventFilter{Allow: func(host string) bool { return host == "nas" }}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.match(record); got != tt.want {
+				t.Errorf("match() = %v，期望 %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// newTestEventBus 在 dir 中打开事件记录
+func newTestEventBus(t *testing.T, dir string) *EventBus {
+	t.Helper()
+	b, err := NewEventBus(&config.Config{
+		DataDir:  dir,
+		EventLog: config.RetentionConfig{RetentionDays: 1, MaxRecords: 100},
+	})
+	if err != nil {
+		t.Fatalf("打开事件记录失败: %v", err)
+	}
+	return b
+}
+
+func TestEventBusIDContinuesAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+
+	b := newTestEventBus(t, dir)
+	sub := b.Subscribe(0)
+	b.Publish(EventWakeSent, "desktop", map[string]string{"actor": "user:admin"})
+	b.Publish(EventLeaseRenewed, "desktop", &model.KeepAwakeLease{Owner: "token:ha"})
+	b.Publish(EventHostState, "desktop", nil)
+
+	var ids []int64
+	for i := 0; i < 3; i++ {
+		ids = append(ids, (<-sub.C).ID)
+	}
+	sub.Close()
+	if ids[0] != 1 || ids[1] != 0 || ids[2] != 2 {
+		t.Fatalf("事件ID为 %v，期望 [1 0 2]，续期租约不占用事件ID", ids)
+	}
+	b.Close()
+
+	b = newTestEventBus(t, dir)
+	defer b.Close()
+
+	events, total := b.Query(EventFilter{Limit: 10})
+	if total != 2 || events[0].ID != 2 || events[1].ID != 1 {
+		t.Fatalf("重启后保存的事件为 %d 条，期望续期租约以外的 2 条按时间倒序", total)
+	}
+	if events[1].Actor != "user:admin" {
+		t.Errorf("发起者为 %q，期望 user:admin", events[1].Actor)
+	}
+
+	// 重启后补发重启前保存的事件，新事件ID继续递增
+	sub = b.Subscribe(1)
+	defer sub.Close()
+	if ev := <-sub.C; ev.ID != 2 {
+		t.Errorf("补发的事件ID为 %d，期望 2", ev.ID)
+	}
+	b.Publish(EventWakeSent, "desktop", nil)
+	if ev := <-sub.C; ev.ID != 3 {
+		t.Errorf("重启后的事件ID为 %d，期望 3", ev.ID)
+	}
+}
+
+func TestEventBusCloseFlushesRecords(t *testing.T) {
+	dir := t.TempDir()
+
+	b := newTestEventBus(t, dir)
+	for i := 0; i < 50; i++ {
+		b.Publish(EventSessionOpened, "desktop", nil)
+	}
+	b.Close()
+	// 关闭后发布的事件不再保存，也不会阻塞
+	b.Publish(EventSessionClosed, "desktop", nil)
+
+	b = newTestEventBus(t, dir)
+	defer b.Close()
+	if _, total := b.Query(EventFilter{}); total != 50 {
+		t.Errorf("保存的事件为 %d 条，期望 50 条", total)
+	}
+}
+
+func TestEventBusFanOut(t *testing.T) {
+	b := newTestEventBus(t, t.TempDir())
+	defer b.Close()
+
+	fast, slow := b.Subscribe(0), b.Subscribe(0)
+	defer fast.Close()
+
+	// 每个订阅者都按发布顺序收到全部事件
+	b.Publish(EventWakeSent, "desktop", nil)
+	b.Publish(EventLeaseRenewed, "desktop", nil)
+	for _, sub := range []*Subscription{fast, slow} {
+		for _, want := range []string{EventWakeSent, EventLeaseRenewed} {
+			if ev := <-sub.C; ev.Type != want {
+				t.Errorf("收到的事件为 %s，期望 %s", ev.Type, want)
+			}
+		}
+	}
+
+	// 消费过慢的订阅者缓冲满后被断开，不影响其他订阅者
+	for i := 0; i < subscriberQueue+1; i++ {
+		b.Publish(EventHostState, "desktop", nil)
+		<-fast.C
+	}
+	received := 0
+	for range slow.C {
+		received++
+	}
+	if received != subscriberQueue {
+		t.Errorf("被断开前收到 %d 个事件，期望 %d 个", received, subscriberQueue)
+	}
+	slow.Close() // 已断开的订阅可以再次关闭
+
+	b.Publish(EventWakeFinished, "desktop", nil)
+	if ev := <-fast.C; ev.Type != EventWakeFinished {
+		t.Errorf("其他订阅者收到的事件为 %s，期望 %s", ev.Type, EventWakeFinished)
+	}
+
+	// 取消订阅后关闭 C，不再收到事件
+	fast.Close()
+	b.Publish(EventWakeSent, "desktop", nil)
+	if _, ok := <-fast.C; ok {
+		t.Error("取消订阅后仍收到事件")
+	}
+}
+
+func TestEventBusSubscribeReplay(t *testing.T) {
+	b := newTestEventBus(t, t.TempDir())
+	defer b.Close()
+
+	for i := 0; i < eventHistory+10; i++ {
+		b.Publish(EventWakeSent, "desktop", nil)
+	}
+
+	// 只补发仍保留的、lastID 之后的事件
+	tests := []struct {
+		name      string
+		lastID    int64
+		wantFirst int64
+		wantCount int
+	}{
+		{"不补发", 0, 0, 0},
+		{"补发之后的事件", eventHistory, eventHistory + 1, 10},
+		{"已清理的事件不补发", 1, 11, eventHistory},
+		{"没有新事件", eventHistory + 10, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := b.Subscribe(tt.lastID)
+			defer sub.Close()
+
+			var ids []int64
+			for len(sub.C) > 0 {
+				ids = append(ids, (<-sub.C).ID)
+			}
+			if len(ids) != tt.wantCount || (tt.wantCount > 0 && ids[0] != tt.wantFirst) {
+				t.Errorf("补发 %d 个事件 %v，期望从 %d 开始的 %d 个", len(ids), ids, tt.wantFirst, tt.wantCount)
+			}
+		})
+	}
+}
@@ -1,11 +1,14 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"greenwake-bridge/internal/config"
@@ -15,40 +18,115 @@ import (
 type ForwardService struct {
 	config         *config.Config
 	pcService      *PCService
-	channels       sync.Map // key: servicePort, value: *model.ForwardChannel
-	listeners      map[int]net.Listener
+	channels       sync.Map                        // key: channelId, value: *model.ForwardChannel
+	forwards       map[string]config.ForwardConfig // key: channelId，已启动的转发配置
+	listeners      map[string]io.Closer            // key: channelId
 	mu             sync.Mutex
-	channelClients sync.Map // key: channelId, value: *sync.Map[clientId]*ChannelClient
-	activeCount    int64    // 全局活跃连接计数
-	countMu        sync.Mutex
+	channelClients sync.Map // key: channelId, value: *sync.Map[clientId]*channelClient
+	stats          sync.Map // key: channelId, value: *channelStats
+	draining       sync.Map // key: *channelStats, value: 目标主机名，已删除但仍有连接的通道
+	sessions       *SessionService
 	cleaner        *time.Ticker
 }
 
-func NewForwardService(cfg *config.Config, pcService *PCService) *ForwardService {
+func NewForwardService(cfg *config.Config, pcService *PCService, sessions *SessionService) *ForwardService {
 	s := &ForwardService{
 		config:    cfg,
 		pcService: pcService,
-		listeners: make(map[int]net.Listener),
+		sessions:  sessions,
+		forwards:  make(map[string]config.ForwardConfig),
+		listeners: make(map[string]io.Closer),
 		cleaner:   time.NewTicker(40 * time.Second), // 每40秒清理一次
 	}
 
 	// 初始化所有转发通道
-	for _, fc := range cfg.Forwards {
+	s.UpdateForwards(cfg.Forwards)
+
+	// 启动清理协程
+	go s.cleanInactiveClients()
+
+	return s
+}
+
+// UpdateForwards 按新的配置启动新增的转发通道，停止已删除的通道
+// 停止的通道不再接受新连接，已建立的连接继续转发直到结束
+func (s *ForwardService) UpdateForwards(forwards []config.ForwardConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wanted := make(map[string]bool, len(forwards))
+	udpPorts := make(map[int]bool) // 新配置中的UDP监听端口
+	for _, fc := range forwards {
+		id := channelID(fc)
+		wanted[id] = true
+		if fc.Protocol == config.ProtocolUDP {
+			udpPorts[fc.ServicePort] = true
+		}
+
+		if value, exists := s.forwards[id]; exists {
+			if value != fc {
+				log.Printf("转发通道 %s 的 idle_timeout 修改需要重启后生效", id)
+			}
+			continue
+		}
+		s.forwards[id] = fc
+
 		channel := &model.ForwardChannel{
-			ID:          fmt.Sprintf("%d-%s:%d", fc.ServicePort, fc.TargetHost, fc.TargetPort),
+			ID:          id,
+			Protocol:    fc.Protocol,
 			ServicePort: fc.ServicePort,
 			TargetHost:  fc.TargetHost,
 			TargetPort:  fc.TargetPort,
 			Status:      "inactive",
 		}
-		s.channels.Store(fc.ServicePort, channel)
+		if fc.Protocol == config.ProtocolUDP {
+			channel.IdleTimeout = fc.IdleTimeout
+		}
+		s.channels.Store(channel.ID, channel)
+		s.stats.Store(channel.ID, &channelStats{})
 		go s.startForward(channel)
 	}
 
-	// 启动清理协程
-	go s.cleanInactiveClients()
+	for id := range s.forwards {
+		if wanted[id] {
+			continue
+		}
+		log.Printf("停止转发通道: %s，已建立的连接继续转发", id)
+		port := s.forwards[id].ServicePort
+		if stats := s.channelStats(id); stats.active.Load() > 0 {
+			s.draining.Store(stats, s.forwards[id].TargetHost)
+		}
+		delete(s.forwards, id)
+		s.channels.Delete(id)
+		if listener, exists := s.listeners[id]; exists {
+			if forwarder, ok := listener.(*udpForwarder); ok {
+				// 同一端口改为其他目标时立即关闭旧监听，否则新通道在旧会话结束前无法绑定端口
+				if udpPorts[port] {
+					log.Printf("UDP转发端口 %d 改用新的配置，结束原有会话", port)
+					forwarder.Close()
+				} else {
+					forwarder.drain()
+				}
+			} else {
+				listener.Close()
+			}
+			delete(s.listeners, id)
+		}
+	}
+}
 
-	return s
+// channelID 生成通道ID，TCP 通道保持原有格式
+func channelID(fc config.ForwardConfig) string {
+	if fc.Protocol == config.ProtocolUDP {
+		return fmt.Sprintf("%d/udp-%s:%d", fc.ServicePort, fc.TargetHost, fc.TargetPort)
+	}
+	return fmt.Sprintf("%d-%s:%d", fc.ServicePort, fc.TargetHost, fc.TargetPort)
+}
+
+// started 判断通道是否仍在配置中，调用方需持有锁
+func (s *ForwardService) started(channelId string) bool {
+	_, exists := s.forwards[channelId]
+	return exists
 }
 
 func (s *ForwardService) cleanInactiveClients() {
@@ -57,11 +135,11 @@ func (s *ForwardService) cleanInactiveClients() {
 		s.channelClients.Range(func(channelId, value interface{}) bool {
 			if clientsMap, ok := value.(*sync.Map); ok {
 				clientsMap.Range(func(clientId, v interface{}) bool {
-					if client, ok := v.(*model.ChannelClient); ok {
-						lastActive, err := time.Parse(time.RFC3339, client.LastActive)
-						if err == nil && now.Sub(lastActive) > 40*time.Second {
+					if client, ok := v.(*channelClient); ok {
+						lastActive := client.lastActiveTime()
+						if now.Sub(lastActive) > 40*time.Second {
 							log.Printf("清理不活跃转发客户端: 通道=%v, 客户端=%s, IP=%s, 最后活跃=%s",
-								channelId, client.ID, client.IP, client.LastActive)
+								channelId, client.id, client.ip, lastActive.Format(time.RFC3339))
 							clientsMap.Delete(clientId)
 						}
 					}
@@ -74,8 +152,13 @@ func (s *ForwardService) cleanInactiveClients() {
 }
 
 func (s *ForwardService) startForward(channel *model.ForwardChannel) {
+	if channel.Protocol == config.ProtocolUDP {
+		s.startUDPForward(channel)
+		return
+	}
+
 	s.mu.Lock()
-	if _, exists := s.listeners[channel.ServicePort]; exists {
+	if _, exists := s.listeners[channel.ID]; exists || !s.started(channel.ID) {
 		s.mu.Unlock()
 		return
 	}
@@ -86,7 +169,7 @@ func (s *ForwardService) startForward(channel *model.ForwardChannel) {
 		s.mu.Unlock()
 		return
 	}
-	s.listeners[channel.ServicePort] = listener
+	s.listeners[channel.ID] = listener
 	s.mu.Unlock()
 
 	log.Printf("启动转发监听 [%d -> %s:%d]", channel.ServicePort, channel.TargetHost, channel.TargetPort)
@@ -94,7 +177,9 @@ func (s *ForwardService) startForward(channel *model.ForwardChannel) {
 	for {
 		client, err := listener.Accept()
 		if err != nil {
-			log.Printf("接受连接失败 [%d]: %v", channel.ServicePort, err)
+			if !errors.Is(err, net.ErrClosed) {
+				log.Printf("接受连接失败 [%d]: %v", channel.ServicePort, err)
+			}
 			break
 		}
 
@@ -103,29 +188,45 @@ func (s *ForwardService) startForward(channel *model.ForwardChannel) {
 }
 
 func (s *ForwardService) handleConnection(client net.Conn, channel *model.ForwardChannel) {
+	s.relay(client, channel, nil)
+}
+
+// relay 唤醒目标主机后在客户端和目标之间转发数据
+// reply 不为空时在连接目标成功或失败后调用，用于代理协议向客户端返回连接结果
+func (s *ForwardService) relay(client net.Conn, channel *model.ForwardChannel, reply func(reason string) error) {
 	defer client.Close()
 
 	clientAddr := client.RemoteAddr().(*net.TCPAddr)
 	clientId := fmt.Sprintf("%s:%d", clientAddr.IP.String(), clientAddr.Port)
 
 	// 记录客户端信息
-	clientInfo := &model.ChannelClient{
-		ID:         clientId,
-		IP:         clientAddr.IP.String(),
-		Port:       fmt.Sprintf("%d", clientAddr.Port),
-		Status:     "active",
-		LastActive: time.Now().Format(time.RFC3339),
+	clientInfo := newChannelClient(clientId, clientAddr.IP.String(), fmt.Sprintf("%d", clientAddr.Port))
+
+	// 增加通道的活跃连接计数
+	stats := s.channelStats(channel.ID)
+	stats.sessionStarted()
+
+	// 连接结束时保存会话记录
+	session := sessionRecord{
+		ID:          newSessionID(),
+		Channel:     channel.ID,
+		Protocol:    channel.Protocol,
+		ServicePort: channel.ServicePort,
+		TargetHost:  channel.TargetHost,
+		TargetPort:  channel.TargetPort,
+		ClientIP:    clientAddr.IP.String(),
+		ClientPort:  clientAddr.Port,
+		StartTime:   time.Now(),
 	}
-
-	// 增加活跃连接计数
-	s.countMu.Lock()
-	s.activeCount++
-	channel.ActiveCount = int(s.activeCount)
-	s.countMu.Unlock()
-
-	channel.Status = "active"
-	channel.LastActive = time.Now().Format(time.RFC3339)
-	s.channels.Store(channel.ServicePort, channel)
+	s.pcService.events.Publish(EventSessionOpened, channel.TargetHost, session.toModel())
+	var bytesToTarget, bytesToClient atomic.Int64
+	defer func() {
+		session.EndTime = time.Now()
+		session.BytesToTarget = bytesToTarget.Load()
+		session.BytesToClient = bytesToClient.Load()
+		s.sessions.record(session)
+		s.pcService.events.Publish(EventSessionClosed, channel.TargetHost, session.toModel())
+	}()
 
 	// 获取通道的客户端映射
 	clientsMap, _ := s.channelClients.LoadOrStore(channel.ID, &sync.Map{})
@@ -133,18 +234,8 @@ func (s *ForwardService) handleConnection(client net.Conn, channel *model.Forwar
 
 	// 在连接结束时清理
 	defer func() {
-		// 减少活跃连接计数
-		s.countMu.Lock()
-		s.activeCount--
-		count := s.activeCount
-		s.countMu.Unlock()
-
-		// 更新通道状态
-		if count == 0 {
-			channel.Status = "inactive"
-		}
-		channel.ActiveCount = int(count)
-		s.channels.Store(channel.ServicePort, channel)
+		// 减少通道的活跃连接计数
+		stats.sessionEnded()
 
 		// 删除客户端记录
 		if cm, ok := s.channelClients.Load(channel.ID); ok {
@@ -162,146 +253,203 @@ func (s *ForwardService) handleConnection(client net.Conn, channel *model.Forwar
 
 		for {
 			select {
-			case <-ticker.C:
-				if cm, ok := s.channelClients.Load(channel.ID); ok {
-					if ci, exists := cm.(*sync.Map).Load(clientId); exists {
-						if client, ok := ci.(*model.ChannelClient); ok {
-							client.LastActive = time.Now().Format(time.RFC3339)
-						}
-					}
-				}
+			case now := <-ticker.C:
+				clientInfo.touch(now)
 			case <-stopUpdate:
 				return
 			}
 		}
 	}()
 
-	// 获取唤醒间隔时间
-	wakeInterval := 120 // 默认120秒
-	if cfgHost, exists := s.pcService.cfgHosts[channel.TargetHost]; exists && cfgHost.WakeInterval > 0 {
-		wakeInterval = cfgHost.WakeInterval
-	}
-
-	// 创建定时唤醒的 ticker
-	wakeTicker := time.NewTicker(time.Duration(wakeInterval) * time.Second)
-	defer wakeTicker.Stop()
-
-	// 启动定时唤醒协程
-	stopWake := make(chan struct{})
-	defer close(stopWake)
+	// 连接期间持续发送唤醒包，保持主机在线
+	defer s.keepTargetAwake(channel)()
 
-	go func() {
-		for {
-			select {
-			case <-wakeTicker.C:
-				// 检查主机是否在线
-				if host, exists := s.pcService.hosts[channel.TargetHost]; exists {
-					// 通道活跃期间持续发送唤醒包，保持主机在线
-					log.Printf("保持主机唤醒: %s", channel.TargetHost)
-					s.pcService.sendWakePacket(host)
-				}
-			case <-stopWake:
-				return
+	// 未能连接目标时通知客户端失败原因
+	connected := false
+	if reply != nil {
+		defer func() {
+			if !connected {
+				reply(session.CloseReason)
 			}
-		}
-	}()
+		}()
+	}
 
 	// 获取目标主机信息
-	host, exists := s.pcService.hosts[channel.TargetHost]
+	host, exists := s.pcService.host(channel.TargetHost)
 	if !exists {
 		log.Printf("目标主机不存在: %s", channel.TargetHost)
+		stats.failedSessions.Add(1)
+		session.CloseReason = CloseHostNotFound
 		return
 	}
 
-	isOnline := checkHostOnline(host.IP, host.MonitorPort)
-	if !isOnline {
-		cfgHost, exists := s.pcService.cfgHosts[channel.TargetHost]
-		retryCount := 1 // 默认重试1次
-		if exists && cfgHost.RetryCount > 0 {
-			retryCount = cfgHost.RetryCount
-		}
-
-		// 重试循环
-		for retry := 0; retry <= retryCount; retry++ {
-			if retry > 0 {
-				log.Printf("第%d/%d次重试唤醒主机: %s", retry, retryCount, channel.TargetHost)
-			}
-
-			log.Printf("目标主机离线，尝试唤醒: %s [%d -> %s:%d]", channel.TargetHost, channel.ServicePort, host.IP, channel.TargetPort)
-			s.pcService.sendWakePacket(host)
-
-			// 获取唤醒超时时间
-			wakeTimeout := 10 // 默认30秒
-			if exists && cfgHost.WakeTimeout > 0 {
-				wakeTimeout = cfgHost.WakeTimeout
-			}
-
-			// 等待主机上线
-			startTime := time.Now()
-			for {
-				if checkHostOnline(host.IP, host.MonitorPort) {
-					log.Printf("目标主机已上线，开始转发: %s [%d -> %s:%d]", channel.TargetHost, channel.ServicePort, host.IP, channel.TargetPort)
-					goto Connected
-				}
-
-				if time.Since(startTime) > time.Duration(wakeTimeout)*time.Second {
-					log.Printf("等待主机上线超时（%d秒）: %s [%d -> %s:%d]", wakeTimeout, channel.TargetHost, channel.ServicePort, host.IP, channel.TargetPort)
-					break
-				}
-
-				time.Sleep(100 * time.Millisecond)
-			}
-
-			// 如果是最后一次重试且失败
-			if retry == retryCount {
-				log.Printf("已重试%d次，主机仍未上线，放弃连接: %s", retryCount, channel.TargetHost)
-				return
-			}
-		}
+	if !s.wakeTarget(channel, host, stats, &session) {
+		return
 	}
 
-Connected:
 	// 连接目标地址
 	target, err := net.DialTimeout("tcp", fmt.Sprintf("%s:%d", host.IP, channel.TargetPort), 5*time.Second)
 	if err != nil {
 		log.Printf("连接目标失败 [%s:%d]: %v", host.IP, channel.TargetPort, err)
+		stats.dialFailed()
+		session.CloseReason = CloseDialFailed
 		return
 	}
 	defer target.Close()
 
+	connected = true
+	if reply != nil {
+		if err := reply(""); err != nil {
+			return
+		}
+	}
+
 	// 使用 WaitGroup 等待两个方向的数据传输都完成
 	var wg sync.WaitGroup
 	wg.Add(2)
 
+	// 先结束的一方即为关闭连接的一方
+	var closeOnce sync.Once
+	closedBy := func(reason string) {
+		closeOnce.Do(func() { session.CloseReason = reason })
+	}
+
 	// 客户端 -> 目标主机
 	go func() {
 		defer wg.Done()
-		if _, err := io.Copy(target, client); err != nil {
+		if _, err := io.Copy(&countingWriter{w: target, stats: stats, counters: []*atomic.Int64{&stats.bytesToTarget, &bytesToTarget}}, client); err != nil {
 			log.Printf("转发错误 (client->target): %v", err)
 		}
+		closedBy(CloseClientClosed)
 		// 通知另一个方向结束
-		target.(*net.TCPConn).CloseWrite()
+		closeWrite(target)
 	}()
 
 	// 目标主机 -> 客户端
 	go func() {
 		defer wg.Done()
-		if _, err := io.Copy(client, target); err != nil {
+		if _, err := io.Copy(&countingWriter{w: client, stats: stats, counters: []*atomic.Int64{&stats.bytesToClient, &bytesToClient}}, target); err != nil {
 			log.Printf("转发错误 (target->client): %v", err)
 		}
+		closedBy(CloseTargetClosed)
 		// 通知另一个方向结束
-		client.(*net.TCPConn).CloseWrite()
+		closeWrite(client)
 	}()
 
 	// 等待两个方向的数据传输都完成
 	wg.Wait()
 }
 
+// closeWrite 关闭连接的写方向，通知对端数据已发送完毕
+func closeWrite(conn net.Conn) {
+	if cw, ok := conn.(interface{ CloseWrite() error }); ok {
+		cw.CloseWrite()
+	}
+}
+
+// keepTargetAwake 按主机的唤醒间隔持续发送唤醒包，返回停止函数
+func (s *ForwardService) keepTargetAwake(channel *model.ForwardChannel) func() {
+	// 获取唤醒间隔时间
+	wakeInterval := s.pcService.wakeInterval(channel.TargetHost)
+
+	// 创建定时唤醒的 ticker
+	wakeTicker := time.NewTicker(wakeInterval)
+	stopWake := make(chan struct{})
+
+	go func() {
+		defer wakeTicker.Stop()
+		for {
+			select {
+			case <-wakeTicker.C:
+				// 主机上的 guard 已按使用状态保持唤醒时无需重发
+				if s.pcService.keptAwakeByGuard(channel.TargetHost) {
+					continue
+				}
+				// 主机被强制睡眠后不再唤醒，直到再次主动唤醒
+				if s.pcService.sleepForced(channel.TargetHost) {
+					continue
+				}
+				// 通道活跃期间持续发送唤醒包，保持主机在线
+				log.Printf("保持主机唤醒: %s", channel.TargetHost)
+				s.pcService.keepAwake(channel.TargetHost, "forward:"+channel.ID)
+			case <-stopWake:
+				return
+			}
+		}
+	}()
+
+	return func() { close(stopWake) }
+}
+
+// wakeTarget 目标主机离线时发送唤醒包并等待上线，按配置重试
+// 返回 false 表示重试后主机仍未上线，会话记录唤醒耗时和关闭原因
+func (s *ForwardService) wakeTarget(channel *model.ForwardChannel, host *model.PCHostInfo, stats *channelStats, session *sessionRecord) bool {
+	if s.pcService.probe(context.Background(), host) {
+		return true
+	}
+
+	wakeStart := time.Now()
+	session.WakeNeeded = true
+	cfgHost, exists := s.pcService.hostConfig(channel.TargetHost)
+	retryCount := 1 // 默认重试1次
+	if exists && cfgHost.RetryCount > 0 {
+		retryCount = cfgHost.RetryCount
+	}
+
+	// 获取唤醒超时时间
+	wakeTimeout := config.DefaultWakeTimeout
+	if exists && cfgHost.WakeTimeout > 0 {
+		wakeTimeout = cfgHost.WakeTimeout
+	}
+
+	// 重试循环
+	for retry := 0; retry <= retryCount; retry++ {
+		if retry > 0 {
+			log.Printf("第%d/%d次重试唤醒主机: %s", retry, retryCount, channel.TargetHost)
+		}
+
+		log.Printf("目标主机离线，尝试唤醒: %s [%s %d -> %s:%d]", channel.TargetHost, channel.Protocol, channel.ServicePort, host.IP, channel.TargetPort)
+		s.pcService.sendWakePacket(context.Background(), host, "forward:"+channel.ID, false)
+
+		// 等待主机上线
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(wakeTimeout)*time.Second)
+		online := s.pcService.waitOnline(ctx, host)
+		cancel()
+		if online {
+			session.WakeDuration = time.Since(wakeStart)
+			stats.wakeSucceeded(session.WakeDuration)
+			log.Printf("目标主机已上线，开始转发: %s [%s %d -> %s:%d]", channel.TargetHost, channel.Protocol, channel.ServicePort, host.IP, channel.TargetPort)
+			return true
+		}
+		log.Printf("等待主机上线超时（%d秒）: %s [%s %d -> %s:%d]", wakeTimeout, channel.TargetHost, channel.Protocol, channel.ServicePort, host.IP, channel.TargetPort)
+	}
+
+	log.Printf("已重试%d次，主机仍未上线，放弃连接: %s", retryCount, channel.TargetHost)
+	stats.wakeTimedOut()
+	session.WakeDuration = time.Since(wakeStart)
+	session.CloseReason = CloseWakeTimeout
+	return false
+}
+
+// channelStats 获取通道的连接统计
+func (s *ForwardService) channelStats(channelId string) *channelStats {
+	stats, _ := s.stats.LoadOrStore(channelId, &channelStats{})
+	return stats.(*channelStats)
+}
+
+// snapshot 复制通道信息并填充统计数据，避免并发修改共享的通道对象
+func (s *ForwardService) snapshot(channel *model.ForwardChannel) *model.ForwardChannel {
+	c := *channel
+	s.channelStats(channel.ID).fill(&c)
+	c.Clients = s.aggregateClients(channel.ID)
+	return &c
+}
+
 func (s *ForwardService) GetChannels() []*model.ForwardChannel {
 	var channels []*model.ForwardChannel
 	s.channels.Range(func(_, value interface{}) bool {
 		if channel, ok := value.(*model.ForwardChannel); ok {
-			channels = append(channels, channel)
+			channels = append(channels, s.snapshot(channel))
 		}
 		return true
 	})
@@ -326,18 +474,19 @@ func (s *ForwardService) aggregateClients(channelId string) []*model.AggregatedC
 
 	if clientsMap, ok := s.channelClients.Load(channelId); ok {
 		clientsMap.(*sync.Map).Range(func(_, v interface{}) bool {
-			if client, ok := v.(*model.ChannelClient); ok {
-				if agg, exists := clientMap[client.IP]; exists {
-					agg.Ports = append(agg.Ports, client.Port)
-					if client.LastActive > agg.LastActive {
-						agg.LastActive = client.LastActive
+			if client, ok := v.(*channelClient); ok {
+				lastActive := client.lastActiveTime().Format(time.RFC3339)
+				if agg, exists := clientMap[client.ip]; exists {
+					agg.Ports = append(agg.Ports, client.port)
+					if lastActive > agg.LastActive {
+						agg.LastActive = lastActive
 					}
 				} else {
-					clientMap[client.IP] = &model.AggregatedClient{
-						IP:         client.IP,
-						Ports:      []string{client.Port},
-						Status:     client.Status,
-						LastActive: client.LastActive,
+					clientMap[client.ip] = &model.AggregatedClient{
+						IP:         client.ip,
+						Ports:      []string{client.port},
+						Status:     "active",
+						LastActive: lastActive,
 					}
 				}
 			}
@@ -358,12 +507,7 @@ func (s *ForwardService) GetHostChannels(hostName string) []*model.ForwardChanne
 	s.channels.Range(func(_, value interface{}) bool {
 		if channel, ok := value.(*model.ForwardChannel); ok {
 			if channel.TargetHost == hostName {
-				channel.Clients = s.aggregateClients(channel.ID)
-				// 更新活跃连接数
-				s.countMu.Lock()
-				channel.ActiveCount = int(s.activeCount)
-				s.countMu.Unlock()
-				channels = append(channels, channel)
+				channels = append(channels, s.snapshot(channel))
 			}
 		}
 		return true
@@ -371,6 +515,24 @@ func (s *ForwardService) GetHostChannels(hostName string) []*model.ForwardChanne
 	return channels
 }
 
+// HostActiveSessions 统计目标为该主机的活跃连接数，包括已删除但连接尚未结束的通道
+func (s *ForwardService) HostActiveSessions(hostName string) int {
+	sessions := 0
+	for _, channel := range s.GetHostChannels(hostName) {
+		sessions += channel.ActiveCount
+	}
+	s.draining.Range(func(key, value interface{}) bool {
+		active := key.(*channelStats).active.Load()
+		if active == 0 {
+			s.draining.Delete(key)
+		} else if value.(string) == hostName {
+			sessions += int(active)
+		}
+		return true
+	})
+	return sessions
+}
+
 func (s *ForwardService) logError(format string, v ...interface{}) {
 	log.Printf("[ERROR] "+format, v...)
 }
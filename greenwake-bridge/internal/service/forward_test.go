@@ -0,0 +1,266 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"greenwake-bridge/internal/config"
+	"greenwake-bridge/internal/model"
+)
+
+func TestChannelStatsFill(t *testing.T) {
+	var st channelStats
+	st.sessionStarted()
+	st.sessionStarted()
+	st.sessionStarted()
+	st.sessionEnded()
+	st.wakeSucceeded(2 * time.Second)
+	st.wakeSucceeded(4 * time.Second)
+	st.wakeTimedOut()
+	st.dialFailed()
+
+	// 转发的字节同时累计到通道和会话
+	var session atomic.Int64
+	var buf bytes.Buffer
+	w := &countingWriter{w: &buf, stats: &st, counters: []*atomic.Int64{&st.bytesToTarget, &session}}
+	io.WriteString(w, "hello")
+	io.WriteString(w, " world")
+	st.bytesToClient.Add(3)
+
+	var channel model.ForwardChannel
+	st.fill(&channel)
+	want := model.ForwardChannel{
+		Status:           "active",
+		LastActive:       channel.LastActive,
+		ActiveCount:      2,
+		TotalSessions:    3,
+		FailedSessions:   2,
+		WakeTimeouts:     1,
+		DialFailures:     1,
+		BytesToTarget:    11,
+		BytesToClient:    3,
+		Wakes:            2,
+		AvgWakeLatencyMs: 3000,
+	}
+	if channel.LastActive == "" {
+		t.Error("未记录最后活跃时间")
+	}
+	if !reflect.DeepEqual(channel, want) {
+		t.Errorf("通道统计为 %+v，期望 %+v", channel, want)
+	}
+	if session.Load() != 11 || buf.String() != "hello world" {
+		t.Errorf("会话字节数为 %d，写入 %q，期望 11 和 hello world", session.Load(), buf.String())
+	}
+
+	st.sessionEnded()
+	st.sessionEnded()
+	st.fill(&channel)
+	if channel.Status != "inactive" || channel.ActiveCount != 0 {
+		t.Errorf("连接全部结束后状态为 %s，活跃连接 %d，期望 inactive 和 0", channel.Status, channel.ActiveCount)
+	}
+}
+
+// echoServer 在本地监听TCP端口，原样返回收到的数据
+func echoServer(t *testing.T) int {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听本地端口失败: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+// wakeListener 在本地接收唤醒包，收到后将 prober 设为在线，返回监听端口
+func wakeListener(t *testing.T, prober *fakeProber) int {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听唤醒包失败: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, 256)
+		for {
+			if _, _, err := conn.ReadFrom(buf); err != nil {
+				return
+			}
+			prober.online.Store(true)
+		}
+	}()
+	return conn.LocalAddr().(*net.UDPAddr).Port
+}
+
+// newTestForwardService 创建转发服务，测试结束后关闭
+func newTestForwardService(t *testing.T, pcService *PCService, forwards []config.ForwardConfig) *ForwardService {
+	t.Helper()
+	cfg := &config.Config{DataDir: t.TempDir(), Forwards: forwards}
+	sessions, err := NewSessionService(cfg)
+	if err != nil {
+		t.Fatalf("创建会话记录失败: %v", err)
+	}
+	s := NewForwardService(cfg, pcService, sessions)
+	t.Cleanup(func() {
+		s.Close()
+		sessions.Close()
+	})
+	return s
+}
+
+// forwardChannel 获取通道信息和统计
+func forwardChannel(t *testing.T, s *ForwardService, id string) *model.ForwardChannel {
+	t.Helper()
+	for _, channel := range s.GetChannels() {
+		if channel.ID == id {
+			return channel
+		}
+	}
+	t.Fatalf("通道不存在: %s", id)
+	return nil
+}
+
+// waitFor 等待条件成立
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("等待%s超时", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// dialForward 连接转发端口，等待监听启动
+func dialForward(t *testing.T, network string, port int) net.Conn {
+	t.Helper()
+	var conn net.Conn
+	waitFor(t, "转发监听启动", func() bool {
+		var err error
+		conn, err = net.Dial(network, fmt.Sprintf("127.0.0.1:%d", port))
+		return err == nil
+	})
+	return conn
+}
+
+// echo 发送数据并读取相同长度的响应
+func echo(t *testing.T, conn net.Conn, data string) string {
+	t.Helper()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := io.WriteString(conn, data); err != nil {
+		t.Fatalf("发送数据失败: %v", err)
+	}
+	buf := make([]byte, len(data))
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		t.Fatalf("读取响应失败: %v", err)
+	}
+	return string(buf)
+}
+
+func TestForwardChannelStats(t *testing.T) {
+	prober := &fakeProber{}
+	host := testHost
+	host.WakeTimeout = 5
+	host.WOL = &config.WOLConfig{Address: "127.0.0.1", Port: wakeListener(t, prober)}
+	pcService := newMonitoredPCService(t, host, prober)
+
+	target, closed := echoServer(t), freePort(t)
+	ok := config.ForwardConfig{ServicePort: freePort(t), TargetHost: "desktop", TargetPort: target, Protocol: config.ProtocolTCP}
+	refused := config.ForwardConfig{ServicePort: freePort(t), TargetHost: "desktop", TargetPort: closed, Protocol: config.ProtocolTCP}
+	s := newTestForwardService(t, pcService, []config.ForwardConfig{ok, refused})
+	okID, refusedID := channelID(ok), channelID(refused)
+
+	// 主机离线时第一个连接唤醒主机，连接期间计为活跃
+	conn := dialForward(t, "tcp", ok.ServicePort)
+	if got := echo(t, conn, "hello"); got != "hello" {
+		t.Fatalf("响应为 %q，期望 hello", got)
+	}
+	channel := forwardChannel(t, s, okID)
+	if channel.Status != "active" || channel.ActiveCount != 1 || len(channel.Clients) != 1 || channel.Clients[0].IP != "127.0.0.1" {
+		t.Errorf("连接期间状态为 %s，活跃连接 %d，客户端 %v，期望 active、1 和 127.0.0.1", channel.Status, channel.ActiveCount, channel.Clients)
+	}
+	if channel.Wakes != 1 {
+		t.Errorf("唤醒成功次数为 %d，期望 1", channel.Wakes)
+	}
+
+	// 主机已在线时不再唤醒
+	second := dialForward(t, "tcp", ok.ServicePort)
+	if got := echo(t, second, "hi"); got != "hi" {
+		t.Fatalf("响应为 %q，期望 hi", got)
+	}
+	conn.Close()
+	second.Close()
+	waitFor(t, "连接结束", func() bool { return forwardChannel(t, s, okID).ActiveCount == 0 })
+
+	channel = forwardChannel(t, s, okID)
+	if channel.Status != "inactive" || channel.TotalSessions != 2 || channel.FailedSessions != 0 || channel.Wakes != 1 {
+		t.Errorf("连接结束后状态为 %s，连接 %d，失败 %d，唤醒 %d，期望 inactive、2、0 和 1",
+			channel.Status, channel.TotalSessions, channel.FailedSessions, channel.Wakes)
+	}
+	if channel.BytesToTarget != 7 || channel.BytesToClient != 7 {
+		t.Errorf("转发字节数为 %d/%d，期望 7/7", channel.BytesToTarget, channel.BytesToClient)
+	}
+
+	// 连接目标失败只计入对应通道
+	failed := dialForward(t, "tcp", refused.ServicePort)
+	failed.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := failed.Read(make([]byte, 1)); err == nil {
+		t.Error("连接目标失败后客户端连接未关闭")
+	}
+	failed.Close()
+	waitFor(t, "连接结束", func() bool { return forwardChannel(t, s, refusedID).ActiveCount == 0 })
+
+	channel = forwardChannel(t, s, refusedID)
+	if channel.TotalSessions != 1 || channel.FailedSessions != 1 || channel.DialFailures != 1 || channel.WakeTimeouts != 0 {
+		t.Errorf("连接 %d，失败 %d，连接目标失败 %d，唤醒超时 %d，期望 1、1、1 和 0",
+			channel.TotalSessions, channel.FailedSessions, channel.DialFailures, channel.WakeTimeouts)
+	}
+	if channel := forwardChannel(t, s, okID); channel.TotalSessions != 2 || channel.FailedSessions != 0 {
+		t.Errorf("其他通道的连接为 %d，失败 %d，期望不变", channel.TotalSessions, channel.FailedSessions)
+	}
+}
+
+func TestHostActiveSessionsDraining(t *testing.T) {
+	prober := &fakeProber{}
+	prober.online.Store(true)
+	pcService := newMonitoredPCService(t, testHost, prober)
+
+	fc := config.ForwardConfig{ServicePort: freePort(t), TargetHost: "desktop", TargetPort: echoServer(t), Protocol: config.ProtocolTCP}
+	s := newTestForwardService(t, pcService, []config.ForwardConfig{fc})
+
+	conn := dialForward(t, "tcp", fc.ServicePort)
+	defer conn.Close()
+	if got := echo(t, conn, "hello"); got != "hello" {
+		t.Fatalf("响应为 %q，期望 hello", got)
+	}
+
+	// 删除通道后已建立的连接继续计为主机的活跃连接
+	s.UpdateForwards(nil)
+	if got := s.HostActiveSessions("desktop"); got != 1 {
+		t.Errorf("删除通道后活跃连接为 %d，期望 1", got)
+	}
+	if got := s.HostActiveSessions("nas"); got != 0 {
+		t.Errorf("其他主机的活跃连接为 %d，期望 0", got)
+	}
+
+	conn.Close()
+	waitFor(t, "连接结束", func() bool { return s.HostActiveSessions("desktop") == 0 })
+}
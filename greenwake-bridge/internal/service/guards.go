@@ -0,0 +1,305 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"greenwake-bridge/internal/config"
+	"greenwake-bridge/internal/guard"
+	"greenwake-bridge/internal/model"
+)
+
+const guardCheckInterval = 5 * time.Second // 检查 guard 心跳超时的间隔
+
+var (
+	// ErrGuardsDisabled 未配置 guards.secret，不接受 guard 注册和心跳
+	ErrGuardsDisabled = errors.New("未配置 guards.secret，不接受 guard 注册")
+	// ErrGuardNotRegistered 心跳来自未注册的 guard（如 bridge 重启后），guard 收到后重新注册
+	ErrGuardNotRegistered = errors.New("guard 未注册")
+)
+
+// guardAgent 一个已注册的 guard 及其最近一次心跳的时间
+type guardAgent struct {
+	status model.GuardStatus
+	seen   time.Time
+}
+
+// GuardService 接收主机上的 greenwake-guard 注册和心跳，guard 只能匹配 IP 与请求来源地址相同的主机，
+// 所有 guard 共用签名密钥，不能通过上报的主机名或网卡冒充其他主机
+// guard 在睡眠或关机前发送心跳，主机立即标记为离线；心跳超时时立即检测主机状态
+type GuardService struct {
+	pc       *PCService
+	verifier *guard.Verifier
+	mu       sync.RWMutex
+	cfg      config.GuardsConfig
+	agents   map[string]*guardAgent // key: agentKey(guard 所在主机名, 来源地址)
+	done     chan struct{}
+}
+
+func newGuardService(pc *PCService, cfg config.GuardsConfig) *GuardService {
+	s := &GuardService{
+		pc:       pc,
+		verifier: guard.NewVerifier(),
+		cfg:      cfg,
+		agents:   make(map[string]*guardAgent),
+		done:     make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+// UpdateConfig 更新签名密钥和心跳间隔，已注册的 guard 在下次注册时使用新的间隔
+func (s *GuardService) UpdateConfig(cfg config.GuardsConfig) {
+	s.mu.Lock()
+	s.cfg = cfg
+	s.mu.Unlock()
+}
+
+// Verify 校验 guard 请求的签名，path 为协议中的接口路径
+func (s *GuardService) Verify(method, path string, header http.Header, body []byte) error {
+	s.mu.RLock()
+	secret := s.cfg.Secret
+	s.mu.RUnlock()
+	if secret == "" {
+		return ErrGuardsDisabled
+	}
+	return s.verifier.Verify(secret, method, path, header, body)
+}
+
+// agentKey 已注册 guard 的标识，来源地址不同的 guard 不会覆盖彼此的状态
+func agentKey(hostname, address string) string {
+	return hostname + "@" + address
+}
+
+// Register 注册 guard 或更新已注册的 guard，返回匹配的主机和心跳间隔
+func (s *GuardService) Register(hb guard.Heartbeat, address string) guard.RegisterResponse {
+	host := s.pc.matchGuard(hb, address)
+	now := time.Now()
+
+	s.mu.Lock()
+	agent, exists := s.agents[agentKey(hb.Hostname, address)]
+	if !exists {
+		agent = &guardAgent{}
+		s.agents[agentKey(hb.Hostname, address)] = agent
+	}
+	agent.status.RegisteredAt = now.Format(time.RFC3339)
+	prev := s.apply(agent, hb, host, address, now)
+	interval := s.cfg.HeartbeatInterval
+	s.mu.Unlock()
+
+	if host == "" {
+		log.Printf("guard 注册: %s (%s), 未匹配到主机，主机 IP 需与 guard 的来源地址相同", hb.Hostname, address)
+	} else {
+		log.Printf("guard 注册: %s (%s), 主机: %s, 状态: %s", hb.Hostname, address, host, hb.State)
+	}
+	s.changed(host, hb, prev)
+	return guard.RegisterResponse{Version: guard.ProtocolVersion, Host: host, HeartbeatInterval: interval}
+}
+
+// Heartbeat 更新已注册 guard 的状态，返回匹配的主机
+func (s *GuardService) Heartbeat(hb guard.Heartbeat, address string) (string, error) {
+	host := s.pc.matchGuard(hb, address)
+
+	s.mu.Lock()
+	agent, exists := s.agents[agentKey(hb.Hostname, address)]
+	if !exists {
+		s.mu.Unlock()
+		return "", ErrGuardNotRegistered
+	}
+	prev := s.apply(agent, hb, host, address, time.Now())
+	s.mu.Unlock()
+
+	s.changed(host, hb, prev)
+	return host, nil
+}
+
+// apply 用心跳更新 guard 状态，返回之前的状态（离线时为 offline），调用方需持有锁
+func (s *GuardService) apply(agent *guardAgent, hb guard.Heartbeat, host, address string, now time.Time) string {
+	prev := agent.status.State
+	if !agent.status.Online {
+		prev = HostStateOffline
+	}
+
+	agent.seen = now
+	agent.status.Hostname = hb.Hostname
+	agent.status.Host = host
+	agent.status.OS = hb.OS
+	agent.status.Version = hb.Version
+	agent.status.Online = true
+	agent.status.State = hb.State
+	agent.status.Strategy = hb.Strategy
+	agent.status.SleepMode = hb.SleepMode
+	agent.status.TemporaryWake = hb.TemporaryWake
+	agent.status.SleepAt = ""
+	if hb.SleepInSecs != nil {
+		agent.status.SleepAt = now.Add(time.Duration(*hb.SleepInSecs) * time.Second).Format(time.RFC3339)
+	}
+	agent.status.LastWake = nil
+	if hb.LastWake != nil {
+		agent.status.LastWake = &model.GuardWakeEvent{Type: hb.LastWake.Type, Source: hb.LastWake.Source, Time: hb.LastWake.Time}
+	}
+	agent.status.Interfaces = make([]model.GuardInterface, 0, len(hb.Interfaces))
+	for _, iface := range hb.Interfaces {
+		agent.status.Interfaces = append(agent.status.Interfaces, model.GuardInterface{Name: iface.Name, MAC: iface.MAC, IPs: iface.IPs})
+	}
+	agent.status.Address = address
+	agent.status.LastHeartbeat = now.Format(time.RFC3339)
+	return prev
+}
+
+// changed guard 状态变化时发布事件，并按 guard 的状态更新主机状态
+func (s *GuardService) changed(host string, hb guard.Heartbeat, prev string) {
+	if hb.State == prev {
+		return
+	}
+	log.Printf("guard 状态变化: %s %s -> %s", hb.Hostname, prev, hb.State)
+	s.pc.events.Publish(EventGuardState, host, map[string]string{"guard": hb.Hostname, "from": prev, "to": hb.State})
+	if host == "" {
+		return
+	}
+
+	switch hb.State {
+	case guard.StateSleeping, guard.StateShutdown:
+		// 睡眠前主机可能仍能响应检测，直接标记为离线
+		s.pc.observe(host, false)
+	case guard.StateAwake:
+		if s.pc.hostState(host) != HostStateOnline {
+			s.pc.recheck(host)
+		}
+	}
+}
+
+// run 定期检查心跳超时的 guard，直到服务关闭
+func (s *GuardService) run() {
+	ticker := time.NewTicker(guardCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+		}
+
+		type lost struct{ hostname, host, state string }
+		var expired []lost
+		now := time.Now()
+		s.mu.Lock()
+		timeout := 3 * time.Duration(s.cfg.HeartbeatInterval) * time.Second
+		for _, agent := range s.agents {
+			if agent.status.Online && now.Sub(agent.seen) > timeout {
+				agent.status.Online = false
+				expired = append(expired, lost{agent.status.Hostname, agent.status.Host, agent.status.State})
+			}
+		}
+		s.mu.Unlock()
+
+		for _, g := range expired {
+			log.Printf("guard 心跳超时: %s, 主机: %s", g.hostname, g.host)
+			s.pc.events.Publish(EventGuardState, g.host, map[string]string{"guard": g.hostname, "from": g.state, "to": HostStateOffline})
+			if g.host != "" {
+				s.pc.recheck(g.host)
+			}
+		}
+	}
+}
+
+// Close 停止心跳超时检查
+func (s *GuardService) Close() {
+	close(s.done)
+}
+
+// GetGuards 获取全部已注册的 guard，按主机名排序
+func (s *GuardService) GetGuards() []*model.GuardStatus {
+	s.mu.RLock()
+	result := make([]*model.GuardStatus, 0, len(s.agents))
+	for _, agent := range s.agents {
+		status := agent.status
+		result = append(result, &status)
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Hostname < result[j].Hostname
+	})
+	return result
+}
+
+// ForHost 获取匹配到主机的 guard，有多个时返回最近发送心跳的
+func (s *GuardService) ForHost(hostName string) *model.GuardStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var found *guardAgent
+	for _, agent := range s.agents {
+		if agent.status.Host == hostName && (found == nil || agent.seen.After(found.seen)) {
+			found = agent
+		}
+	}
+	if found == nil {
+		return nil
+	}
+	status := found.status
+	return &status
+}
+
+// MAC 从已注册的 guard 中查找主机的 MAC 地址，用于新增主机时自动填写
+// 只使用已匹配到该主机或来源地址与主机 IP 相同的 guard，优先使用带有主机 IP 的网卡
+func (s *GuardService) MAC(hostName, ip string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, agent := range s.agents {
+		if agent.status.Host != hostName && (ip == "" || !sameIP(agent.status.Address, ip)) {
+			continue
+		}
+		for _, iface := range agent.status.Interfaces {
+			if ip == "" || containsIP(iface.IPs, ip) {
+				return iface.MAC
+			}
+		}
+	}
+	return ""
+}
+
+// matchGuard 匹配 guard 所在的主机：IP 与请求来源地址 address 相同的主机，
+// guard 配置了主机名时还需与该主机一致，避免持有共享密钥的 guard 冒充其他主机
+func (s *PCService) matchGuard(hb guard.Heartbeat, address string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for name, host := range s.cfgHosts {
+		if !sameIP(host.IP, address) {
+			continue
+		}
+		if hb.Host != "" && hb.Host != name {
+			log.Printf("guard %s 配置的主机 %s 与来源地址 %s 对应的主机 %s 不一致，不匹配主机", hb.Hostname, hb.Host, address, name)
+			return ""
+		}
+		return name
+	}
+	if hb.Host != "" {
+		log.Printf("guard %s 的来源地址 %s 与主机 %s 的 IP 不一致，不匹配主机", hb.Hostname, address, hb.Host)
+	}
+	return ""
+}
+
+// sameIP 比较两个 IP 地址，IPv4 映射的 IPv6 地址与对应的 IPv4 地址相同
+func sameIP(a, b string) bool {
+	ia, ib := net.ParseIP(a), net.ParseIP(b)
+	return ia != nil && ib != nil && ia.Equal(ib)
+}
+
+func containsIP(ips []string, ip string) bool {
+	for _, addr := range ips {
+		if addr == ip {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,363 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"greenwake-bridge/internal/model"
+)
+
+// ErrLeaseNotOwned 租约由其他调用者创建
+var ErrLeaseNotOwned = errors.New("lease owned by another caller")
+
+// keepAwakeLease 保持唤醒租约，租约有效期间定期向主机发送唤醒包
+type keepAwakeLease struct {
+	ID        string        `json:"id"`
+	Host      string        `json:"host"`
+	Owner     string        `json:"owner"`  // 创建租约的调用者，如 user:admin、token:ha
+	Reason    string        `json:"reason"` // 保持唤醒的原因
+	ClientIP  string        `json:"clientIp"`
+	UserAgent string        `json:"userAgent"`
+	TTL       time.Duration `json:"ttl"`
+	CreatedAt time.Time     `json:"createdAt"`
+	RenewedAt time.Time     `json:"renewedAt"`
+	ExpiresAt time.Time     `json:"expiresAt"`
+	EndAt     time.Time     `json:"endAt"` // 租约最晚结束时间，续期不能超过该时间，零值表示不限制
+}
+
+func (l *keepAwakeLease) toModel() *model.KeepAwakeLease {
+	lease := &model.KeepAwakeLease{
+		ID:        l.ID,
+		Host:      l.Host,
+		Owner:     l.Owner,
+		Reason:    l.Reason,
+		ClientIP:  l.ClientIP,
+		UserAgent: l.UserAgent,
+		TTL:       int(l.TTL.Seconds()),
+		CreatedAt: l.CreatedAt.Format(time.RFC3339),
+		RenewedAt: l.RenewedAt.Format(time.RFC3339),
+		ExpiresAt: l.ExpiresAt.Format(time.RFC3339),
+	}
+	if !l.EndAt.IsZero() {
+		lease.EndAt = l.EndAt.Format(time.RFC3339)
+	}
+	return lease
+}
+
+// renew 按TTL续期，续期后的到期时间不超过最晚结束时间
+func (l *keepAwakeLease) renew(now time.Time) {
+	l.RenewedAt = now
+	l.ExpiresAt = now.Add(l.TTL)
+	if !l.EndAt.IsZero() && l.ExpiresAt.After(l.EndAt) {
+		l.ExpiresAt = l.EndAt
+	}
+}
+
+// LeaseRequest 创建保持唤醒租约的参数
+type LeaseRequest struct {
+	Owner     string
+	Reason    string
+	ClientIP  string
+	UserAgent string
+	TTL       time.Duration
+	EndAt     time.Time // 零值表示不限制
+}
+
+type KeepAwakeService struct {
+	pcService *PCService
+	path      string // 租约持久化文件，重启后恢复
+	mu        sync.Mutex
+	leases    map[string]*keepAwakeLease // key: leaseId
+	done      chan struct{}
+}
+
+func NewKeepAwakeService(pcService *PCService, dataDir string) (*KeepAwakeService, error) {
+	s := &KeepAwakeService{
+		pcService: pcService,
+		path:      filepath.Join(dataDir, "leases.json"),
+		leases:    make(map[string]*keepAwakeLease),
+		done:      make(chan struct{}),
+	}
+
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+
+	// 启动保持唤醒协程
+	go s.keepAwake()
+
+	return s, nil
+}
+
+// load 恢复重启前未过期的租约
+func (s *KeepAwakeService) load() error {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取租约文件失败: %v", err)
+	}
+
+	var leases []*keepAwakeLease
+	if err := json.Unmarshal(data, &leases); err != nil {
+		return fmt.Errorf("解析租约文件失败: %v", err)
+	}
+
+	now := time.Now()
+	for _, lease := range leases {
+		if now.After(lease.ExpiresAt) {
+			continue
+		}
+		if _, exists := s.pcService.host(lease.Host); !exists {
+			log.Printf("丢弃不存在主机的保持唤醒租约: %s, 主机: %s", lease.ID, lease.Host)
+			continue
+		}
+		s.leases[lease.ID] = lease
+	}
+	log.Printf("恢复保持唤醒租约: %d 个", len(s.leases))
+	return nil
+}
+
+// save 持久化当前租约，调用方需持有锁
+func (s *KeepAwakeService) save() {
+	leases := make([]*keepAwakeLease, 0, len(s.leases))
+	for _, lease := range s.leases {
+		leases = append(leases, lease)
+	}
+
+	data, err := json.MarshalIndent(leases, "", "  ")
+	if err != nil {
+		log.Printf("序列化租约失败: %v", err)
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		log.Printf("创建数据目录失败: %v", err)
+		return
+	}
+	tmp := s.path + ".tmp"
+	// 租约中包含持有者身份，与生成的配置文件一样仅允许所有者读写
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		log.Printf("写入租约文件失败: %v", err)
+		return
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		log.Printf("写入租约文件失败: %v", err)
+	}
+}
+
+// keepAwake 清理过期租约，并按主机的唤醒间隔向有租约的主机发送唤醒包
+func (s *KeepAwakeService) keepAwake() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+		}
+
+		now := time.Now()
+		hosts := make(map[string]*keepAwakeLease) // 每台主机最早创建的租约，唤醒记为该租约持有者发起
+
+		s.mu.Lock()
+		expired := false
+		for id, lease := range s.leases {
+			if now.After(lease.ExpiresAt) {
+				log.Printf("保持唤醒租约已过期: %s, 主机: %s, 持有者: %s", id, lease.Host, lease.Owner)
+				delete(s.leases, id)
+				s.pcService.events.Publish(EventLeaseExpired, lease.Host, lease.toModel())
+				expired = true
+				continue
+			}
+			if first, ok := hosts[lease.Host]; !ok || lease.CreatedAt.Before(first.CreatedAt) {
+				hosts[lease.Host] = lease
+			}
+		}
+		if expired {
+			s.save()
+		}
+		s.mu.Unlock()
+
+		for hostName, lease := range hosts {
+			if s.pcService.keptAwakeByGuard(hostName) {
+				continue
+			}
+			lastWake, ok := s.pcService.lastWakeTime(hostName)
+			if ok && now.Sub(lastWake) < s.pcService.wakeInterval(hostName) {
+				continue
+			}
+			if err := s.pcService.keepAwake(hostName, keepAwakeActor(lease.Owner)); err != nil {
+				log.Printf("保持唤醒发送唤醒包失败: %s, %v", hostName, err)
+			}
+		}
+	}
+}
+
+// keepAwakeActor 保持唤醒发送唤醒包的发起者，如 keep-awake:user:admin
+func keepAwakeActor(owner string) string {
+	return "keep-awake:" + owner
+}
+
+// Close 停止租约检查协程
+func (s *KeepAwakeService) Close() {
+	close(s.done)
+}
+
+// Acquire 为主机创建保持唤醒租约，返回租约信息
+func (s *KeepAwakeService) Acquire(hostName string, req LeaseRequest) (*model.KeepAwakeLease, error) {
+	if _, exists := s.pcService.host(hostName); !exists {
+		return nil, fmt.Errorf("host not found: %s", hostName)
+	}
+
+	now := time.Now()
+	if !req.EndAt.IsZero() && !req.EndAt.After(now) {
+		return nil, fmt.Errorf("结束时间已过: %s", req.EndAt.Format(time.RFC3339))
+	}
+
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("生成租约ID失败: %v", err)
+	}
+
+	lease := &keepAwakeLease{
+		ID:        hex.EncodeToString(buf),
+		Host:      hostName,
+		Owner:     req.Owner,
+		Reason:    req.Reason,
+		ClientIP:  req.ClientIP,
+		UserAgent: req.UserAgent,
+		TTL:       req.TTL,
+		CreatedAt: now,
+		EndAt:     req.EndAt,
+	}
+	lease.renew(now)
+
+	s.mu.Lock()
+	s.leases[lease.ID] = lease
+	s.save()
+	s.mu.Unlock()
+	log.Printf("创建保持唤醒租约: %s, 主机: %s, 持有者: %s, 原因: %s, 有效期: %v", lease.ID, hostName, lease.Owner, lease.Reason, lease.TTL)
+	s.pcService.events.Publish(EventLeaseAcquired, hostName, lease.toModel())
+
+	// 立即发送一次唤醒包，不必等待下一次检查
+	if lastWake, ok := s.pcService.lastWakeTime(hostName); !ok || now.Sub(lastWake) >= s.pcService.wakeInterval(hostName) {
+		go s.pcService.keepAwake(hostName, keepAwakeActor(lease.Owner))
+	}
+
+	return lease.toModel(), nil
+}
+
+// Renew 续期 owner 创建的租约，ttl 为0时沿用创建时的有效期
+func (s *KeepAwakeService) Renew(hostName string, leaseId string, owner string, ttl time.Duration) (*model.KeepAwakeLease, error) {
+	return s.renew(hostName, leaseId, owner, false, ttl)
+}
+
+// AdminRenew 续期任意调用者创建的租约，用于管理员
+func (s *KeepAwakeService) AdminRenew(hostName string, leaseId string, ttl time.Duration) (*model.KeepAwakeLease, error) {
+	return s.renew(hostName, leaseId, "", true, ttl)
+}
+
+// renew 续期租约，admin 为 false 时只能续期 owner 创建的租约
+func (s *KeepAwakeService) renew(hostName string, leaseId string, owner string, admin bool, ttl time.Duration) (*model.KeepAwakeLease, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lease, ok := s.leases[leaseId]
+	if !ok || lease.Host != hostName || time.Now().After(lease.ExpiresAt) {
+		return nil, fmt.Errorf("lease not found: %s", leaseId)
+	}
+	if !admin && lease.Owner != owner {
+		return nil, fmt.Errorf("%w: %s", ErrLeaseNotOwned, leaseId)
+	}
+	if ttl > 0 {
+		lease.TTL = ttl
+	}
+	lease.renew(time.Now())
+	s.save()
+	s.pcService.events.Publish(EventLeaseRenewed, hostName, lease.toModel())
+
+	return lease.toModel(), nil
+}
+
+// Release 结束 owner 创建的保持唤醒租约
+func (s *KeepAwakeService) Release(hostName string, leaseId string, owner string) error {
+	return s.release(hostName, leaseId, owner, false)
+}
+
+// AdminRelease 结束任意调用者创建的保持唤醒租约，用于管理员和强制睡眠
+func (s *KeepAwakeService) AdminRelease(hostName string, leaseId string) error {
+	return s.release(hostName, leaseId, "", true)
+}
+
+// release 结束租约，admin 为 false 时只能结束 owner 创建的租约
+func (s *KeepAwakeService) release(hostName string, leaseId string, owner string, admin bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lease, ok := s.leases[leaseId]
+	if !ok || lease.Host != hostName {
+		return fmt.Errorf("lease not found: %s", leaseId)
+	}
+	if !admin && lease.Owner != owner {
+		return fmt.Errorf("%w: %s", ErrLeaseNotOwned, leaseId)
+	}
+	delete(s.leases, leaseId)
+	s.save()
+	s.pcService.events.Publish(EventLeaseReleased, hostName, lease.toModel())
+	return nil
+}
+
+// DropRemovedHosts 结束已从配置中删除的主机的保持唤醒租约，配置重新加载后调用
+func (s *KeepAwakeService) DropRemovedHosts() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dropped := false
+	for id, lease := range s.leases {
+		if _, exists := s.pcService.host(lease.Host); exists {
+			continue
+		}
+		log.Printf("主机已删除，结束保持唤醒租约: %s, 主机: %s, 持有者: %s", id, lease.Host, lease.Owner)
+		delete(s.leases, id)
+		s.pcService.events.Publish(EventLeaseReleased, lease.Host, lease.toModel())
+		dropped = true
+	}
+	if dropped {
+		s.save()
+	}
+}
+
+// GetHostLeases 获取主机有效的保持唤醒租约
+func (s *KeepAwakeService) GetHostLeases(hostName string) []*model.KeepAwakeLease {
+	now := time.Now()
+	leases := make([]*model.KeepAwakeLease, 0)
+
+	s.mu.Lock()
+	for _, lease := range s.leases {
+		if lease.Host == hostName && now.Before(lease.ExpiresAt) {
+			leases = append(leases, lease.toModel())
+		}
+	}
+	s.mu.Unlock()
+
+	sort.Slice(leases, func(i, j int) bool {
+		return leases[i].CreatedAt < leases[j].CreatedAt
+	})
+	return leases
+}
+
+// IsKeptAwake 判断主机当前是否有有效的保持唤醒租约
+func (s *KeepAwakeService) IsKeptAwake(hostName string) bool {
+	return len(s.GetHostLeases(hostName)) > 0
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"greenwake-bridge/internal/config"
+)
+
+// newTestKeepAwakeService 创建只有一台主机的保持唤醒服务
+func newTestKeepAwakeService(t *testing.T) (*KeepAwakeService, string) {
+	t.Helper()
+	dir := t.TempDir()
+	pcService, err := NewPCService(&config.Config{
+		DataDir: dir,
+		Hosts:   []config.PCHostConfig{{Name: "desktop", IP: "127.0.0.1", MAC: "00:11:22:33:44:55", MonitorPort: 1}},
+	})
+	if err != nil {
+		t.Fatalf("创建主机服务失败: %v", err)
+	}
+	t.Cleanup(pcService.Close)
+
+	s, err := NewKeepAwakeService(pcService, dir)
+	if err != nil {
+		t.Fatalf("创建保持唤醒服务失败: %v", err)
+	}
+	return s, dir
+}
+
+func TestKeepAwakeServiceClose(t *testing.T) {
+	s, _ := newTestKeepAwakeService(t)
+	s.Close()
+
+	// 关闭后检查协程应立即退出，而不是阻塞在 ticker 上
+	exited := make(chan struct{})
+	go func() {
+		s.keepAwake()
+		close(exited)
+	}()
+	select {
+	case <-exited:
+	case <-time.After(3 * time.Second):
+		t.Fatal("关闭后租约检查协程未退出")
+	}
+}
+
+func TestKeepAwakeLeasesFileMode(t *testing.T) {
+	s, dir := newTestKeepAwakeService(t)
+	defer s.Close()
+
+	if _, err := s.Acquire("desktop", LeaseRequest{Owner: "user:admin", TTL: time.Minute}); err != nil {
+		t.Fatalf("创建租约失败: %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(dir, "leases.json"))
+	if err != nil {
+		t.Fatalf("读取租约文件失败: %v", err)
+	}
+	if mode := info.Mode().Perm(); mode != 0600 {
+		t.Errorf("租约文件权限为 %o，期望 600", mode)
+	}
+}
+
+func TestKeepAwakeLeaseOwner(t *testing.T) {
+	s, _ := newTestKeepAwakeService(t)
+	defer s.Close()
+
+	tests := []struct {
+		name    string
+		release func(id string) error
+		wantErr bool
+	}{
+		{"创建者结束租约", func(id string) error { return s.Release("desktop", id, "token:ha") }, false},
+		{"其他调用者结束租约", func(id string) error { return s.Release("desktop", id, "token:other") }, true},
+		{"调用者为空", func(id string) error { return s.Release("desktop", id, "") }, true},
+		{"管理员结束租约", func(id string) error { return s.AdminRelease("desktop", id) }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lease, err := s.Acquire("desktop", LeaseRequest{Owner: "token:ha", TTL: time.Minute})
+			if err != nil {
+				t.Fatalf("创建租约失败: %v", err)
+			}
+			defer s.AdminRelease("desktop", lease.ID)
+
+			_, renewErr := s.Renew("desktop", lease.ID, "", 0)
+			if !errors.Is(renewErr, ErrLeaseNotOwned) {
+				t.Errorf("调用者为空时续期的错误为 %v，期望 ErrLeaseNotOwned", renewErr)
+			}
+			if _, err := s.AdminRenew("desktop", lease.ID, 0); err != nil {
+				t.Errorf("管理员续期失败: %v", err)
+			}
+
+			err = tt.release(lease.ID)
+			if tt.wantErr != errors.Is(err, ErrLeaseNotOwned) {
+				t.Errorf("结束租约的错误为 %v，期望租约不属于调用者 %v", err, tt.wantErr)
+			}
+			want := 0
+			if tt.wantErr {
+				want = 1
+			}
+			if remaining := len(s.GetHostLeases("desktop")); remaining != want {
+				t.Errorf("剩余租约 %d 个，期望 %d 个", remaining, want)
+			}
+		})
+	}
+}
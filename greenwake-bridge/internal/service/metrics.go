@@ -0,0 +1,101 @@
+package service
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	hostOnlineDesc = prometheus.NewDesc("greenwake_host_online",
+		"Whether the host is online (1) according to the background monitor.",
+		[]string{"host"}, nil)
+	hostStateDesc = prometheus.NewDesc("greenwake_host_state",
+		"Current monitor state of the host, 1 for the active state.",
+		[]string{"host", "state"}, nil)
+	keepAwakeDesc = prometheus.NewDesc("greenwake_keep_awake_leases",
+		"Number of active keep-awake leases, by host.",
+		[]string{"host"}, nil)
+	relayUpDesc = prometheus.NewDesc("greenwake_relay_up",
+		"Whether the wake relay (greenwake-guard) answered the last check (1).",
+		[]string{"relay"}, nil)
+	forwardActiveDesc = prometheus.NewDesc("greenwake_forward_active_sessions",
+		"Number of active forward sessions, by channel.",
+		[]string{"channel", "protocol", "service_port", "host"}, nil)
+	forwardSessionsDesc = prometheus.NewDesc("greenwake_forward_sessions_total",
+		"Number of forward sessions, by channel.",
+		[]string{"channel", "protocol", "service_port", "host"}, nil)
+	forwardFailuresDesc = prometheus.NewDesc("greenwake_forward_failed_sessions_total",
+		"Number of failed forward sessions, by channel and reason.",
+		[]string{"channel", "protocol", "service_port", "host", "reason"}, nil)
+	forwardBytesDesc = prometheus.NewDesc("greenwake_forward_bytes_total",
+		"Bytes forwarded, by channel and direction (to_target or to_client).",
+		[]string{"channel", "protocol", "service_port", "host", "direction"}, nil)
+)
+
+var hostStates = []string{HostStateUnknown, HostStateOnline, HostStateOffline, HostStateWaking}
+
+// MetricsCollector 抓取指标时读取主机状态、保持唤醒租约、中继状态和转发通道统计
+type MetricsCollector struct {
+	pcService        *PCService
+	forwardService   *ForwardService
+	keepAwakeService *KeepAwakeService
+}
+
+func NewMetricsCollector(pcService *PCService, forwardService *ForwardService, keepAwakeService *KeepAwakeService) *MetricsCollector {
+	return &MetricsCollector{
+		pcService:        pcService,
+		forwardService:   forwardService,
+		keepAwakeService: keepAwakeService,
+	}
+}
+
+func (c *MetricsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- hostOnlineDesc
+	ch <- hostStateDesc
+	ch <- keepAwakeDesc
+	ch <- relayUpDesc
+	ch <- forwardActiveDesc
+	ch <- forwardSessionsDesc
+	ch <- forwardFailuresDesc
+	ch <- forwardBytesDesc
+}
+
+func (c *MetricsCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, host := range c.pcService.GetHosts() {
+		state := c.pcService.hostState(host.Name)
+		online := 0.0
+		if state == HostStateOnline {
+			online = 1
+		}
+		ch <- prometheus.MustNewConstMetric(hostOnlineDesc, prometheus.GaugeValue, online, host.Name)
+		for _, s := range hostStates {
+			value := 0.0
+			if s == state {
+				value = 1
+			}
+			ch <- prometheus.MustNewConstMetric(hostStateDesc, prometheus.GaugeValue, value, host.Name, s)
+		}
+
+		leases := len(c.keepAwakeService.GetHostLeases(host.Name))
+		ch <- prometheus.MustNewConstMetric(keepAwakeDesc, prometheus.GaugeValue, float64(leases), host.Name)
+	}
+
+	for _, relay := range c.pcService.Relays().GetRelays() {
+		up := 0.0
+		if relay.Reachable {
+			up = 1
+		}
+		ch <- prometheus.MustNewConstMetric(relayUpDesc, prometheus.GaugeValue, up, relay.Name)
+	}
+
+	for _, channel := range c.forwardService.GetChannels() {
+		labels := []string{channel.ID, channel.Protocol, strconv.Itoa(channel.ServicePort), channel.TargetHost}
+		ch <- prometheus.MustNewConstMetric(forwardActiveDesc, prometheus.GaugeValue, float64(channel.ActiveCount), labels...)
+		ch <- prometheus.MustNewConstMetric(forwardSessionsDesc, prometheus.CounterValue, float64(channel.TotalSessions), labels...)
+		ch <- prometheus.MustNewConstMetric(forwardFailuresDesc, prometheus.CounterValue, float64(channel.WakeTimeouts), append(labels, "wake_timeout")...)
+		ch <- prometheus.MustNewConstMetric(forwardFailuresDesc, prometheus.CounterValue, float64(channel.DialFailures), append(labels, "dial_failure")...)
+		ch <- prometheus.MustNewConstMetric(forwardBytesDesc, prometheus.CounterValue, float64(channel.BytesToTarget), append(labels, "to_target")...)
+		ch <- prometheus.MustNewConstMetric(forwardBytesDesc, prometheus.CounterValue, float64(channel.BytesToClient), append(labels, "to_client")...)
+	}
+}
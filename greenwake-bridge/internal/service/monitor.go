@@ -0,0 +1,290 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"greenwake-bridge/internal/config"
+	"greenwake-bridge/internal/metrics"
+	"greenwake-bridge/internal/model"
+)
+
+// 主机状态
+const (
+	HostStateUnknown = "unknown" // 尚未完成首次检测
+	HostStateOnline  = "online"  // 在线
+	HostStateOffline = "offline" // 离线（睡眠或关机）
+	HostStateWaking  = "waking"  // 已发送唤醒包，等待上线
+)
+
+const (
+	wakingProbeInterval = time.Second // 唤醒中的检测间隔，尽快发现主机上线
+	maxTransitions      = 100         // 每台主机保留的状态变化记录数
+)
+
+// hostMonitor 后台检测单台主机的在线状态并缓存结果
+type hostMonitor struct {
+	mu          sync.Mutex
+	state       string
+	since       time.Time // 进入当前状态的时间
+	lastCheck   time.Time
+	wakingUntil time.Time     // 唤醒等待截止时间，超过后仍未上线则视为离线
+	wakeStarted time.Time     // 本次唤醒开始的时间，用于统计上线耗时
+	interval    time.Duration // 下一次检测的间隔
+	transitions []model.StatusTransition
+	kick        chan struct{} // 发送唤醒包后立即触发检测
+	stop        chan struct{} // 主机从配置中删除时停止检测
+}
+
+func newHostMonitor() *hostMonitor {
+	return &hostMonitor{
+		state: HostStateUnknown,
+		since: time.Now(),
+		kick:  make(chan struct{}, 1),
+		stop:  make(chan struct{}),
+	}
+}
+
+// recheck 立即触发一次检测，如主机配置更新后
+func (m *hostMonitor) recheck() {
+	select {
+	case m.kick <- struct{}{}:
+	default:
+	}
+}
+
+// observe 记录一次检测结果，返回之前和新的状态，唤醒后上线时 woke 为唤醒耗时
+func (m *hostMonitor) observe(online bool, now time.Time) (prev, state string, woke time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lastCheck = now
+	state = HostStateOffline
+	if online {
+		state = HostStateOnline
+		m.wakingUntil = time.Time{}
+	} else if now.Before(m.wakingUntil) {
+		state = HostStateWaking
+	}
+
+	prev = m.state
+	if prev == HostStateWaking && state != HostStateWaking {
+		if online {
+			woke = now.Sub(m.wakeStarted)
+		}
+		m.wakeStarted = time.Time{}
+	}
+	m.setState(state, now)
+	return prev, state, woke
+}
+
+// markWaking 发送唤醒包后进入唤醒中状态，直到主机上线或超过等待时间，返回之前的状态
+func (m *hostMonitor) markWaking(until time.Time) (prev string) {
+	m.mu.Lock()
+	if until.After(m.wakingUntil) {
+		m.wakingUntil = until
+	}
+	prev = m.state
+	online := prev == HostStateOnline
+	if !online {
+		now := time.Now()
+		if m.wakeStarted.IsZero() {
+			m.wakeStarted = now
+		}
+		m.setState(HostStateWaking, now)
+	}
+	m.mu.Unlock()
+
+	// 主机在线时（如保持唤醒重发唤醒包）无需立即检测
+	if !online {
+		m.recheck()
+	}
+	return prev
+}
+
+// setState 切换状态并记录状态变化，调用方需持有锁
+func (m *hostMonitor) setState(state string, now time.Time) {
+	if m.state == state {
+		return
+	}
+	m.state = state
+	m.since = now
+	m.transitions = append(m.transitions, model.StatusTransition{
+		State: state,
+		At:    now.Format(time.RFC3339),
+	})
+	if len(m.transitions) > maxTransitions {
+		m.transitions = m.transitions[len(m.transitions)-maxTransitions:]
+	}
+}
+
+// nextInterval 计算下一次检测间隔：在线按基础间隔，唤醒中快速检测，离线时指数退避
+func (m *hostMonitor) nextInterval(base, max time.Duration) time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch m.state {
+	case HostStateWaking:
+		m.interval = wakingProbeInterval
+	case HostStateOffline:
+		if m.interval < base {
+			m.interval = base
+		} else {
+			m.interval *= 2
+		}
+		if m.interval > max {
+			m.interval = max
+		}
+	default:
+		m.interval = base
+	}
+	return m.interval
+}
+
+func (m *hostMonitor) snapshot() (state string, since, lastCheck time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state, m.since, m.lastCheck
+}
+
+func (m *hostMonitor) history() []model.StatusTransition {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	history := make([]model.StatusTransition, len(m.transitions))
+	copy(history, m.transitions)
+	return history
+}
+
+// runMonitor 按配置的间隔后台检测主机状态，直到服务关闭
+func (s *PCService) runMonitor(hostName string, m *hostMonitor) {
+	s.mu.RLock()
+	monitor := s.cfg.Monitor
+	s.mu.RUnlock()
+	base := time.Duration(monitor.Interval) * time.Second
+	max := time.Duration(monitor.MaxInterval) * time.Second
+	if base <= 0 {
+		base = time.Duration(config.DefaultMonitorInterval) * time.Second
+	}
+	if max < base {
+		max = base
+	}
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-m.stop:
+			return
+		case <-m.kick:
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+		case <-timer.C:
+		}
+
+		// 每次检测时获取主机信息，配置更新后使用新的地址
+		if host, exists := s.host(hostName); exists {
+			s.probe(context.Background(), host)
+		}
+		timer.Reset(m.nextInterval(base, max))
+	}
+}
+
+// probe 使用主机配置的检测方式检测是否在线，并更新缓存状态
+func (s *PCService) probe(ctx context.Context, host *model.PCHostInfo) bool {
+	s.mu.RLock()
+	prober, ok := s.probers[host.Name]
+	s.mu.RUnlock()
+	if !ok {
+		return false
+	}
+	online := prober.Probe(ctx)
+
+	// ctx 被取消时检测结果不可信，不更新状态
+	if online || ctx.Err() == nil {
+		s.observe(host.Name, online)
+	}
+	return online
+}
+
+// observe 记录主机是否在线，状态变化时发布事件，也用于 guard 通知即将睡眠时直接标记离线
+func (s *PCService) observe(hostName string, online bool) {
+	m, ok := s.monitor(hostName)
+	if !ok {
+		return
+	}
+
+	prev, state, woke := m.observe(online, time.Now())
+	if state != prev {
+		log.Printf("主机状态变化: %s %s -> %s", hostName, prev, state)
+		s.events.Publish(EventHostState, hostName, map[string]string{"from": prev, "to": state})
+		if state == HostStateOnline {
+			s.resetWaker(hostName)
+			// 强制睡眠后主机被其他方式唤醒，恢复转发通道保持唤醒
+			s.forcedSleep.Delete(hostName)
+		}
+		if prev == HostStateWaking {
+			metrics.WakeFinished(hostName, state == HostStateOnline, woke)
+			result := "timeout"
+			if state == HostStateOnline {
+				result = "success"
+			}
+			s.events.Publish(EventWakeFinished, hostName, map[string]interface{}{
+				"actor":      s.lastWakeActor(hostName),
+				"result":     result,
+				"durationMs": woke.Milliseconds(),
+			})
+		}
+	}
+}
+
+// recheck 立即检测一次主机状态，如 guard 心跳超时或主机上的 guard 重新发送心跳
+func (s *PCService) recheck(hostName string) {
+	if m, ok := s.monitor(hostName); ok {
+		m.recheck()
+	}
+}
+
+// markWaking 记录主机正在被唤醒，等待时间为唤醒超时乘以重试次数，actor 为发起唤醒的调用者
+func (s *PCService) markWaking(hostName, actor string) {
+	m, ok := s.monitor(hostName)
+	if !ok {
+		return
+	}
+
+	wait := time.Duration(config.DefaultWakeTimeout) * time.Second
+	if cfgHost, exists := s.hostConfig(hostName); exists && cfgHost.WakeTimeout > 0 {
+		wait = time.Duration(cfgHost.WakeTimeout*(cfgHost.RetryCount+1)) * time.Second
+	}
+	if prev := m.markWaking(time.Now().Add(wait)); prev != HostStateOnline && prev != HostStateWaking {
+		log.Printf("主机状态变化: %s %s -> %s", hostName, prev, HostStateWaking)
+		s.events.Publish(EventHostState, hostName, map[string]string{"from": prev, "to": HostStateWaking, "actor": actor})
+	}
+}
+
+// hostState 获取后台检测缓存的主机状态
+func (s *PCService) hostState(hostName string) string {
+	m, ok := s.monitor(hostName)
+	if !ok {
+		return HostStateUnknown
+	}
+	state, _, _ := m.snapshot()
+	return state
+}
+
+// GetHostHistory 获取主机最近的状态变化记录
+func (s *PCService) GetHostHistory(hostName string) ([]model.StatusTransition, bool) {
+	m, ok := s.monitor(hostName)
+	if !ok {
+		return nil, false
+	}
+	return m.history(), true
+}
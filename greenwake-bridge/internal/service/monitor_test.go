@@ -0,0 +1,235 @@
+package service
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"greenwake-bridge/internal/config"
+	"greenwake-bridge/internal/model"
+)
+
+// fakeProber 返回设定结果的检测方式，记录检测次数
+type fakeProber struct {
+	online atomic.Bool
+	probes atomic.Int32
+}
+
+func (p *fakeProber) Probe(ctx context.Context) bool {
+	p.probes.Add(1)
+	return p.online.Load()
+}
+
+func (p *fakeProber) String() string {
+	return "fake"
+}
+
+// states 提取状态变化记录中的状态
+func states(history []model.StatusTransition) []string {
+	result := make([]string, 0, len(history))
+	for _, h := range history {
+		result = append(result, h.State)
+	}
+	return result
+}
+
+func TestHostMonitorObserve(t *testing.T) {
+	m := newHostMonitor()
+	now := time.Now()
+
+	if prev, state, _ := m.observe(false, now); prev != HostStateUnknown || state != HostStateOffline {
+		t.Fatalf("首次检测离线: %s -> %s，期望 unknown -> offline", prev, state)
+	}
+
+	// 发送唤醒包后进入唤醒中，并立即触发一次检测
+	if prev := m.markWaking(now.Add(time.Minute)); prev != HostStateOffline {
+		t.Errorf("唤醒前的状态为 %s，期望 offline", prev)
+	}
+	select {
+	case <-m.kick:
+	default:
+		t.Error("进入唤醒中后未触发检测")
+	}
+	if prev, state, _ := m.observe(false, now.Add(time.Second)); prev != HostStateWaking || state != HostStateWaking {
+		t.Errorf("等待时间内检测离线: %s -> %s，期望保持 waking", prev, state)
+	}
+	_, state, woke := m.observe(true, time.Now().Add(2*time.Second))
+	if state != HostStateOnline || woke <= 0 {
+		t.Errorf("唤醒后上线: 状态 %s，耗时 %v，期望 online 且耗时大于0", state, woke)
+	}
+
+	// 在线时重发唤醒包不改变状态，也不触发检测
+	if prev := m.markWaking(now.Add(3 * time.Second)); prev != HostStateOnline {
+		t.Errorf("重发唤醒包前的状态为 %s，期望 online", prev)
+	}
+	select {
+	case <-m.kick:
+		t.Error("在线时重发唤醒包触发了检测")
+	default:
+	}
+	// 等待时间内离线仍视为唤醒中，超过后视为离线
+	if _, state, _ := m.observe(false, now.Add(2*time.Second)); state != HostStateWaking {
+		t.Errorf("重发唤醒包后检测离线的状态为 %s，期望 waking", state)
+	}
+	if prev, state, woke := m.observe(false, now.Add(4*time.Second)); prev != HostStateWaking || state != HostStateOffline || woke != 0 {
+		t.Errorf("唤醒超时: %s -> %s，耗时 %v，期望 waking -> offline 且耗时为0", prev, state, woke)
+	}
+
+	want := []string{HostStateOffline, HostStateWaking, HostStateOnline, HostStateWaking, HostStateOffline}
+	if got := states(m.history()); !reflect.DeepEqual(got, want) {
+		t.Errorf("状态变化记录为 %v，期望 %v", got, want)
+	}
+	if state, _, lastCheck := m.snapshot(); state != HostStateOffline || !lastCheck.Equal(now.Add(4*time.Second)) {
+		t.Errorf("缓存的状态为 %s，检测时间 %v，期望 offline 和最后一次检测的时间", state, lastCheck)
+	}
+}
+
+func TestHostMonitorTransitionsLimit(t *testing.T) {
+	m := newHostMonitor()
+	now := time.Now()
+	for i := 0; i < maxTransitions+50; i++ {
+		m.observe(i%2 == 0, now.Add(time.Duration(i)*time.Second))
+	}
+
+	history := m.history()
+	if len(history) != maxTransitions {
+		t.Fatalf("状态变化记录为 %d 条，期望 %d 条", len(history), maxTransitions)
+	}
+	if last := history[len(history)-1]; last.State != HostStateOffline {
+		t.Errorf("最后的状态变化为 %s，期望 offline", last.State)
+	}
+}
+
+func TestHostMonitorNextInterval(t *testing.T) {
+	const base, max = 10 * time.Second, 60 * time.Second
+
+	// 按顺序设置状态并计算下一次检测间隔
+	steps := []struct {
+		state string
+		want  time.Duration
+	}{
+		{HostStateUnknown, base},
+		{HostStateOnline, base},
+		{HostStateOffline, 2 * base},
+		{HostStateOffline, 4 * base},
+		{HostStateOffline, max},
+		{HostStateOffline, max},
+		{HostStateWaking, wakingProbeInterval},
+		{HostStateOffline, base},
+		{HostStateOffline, 2 * base},
+		{HostStateOnline, base},
+	}
+
+	m := newHostMonitor()
+	for i, step := range steps {
+		m.setState(step.state, time.Now())
+		if got := m.nextInterval(base, max); got != step.want {
+			t.Errorf("第 %d 步 %s 的检测间隔为 %v，期望 %v", i+1, step.state, got, step.want)
+		}
+	}
+}
+
+// testHost 测试使用的主机，唤醒包发送到本地地址
+var testHost = config.PCHostConfig{
+	Name: "desktop", IP: "127.0.0.1", MAC: "00:11:22:33:44:55", WakeTimeout: 30,
+	WOL: &config.WOLConfig{Address: "127.0.0.1", Port: 9},
+}
+
+// newMonitoredPCService 创建使用 prober 检测主机的主机服务，并启动后台检测
+func newMonitoredPCService(t *testing.T, host config.PCHostConfig, prober *fakeProber) *PCService {
+	t.Helper()
+	s, err := NewPCService(&config.Config{
+		DataDir: t.TempDir(),
+		Monitor: config.MonitorConfig{Interval: 3600, MaxInterval: 3600},
+	})
+	if err != nil {
+		t.Fatalf("创建主机服务失败: %v", err)
+	}
+	t.Cleanup(s.Close)
+
+	waker, err := s.newWaker(host)
+	if err != nil {
+		t.Fatalf("创建唤醒方式失败: %v", err)
+	}
+	m := newHostMonitor()
+	s.mu.Lock()
+	s.hosts[host.Name] = &model.PCHostInfo{Name: host.Name, IP: host.IP, MAC: host.MAC, MonitorPort: host.MonitorPort}
+	s.cfgHosts[host.Name] = host
+	s.probers[host.Name] = prober
+	s.wakers[host.Name] = waker
+	s.monitors[host.Name] = m
+	s.mu.Unlock()
+	go s.runMonitor(host.Name, m)
+	return s
+}
+
+// waitState 等待后台检测缓存的主机状态变为 want
+func waitState(t *testing.T, s *PCService, want string) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for s.hostState("desktop") != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("主机状态为 %s，期望 %s", s.hostState("desktop"), want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestPCServiceMonitor(t *testing.T) {
+	prober := &fakeProber{}
+	s := newMonitoredPCService(t, testHost, prober)
+	sub := s.events.Subscribe(0)
+	defer sub.Close()
+
+	// 启动后立即检测一次，之后按基础间隔检测
+	waitState(t, s, HostStateOffline)
+	time.Sleep(50 * time.Millisecond)
+	if n := prober.probes.Load(); n != 1 {
+		t.Errorf("启动后检测了 %d 次，期望 1 次", n)
+	}
+
+	// 发送唤醒包后立即检测，检测到在线时结束唤醒
+	prober.online.Store(true)
+	s.markWaking("desktop", "user:admin")
+	waitState(t, s, HostStateOnline)
+
+	want := []string{HostStateOffline, HostStateWaking, HostStateOnline}
+	history, ok := s.GetHostHistory("desktop")
+	if !ok || !reflect.DeepEqual(states(history), want) {
+		t.Errorf("状态变化记录为 %v，期望 %v", states(history), want)
+	}
+
+	var types []string
+	var finished map[string]interface{}
+	for len(types) < 4 {
+		select {
+		case ev := <-sub.C:
+			types = append(types, ev.Type)
+			if ev.Type == EventWakeFinished {
+				finished = ev.Data.(map[string]interface{})
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatalf("收到的事件为 %v，期望 4 个", types)
+		}
+	}
+	// 进入唤醒中的事件由发送唤醒包的调用方发布，可能晚于后台检测发布的上线事件
+	sort.Strings(types)
+	wantTypes := []string{EventHostState, EventHostState, EventHostState, EventWakeFinished}
+	if !reflect.DeepEqual(types, wantTypes) {
+		t.Errorf("事件为 %v，期望 %v", types, wantTypes)
+	}
+	if finished["result"] != "success" {
+		t.Errorf("唤醒结果为 %v，期望 success", finished["result"])
+	}
+
+	// 主机删除后停止检测
+	if err := s.UpdateHosts(nil); err != nil {
+		t.Fatalf("删除主机失败: %v", err)
+	}
+	if state := s.hostState("desktop"); state != HostStateUnknown {
+		t.Errorf("删除后的主机状态为 %s，期望 unknown", state)
+	}
+}
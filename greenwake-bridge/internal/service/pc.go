@@ -1,48 +1,214 @@
 package service
 
 import (
+	"context"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"log"
-	"net"
+	"reflect"
 	"sync"
 	"time"
 
 	"greenwake-bridge/internal/config"
+	"greenwake-bridge/internal/guard"
+	"greenwake-bridge/internal/metrics"
 	"greenwake-bridge/internal/model"
-
-	"github.com/sabhiram/go-wol/wol"
+	"greenwake-bridge/internal/probe"
+	"greenwake-bridge/internal/wake"
 )
 
+// maxWakeDuration 一次唤醒依次尝试全部唤醒方式的最长时间，避免调用方长时间阻塞
+const maxWakeDuration = 30 * time.Second
+
+// ErrNoGuard 主机未配置 guard，不能远程睡眠或关机
+var ErrNoGuard = errors.New("主机未配置 guard，不能远程睡眠或关机")
+
+// ErrHostNotFound 主机不存在
+var ErrHostNotFound = errors.New("host not found")
+
 type PCService struct {
+	mu       sync.RWMutex // 保护当前配置和以下主机映射，配置重新加载时会更新
 	cfg      *config.Config
 	hosts    map[string]*model.PCHostInfo
 	cfgHosts map[string]config.PCHostConfig
-	status   sync.Map // key: hostName, value: *model.PCHostStatus
+	probers  map[string]probe.Prober
+	wakers   map[string]*wake.Chain
+	monitors map[string]*hostMonitor
 	wol      sync.Map // key: hostName, value: time.Time (上次唤醒时间)
+	wakeBy   sync.Map // key: hostName, value: string (上次唤醒的发起者)
+	relays   *RelayService
+	guards   *GuardService
+	events   *EventBus
+	done     chan struct{}
+
+	guardKeepsAwake sync.Map // key: hostName, value: bool (guard 已接受使用中的状态，无需重发唤醒包)
+	forcedSleep     sync.Map // key: hostName, value: bool (强制睡眠后转发通道不再保持唤醒，直到再次主动唤醒)
 }
 
-func NewPCService(cfg *config.Config) *PCService {
+func NewPCService(cfg *config.Config) (*PCService, error) {
+	events, err := NewEventBus(cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	s := &PCService{
 		cfg:      cfg,
 		hosts:    make(map[string]*model.PCHostInfo),
 		cfgHosts: make(map[string]config.PCHostConfig),
+		probers:  make(map[string]probe.Prober),
+		wakers:   make(map[string]*wake.Chain),
+		monitors: make(map[string]*hostMonitor),
+		relays:   NewRelayService(),
+		events:   events,
+		done:     make(chan struct{}),
+	}
+	s.guards = newGuardService(s, cfg.Guards)
+
+	if err := s.relays.UpdateRelays(cfg.Relays); err != nil {
+		s.relays.Close()
+		s.guards.Close()
+		s.events.Close()
+		return nil, err
 	}
 
-	// 初始化主机信息和配置映射
-	for _, host := range cfg.Hosts {
+	// 初始化主机信息和配置映射，并启动后台状态检测
+	if err := s.UpdateHosts(cfg.Hosts); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// UpdateConfig 应用重新加载的配置，更新主机后替换当前配置
+func (s *PCService) UpdateConfig(cfg *config.Config) error {
+	if err := s.UpdateHosts(cfg.Hosts); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.cfg = cfg
+	s.mu.Unlock()
+	return nil
+}
+
+// UpdateHosts 按新的主机配置增加、更新或删除主机，保留已有主机的检测状态和唤醒记录
+// 任一主机配置错误时不做任何修改
+func (s *PCService) UpdateHosts(hosts []config.PCHostConfig) error {
+	probers := make(map[string]probe.Prober, len(hosts))
+	for _, host := range hosts {
+		prober, err := probe.New(host)
+		if err != nil {
+			return fmt.Errorf("主机 %s 在线检测配置错误: %v", host.Name, err)
+		}
+		probers[host.Name] = prober
+	}
+	wakers := make(map[string]*wake.Chain, len(hosts))
+	for _, host := range hosts {
+		waker, err := s.newWaker(host)
+		if err != nil {
+			return fmt.Errorf("主机 %s 唤醒配置错误: %v", host.Name, err)
+		}
+		wakers[host.Name] = waker
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for name, m := range s.monitors {
+		if _, exists := probers[name]; !exists {
+			log.Printf("删除主机: %s", name)
+			close(m.stop)
+			delete(s.hosts, name)
+			delete(s.cfgHosts, name)
+			delete(s.probers, name)
+			delete(s.wakers, name)
+			delete(s.monitors, name)
+		}
+	}
+
+	for _, host := range hosts {
+		old, exists := s.cfgHosts[host.Name]
+		if exists && reflect.DeepEqual(old, host) {
+			continue
+		}
+
+		// 替换主机信息而不修改原对象，进行中的连接继续使用旧的信息
 		s.hosts[host.Name] = &model.PCHostInfo{
 			Name:        host.Name,
 			IP:          host.IP,
 			MAC:         host.MAC,
 			MonitorPort: host.MonitorPort,
+			Relay:       host.Relay,
+			Sleep:       host.Guard != nil,
 		}
 		s.cfgHosts[host.Name] = host
+		s.probers[host.Name] = probers[host.Name]
+		s.wakers[host.Name] = wakers[host.Name]
+		log.Printf("主机 %s 在线检测: %s, 唤醒方式: %s", host.Name, probers[host.Name], wakers[host.Name])
+
+		if exists {
+			log.Printf("更新主机配置: %s", host.Name)
+			s.monitors[host.Name].recheck()
+			continue
+		}
+		m := newHostMonitor()
+		s.monitors[host.Name] = m
+		go s.runMonitor(host.Name, m)
 	}
+	return nil
+}
+
+// host 获取主机信息
+func (s *PCService) host(hostName string) (*model.PCHostInfo, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	host, exists := s.hosts[hostName]
+	return host, exists
+}
+
+// hostConfig 获取主机配置
+func (s *PCService) hostConfig(hostName string) (config.PCHostConfig, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	cfgHost, exists := s.cfgHosts[hostName]
+	return cfgHost, exists
+}
+
+// monitor 获取主机的后台检测状态
+func (s *PCService) monitor(hostName string) (*hostMonitor, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	m, exists := s.monitors[hostName]
+	return m, exists
+}
+
+// Events 返回实时事件总线
+func (s *PCService) Events() *EventBus {
+	return s.events
+}
 
-	return s
+// Relays 返回唤醒包中继服务
+func (s *PCService) Relays() *RelayService {
+	return s.relays
+}
+
+// Guards 返回 guard 注册和心跳服务
+func (s *PCService) Guards() *GuardService {
+	return s.guards
+}
+
+// Close 停止后台状态检测、中继检查和 guard 心跳检查
+func (s *PCService) Close() {
+	close(s.done)
+	s.relays.Close()
+	s.guards.Close()
+	s.events.Close()
 }
 
 func (s *PCService) GetHosts() []*model.PCHostInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
 	hosts := make([]*model.PCHostInfo, 0, len(s.hosts))
 	for _, host := range s.hosts {
 		hosts = append(hosts, host)
@@ -50,103 +216,241 @@ func (s *PCService) GetHosts() []*model.PCHostInfo {
 	return hosts
 }
 
-func (s *PCService) GetHostStatus(hostName string, keepAwake bool) (*model.PCHostStatus, error) {
-	host, exists := s.hosts[hostName]
-	if !exists {
+func (s *PCService) GetHostStatus(hostName string) (*model.PCHostStatus, error) {
+	host, exists := s.host(hostName)
+	m, ok := s.monitor(hostName)
+	if !exists || !ok {
 		return nil, fmt.Errorf("host not found: %s", hostName)
 	}
 
-	// 检查主机在线状态
-	isOnline := checkHostOnline(host.IP, host.MonitorPort)
+	// 返回后台检测缓存的状态，首次检测尚未完成时立即检测一次
+	if state, _, _ := m.snapshot(); state == HostStateUnknown {
+		s.probe(context.Background(), host)
+	}
+	state, since, lastCheck := m.snapshot()
 
 	status := &model.PCHostStatus{
 		Name:       hostName,
-		IsOnline:   isOnline,
-		KeepAwake:  keepAwake,
-		LastUpdate: time.Now().Format(time.RFC3339),
+		IsOnline:   state == HostStateOnline,
+		State:      state,
+		StateSince: since.Format(time.RFC3339),
+	}
+	if !lastCheck.IsZero() {
+		status.LastUpdate = lastCheck.Format(time.RFC3339)
 	}
 
 	// 获取最后唤醒时间
 	if lastWake, ok := s.wol.Load(hostName); ok {
 		status.LastWakeTime = lastWake.(time.Time).Format(time.RFC3339)
+		status.LastWakeBy = s.lastWakeActor(hostName)
 	}
+	status.Guard = s.guards.ForHost(hostName)
 
-	// 处理唤醒逻辑
-	if keepAwake {
-		// 获取唤醒间隔时间
-		wakeInterval := 120 // 默认120秒
-		if cfgHost, exists := s.cfgHosts[hostName]; exists && cfgHost.WakeInterval > 0 {
-			wakeInterval = cfgHost.WakeInterval
-		}
+	return status, nil
+}
 
-		if lastWake, ok := s.wol.Load(hostName); ok {
-			// 如果有上次唤醒记录，检查是否需要再次唤醒
-			if time.Since(lastWake.(time.Time)) > time.Duration(wakeInterval)*time.Second {
-				// 超过唤醒间隔时间，发送唤醒包
-				go s.sendWakePacket(host)
-			}
-		} else {
-			// 第一次唤醒请求，直接发送唤醒包
-			go s.sendWakePacket(host)
-		}
+// Wake 向主机发送一次唤醒包，actor 为发起唤醒的调用者，ctx 取消时停止尝试后续的唤醒方式
+func (s *PCService) Wake(ctx context.Context, hostName string, actor string) error {
+	host, exists := s.host(hostName)
+	if !exists {
+		return fmt.Errorf("host not found: %s", hostName)
 	}
 
-	// 存储状态
-	s.status.Store(hostName, status)
+	log.Printf("唤醒主机: %s, 操作者: %s", hostName, actor)
+	return s.sendWakePacket(ctx, host, actor, false)
+}
 
-	return status, nil
+// keepAwake 保持唤醒时重发唤醒包，总是使用第一种唤醒方式，不切换到后备方式
+func (s *PCService) keepAwake(hostName string, actor string) error {
+	host, exists := s.host(hostName)
+	if !exists {
+		return fmt.Errorf("host not found: %s", hostName)
+	}
+	return s.sendWakePacket(context.Background(), host, actor, true)
 }
 
-func (s *PCService) sendWakePacket(host *model.PCHostInfo) {
-	log.Printf("发送唤醒包到 %s (MAC: %s)", host.Name, host.MAC)
+// Sleep 请求主机上的 guard 睡眠、关机或空闲时睡眠，actor 为发起者
+func (s *PCService) Sleep(ctx context.Context, hostName, action, actor string) (*guard.SleepResponse, error) {
+	cfgHost, exists := s.hostConfig(hostName)
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrHostNotFound, hostName)
+	}
+	if cfgHost.Guard == nil {
+		return nil, ErrNoGuard
+	}
 
-	mp, err := wol.New(host.MAC)
+	client, err := guard.NewClient(cfgHost.Guard.URL, cfgHost.Guard.Secret)
 	if err != nil {
-		log.Printf("创建唤醒包失败: %v", err)
-		return
+		return nil, err
 	}
-
-	bcastAddr := "255.255.255.255:9"
-	udpAddr, err := net.ResolveUDPAddr("udp", bcastAddr)
+	resp, err := client.Sleep(ctx, guard.SleepRequest{Action: action, RequestedBy: actor})
 	if err != nil {
-		log.Printf("解析广播地址失败: %v", err)
-		return
+		log.Printf("请求主机睡眠失败: %s, 操作: %s, %v", hostName, action, err)
+		return nil, fmt.Errorf("guard 请求失败: %v", err)
 	}
 
-	conn, err := net.DialUDP("udp", nil, udpAddr)
-	if err != nil {
-		log.Printf("创建UDP连接失败: %v", err)
-		return
+	log.Printf("主机 %s 已接受睡眠请求: 操作=%s, 操作者=%s", hostName, action, actor)
+	s.events.Publish(EventSleepSent, hostName, map[string]string{"actor": actor, "action": action})
+	return resp, nil
+}
+
+// WaitOnline 等待主机上线，直到检测成功、超时或 ctx 被取消
+func (s *PCService) WaitOnline(ctx context.Context, hostName string, timeout time.Duration) (bool, error) {
+	host, exists := s.host(hostName)
+	if !exists {
+		return false, fmt.Errorf("host not found: %s", hostName)
 	}
-	defer conn.Close()
 
-	bs, err := mp.Marshal()
-	if err != nil {
-		log.Printf("序列化唤醒包失败: %v", err)
-		return
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return s.waitOnline(ctx, host), nil
+}
+
+func (s *PCService) waitOnline(ctx context.Context, host *model.PCHostInfo) bool {
+	for {
+		if s.probe(ctx, host) {
+			return true
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(100 * time.Millisecond):
+		}
 	}
+}
 
-	n, err := conn.Write(bs)
-	if err != nil {
-		log.Printf("发送唤醒包失败: %v", err)
-		return
+// wakeInterval 获取主机保持唤醒时重发唤醒包的间隔
+func (s *PCService) wakeInterval(hostName string) time.Duration {
+	wakeInterval := 120 // 默认120秒
+	if cfgHost, exists := s.hostConfig(hostName); exists && cfgHost.WakeInterval > 0 {
+		wakeInterval = cfgHost.WakeInterval
 	}
-	if n != 102 {
-		log.Printf("发送的数据长度不正确: %d (应为102字节)", n)
-		return
+	return time.Duration(wakeInterval) * time.Second
+}
+
+// keptAwakeByGuard 主机上的 guard 是否已接受 bridge 报告的使用中状态，此时由 guard 保持唤醒
+// 重发的唤醒包会让 guard 进入临时唤醒，推迟会话结束后的睡眠
+func (s *PCService) keptAwakeByGuard(hostName string) bool {
+	keeps, ok := s.guardKeepsAwake.Load(hostName)
+	return ok && keeps.(bool)
+}
+
+// ForceSleep 记录主机被强制睡眠，之后转发通道的活动会话不再重发唤醒包，否则主机睡眠后会被立即唤醒
+// 再次主动唤醒（唤醒接口、新的转发连接或代理请求）或主机重新上线后恢复
+func (s *PCService) ForceSleep(hostName string) {
+	s.forcedSleep.Store(hostName, true)
+}
+
+// sleepForced 判断主机是否在强制睡眠后还没有被主动唤醒
+func (s *PCService) sleepForced(hostName string) bool {
+	_, ok := s.forcedSleep.Load(hostName)
+	return ok
+}
+
+// lastWakeTime 获取主机最后一次发送唤醒包的时间
+func (s *PCService) lastWakeTime(hostName string) (time.Time, bool) {
+	if lastWake, ok := s.wol.Load(hostName); ok {
+		return lastWake.(time.Time), true
 	}
+	return time.Time{}, false
+}
+
+// lastWakeActor 获取主机最后一次唤醒的发起者
+func (s *PCService) lastWakeActor(hostName string) string {
+	if actor, ok := s.wakeBy.Load(hostName); ok {
+		return actor.(string)
+	}
+	return ""
+}
 
+// sendWakePacket 按主机的唤醒方式唤醒主机，actor 为发起唤醒的调用者，如 user:admin、keep-awake:<租约持有者>、forward:<通道>
+// keep 为 true 时用于保持唤醒的重发，只使用第一种唤醒方式
+func (s *PCService) sendWakePacket(ctx context.Context, host *model.PCHostInfo, actor string, keep bool) error {
+	log.Printf("开始唤醒 %s (MAC: %s)", host.Name, host.MAC)
+
+	method, err := s.writeWakePacket(ctx, host, keep)
+	if err != nil {
+		metrics.WakePacketErrors.WithLabelValues(host.Name).Inc()
+		return err
+	}
+	metrics.WakePackets.WithLabelValues(host.Name).Inc()
+
+	if !keep {
+		s.forcedSleep.Delete(host.Name)
+	}
 	// 记录唤醒时间
 	s.wol.Store(host.Name, time.Now())
-	log.Printf("唤醒包发送成功 -> %s", host.Name)
+	s.wakeBy.Store(host.Name, actor)
+	s.events.Publish(EventWakeSent, host.Name, map[string]string{"actor": actor, "method": method})
+	s.markWaking(host.Name, actor)
+	log.Printf("唤醒请求发送成功 -> %s (%s)", host.Name, method)
+	return nil
 }
 
-func checkHostOnline(ip string, port int) bool {
-	address := fmt.Sprintf("%s:%d", ip, port)
-	conn, err := net.DialTimeout("tcp", address, 5*time.Second)
+// writeWakePacket 依次尝试主机的唤醒方式，返回成功的方式
+// 只有主机离线时才从上次的下一种方式开始，主机在线或保持唤醒的重发从第一种开始且不切换
+func (s *PCService) writeWakePacket(ctx context.Context, host *model.PCHostInfo, keep bool) (string, error) {
+	s.mu.RLock()
+	waker, ok := s.wakers[host.Name]
+	s.mu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("host not found: %s", host.Name)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, maxWakeDuration)
+	defer cancel()
+	var used wake.Waker
+	var err error
+	if keep || s.hostState(host.Name) == HostStateOnline {
+		used, err = waker.Keep(ctx)
+	} else {
+		used, err = waker.Wake(ctx)
+	}
 	if err != nil {
-		return false
+		log.Printf("唤醒主机失败: %s, %v", host.Name, err)
+		return "", err
+	}
+	return used.String(), nil
+}
+
+// newWaker 创建主机的唤醒方式，wol 方式在配置了中继时由中继在主机所在网段发送唤醒包
+func (s *PCService) newWaker(host config.PCHostConfig) (*wake.Chain, error) {
+	var packet wake.Waker
+	if host.Relay != "" {
+		packet = &relayWaker{relays: s.relays, name: host.Relay, req: relayWakeRequest(host)}
+	} else {
+		p, err := wake.NewMagicPacket(host)
+		if err != nil {
+			return nil, err
+		}
+		packet = p
+	}
+	return wake.New(host, packet)
+}
+
+// resetWaker 主机上线后，下次唤醒从第一种唤醒方式开始
+func (s *PCService) resetWaker(hostName string) {
+	s.mu.RLock()
+	waker, ok := s.wakers[hostName]
+	s.mu.RUnlock()
+	if ok {
+		waker.Reset()
+	}
+}
+
+// relayWakeRequest 按主机的 wol 配置生成中继唤醒请求
+func relayWakeRequest(host config.PCHostConfig) guard.WakeRequest {
+	req := guard.WakeRequest{MAC: host.MAC}
+	if host.WOL != nil {
+		req.Address = host.WOL.Address
+		req.Port = host.WOL.Port
+		req.Count = host.WOL.Count
+		req.IntervalMs = host.WOL.IntervalMs
+		// 配置已通过校验，密码格式正确
+		if password, _ := host.WOL.SecureOn(); len(password) > 0 {
+			req.Password = hex.EncodeToString(password)
+		}
 	}
-	defer conn.Close()
-	return true
+	return req
 }
@@ -0,0 +1,437 @@
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"greenwake-bridge/internal/config"
+	"greenwake-bridge/internal/model"
+)
+
+const (
+	wakingPageRefresh    = 2                // 唤醒页面自动刷新间隔（秒）
+	proxyShutdownTimeout = 10 * time.Second // 停止监听时等待进行中请求的最长时间
+)
+
+// proxyRoute 反向代理路由及其目标
+type proxyRoute struct {
+	cfg   config.ProxyRoute
+	host  *model.PCHostInfo
+	actor string // 唤醒操作者，如 proxy:8080
+	proxy *httputil.ReverseProxy
+}
+
+// matches 判断请求是否匹配路由，路径前缀按路径段匹配，/app 不匹配 /application
+func (r *proxyRoute) matches(host, path string) bool {
+	if r.cfg.Host != "" && !strings.EqualFold(r.cfg.Host, host) {
+		return false
+	}
+	prefix := r.cfg.PathPrefix
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+	return len(path) == len(prefix) || strings.HasSuffix(prefix, "/") || path[len(prefix)] == '/'
+}
+
+// proxyWake 正在进行的唤醒，同一主机的请求共享
+type proxyWake struct {
+	start    time.Time
+	expected time.Duration // 全部重试用完的预计最长时间
+	attempts int
+	attempt  atomic.Int32
+	online   bool // done 关闭后有效
+	done     chan struct{}
+}
+
+// ProxyService HTTP反向代理，目标主机睡眠时唤醒并展示等待页面
+type ProxyService struct {
+	pcService *PCService
+	serversMu sync.Mutex
+	servers   map[int]*http.Server  // key: 监听端口
+	draining  map[int]chan struct{} // key: 监听端口，正在停止的监听，停止后关闭
+	mu        sync.Mutex
+	wakes     map[string]*proxyWake // key: hostName
+}
+
+func NewProxyService(cfg *config.Config, pcService *PCService) (*ProxyService, error) {
+	s := &ProxyService{
+		pcService: pcService,
+		servers:   make(map[int]*http.Server),
+		draining:  make(map[int]chan struct{}),
+		wakes:     make(map[string]*proxyWake),
+	}
+
+	if err := s.UpdateProxies(cfg.Proxies); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// UpdateProxies 按新的配置替换各监听端口的路由，启动新增的监听，停止已删除的监听
+// 路由总是重新生成，以使用更新后的主机地址；任一路由配置错误时不做任何修改
+func (s *ProxyService) UpdateProxies(proxies []config.ProxyConfig) error {
+	routesByPort := make(map[int][]*proxyRoute, len(proxies))
+	for _, pc := range proxies {
+		routes := make([]*proxyRoute, 0, len(pc.Routes))
+		for _, rc := range pc.Routes {
+			route, err := s.newRoute(pc.ServicePort, rc)
+			if err != nil {
+				return fmt.Errorf("反向代理配置错误 [%d]: %v", pc.ServicePort, err)
+			}
+			routes = append(routes, route)
+		}
+
+		// 优先匹配更长的路径前缀，其次匹配指定了域名的路由
+		sort.SliceStable(routes, func(i, j int) bool {
+			if len(routes[i].cfg.PathPrefix) != len(routes[j].cfg.PathPrefix) {
+				return len(routes[i].cfg.PathPrefix) > len(routes[j].cfg.PathPrefix)
+			}
+			return routes[i].cfg.Host != "" && routes[j].cfg.Host == ""
+		})
+		routesByPort[pc.ServicePort] = routes
+	}
+
+	s.serversMu.Lock()
+	defer s.serversMu.Unlock()
+
+	for port, server := range s.servers {
+		if _, exists := routesByPort[port]; !exists {
+			// 停止接受新请求，进行中的请求处理完后关闭
+			log.Printf("停止HTTP反向代理监听 [%d]", port)
+			delete(s.servers, port)
+			done := make(chan struct{})
+			s.draining[port] = done
+			go s.shutdown(port, server, done)
+		}
+	}
+
+	for port, routes := range routesByPort {
+		routes := routes
+		if server, exists := s.servers[port]; exists {
+			server.Handler.(*proxyHandler).routes.Store(&routes)
+			continue
+		}
+
+		handler := &proxyHandler{service: s}
+		handler.routes.Store(&routes)
+		server := &http.Server{
+			Addr:    fmt.Sprintf(":%d", port),
+			Handler: handler,
+		}
+		s.servers[port] = server
+		drained := s.draining[port]
+
+		go func(port int, count int) {
+			// 同一端口的旧监听仍在停止时，等它停止后再监听，避免端口被占用
+			if drained != nil {
+				log.Printf("等待旧的HTTP反向代理监听停止 [%d]", port)
+				<-drained
+			}
+			log.Printf("启动HTTP反向代理监听 [%d]，路由 %d 条", port, count)
+			if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Printf("启动HTTP反向代理监听失败 [%d]: %v", port, err)
+			}
+		}(port, len(routes))
+	}
+	return nil
+}
+
+// shutdown 停止接受新请求，等待进行中的请求处理完，超时后强制关闭
+func (s *ProxyService) shutdown(port int, server *http.Server, done chan struct{}) {
+	ctx, cancel := context.WithTimeout(context.Background(), proxyShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("HTTP反向代理监听未能按时停止 [%d]，强制关闭: %v", port, err)
+		server.Close()
+	}
+
+	s.serversMu.Lock()
+	if s.draining[port] == done {
+		delete(s.draining, port)
+	}
+	s.serversMu.Unlock()
+	close(done)
+}
+
+func (s *ProxyService) newRoute(port int, rc config.ProxyRoute) (*proxyRoute, error) {
+	host, exists := s.pcService.host(rc.TargetHost)
+	if !exists {
+		return nil, fmt.Errorf("目标主机不存在: %s", rc.TargetHost)
+	}
+	if rc.TargetPort <= 0 {
+		return nil, fmt.Errorf("路由 %s%s 缺少 target_port", rc.Host, rc.PathPrefix)
+	}
+	if rc.Scheme != "http" && rc.Scheme != "https" {
+		return nil, fmt.Errorf("未知的 scheme: %s", rc.Scheme)
+	}
+
+	target := &url.URL{
+		Scheme: rc.Scheme,
+		Host:   net.JoinHostPort(host.IP, strconv.Itoa(rc.TargetPort)),
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if rc.Insecure {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+
+	route := &proxyRoute{
+		cfg:   rc,
+		host:  host,
+		actor: fmt.Sprintf("proxy:%d", port),
+	}
+	route.proxy = &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			if rc.StripPrefix && rc.PathPrefix != "" {
+				pr.Out.URL.Path = "/" + strings.TrimPrefix(strings.TrimPrefix(pr.In.URL.Path, rc.PathPrefix), "/")
+				pr.Out.URL.RawPath = ""
+			}
+			pr.SetURL(target)
+			pr.SetXForwarded()
+			if rc.PreserveHost {
+				pr.Out.Host = pr.In.Host
+			}
+		},
+		Transport: transport,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			if errors.Is(err, context.Canceled) {
+				// 客户端已断开
+				return
+			}
+			log.Printf("反向代理请求失败 [%s -> %s]: %v", r.Host+r.URL.Path, target.Host, err)
+			if !isDialError(err) {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			// 缓存的状态可能已过期，连接失败时按主机睡眠处理
+			wake := s.startWake(route)
+			if isBrowserRequest(r) {
+				s.renderWaking(w, route, wake)
+				return
+			}
+			writeRetryAfter(w, wake)
+		},
+	}
+	return route, nil
+}
+
+// proxyHandler 单个监听端口的路由
+type proxyHandler struct {
+	service *ProxyService
+	routes  atomic.Pointer[[]*proxyRoute] // 配置重新加载时整体替换
+}
+
+func (h *proxyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	host := r.Host
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+
+	for _, route := range *h.routes.Load() {
+		if route.matches(host, r.URL.Path) {
+			h.service.serve(w, r, route)
+			return
+		}
+	}
+	http.NotFound(w, r)
+}
+
+func (s *ProxyService) serve(w http.ResponseWriter, r *http.Request, route *proxyRoute) {
+	hostName := route.host.Name
+
+	wake := s.runningWake(hostName)
+	if wake == nil {
+		// 优先使用后台检测的缓存状态，离线时再实际检测一次
+		if s.pcService.hostState(hostName) == HostStateOnline || s.pcService.probe(r.Context(), route.host) {
+			route.proxy.ServeHTTP(w, r)
+			return
+		}
+		log.Printf("反向代理目标主机离线，尝试唤醒: %s [%s%s]", hostName, r.Host, r.URL.Path)
+		wake = s.startWake(route)
+	}
+
+	// 浏览器展示自动刷新的等待页面
+	if isBrowserRequest(r) {
+		s.renderWaking(w, route, wake)
+		return
+	}
+
+	// 其他客户端等待主机上线后再转发
+	hold := wake.expected - time.Since(wake.start)
+	if route.cfg.HoldTimeout > 0 {
+		hold = time.Duration(route.cfg.HoldTimeout) * time.Second
+	}
+	timer := time.NewTimer(hold)
+	defer timer.Stop()
+
+	select {
+	case <-wake.done:
+		if wake.online {
+			route.proxy.ServeHTTP(w, r)
+			return
+		}
+	case <-timer.C:
+	case <-r.Context().Done():
+		return
+	}
+	writeRetryAfter(w, wake)
+}
+
+// runningWake 获取主机正在进行的唤醒
+func (s *ProxyService) runningWake(hostName string) *proxyWake {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.wakes[hostName]
+}
+
+// startWake 唤醒主机并按配置重试，同一主机同时只进行一次唤醒
+func (s *ProxyService) startWake(route *proxyRoute) *proxyWake {
+	hostName := route.host.Name
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if wake, exists := s.wakes[hostName]; exists {
+		return wake
+	}
+
+	wakeTimeout := time.Duration(config.DefaultWakeTimeout) * time.Second
+	retryCount := config.DefaultRetryCount
+	if cfgHost, exists := s.pcService.hostConfig(hostName); exists {
+		if cfgHost.WakeTimeout > 0 {
+			wakeTimeout = time.Duration(cfgHost.WakeTimeout) * time.Second
+		}
+		retryCount = cfgHost.RetryCount
+	}
+
+	wake := &proxyWake{
+		start:    time.Now(),
+		expected: wakeTimeout * time.Duration(retryCount+1),
+		attempts: retryCount + 1,
+		done:     make(chan struct{}),
+	}
+	wake.attempt.Store(1)
+	s.wakes[hostName] = wake
+
+	go func() {
+		for attempt := 1; attempt <= wake.attempts; attempt++ {
+			wake.attempt.Store(int32(attempt))
+			if err := s.pcService.Wake(context.Background(), hostName, route.actor); err != nil {
+				log.Printf("反向代理唤醒主机失败: %s, %v", hostName, err)
+			}
+			if online, _ := s.pcService.WaitOnline(context.Background(), hostName, wakeTimeout); online {
+				wake.online = true
+				log.Printf("反向代理目标主机已上线: %s, 耗时 %v", hostName, time.Since(wake.start).Round(time.Millisecond))
+				break
+			}
+			log.Printf("等待主机上线超时（%v）: %s，第%d/%d次", wakeTimeout, hostName, attempt, wake.attempts)
+		}
+
+		s.mu.Lock()
+		delete(s.wakes, hostName)
+		s.mu.Unlock()
+		close(wake.done)
+	}()
+
+	return wake
+}
+
+// isDialError 判断是否为连接目标失败（连接被拒绝、超时或无路由），只有这类错误才可能是主机睡眠
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// isBrowserRequest 浏览器页面请求返回等待页面，其他请求按API客户端处理
+func isBrowserRequest(r *http.Request) bool {
+	return r.Method == http.MethodGet && strings.Contains(r.Header.Get("Accept"), "text/html")
+}
+
+// writeRetryAfter 返回503，提示客户端稍后重试
+func writeRetryAfter(w http.ResponseWriter, wake *proxyWake) {
+	retryAfter := int((wake.expected - time.Since(wake.start)).Seconds())
+	if retryAfter < 1 {
+		retryAfter = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	http.Error(w, "target host is waking up", http.StatusServiceUnavailable)
+}
+
+var wakingPage = template.Must(template.New("waking").Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta http-equiv="refresh" content="{{.Refresh}}">
+<title>正在唤醒 {{.Host}}…</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", "PingFang SC", "Microsoft YaHei", sans-serif; background: #f5f5f5; color: #333; display: flex; align-items: center; justify-content: center; height: 100vh; margin: 0; }
+.box { background: #fff; border-radius: 8px; box-shadow: 0 2px 8px rgba(0,0,0,.1); padding: 32px 40px; min-width: 320px; }
+h1 { font-size: 20px; margin: 0 0 20px; }
+.bar { background: #eee; border-radius: 4px; height: 8px; overflow: hidden; }
+.bar div { background: #52c41a; height: 100%; transition: width 1s; }
+p { color: #888; font-size: 14px; }
+</style>
+</head>
+<body>
+<div class="box">
+<h1>正在唤醒 {{.Host}}…</h1>
+<div class="bar"><div style="width: {{.Percent}}%"></div></div>
+<p>第 {{.Attempt}}/{{.Attempts}} 次尝试，已等待 {{.Elapsed}} 秒，预计最多 {{.Expected}} 秒</p>
+<p>主机上线后页面会自动打开</p>
+</div>
+</body>
+</html>
+`))
+
+// renderWaking 返回自动刷新的唤醒等待页面
+func (s *ProxyService) renderWaking(w http.ResponseWriter, route *proxyRoute, wake *proxyWake) {
+	elapsed := time.Since(wake.start)
+	percent := 99
+	if wake.expected > 0 {
+		percent = int(elapsed * 100 / wake.expected)
+	}
+	if percent > 99 {
+		percent = 99
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Retry-After", strconv.Itoa(wakingPageRefresh))
+	w.WriteHeader(http.StatusServiceUnavailable)
+	wakingPage.Execute(w, map[string]interface{}{
+		"Host":     route.host.Name,
+		"Refresh":  wakingPageRefresh,
+		"Percent":  percent,
+		"Attempt":  wake.attempt.Load(),
+		"Attempts": wake.attempts,
+		"Elapsed":  int(elapsed.Seconds()),
+		"Expected": int(wake.expected.Seconds()),
+	})
+}
+
+// Close 停止所有反向代理监听
+func (s *ProxyService) Close() {
+	s.serversMu.Lock()
+	defer s.serversMu.Unlock()
+	for _, server := range s.servers {
+		server.Close()
+	}
+}
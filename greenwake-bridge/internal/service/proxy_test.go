@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	"greenwake-bridge/internal/config"
+)
+
+func TestProxyRouteMatches(t *testing.T) {
+	tests := []struct {
+		host, prefix string
+		reqHost      string
+		path         string
+		want         bool
+	}{
+		{"", "", "a.local", "/", true},
+		{"", "/", "a.local", "/app", true},
+		{"", "/app", "a.local", "/app", true},
+		{"", "/app", "a.local", "/app/", true},
+		{"", "/app", "a.local", "/app/x", true},
+		{"", "/app", "a.local", "/application", false},
+		{"", "/app/", "a.local", "/app/x", true},
+		{"", "/app/", "a.local", "/app", false},
+		{"code.local", "/", "CODE.local", "/x", true},
+		{"code.local", "/", "a.local", "/x", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.reqHost+tt.path, func(t *testing.T) {
+			r := &proxyRoute{cfg: config.ProxyRoute{Host: tt.host, PathPrefix: tt.prefix}}
+			if got := r.matches(tt.reqHost, tt.path); got != tt.want {
+				t.Errorf("路由 %s%s 匹配 %s%s 为 %v，期望 %v", tt.host, tt.prefix, tt.reqHost, tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsDialError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"连接被拒绝", &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}, true},
+		{"连接超时", fmt.Errorf("proxy: %w", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("i/o timeout")}), true},
+		{"读取时连接被拒绝", &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNREFUSED}, true},
+		{"客户端取消", context.Canceled, false},
+		{"读取响应失败", &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isDialError(tt.err); got != tt.want {
+				t.Errorf("isDialError(%v) = %v，期望 %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+// freePort 返回一个当前空闲的本地端口
+func freePort(t *testing.T) int {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("获取空闲端口失败: %v", err)
+	}
+	defer ln.Close()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+func TestUpdateProxiesRebindsDrainingPort(t *testing.T) {
+	// 目标服务在收到 /slow 请求后阻塞，直到 release 关闭
+	release := make(chan struct{})
+	started := make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			close(started)
+			<-release
+		}
+		io.WriteString(w, r.URL.Path)
+	}))
+	defer backend.Close()
+	u, _ := url.Parse(backend.URL)
+	backendPort, _ := strconv.Atoi(u.Port())
+
+	pcService, err := NewPCService(&config.Config{
+		DataDir: t.TempDir(),
+		Hosts:   []config.PCHostConfig{{Name: "desktop", IP: "127.0.0.1", MAC: "00:11:22:33:44:55", MonitorPort: backendPort}},
+	})
+	if err != nil {
+		t.Fatalf("创建主机服务失败: %v", err)
+	}
+	defer pcService.Close()
+
+	port := freePort(t)
+	proxies := []config.ProxyConfig{{ServicePort: port, Routes: []config.ProxyRoute{
+		{TargetHost: "desktop", TargetPort: backendPort, Scheme: "http"},
+	}}}
+	s, err := NewProxyService(&config.Config{Proxies: proxies}, pcService)
+	if err != nil {
+		t.Fatalf("创建反向代理失败: %v", err)
+	}
+	defer s.Close()
+
+	proxyURL := fmt.Sprintf("http://127.0.0.1:%d", port)
+	get := func(path string) (string, error) {
+		resp, err := http.Get(proxyURL + path)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		return string(body), err
+	}
+
+	// 等待监听启动后发起一个进行中的请求
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := get("/ready"); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("反向代理监听未启动")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	slow := make(chan string, 1)
+	go func() {
+		body, err := get("/slow")
+		if err != nil {
+			body = err.Error()
+		}
+		slow <- body
+	}()
+	<-started
+
+	// 删除后立即重新添加同一端口，新的监听等待旧监听处理完进行中的请求
+	if err := s.UpdateProxies(nil); err != nil {
+		t.Fatalf("删除监听失败: %v", err)
+	}
+	if err := s.UpdateProxies(proxies); err != nil {
+		t.Fatalf("重新添加监听失败: %v", err)
+	}
+	close(release)
+
+	select {
+	case body := <-slow:
+		if body != "/slow" {
+			t.Errorf("进行中的请求响应为 %q，期望 /slow", body)
+		}
+	case <-time.After(proxyShutdownTimeout):
+		t.Fatal("进行中的请求未完成")
+	}
+
+	deadline = time.Now().Add(5 * time.Second)
+	for {
+		body, err := get("/again")
+		if err == nil && body == "/again" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("重新添加的监听未启动: %v", err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"greenwake-bridge/internal/config"
+	"greenwake-bridge/internal/guard"
+	"greenwake-bridge/internal/model"
+)
+
+const relayCheckInterval = 30 * time.Second // 检查中继可达性的间隔
+
+// relay 一个唤醒包中继及其最近一次检查结果
+type relay struct {
+	cfg    config.RelayConfig
+	client *guard.Client
+	mu     sync.Mutex
+	status model.RelayStatus
+}
+
+// RelayService 管理作为唤醒包中继的 greenwake-guard，定期检查是否可达
+type RelayService struct {
+	mu     sync.RWMutex
+	relays map[string]*relay
+	kick   chan struct{}
+	done   chan struct{}
+}
+
+func NewRelayService() *RelayService {
+	s := &RelayService{
+		relays: make(map[string]*relay),
+		kick:   make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+// UpdateRelays 按新的配置替换中继，配置未变化的中继保留检查结果
+func (s *RelayService) UpdateRelays(relays []config.RelayConfig) error {
+	clients := make(map[string]*guard.Client, len(relays))
+	for _, rc := range relays {
+		client, err := guard.NewClient(rc.URL, rc.Secret)
+		if err != nil {
+			return fmt.Errorf("中继 %s 配置错误: %v", rc.Name, err)
+		}
+		clients[rc.Name] = client
+	}
+
+	s.mu.Lock()
+	updated := make(map[string]*relay, len(relays))
+	for _, rc := range relays {
+		if old, ok := s.relays[rc.Name]; ok && reflect.DeepEqual(old.cfg, rc) {
+			updated[rc.Name] = old
+			continue
+		}
+		updated[rc.Name] = &relay{
+			cfg:    rc,
+			client: clients[rc.Name],
+			status: model.RelayStatus{Name: rc.Name, URL: rc.URL},
+		}
+	}
+	s.relays = updated
+	s.mu.Unlock()
+
+	// 立即检查新增或修改的中继
+	select {
+	case s.kick <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (s *RelayService) relay(name string) (*relay, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	r, ok := s.relays[name]
+	return r, ok
+}
+
+// run 定期检查全部中继，直到服务关闭
+func (s *RelayService) run() {
+	ticker := time.NewTicker(relayCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+		case <-s.kick:
+		}
+
+		s.mu.RLock()
+		relays := make([]*relay, 0, len(s.relays))
+		for _, r := range s.relays {
+			relays = append(relays, r)
+		}
+		s.mu.RUnlock()
+
+		var wg sync.WaitGroup
+		for _, r := range relays {
+			wg.Add(1)
+			go func(r *relay) {
+				defer wg.Done()
+				r.check()
+			}(r)
+		}
+		wg.Wait()
+	}
+}
+
+// check 检查中继是否可达，可达状态变化时记录日志
+func (r *relay) check() {
+	start := time.Now()
+	ping, err := r.client.Ping(context.Background())
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	firstCheck := r.status.LastCheck == ""
+	wasReachable, wasRelay := r.status.Reachable, r.status.WOLRelay
+	r.status.LastCheck = start.Format(time.RFC3339)
+	if err != nil {
+		r.status.Reachable = false
+		r.status.Error = err.Error()
+		r.status.LatencyMs = 0
+		if firstCheck || wasReachable {
+			log.Printf("唤醒包中继不可达: %s (%s), %v", r.cfg.Name, r.cfg.URL, err)
+		}
+		return
+	}
+
+	r.status.Reachable = true
+	r.status.Error = ""
+	r.status.LatencyMs = time.Since(start).Milliseconds()
+	r.status.Version = ping.Version
+	r.status.Hostname = ping.Hostname
+	r.status.WOLRelay = ping.WOLRelay
+	if !wasReachable {
+		log.Printf("唤醒包中继可达: %s (%s), 主机: %s, 协议版本: %d", r.cfg.Name, r.cfg.URL, ping.Hostname, ping.Version)
+	}
+	if !ping.WOLRelay && (!wasReachable || wasRelay) {
+		log.Printf("中继 %s 未启用唤醒包中继，请在 guard 配置中设置 control.wol_relay: true", r.cfg.Name)
+	}
+}
+
+// Wake 请求中继发送唤醒包，失败时标记中继不可达
+func (s *RelayService) Wake(ctx context.Context, name string, req guard.WakeRequest) error {
+	r, ok := s.relay(name)
+	if !ok {
+		return fmt.Errorf("中继不存在: %s", name)
+	}
+
+	if err := r.client.Wake(ctx, req); err != nil {
+		r.mu.Lock()
+		r.status.Reachable = false
+		r.status.Error = err.Error()
+		r.mu.Unlock()
+		return fmt.Errorf("中继 %s 发送唤醒包失败: %v", name, err)
+	}
+	return nil
+}
+
+// relayWaker 通过中继发送唤醒包，作为配置了 relay 的主机的 wol 唤醒方式
+type relayWaker struct {
+	relays *RelayService
+	name   string
+	req    guard.WakeRequest
+}
+
+func (w *relayWaker) Wake(ctx context.Context) error {
+	return w.relays.Wake(ctx, w.name, w.req)
+}
+
+func (w *relayWaker) String() string {
+	return "relay:" + w.name
+}
+
+// GetRelays 获取全部中继的可达状态
+func (s *RelayService) GetRelays() []*model.RelayStatus {
+	s.mu.RLock()
+	result := make([]*model.RelayStatus, 0, len(s.relays))
+	for _, r := range s.relays {
+		r.mu.Lock()
+		status := r.status
+		r.mu.Unlock()
+		result = append(result, &status)
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// Close 停止检查
+func (s *RelayService) Close() {
+	close(s.done)
+}
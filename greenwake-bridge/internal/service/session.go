@@ -0,0 +1,147 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"greenwake-bridge/internal/config"
+	"greenwake-bridge/internal/model"
+	"greenwake-bridge/internal/store"
+)
+
+// 转发连接的关闭原因
+const (
+	CloseClientClosed = "client_closed"  // 客户端关闭连接
+	CloseTargetClosed = "target_closed"  // 目标主机关闭连接
+	CloseWakeTimeout  = "wake_timeout"   // 唤醒主机超时
+	CloseDialFailed   = "dial_failed"    // 连接目标端口失败
+	CloseHostNotFound = "host_not_found" // 目标主机未配置
+	CloseIdleTimeout  = "idle_timeout"   // udp: 会话空闲超时
+	CloseShutdown     = "shutdown"       // 转发监听已停止
+)
+
+// sessionRecord 转发会话记录
+type sessionRecord struct {
+	ID            string        `json:"id"`
+	Channel       string        `json:"channel"`
+	Protocol      string        `json:"protocol"`
+	ServicePort   int           `json:"servicePort"`
+	TargetHost    string        `json:"targetHost"`
+	TargetPort    int           `json:"targetPort"`
+	ClientIP      string        `json:"clientIp"`
+	ClientPort    int           `json:"clientPort"`
+	StartTime     time.Time     `json:"startTime"`
+	EndTime       time.Time     `json:"endTime"`
+	WakeNeeded    bool          `json:"wakeNeeded"`
+	WakeDuration  time.Duration `json:"wakeDuration"`
+	BytesToTarget int64         `json:"bytesToTarget"`
+	BytesToClient int64         `json:"bytesToClient"`
+	CloseReason   string        `json:"closeReason"`
+}
+
+func (r sessionRecord) toModel() *model.ForwardSession {
+	session := &model.ForwardSession{
+		ID:             r.ID,
+		Channel:        r.Channel,
+		Protocol:       r.Protocol,
+		ServicePort:    r.ServicePort,
+		TargetHost:     r.TargetHost,
+		TargetPort:     r.TargetPort,
+		ClientIP:       r.ClientIP,
+		ClientPort:     r.ClientPort,
+		StartTime:      r.StartTime.Format(time.RFC3339),
+		WakeNeeded:     r.WakeNeeded,
+		WakeDurationMs: r.WakeDuration.Milliseconds(),
+		BytesToTarget:  r.BytesToTarget,
+		BytesToClient:  r.BytesToClient,
+		CloseReason:    r.CloseReason,
+	}
+	// 会话进行中时没有结束时间
+	if !r.EndTime.IsZero() {
+		session.EndTime = r.EndTime.Format(time.RFC3339)
+	}
+	return session
+}
+
+// SessionFilter 转发会话查询条件，零值表示不限制
+type SessionFilter struct {
+	Host    string
+	Channel string // 通道ID或服务端口
+	From    time.Time
+	To      time.Time
+	Allow   func(host string) bool // 调用者可访问的主机
+	Offset  int
+	Limit   int
+}
+
+func (f *SessionFilter) match(r sessionRecord) bool {
+	if f.Host != "" && r.TargetHost != f.Host {
+		return false
+	}
+	if f.Channel != "" && r.Channel != f.Channel && strconv.Itoa(r.ServicePort) != f.Channel {
+		return false
+	}
+	if !f.From.IsZero() && r.EndTime.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && r.StartTime.After(f.To) {
+		return false
+	}
+	if f.Allow != nil && !f.Allow(r.TargetHost) {
+		return false
+	}
+	return true
+}
+
+// newSessionID 生成会话记录ID
+func newSessionID() string {
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// SessionService 持久化转发会话记录
+type SessionService struct {
+	log *store.Log[sessionRecord]
+}
+
+func NewSessionService(cfg *config.Config) (*SessionService, error) {
+	l, err := store.Open(filepath.Join(cfg.DataDir, "sessions.jsonl"), store.Options{
+		MaxAge:     time.Duration(cfg.SessionLog.RetentionDays) * 24 * time.Hour,
+		MaxRecords: cfg.SessionLog.MaxRecords,
+	}, func(r sessionRecord) time.Time {
+		return r.EndTime
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &SessionService{log: l}, nil
+}
+
+// record 保存一条结束的转发会话
+func (s *SessionService) record(r sessionRecord) {
+	if s == nil {
+		return
+	}
+	if err := s.log.Append(r); err != nil {
+		log.Printf("保存转发会话记录失败: %v", err)
+	}
+}
+
+// Query 查询转发会话记录，返回按结束时间倒序的记录和匹配总数
+func (s *SessionService) Query(filter SessionFilter) ([]*model.ForwardSession, int) {
+	records, total := s.log.Query(filter.match, filter.Offset, filter.Limit)
+	sessions := make([]*model.ForwardSession, 0, len(records))
+	for _, r := range records {
+		sessions = append(sessions, r.toModel())
+	}
+	return sessions, total
+}
+
+func (s *SessionService) Close() {
+	s.log.Close()
+}
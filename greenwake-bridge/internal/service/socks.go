@@ -0,0 +1,432 @@
+package service
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"greenwake-bridge/internal/auth"
+	"greenwake-bridge/internal/config"
+	"greenwake-bridge/internal/model"
+)
+
+// 代理协议，用于通道和会话记录
+const (
+	ProtocolSOCKS5  = "socks5"
+	ProtocolConnect = "connect"
+)
+
+const handshakeTimeout = 10 * time.Second // 代理握手超时
+
+// SOCKS5 协议常量（RFC 1928 / RFC 1929）
+const (
+	socksVersion       = 0x05
+	socksAuthNone      = 0x00
+	socksAuthPassword  = 0x02
+	socksAuthNoMethods = 0xFF
+	socksAuthVersion   = 0x01 // 用户名密码认证子协商版本（RFC 1929）
+	socksCmdConnect    = 0x01
+	socksAtypIPv4      = 0x01
+	socksAtypDomain    = 0x03
+	socksAtypIPv6      = 0x04
+
+	socksRepSucceeded       = 0x00
+	socksRepNotAllowed      = 0x02
+	socksRepHostUnreachable = 0x04
+	socksRepRefused         = 0x05
+	socksRepCmdUnsupported  = 0x07
+	socksRepAtypUnsupported = 0x08
+)
+
+// Credentials 校验代理客户端的用户名密码，返回 nil 表示认证失败
+type Credentials func(user, password string) *auth.Principal
+
+// SocksService SOCKS5 和 HTTP CONNECT 代理，按目标地址匹配主机，唤醒后转发连接
+type SocksService struct {
+	pcService      *PCService
+	forwardService *ForwardService
+	credentials    Credentials // 为空表示匿名模式，不需要认证
+	mu             sync.Mutex
+	listeners      []net.Listener
+}
+
+func NewSocksService(cfg *config.Config, pcService *PCService, forwardService *ForwardService, credentials Credentials) (*SocksService, error) {
+	s := &SocksService{
+		pcService:      pcService,
+		forwardService: forwardService,
+		credentials:    credentials,
+	}
+
+	if cfg.Socks.ServicePort > 0 {
+		if err := s.listen(cfg.Socks.ServicePort, ProtocolSOCKS5, s.handleSOCKS5); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Socks.ConnectPort > 0 {
+		if err := s.listen(cfg.Socks.ConnectPort, ProtocolConnect, s.handleConnect); err != nil {
+			s.Close()
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (s *SocksService) listen(port int, protocol string, handle func(net.Conn, int)) error {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return fmt.Errorf("启动%s代理监听失败 [%d]: %v", protocol, port, err)
+	}
+	s.mu.Lock()
+	s.listeners = append(s.listeners, listener)
+	s.mu.Unlock()
+
+	log.Printf("启动%s代理监听 [%d]", protocol, port)
+	go func() {
+		for {
+			client, err := listener.Accept()
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					log.Printf("接受连接失败 [%d]: %v", port, err)
+				}
+				return
+			}
+			go handle(client, port)
+		}
+	}()
+	return nil
+}
+
+// resolveTarget 按主机名、别名或IP匹配配置的主机，并检查端口是否允许访问
+func (s *SocksService) resolveTarget(addr string, port int) (*model.PCHostInfo, error) {
+	var host *model.PCHostInfo
+	var allowedPorts []int
+	s.pcService.mu.RLock()
+	for name, cfgHost := range s.pcService.cfgHosts {
+		if strings.EqualFold(name, addr) || addr == cfgHost.IP || containsFold(cfgHost.Aliases, addr) {
+			host = s.pcService.hosts[name]
+			allowedPorts = cfgHost.AllowedPorts
+			break
+		}
+	}
+	s.pcService.mu.RUnlock()
+	if host == nil {
+		return nil, fmt.Errorf("目标地址不是已配置的主机: %s", addr)
+	}
+
+	for _, allowed := range allowedPorts {
+		if allowed == port {
+			return host, nil
+		}
+	}
+	return nil, fmt.Errorf("主机 %s 不允许访问端口 %d", host.Name, port)
+}
+
+func containsFold(list []string, v string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// authorize 校验调用者是否可以唤醒并访问主机
+func (s *SocksService) authorize(principal *auth.Principal, host *model.PCHostInfo) error {
+	if principal != nil && !principal.Allows(host.Name, auth.ActionWake) {
+		return fmt.Errorf("%s 无权访问主机 %s", principal, host.Name)
+	}
+	return nil
+}
+
+// handleSOCKS5 处理 SOCKS5 握手，仅支持 CONNECT 命令
+func (s *SocksService) handleSOCKS5(client net.Conn, port int) {
+	client.SetDeadline(time.Now().Add(handshakeTimeout))
+
+	principal, err := s.socksAuthenticate(client)
+	if err != nil {
+		log.Printf("SOCKS5握手失败 [%s]: %v", client.RemoteAddr(), err)
+		client.Close()
+		return
+	}
+
+	// 请求: VER CMD RSV ATYP DST.ADDR DST.PORT
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(client, header); err != nil || header[0] != socksVersion {
+		client.Close()
+		return
+	}
+	var addr string
+	switch header[3] {
+	case socksAtypIPv4, socksAtypIPv6:
+		ip := make(net.IP, net.IPv4len)
+		if header[3] == socksAtypIPv6 {
+			ip = make(net.IP, net.IPv6len)
+		}
+		if _, err := io.ReadFull(client, ip); err != nil {
+			client.Close()
+			return
+		}
+		addr = ip.String()
+	case socksAtypDomain:
+		domain, err := readSocksString(client)
+		if err != nil {
+			client.Close()
+			return
+		}
+		addr = domain
+	default:
+		writeSocksReply(client, socksRepAtypUnsupported)
+		client.Close()
+		return
+	}
+	portBuf := make([]byte, 2)
+	if _, err := io.ReadFull(client, portBuf); err != nil {
+		client.Close()
+		return
+	}
+	targetPort := int(binary.BigEndian.Uint16(portBuf))
+
+	if header[1] != socksCmdConnect {
+		writeSocksReply(client, socksRepCmdUnsupported)
+		client.Close()
+		return
+	}
+
+	host, err := s.resolveTarget(addr, targetPort)
+	if err == nil {
+		err = s.authorize(principal, host)
+	}
+	if err != nil {
+		log.Printf("拒绝SOCKS5连接 [%s]: %v", client.RemoteAddr(), err)
+		writeSocksReply(client, socksRepNotAllowed)
+		client.Close()
+		return
+	}
+
+	client.SetDeadline(time.Time{})
+	channel := s.forwardService.proxyChannel(ProtocolSOCKS5, port, host.Name, targetPort)
+	s.forwardService.relay(client, channel, func(reason string) error {
+		switch reason {
+		case "":
+			return writeSocksReply(client, socksRepSucceeded)
+		case CloseWakeTimeout:
+			return writeSocksReply(client, socksRepHostUnreachable)
+		default:
+			return writeSocksReply(client, socksRepRefused)
+		}
+	})
+}
+
+// socksAuthenticate 协商认证方式，需要认证时只接受用户名密码方式
+func (s *SocksService) socksAuthenticate(client net.Conn) (*auth.Principal, error) {
+	// 问候: VER NMETHODS METHODS
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(client, header); err != nil {
+		return nil, err
+	}
+	if header[0] != socksVersion {
+		return nil, fmt.Errorf("不支持的SOCKS版本: %d", header[0])
+	}
+	methods := make([]byte, header[1])
+	if _, err := io.ReadFull(client, methods); err != nil {
+		return nil, err
+	}
+
+	method := byte(socksAuthNone)
+	if s.credentials != nil {
+		method = socksAuthPassword
+	}
+	offered := false
+	for _, m := range methods {
+		if m == method {
+			offered = true
+			break
+		}
+	}
+	if !offered {
+		client.Write([]byte{socksVersion, socksAuthNoMethods})
+		return nil, errors.New("客户端不支持所需的认证方式")
+	}
+	if _, err := client.Write([]byte{socksVersion, method}); err != nil {
+		return nil, err
+	}
+	if method == socksAuthNone {
+		return nil, nil
+	}
+
+	// 用户名密码认证: VER ULEN UNAME PLEN PASSWD
+	version := make([]byte, 1)
+	if _, err := io.ReadFull(client, version); err != nil {
+		return nil, err
+	}
+	if version[0] != socksAuthVersion {
+		client.Write([]byte{socksAuthVersion, 0x01})
+		return nil, fmt.Errorf("不支持的用户名密码认证版本: %d", version[0])
+	}
+	user, err := readSocksString(client)
+	if err != nil {
+		return nil, err
+	}
+	password, err := readSocksString(client)
+	if err != nil {
+		return nil, err
+	}
+	principal := s.credentials(user, password)
+	if principal == nil {
+		client.Write([]byte{socksAuthVersion, 0x01})
+		return nil, fmt.Errorf("认证失败: 用户=%s", user)
+	}
+	if _, err := client.Write([]byte{socksAuthVersion, 0x00}); err != nil {
+		return nil, err
+	}
+	return principal, nil
+}
+
+// readSocksString 读取一个字节长度前缀的字符串
+func readSocksString(r io.Reader) (string, error) {
+	length := make([]byte, 1)
+	if _, err := io.ReadFull(r, length); err != nil {
+		return "", err
+	}
+	buf := make([]byte, length[0])
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+// writeSocksReply 返回连接结果，绑定地址固定为 0.0.0.0:0
+func writeSocksReply(w io.Writer, rep byte) error {
+	_, err := w.Write([]byte{socksVersion, rep, 0x00, socksAtypIPv4, 0, 0, 0, 0, 0, 0})
+	return err
+}
+
+// handleConnect 处理 HTTP CONNECT 请求
+func (s *SocksService) handleConnect(client net.Conn, port int) {
+	client.SetDeadline(time.Now().Add(handshakeTimeout))
+
+	reader := bufio.NewReader(client)
+	req, err := http.ReadRequest(reader)
+	if err != nil {
+		client.Close()
+		return
+	}
+	if req.Method != http.MethodConnect {
+		writeConnectStatus(client, http.StatusMethodNotAllowed)
+		client.Close()
+		return
+	}
+
+	var principal *auth.Principal
+	if s.credentials != nil {
+		user, password, ok := proxyBasicAuth(req)
+		if ok {
+			principal = s.credentials(user, password)
+		}
+		if principal == nil {
+			if ok {
+				log.Printf("CONNECT认证失败: 用户=%s, IP=%s", user, client.RemoteAddr())
+			}
+			io.WriteString(client, "HTTP/1.1 407 Proxy Authentication Required\r\nProxy-Authenticate: Basic realm=\"greenwake-bridge\"\r\nContent-Length: 0\r\n\r\n")
+			client.Close()
+			return
+		}
+	}
+
+	addr, portStr, err := net.SplitHostPort(req.Host)
+	targetPort, _ := strconv.Atoi(portStr)
+	if err != nil || targetPort <= 0 {
+		writeConnectStatus(client, http.StatusBadRequest)
+		client.Close()
+		return
+	}
+	host, err := s.resolveTarget(strings.Trim(addr, "[]"), targetPort)
+	if err == nil {
+		err = s.authorize(principal, host)
+	}
+	if err != nil {
+		log.Printf("拒绝CONNECT连接 [%s]: %v", client.RemoteAddr(), err)
+		writeConnectStatus(client, http.StatusForbidden)
+		client.Close()
+		return
+	}
+
+	client.SetDeadline(time.Time{})
+	channel := s.forwardService.proxyChannel(ProtocolConnect, port, host.Name, targetPort)
+	s.forwardService.relay(&bufferedConn{Conn: client, reader: reader}, channel, func(reason string) error {
+		switch reason {
+		case "":
+			_, err := io.WriteString(client, "HTTP/1.1 200 Connection Established\r\n\r\n")
+			return err
+		case CloseWakeTimeout:
+			return writeConnectStatus(client, http.StatusGatewayTimeout)
+		default:
+			return writeConnectStatus(client, http.StatusBadGateway)
+		}
+	})
+}
+
+// proxyBasicAuth 解析 Proxy-Authorization 头中的 Basic 认证
+func proxyBasicAuth(req *http.Request) (string, string, bool) {
+	header := req.Header.Get("Proxy-Authorization")
+	if header == "" {
+		return "", "", false
+	}
+	r := &http.Request{Header: http.Header{"Authorization": {header}}}
+	return r.BasicAuth()
+}
+
+func writeConnectStatus(w io.Writer, status int) error {
+	_, err := fmt.Fprintf(w, "HTTP/1.1 %d %s\r\nContent-Length: 0\r\n\r\n", status, http.StatusText(status))
+	return err
+}
+
+// bufferedConn 读取握手时可能已缓冲了客户端后续发送的数据
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.reader.Read(p)
+}
+
+func (c *bufferedConn) CloseWrite() error {
+	if cw, ok := c.Conn.(interface{ CloseWrite() error }); ok {
+		return cw.CloseWrite()
+	}
+	return nil
+}
+
+// Close 停止代理监听，已建立的连接继续转发直到结束
+func (s *SocksService) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, listener := range s.listeners {
+		listener.Close()
+	}
+}
+
+// proxyChannel 获取代理连接对应的转发通道，首次访问时创建，
+// 以便在通道列表和会话记录中展示代理流量
+func (s *ForwardService) proxyChannel(protocol string, servicePort int, hostName string, targetPort int) *model.ForwardChannel {
+	id := fmt.Sprintf("%d/%s-%s:%d", servicePort, protocol, hostName, targetPort)
+	channel, _ := s.channels.LoadOrStore(id, &model.ForwardChannel{
+		ID:          id,
+		Protocol:    protocol,
+		ServicePort: servicePort,
+		TargetHost:  hostName,
+		TargetPort:  targetPort,
+		Status:      "inactive",
+	})
+	return channel.(*model.ForwardChannel)
+}
@@ -0,0 +1,222 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"greenwake-bridge/internal/auth"
+	"greenwake-bridge/internal/config"
+)
+
+// testCredentials admin 可以访问全部主机，令牌 ha 只能唤醒 nas
+func testCredentials(user, password string) *auth.Principal {
+	switch {
+	case user == "admin" && password == "greenwake":
+		return &auth.Principal{Kind: auth.KindUser, Name: "admin"}
+	case user == "ha" && password == "gwb_test-token":
+		return &auth.Principal{Kind: auth.KindToken, Name: "ha", Token: &auth.Token{
+			Hosts: []string{"nas"}, Actions: []string{auth.ActionWake},
+		}}
+	}
+	return nil
+}
+
+// newTestSocksService 启动代理监听，目标主机 desktop 在线且只允许访问 allowed 端口
+func newTestSocksService(t *testing.T, credentials Credentials, allowed ...int) (socksPort, connectPort int) {
+	t.Helper()
+	prober := &fakeProber{}
+	prober.online.Store(true)
+	host := testHost
+	host.AllowedPorts = allowed
+	host.Aliases = []string{"pc.lan"}
+	pcService := newMonitoredPCService(t, host, prober)
+	forwardService := newTestForwardService(t, pcService, nil)
+
+	socksPort, connectPort = freePort(t), freePort(t)
+	s, err := NewSocksService(&config.Config{Socks: config.SocksConfig{ServicePort: socksPort, ConnectPort: connectPort}}, pcService, forwardService, credentials)
+	if err != nil {
+		t.Fatalf("启动代理失败: %v", err)
+	}
+	t.Cleanup(s.Close)
+	return socksPort, connectPort
+}
+
+// socksAuth 用户名密码认证请求
+func socksAuth(version byte, user, password string) []byte {
+	b := []byte{version, byte(len(user))}
+	b = append(b, user...)
+	b = append(b, byte(len(password)))
+	return append(b, password...)
+}
+
+// socksRequest 连接请求，addr 为IP时按IP地址类型发送，否则按域名发送
+func socksRequest(cmd byte, addr string, port int) []byte {
+	b := []byte{socksVersion, cmd, 0x00}
+	if ip := net.ParseIP(addr); ip != nil && ip.To4() != nil {
+		b = append(b, socksAtypIPv4)
+		b = append(b, ip.To4()...)
+	} else {
+		b = append(b, socksAtypDomain, byte(len(addr)))
+		b = append(b, addr...)
+	}
+	return binary.BigEndian.AppendUint16(b, uint16(port))
+}
+
+// exchange 发送数据并读取 n 字节的响应，连接关闭时返回已读取的部分
+func exchange(t *testing.T, conn net.Conn, data []byte, n int) []byte {
+	t.Helper()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Write(data); err != nil {
+		t.Fatalf("发送数据失败: %v", err)
+	}
+	buf := make([]byte, n)
+	read, _ := io.ReadFull(conn, buf)
+	return buf[:read]
+}
+
+func TestSOCKS5Handshake(t *testing.T) {
+	target, closed := echoServer(t), freePort(t)
+	socksPort, _ := newTestSocksService(t, testCredentials, target, closed)
+
+	password := []byte{socksVersion, 1, socksAuthPassword}
+	tests := []struct {
+		name       string
+		greeting   []byte
+		wantMethod byte
+		auth       []byte // 为空时不进行认证
+		wantStatus byte
+		request    []byte // 为空时认证后不发送请求
+		wantRep    byte
+	}{
+		{"按主机名连接", password, socksAuthPassword, socksAuth(socksAuthVersion, "admin", "greenwake"), 0x00, socksRequest(socksCmdConnect, "desktop", target), socksRepSucceeded},
+		{"按别名连接", password, socksAuthPassword, socksAuth(socksAuthVersion, "admin", "greenwake"), 0x00, socksRequest(socksCmdConnect, "PC.lan", target), socksRepSucceeded},
+		{"按IP连接", password, socksAuthPassword, socksAuth(socksAuthVersion, "admin", "greenwake"), 0x00, socksRequest(socksCmdConnect, "127.0.0.1", target), socksRepSucceeded},
+		{"客户端不支持用户名密码认证", []byte{socksVersion, 1, socksAuthNone}, socksAuthNoMethods, nil, 0, nil, 0},
+		{"认证版本错误", password, socksAuthPassword, socksAuth(socksVersion, "admin", "greenwake"), 0x01, nil, 0},
+		{"密码错误", password, socksAuthPassword, socksAuth(socksAuthVersion, "admin", "wrong"), 0x01, nil, 0},
+		{"端口不在允许列表", password, socksAuthPassword, socksAuth(socksAuthVersion, "admin", "greenwake"), 0x00, socksRequest(socksCmdConnect, "desktop", 22), socksRepNotAllowed},
+		{"不是已配置的主机", password, socksAuthPassword, socksAuth(socksAuthVersion, "admin", "greenwake"), 0x00, socksRequest(socksCmdConnect, "example.com", target), socksRepNotAllowed},
+		{"令牌无权访问主机", password, socksAuthPassword, socksAuth(socksAuthVersion, "ha", "gwb_test-token"), 0x00, socksRequest(socksCmdConnect, "desktop", target), socksRepNotAllowed},
+		{"不支持的命令", password, socksAuthPassword, socksAuth(socksAuthVersion, "admin", "greenwake"), 0x00, socksRequest(0x02, "desktop", target), socksRepCmdUnsupported},
+		{"不支持的地址类型", password, socksAuthPassword, socksAuth(socksAuthVersion, "admin", "greenwake"), 0x00, []byte{socksVersion, socksCmdConnect, 0x00, 0x05}, socksRepAtypUnsupported},
+		{"连接目标失败", password, socksAuthPassword, socksAuth(socksAuthVersion, "admin", "greenwake"), 0x00, socksRequest(socksCmdConnect, "desktop", closed), socksRepRefused},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := dialForward(t, "tcp", socksPort)
+			defer conn.Close()
+
+			if got := exchange(t, conn, tt.greeting, 2); !bytes.Equal(got, []byte{socksVersion, tt.wantMethod}) {
+				t.Fatalf("认证方式为 %v，期望 %v", got, []byte{socksVersion, tt.wantMethod})
+			}
+			if tt.auth == nil {
+				return
+			}
+			if got := exchange(t, conn, tt.auth, 2); !bytes.Equal(got, []byte{socksAuthVersion, tt.wantStatus}) {
+				t.Fatalf("认证结果为 %v，期望 %v", got, []byte{socksAuthVersion, tt.wantStatus})
+			}
+			if tt.request == nil {
+				// 认证失败后关闭连接
+				if n, err := conn.Read(make([]byte, 1)); err == nil {
+					t.Errorf("认证失败后连接未关闭，收到 %d 字节", n)
+				}
+				return
+			}
+			reply := exchange(t, conn, tt.request, 10)
+			if len(reply) != 10 || reply[1] != tt.wantRep {
+				t.Fatalf("连接结果为 %v，期望 %#x", reply, tt.wantRep)
+			}
+			if tt.wantRep == socksRepSucceeded {
+				if got := echo(t, conn, "hello"); got != "hello" {
+					t.Errorf("响应为 %q，期望 hello", got)
+				}
+			}
+		})
+	}
+}
+
+func TestSOCKS5Anonymous(t *testing.T) {
+	target := echoServer(t)
+	socksPort, _ := newTestSocksService(t, nil, target)
+
+	conn := dialForward(t, "tcp", socksPort)
+	defer conn.Close()
+	if got := exchange(t, conn, []byte{socksVersion, 2, socksAuthPassword, socksAuthNone}, 2); !bytes.Equal(got, []byte{socksVersion, socksAuthNone}) {
+		t.Fatalf("匿名模式的认证方式为 %v，期望不需要认证", got)
+	}
+	if reply := exchange(t, conn, socksRequest(socksCmdConnect, "desktop", target), 10); len(reply) != 10 || reply[1] != socksRepSucceeded {
+		t.Fatalf("连接结果为 %v，期望成功", reply)
+	}
+	if got := echo(t, conn, "hello"); got != "hello" {
+		t.Errorf("响应为 %q，期望 hello", got)
+	}
+}
+
+func TestHTTPConnect(t *testing.T) {
+	target := echoServer(t)
+	_, connectPort := newTestSocksService(t, testCredentials, target)
+
+	tests := []struct {
+		name       string
+		method     string
+		host       string
+		user       string
+		password   string
+		wantStatus int
+	}{
+		{"认证后连接", http.MethodConnect, fmt.Sprintf("desktop:%d", target), "admin", "greenwake", http.StatusOK},
+		{"按IP连接", http.MethodConnect, fmt.Sprintf("127.0.0.1:%d", target), "admin", "greenwake", http.StatusOK},
+		{"缺少认证", http.MethodConnect, fmt.Sprintf("desktop:%d", target), "", "", http.StatusProxyAuthRequired},
+		{"密码错误", http.MethodConnect, fmt.Sprintf("desktop:%d", target), "admin", "wrong", http.StatusProxyAuthRequired},
+		{"端口不在允许列表", http.MethodConnect, "desktop:22", "admin", "greenwake", http.StatusForbidden},
+		{"令牌无权访问主机", http.MethodConnect, fmt.Sprintf("desktop:%d", target), "ha", "gwb_test-token", http.StatusForbidden},
+		{"缺少端口", http.MethodConnect, "desktop", "admin", "greenwake", http.StatusBadRequest},
+		{"不是 CONNECT 请求", http.MethodGet, fmt.Sprintf("desktop:%d", target), "admin", "greenwake", http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := dialForward(t, "tcp", connectPort)
+			defer conn.Close()
+			conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+			req := fmt.Sprintf("%s %s HTTP/1.1\r\nHost: %s\r\n", tt.method, tt.host, tt.host)
+			if tt.user != "" {
+				r := &http.Request{Header: http.Header{}}
+				r.SetBasicAuth(tt.user, tt.password)
+				req += "Proxy-Authorization: " + r.Header.Get("Authorization") + "\r\n"
+			}
+			// 握手后立即发送的数据不会丢失
+			if _, err := io.WriteString(conn, req+"\r\nhello"); err != nil {
+				t.Fatalf("发送请求失败: %v", err)
+			}
+
+			reader := bufio.NewReader(conn)
+			resp, err := http.ReadResponse(reader, &http.Request{Method: tt.method})
+			if err != nil {
+				t.Fatalf("读取响应失败: %v", err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("状态码为 %d，期望 %d", resp.StatusCode, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusProxyAuthRequired && resp.Header.Get("Proxy-Authenticate") == "" {
+				t.Error("407 响应缺少 Proxy-Authenticate")
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			buf := make([]byte, 5)
+			if _, err := io.ReadFull(reader, buf); err != nil || string(buf) != "hello" {
+				t.Errorf("响应为 %q，%v，期望 hello", buf, err)
+			}
+		})
+	}
+}
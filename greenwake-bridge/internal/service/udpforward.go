@@ -0,0 +1,307 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"greenwake-bridge/internal/model"
+)
+
+const (
+	maxUDPDatagram     = 64 * 1024 // UDP 数据报最大长度
+	maxPendingPackets  = 64        // 唤醒期间每个会话最多缓存的数据报数
+	maxPendingBytes    = 256 * 1024
+	udpSessionTickTime = time.Second // 会话空闲检查和活跃时间更新间隔
+)
+
+// udpSession 一个客户端地址对应的UDP转发会话，类似NAT映射
+type udpSession struct {
+	channel    *model.ForwardChannel
+	clientAddr *net.UDPAddr
+	clientId   string
+	listener   *net.UDPConn // 服务端监听，用于回复客户端
+	stats      *channelStats
+
+	mu           sync.Mutex
+	target       *net.UDPConn // 连接到目标主机，唤醒完成前为空
+	pending      [][]byte     // 唤醒期间缓存的客户端数据报
+	pendingBytes int
+	closed       bool
+
+	lastActive    atomic.Int64 // 最后一次收发数据的时间（Unix纳秒）
+	bytesToTarget atomic.Int64
+	bytesToClient atomic.Int64
+	done          chan struct{}
+}
+
+// deliver 转发客户端数据报，目标未就绪时先缓存
+func (u *udpSession) deliver(data []byte) {
+	u.lastActive.Store(time.Now().UnixNano())
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.closed {
+		return
+	}
+	if u.target == nil {
+		if len(u.pending) >= maxPendingPackets || u.pendingBytes+len(data) > maxPendingBytes {
+			return // 缓存已满，丢弃数据报，由客户端重传
+		}
+		buf := make([]byte, len(data))
+		copy(buf, data)
+		u.pending = append(u.pending, buf)
+		u.pendingBytes += len(buf)
+		return
+	}
+	u.writeTarget(data)
+}
+
+// writeTarget 发送数据报到目标主机，调用方需持有锁
+func (u *udpSession) writeTarget(data []byte) {
+	n, err := u.target.Write(data)
+	if err != nil {
+		log.Printf("转发错误 (client->target) [%s]: %v", u.clientId, err)
+		return
+	}
+	u.bytesToTarget.Add(int64(n))
+	u.stats.bytesToTarget.Add(int64(n))
+	u.stats.touch()
+}
+
+// attach 目标主机就绪后发送缓存的数据报
+func (u *udpSession) attach(target *net.UDPConn) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.target = target
+	for _, data := range u.pending {
+		u.writeTarget(data)
+	}
+	u.pending = nil
+	u.pendingBytes = 0
+}
+
+// close 结束会话，之后收到的数据报将创建新会话
+func (u *udpSession) close() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.closed {
+		return
+	}
+	u.closed = true
+	close(u.done)
+	if u.target != nil {
+		u.target.Close()
+	}
+}
+
+// udpForwarder 一个UDP转发通道的会话表
+type udpForwarder struct {
+	listener *net.UDPConn
+	mu       sync.Mutex
+	sessions map[string]*udpSession // key: 客户端地址
+	draining bool                   // 通道已删除，不再创建新会话，最后一个会话结束后关闭监听
+}
+
+// drain 停止创建新会话，已有会话结束后关闭监听
+func (f *udpForwarder) drain() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.draining = true
+	if len(f.sessions) == 0 {
+		f.listener.Close()
+	}
+}
+
+func (f *udpForwarder) Close() error {
+	err := f.listener.Close()
+	f.mu.Lock()
+	for _, session := range f.sessions {
+		session.close()
+	}
+	f.mu.Unlock()
+	return err
+}
+
+func (s *ForwardService) startUDPForward(channel *model.ForwardChannel) {
+	s.mu.Lock()
+	if _, exists := s.listeners[channel.ID]; exists || !s.started(channel.ID) {
+		s.mu.Unlock()
+		return
+	}
+
+	listener, err := net.ListenUDP("udp", &net.UDPAddr{Port: channel.ServicePort})
+	if err != nil {
+		log.Printf("启动UDP转发监听失败 [%d]: %v", channel.ServicePort, err)
+		s.mu.Unlock()
+		return
+	}
+	forwarder := &udpForwarder{
+		listener: listener,
+		sessions: make(map[string]*udpSession),
+	}
+	s.listeners[channel.ID] = forwarder
+	s.mu.Unlock()
+
+	log.Printf("启动UDP转发监听 [%d -> %s:%d]", channel.ServicePort, channel.TargetHost, channel.TargetPort)
+
+	buf := make([]byte, maxUDPDatagram)
+	for {
+		n, clientAddr, err := listener.ReadFromUDP(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				break
+			}
+			log.Printf("接收UDP数据失败 [%d]: %v", channel.ServicePort, err)
+			continue
+		}
+
+		key := clientAddr.String()
+		forwarder.mu.Lock()
+		session, exists := forwarder.sessions[key]
+		if !exists && forwarder.draining {
+			forwarder.mu.Unlock()
+			continue
+		}
+		if !exists {
+			session = &udpSession{
+				channel:    channel,
+				clientAddr: clientAddr,
+				clientId:   key,
+				listener:   listener,
+				stats:      s.channelStats(channel.ID),
+				done:       make(chan struct{}),
+			}
+			forwarder.sessions[key] = session
+		}
+		forwarder.mu.Unlock()
+
+		session.deliver(buf[:n])
+		if !exists {
+			go s.handleUDPSession(forwarder, session)
+		}
+	}
+}
+
+// handleUDPSession 唤醒目标主机并在目标和客户端之间转发数据报，直到会话空闲超时
+func (s *ForwardService) handleUDPSession(forwarder *udpForwarder, u *udpSession) {
+	channel := u.channel
+	stats := u.stats
+	stats.sessionStarted()
+
+	// 会话结束时保存记录
+	session := sessionRecord{
+		ID:          newSessionID(),
+		Channel:     channel.ID,
+		Protocol:    channel.Protocol,
+		ServicePort: channel.ServicePort,
+		TargetHost:  channel.TargetHost,
+		TargetPort:  channel.TargetPort,
+		ClientIP:    u.clientAddr.IP.String(),
+		ClientPort:  u.clientAddr.Port,
+		StartTime:   time.Now(),
+	}
+	s.pcService.events.Publish(EventSessionOpened, channel.TargetHost, session.toModel())
+
+	clientInfo := newChannelClient(u.clientId, u.clientAddr.IP.String(), strconv.Itoa(u.clientAddr.Port))
+	clientsMap, _ := s.channelClients.LoadOrStore(channel.ID, &sync.Map{})
+	clientsMap.(*sync.Map).Store(u.clientId, clientInfo)
+
+	defer func() {
+		// 从会话表移除，之后的数据报会创建新会话
+		forwarder.mu.Lock()
+		if forwarder.sessions[u.clientId] == u {
+			delete(forwarder.sessions, u.clientId)
+		}
+		if forwarder.draining && len(forwarder.sessions) == 0 {
+			forwarder.listener.Close()
+		}
+		forwarder.mu.Unlock()
+		u.close()
+
+		stats.sessionEnded()
+		clientsMap.(*sync.Map).Delete(u.clientId)
+
+		session.EndTime = time.Now()
+		session.BytesToTarget = u.bytesToTarget.Load()
+		session.BytesToClient = u.bytesToClient.Load()
+		s.sessions.record(session)
+		s.pcService.events.Publish(EventSessionClosed, channel.TargetHost, session.toModel())
+	}()
+
+	// 会话期间持续发送唤醒包，保持主机在线
+	defer s.keepTargetAwake(channel)()
+
+	host, exists := s.pcService.host(channel.TargetHost)
+	if !exists {
+		log.Printf("目标主机不存在: %s", channel.TargetHost)
+		stats.failedSessions.Add(1)
+		session.CloseReason = CloseHostNotFound
+		return
+	}
+
+	// 唤醒期间客户端的数据报会被缓存
+	if !s.wakeTarget(channel, host, stats, &session) {
+		return
+	}
+
+	targetAddr, err := net.ResolveUDPAddr("udp", net.JoinHostPort(host.IP, strconv.Itoa(channel.TargetPort)))
+	if err != nil {
+		log.Printf("解析目标地址失败 [%s:%d]: %v", host.IP, channel.TargetPort, err)
+		stats.dialFailed()
+		session.CloseReason = CloseDialFailed
+		return
+	}
+	target, err := net.DialUDP("udp", nil, targetAddr)
+	if err != nil {
+		log.Printf("连接目标失败 [%s:%d]: %v", host.IP, channel.TargetPort, err)
+		stats.dialFailed()
+		session.CloseReason = CloseDialFailed
+		return
+	}
+	u.attach(target)
+
+	idleTimeout := time.Duration(channel.IdleTimeout) * time.Second
+	buf := make([]byte, maxUDPDatagram)
+	for {
+		target.SetReadDeadline(time.Now().Add(udpSessionTickTime))
+		n, err := target.Read(buf)
+		if err == nil {
+			u.lastActive.Store(time.Now().UnixNano())
+			if _, err := u.listener.WriteToUDP(buf[:n], u.clientAddr); err != nil {
+				log.Printf("转发错误 (target->client) [%s]: %v", u.clientId, err)
+			}
+			u.bytesToClient.Add(int64(n))
+			stats.bytesToClient.Add(int64(n))
+			stats.touch()
+			continue
+		}
+
+		select {
+		case <-u.done:
+			session.CloseReason = CloseShutdown
+			return
+		default:
+		}
+
+		if !errors.Is(err, os.ErrDeadlineExceeded) {
+			// 目标端口未监听时会收到ICMP不可达，继续等待直到空闲超时
+			log.Printf("接收目标数据失败 [%s:%d]: %v", host.IP, channel.TargetPort, err)
+		}
+
+		lastActive := time.Unix(0, u.lastActive.Load())
+		clientInfo.touch(lastActive)
+		if time.Since(lastActive) > idleTimeout {
+			session.CloseReason = CloseIdleTimeout
+			return
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package service
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"greenwake-bridge/internal/config"
+	"greenwake-bridge/internal/model"
+)
+
+func TestUDPSessionPending(t *testing.T) {
+	u := &udpSession{stats: &channelStats{}, done: make(chan struct{})}
+
+	// 目标就绪前缓存数据报，超过数量或字节数上限时丢弃
+	for i := 0; i < maxPendingPackets+10; i++ {
+		u.deliver([]byte{byte(i)})
+	}
+	if len(u.pending) != maxPendingPackets {
+		t.Errorf("缓存的数据报为 %d 个，期望 %d 个", len(u.pending), maxPendingPackets)
+	}
+	u.pending, u.pendingBytes = nil, 0
+	u.deliver(make([]byte, maxPendingBytes))
+	u.deliver([]byte{1})
+	if len(u.pending) != 1 || u.pendingBytes != maxPendingBytes {
+		t.Errorf("缓存的数据报为 %d 个 %d 字节，期望超过字节数上限的数据报被丢弃", len(u.pending), u.pendingBytes)
+	}
+
+	// 关闭后收到的数据报不再缓存
+	u.pending, u.pendingBytes = nil, 0
+	u.close()
+	u.deliver([]byte{1})
+	if len(u.pending) != 0 {
+		t.Errorf("关闭后缓存了 %d 个数据报", len(u.pending))
+	}
+}
+
+// udpEchoServer 在本地监听UDP端口，原样返回收到的数据报
+func udpEchoServer(t *testing.T) int {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听本地端口失败: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, maxUDPDatagram)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			conn.WriteTo(buf[:n], addr)
+		}
+	}()
+	return conn.LocalAddr().(*net.UDPAddr).Port
+}
+
+// freeUDPPort 返回一个当前空闲的本地UDP端口
+func freeUDPPort(t *testing.T) int {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("获取空闲端口失败: %v", err)
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).Port
+}
+
+// dialUDPForward 等待UDP转发监听启动后连接
+func dialUDPForward(t *testing.T, s *ForwardService, fc config.ForwardConfig) net.Conn {
+	t.Helper()
+	waitFor(t, "转发监听启动", func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		_, exists := s.listeners[channelID(fc)]
+		return exists
+	})
+	return dialForward(t, "udp", fc.ServicePort)
+}
+
+// udpEcho 发送数据报并读取响应
+func udpEcho(t *testing.T, conn net.Conn, data string) string {
+	t.Helper()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Write([]byte(data)); err != nil {
+		t.Fatalf("发送数据失败: %v", err)
+	}
+	buf := make([]byte, maxUDPDatagram)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("读取响应失败: %v", err)
+	}
+	return string(buf[:n])
+}
+
+// nextSessionClosed 等待下一个会话结束事件
+func nextSessionClosed(t *testing.T, sub *Subscription) *model.ForwardSession {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case ev := <-sub.C:
+			if ev.Type == EventSessionClosed {
+				return ev.Data.(*model.ForwardSession)
+			}
+		case <-timeout:
+			t.Fatal("等待会话结束超时")
+		}
+	}
+}
+
+func TestUDPForwardWakeAndIdleTimeout(t *testing.T) {
+	prober := &fakeProber{}
+	host := testHost
+	host.WakeTimeout = 5
+	host.WOL = &config.WOLConfig{Address: "127.0.0.1", Port: wakeListener(t, prober)}
+	pcService := newMonitoredPCService(t, host, prober)
+	sub := pcService.events.Subscribe(0)
+	defer sub.Close()
+
+	fc := config.ForwardConfig{ServicePort: freeUDPPort(t), TargetHost: "desktop", TargetPort: udpEchoServer(t), Protocol: config.ProtocolUDP, IdleTimeout: 1}
+	s := newTestForwardService(t, pcService, []config.ForwardConfig{fc})
+	id := channelID(fc)
+
+	// 主机离线时第一个数据报唤醒主机，唤醒期间缓存，上线后转发
+	conn := dialUDPForward(t, s, fc)
+	defer conn.Close()
+	if got := udpEcho(t, conn, "ping"); got != "ping" {
+		t.Fatalf("响应为 %q，期望 ping", got)
+	}
+	channel := forwardChannel(t, s, id)
+	if channel.ActiveCount != 1 || channel.Wakes != 1 {
+		t.Errorf("活跃会话为 %d，唤醒 %d，期望 1 和 1", channel.ActiveCount, channel.Wakes)
+	}
+	if got := udpEcho(t, conn, "pong"); got != "pong" {
+		t.Fatalf("响应为 %q，期望 pong", got)
+	}
+
+	// 空闲超过 idle_timeout 后结束会话
+	session := nextSessionClosed(t, sub)
+	if session.CloseReason != CloseIdleTimeout || !session.WakeNeeded || session.BytesToTarget != 8 || session.BytesToClient != 8 {
+		t.Errorf("会话结束原因为 %s，需要唤醒 %v，字节数 %d/%d，期望 idle_timeout、true 和 8/8",
+			session.CloseReason, session.WakeNeeded, session.BytesToTarget, session.BytesToClient)
+	}
+	waitFor(t, "会话结束", func() bool { return forwardChannel(t, s, id).ActiveCount == 0 })
+
+	// 同一客户端之后的数据报创建新会话，主机在线时不再唤醒
+	if got := udpEcho(t, conn, "again"); got != "again" {
+		t.Fatalf("响应为 %q，期望 again", got)
+	}
+	channel = forwardChannel(t, s, id)
+	if channel.TotalSessions != 2 || channel.ActiveCount != 1 || channel.Wakes != 1 {
+		t.Errorf("会话为 %d，活跃 %d，唤醒 %d，期望 2、1 和 1", channel.TotalSessions, channel.ActiveCount, channel.Wakes)
+	}
+}
+
+func TestUDPForwardDrain(t *testing.T) {
+	prober := &fakeProber{}
+	prober.online.Store(true)
+	pcService := newMonitoredPCService(t, testHost, prober)
+	sub := pcService.events.Subscribe(0)
+	defer sub.Close()
+
+	fc := config.ForwardConfig{ServicePort: freeUDPPort(t), TargetHost: "desktop", TargetPort: udpEchoServer(t), Protocol: config.ProtocolUDP, IdleTimeout: 1}
+	s := newTestForwardService(t, pcService, []config.ForwardConfig{fc})
+
+	conn := dialUDPForward(t, s, fc)
+	defer conn.Close()
+	if got := udpEcho(t, conn, "ping"); got != "ping" {
+		t.Fatalf("响应为 %q，期望 ping", got)
+	}
+
+	// 删除通道后已有会话继续转发，新的客户端不再创建会话
+	s.UpdateForwards(nil)
+	if got := udpEcho(t, conn, "still"); got != "still" {
+		t.Fatalf("删除通道后响应为 %q，期望 still", got)
+	}
+	other := dialForward(t, "udp", fc.ServicePort)
+	defer other.Close()
+	other.Write([]byte("new"))
+	other.SetReadDeadline(time.Now().Add(300 * time.Millisecond))
+	if n, err := other.Read(make([]byte, 16)); err == nil {
+		t.Errorf("删除通道后新的客户端收到 %d 字节响应", n)
+	}
+
+	// 最后一个会话结束后关闭监听，端口可以重新绑定
+	nextSessionClosed(t, sub)
+	waitFor(t, "监听关闭", func() bool {
+		ln, err := net.ListenUDP("udp", &net.UDPAddr{Port: fc.ServicePort})
+		if err != nil {
+			return false
+		}
+		ln.Close()
+		return true
+	})
+}
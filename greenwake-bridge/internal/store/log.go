@@ -0,0 +1,241 @@
+package store
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// pruneInterval 定期清理超过保留时间的记录
+const pruneInterval = time.Hour
+
+// Options 记录保留策略
+type Options struct {
+	MaxAge     time.Duration // 记录最长保留时间，0 表示不按时间清理
+	MaxRecords int           // 最多保留的记录数，0 表示不限制
+}
+
+// Log 追加写入的本地记录文件（每行一条JSON），内存中保留全部有效记录用于查询
+type Log[T any] struct {
+	mu      sync.Mutex
+	path    string
+	opts    Options
+	at      func(T) time.Time // 获取记录时间，用于按保留时间清理
+	records []T               // 按写入顺序，最早的在前
+	file    *os.File
+	stale   int // 文件中已清理但尚未重写的记录数
+	ticker  *time.Ticker
+	done    chan struct{} // 关闭时停止定期清理
+}
+
+// Open 打开记录文件并加载保留期内的记录
+func Open[T any](path string, opts Options, at func(T) time.Time) (*Log[T], error) {
+	l := &Log[T]{
+		path: path,
+		opts: opts,
+		at:   at,
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("创建数据目录失败: %v", err)
+	}
+	if err := l.load(); err != nil {
+		return nil, err
+	}
+
+	l.prune(time.Now())
+	if err := l.compact(); err != nil {
+		return nil, err
+	}
+
+	l.ticker = time.NewTicker(pruneInterval)
+	l.done = make(chan struct{})
+	go l.pruneLoop(l.ticker, l.done)
+
+	return l, nil
+}
+
+// pruneLoop 定期清理超过保留时间的记录，直到记录文件关闭
+func (l *Log[T]) pruneLoop(ticker *time.Ticker, done <-chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		case now := <-ticker.C:
+			l.mu.Lock()
+			// 关闭后不再清理，避免重写时重新打开文件
+			if l.file != nil {
+				l.prune(now)
+			}
+			l.mu.Unlock()
+		}
+	}
+}
+
+func (l *Log[T]) load() error {
+	f, err := os.Open(l.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取记录文件失败: %v", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var rec T
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			// 进程异常退出时最后一行可能不完整，跳过即可
+			log.Printf("跳过无法解析的记录 %s:%d: %v", l.path, line, err)
+			continue
+		}
+		l.records = append(l.records, rec)
+	}
+	return scanner.Err()
+}
+
+// Append 追加一条记录
+func (l *Log[T]) Append(rec T) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("序列化记录失败: %v", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return fmt.Errorf("记录文件已关闭: %s", l.path)
+	}
+	if _, err := l.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("写入记录失败: %v", err)
+	}
+	l.records = append(l.records, rec)
+
+	if l.opts.MaxRecords > 0 && len(l.records) > l.opts.MaxRecords {
+		drop := len(l.records) - l.opts.MaxRecords
+		l.records = append([]T(nil), l.records[drop:]...)
+		l.stale += drop
+		l.maybeCompact()
+	}
+	return nil
+}
+
+// Query 按条件查询记录，按时间倒序返回 offset 开始的最多 limit 条，以及匹配的总数
+func (l *Log[T]) Query(match func(T) bool, offset, limit int) ([]T, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	result := make([]T, 0)
+	total := 0
+	for i := len(l.records) - 1; i >= 0; i-- {
+		rec := l.records[i]
+		if match != nil && !match(rec) {
+			continue
+		}
+		if total >= offset && (limit <= 0 || len(result) < limit) {
+			result = append(result, rec)
+		}
+		total++
+	}
+	return result, total
+}
+
+// prune 清理超过保留时间的记录，调用方需持有锁
+func (l *Log[T]) prune(now time.Time) {
+	if l.opts.MaxAge <= 0 {
+		return
+	}
+	cutoff := now.Add(-l.opts.MaxAge)
+	drop := 0
+	for drop < len(l.records) && l.at(l.records[drop]).Before(cutoff) {
+		drop++
+	}
+	if drop == 0 {
+		return
+	}
+	l.records = append([]T(nil), l.records[drop:]...)
+	l.stale += drop
+	l.maybeCompact()
+}
+
+// maybeCompact 已清理的记录较多时重写文件，调用方需持有锁
+func (l *Log[T]) maybeCompact() {
+	threshold := l.opts.MaxRecords / 10
+	if threshold < 100 {
+		threshold = 100
+	}
+	if l.stale < threshold {
+		return
+	}
+	if err := l.compact(); err != nil {
+		log.Printf("重写记录文件失败: %v", err)
+	}
+}
+
+// compact 只保留有效记录重写文件，并重新打开用于追加，调用方需持有锁
+func (l *Log[T]) compact() error {
+	tmp := l.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("写入记录文件失败: %v", err)
+	}
+	w := bufio.NewWriter(f)
+	for _, rec := range l.records {
+		data, err := json.Marshal(rec)
+		if err != nil {
+			continue
+		}
+		w.Write(append(data, '\n'))
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return fmt.Errorf("写入记录文件失败: %v", err)
+	}
+	f.Close()
+
+	if l.file != nil {
+		l.file.Close()
+		l.file = nil
+	}
+	if err := os.Rename(tmp, l.path); err != nil {
+		return fmt.Errorf("写入记录文件失败: %v", err)
+	}
+
+	l.file, err = os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("打开记录文件失败: %v", err)
+	}
+	l.stale = 0
+	return nil
+}
+
+// Close 停止定期清理并关闭记录文件
+func (l *Log[T]) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.ticker != nil {
+		l.ticker.Stop()
+		close(l.done)
+		l.ticker = nil
+	}
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
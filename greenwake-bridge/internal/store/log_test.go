@@ -0,0 +1,184 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testRecord struct {
+	ID   int       `json:"id"`
+	Time time.Time `json:"time"`
+}
+
+func recordTime(r testRecord) time.Time {
+	return r.Time
+}
+
+func openTestLog(t *testing.T, path string, opts Options) *Log[testRecord] {
+	t.Helper()
+	l, err := Open(path, opts, recordTime)
+	if err != nil {
+		t.Fatalf("打开记录文件失败: %v", err)
+	}
+	return l
+}
+
+// fileLines 返回记录文件中的行数
+func fileLines(t *testing.T, path string) int {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Count(string(data), "\n")
+}
+
+func ids(records []testRecord) []int {
+	result := make([]int, 0, len(records))
+	for _, r := range records {
+		result = append(result, r.ID)
+	}
+	return result
+}
+
+func TestLogAppendAndReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "records.jsonl")
+	now := time.Now()
+
+	l := openTestLog(t, path, Options{})
+	for i := 1; i <= 3; i++ {
+		if err := l.Append(testRecord{ID: i, Time: now}); err != nil {
+			t.Fatalf("追加记录失败: %v", err)
+		}
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Close(); err != nil {
+		t.Errorf("重复关闭应返回 nil，得到 %v", err)
+	}
+	if err := l.Append(testRecord{ID: 4, Time: now}); err == nil {
+		t.Error("关闭后追加记录应返回错误")
+	}
+
+	// 进程异常退出时最后一行可能不完整
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"id":4,"ti`)
+	f.Close()
+
+	l = openTestLog(t, path, Options{})
+	defer l.Close()
+	records, total := l.Query(nil, 0, 0)
+	if total != 3 || len(records) != 3 || records[0].ID != 3 {
+		t.Errorf("重新打开后的记录为 %v，期望 [3 2 1]", ids(records))
+	}
+	if lines := fileLines(t, path); lines != 3 {
+		t.Errorf("打开时应重写文件去掉不完整的行，文件有 %d 行", lines)
+	}
+}
+
+func TestLogQuery(t *testing.T) {
+	l := openTestLog(t, filepath.Join(t.TempDir(), "records.jsonl"), Options{})
+	defer l.Close()
+	now := time.Now()
+	for i := 1; i <= 10; i++ {
+		l.Append(testRecord{ID: i, Time: now})
+	}
+	even := func(r testRecord) bool { return r.ID%2 == 0 }
+
+	tests := []struct {
+		name          string
+		match         func(testRecord) bool
+		offset, limit int
+		want          []int
+		total         int
+	}{
+		{"全部按倒序", nil, 0, 0, []int{10, 9, 8, 7, 6, 5, 4, 3, 2, 1}, 10},
+		{"分页", nil, 2, 3, []int{8, 7, 6}, 10},
+		{"按条件过滤", even, 0, 2, []int{10, 8}, 5},
+		{"过滤后分页", even, 3, 10, []int{4, 2}, 5},
+		{"超出范围", even, 5, 10, []int{}, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, total := l.Query(tt.match, tt.offset, tt.limit)
+			got := ids(records)
+			if total != tt.total || len(got) != len(tt.want) {
+				t.Fatalf("得到 %v（共 %d 条），期望 %v（共 %d 条）", got, total, tt.want, tt.total)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("得到 %v，期望 %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestLogPruneByAge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "records.jsonl")
+	now := time.Now()
+
+	l := openTestLog(t, path, Options{})
+	l.Append(testRecord{ID: 1, Time: now.Add(-48 * time.Hour)})
+	l.Append(testRecord{ID: 2, Time: now.Add(-25 * time.Hour)})
+	l.Append(testRecord{ID: 3, Time: now.Add(-time.Hour)})
+	l.Close()
+
+	// 打开时清理超过保留时间的记录并重写文件
+	l = openTestLog(t, path, Options{MaxAge: 24 * time.Hour})
+	defer l.Close()
+	if records, _ := l.Query(nil, 0, 0); len(records) != 1 || records[0].ID != 3 {
+		t.Errorf("清理后的记录为 %v，期望 [3]", ids(records))
+	}
+	if lines := fileLines(t, path); lines != 1 {
+		t.Errorf("清理后文件有 %d 行，期望 1 行", lines)
+	}
+
+	// 定期清理只移除内存中的记录，累计较多时才重写文件
+	l.Append(testRecord{ID: 4, Time: now})
+	l.mu.Lock()
+	l.prune(now.Add(24*time.Hour - time.Minute))
+	l.mu.Unlock()
+	if records, _ := l.Query(nil, 0, 0); len(records) != 1 || records[0].ID != 4 {
+		t.Errorf("定期清理后的记录为 %v，期望 [4]", ids(records))
+	}
+	if lines := fileLines(t, path); lines != 2 {
+		t.Errorf("少量清理不应重写文件，文件有 %d 行，期望 2 行", lines)
+	}
+}
+
+func TestLogMaxRecordsCompact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "records.jsonl")
+	now := time.Now()
+
+	l := openTestLog(t, path, Options{MaxRecords: 50})
+	defer l.Close()
+	for i := 1; i <= 149; i++ {
+		l.Append(testRecord{ID: i, Time: now})
+	}
+	records, total := l.Query(nil, 0, 1)
+	if total != 50 || records[0].ID != 149 {
+		t.Errorf("保留的记录为 %d 条，最新为 %v，期望 50 条且最新为 149", total, ids(records))
+	}
+	// 清理的记录未达到100条时不重写
+	if lines := fileLines(t, path); lines != 149 {
+		t.Errorf("文件有 %d 行，期望 149 行", lines)
+	}
+
+	l.Append(testRecord{ID: 150, Time: now})
+	if lines := fileLines(t, path); lines != 50 {
+		t.Errorf("清理的记录达到100条时应重写文件，文件有 %d 行，期望 50 行", lines)
+	}
+	// 重写后继续追加到新文件
+	l.Append(testRecord{ID: 151, Time: now})
+	if lines := fileLines(t, path); lines != 51 {
+		t.Errorf("重写后追加的记录未写入文件，文件有 %d 行，期望 51 行", lines)
+	}
+}
@@ -0,0 +1,18 @@
+package wake
+
+import (
+	"log"
+	"syscall"
+)
+
+// bindToDevice 将套接字绑定到网卡，保证广播从该网卡发出
+// 缺少 CAP_NET_RAW 权限时只绑定网卡的源地址
+func bindToDevice(iface string) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		return c.Control(func(fd uintptr) {
+			if err := syscall.SetsockoptString(int(fd), syscall.SOL_SOCKET, syscall.SO_BINDTODEVICE, iface); err != nil {
+				log.Printf("绑定网卡 %s 失败，按源地址发送: %v", iface, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package wake
+
+import "syscall"
+
+// bindToDevice 其他系统不支持按网卡绑定，只绑定网卡的源地址
+func bindToDevice(iface string) func(network, address string, c syscall.RawConn) error {
+	return nil
+}
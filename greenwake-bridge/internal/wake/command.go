@@ -0,0 +1,42 @@
+package wake
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"greenwake-bridge/internal/config"
+)
+
+// commandWaker 执行自定义命令唤醒主机，退出码为0时认为成功
+// 命令可以通过环境变量 GREENWAKE_HOST、GREENWAKE_IP、GREENWAKE_MAC 获取主机信息，bridge 的其他 GREENWAKE_ 环境变量不会传递
+type commandWaker struct {
+	host    config.PCHostConfig
+	command []string
+}
+
+func (w *commandWaker) Wake(ctx context.Context) error {
+	cmd := exec.CommandContext(ctx, w.command[0], w.command[1:]...)
+	cmd.Env = append(config.ChildEnviron(),
+		"GREENWAKE_HOST="+w.host.Name,
+		"GREENWAKE_IP="+w.host.IP,
+		"GREENWAKE_MAC="+w.host.MAC,
+	)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		output := strings.TrimSpace(string(out))
+		if len(output) > maxErrorBody {
+			output = output[:maxErrorBody]
+		}
+		if output == "" {
+			return err
+		}
+		return fmt.Errorf("%v: %s", err, output)
+	}
+	return nil
+}
+
+func (w *commandWaker) String() string {
+	return "command:" + strings.Join(w.command, " ")
+}
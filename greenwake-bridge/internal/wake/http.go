@@ -0,0 +1,83 @@
+package wake
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"greenwake-bridge/internal/config"
+)
+
+const maxErrorBody = 200 // 错误信息中最多包含的响应内容长度
+
+// httpWaker 调用HTTP接口唤醒主机，如打开智能插座或调用 Home Assistant 服务
+type httpWaker struct {
+	method   string
+	url      string
+	headers  map[string]string
+	body     string
+	user     string
+	password string
+	status   int
+	client   *http.Client
+}
+
+func newHTTPWaker(m config.WakeMethod) *httpWaker {
+	method := strings.ToUpper(m.Method)
+	if method == "" {
+		method = http.MethodPost
+	}
+	return &httpWaker{
+		method:   method,
+		url:      m.URL,
+		headers:  m.Headers,
+		body:     m.Body,
+		user:     m.User,
+		password: m.Password,
+		status:   m.Status,
+		client:   newHTTPClient(m.Insecure),
+	}
+}
+
+// Wake 发送请求，返回期望的状态码（默认任意2xx）时认为成功
+func (w *httpWaker) Wake(ctx context.Context) error {
+	var body io.Reader
+	if w.body != "" {
+		body = strings.NewReader(w.body)
+	}
+	req, err := http.NewRequestWithContext(ctx, w.method, w.url, body)
+	if err != nil {
+		return err
+	}
+	if w.body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range w.headers {
+		req.Header.Set(k, v)
+	}
+	if w.user != "" {
+		req.SetBasicAuth(w.user, w.password)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	ok := resp.StatusCode >= 200 && resp.StatusCode <= 299
+	if w.status != 0 {
+		ok = resp.StatusCode == w.status
+	}
+	if !ok {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return fmt.Errorf("状态码 %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	return nil
+}
+
+func (w *httpWaker) String() string {
+	return "http:" + w.method + " " + w.url
+}
@@ -0,0 +1,209 @@
+// Package wake 唤醒主机：Wake-on-LAN 唤醒包、Redfish、HTTP 接口和自定义命令
+package wake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"greenwake-bridge/internal/config"
+
+	"github.com/sabhiram/go-wol/wol"
+)
+
+const defaultBroadcast = "255.255.255.255"
+
+// MagicPacket 按主机的 wol 配置通过UDP发送唤醒包
+type MagicPacket struct {
+	transport string
+	mac       string
+	password  []byte // SecureOn 密码，附加在唤醒包末尾
+	address   string // 目标地址，为空时使用网卡的子网广播地址或 255.255.255.255
+	port      int
+	iface     string
+	count     int
+	interval  time.Duration
+}
+
+// NewMagicPacket 根据主机配置创建唤醒包发送方式
+func NewMagicPacket(host config.PCHostConfig) (*MagicPacket, error) {
+	if _, err := wol.New(host.MAC); err != nil {
+		return nil, fmt.Errorf("MAC 地址格式错误: %s", host.MAC)
+	}
+
+	p := &MagicPacket{
+		mac:      host.MAC,
+		port:     config.DefaultWOLPort,
+		count:    config.DefaultWOLCount,
+		interval: time.Duration(config.DefaultWOLInterval) * time.Millisecond,
+	}
+	cfg := host.WOL
+	if cfg == nil {
+		return p, nil
+	}
+
+	password, err := cfg.SecureOn()
+	if err != nil {
+		return nil, err
+	}
+	p.password = password
+	p.transport = cfg.Transport
+	p.address = cfg.Address
+	p.iface = cfg.Interface
+	if cfg.Port > 0 {
+		p.port = cfg.Port
+	}
+	if cfg.Count > 0 {
+		p.count = cfg.Count
+	}
+	if cfg.IntervalMs > 0 {
+		p.interval = time.Duration(cfg.IntervalMs) * time.Millisecond
+	}
+	return p, nil
+}
+
+// payload 生成唤醒包：6字节 0xFF、16次重复的 MAC 地址和可选的 SecureOn 密码
+func (p *MagicPacket) payload() ([]byte, error) {
+	mp, err := wol.New(p.mac)
+	if err != nil {
+		return nil, err
+	}
+	bs, err := mp.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	return append(bs, p.password...), nil
+}
+
+// Wake 按配置的次数和间隔发送唤醒包，任一次发送失败即返回错误
+// 以太网帧方式在缺少权限或不支持的系统上改用UDP发送
+func (p *MagicPacket) Wake(ctx context.Context) error {
+	bs, err := p.payload()
+	if err != nil {
+		return fmt.Errorf("生成唤醒包失败: %v", err)
+	}
+
+	if p.transport == config.WOLTransportRaw {
+		err := p.sendRaw(ctx, bs)
+		if !errors.Is(err, errRawUnavailable) {
+			return err
+		}
+		log.Printf("无法通过网卡 %s 发送以太网帧，改用UDP发送唤醒包: %v", p.iface, err)
+	}
+	return p.sendUDP(ctx, bs)
+}
+
+func (p *MagicPacket) sendUDP(ctx context.Context, bs []byte) error {
+	dialer := net.Dialer{}
+	address := p.address
+	if p.iface != "" {
+		local, broadcast, err := interfaceAddr(p.iface)
+		if err != nil {
+			return err
+		}
+		dialer.LocalAddr = &net.UDPAddr{IP: local}
+		dialer.Control = bindToDevice(p.iface)
+		if address == "" {
+			address = broadcast.String()
+		}
+	}
+	if address == "" {
+		address = defaultBroadcast
+	}
+
+	conn, err := dialer.DialContext(ctx, "udp4", net.JoinHostPort(address, strconv.Itoa(p.port)))
+	if err != nil {
+		return fmt.Errorf("创建UDP连接失败: %v", err)
+	}
+	defer conn.Close()
+
+	return p.repeat(ctx, func() error {
+		n, err := conn.Write(bs)
+		if err != nil {
+			return fmt.Errorf("发送唤醒包失败: %v", err)
+		}
+		if n != len(bs) {
+			return fmt.Errorf("发送的数据长度不正确: %d (应为%d字节)", n, len(bs))
+		}
+		return nil
+	})
+}
+
+// repeat 按配置的次数和间隔调用 send
+func (p *MagicPacket) repeat(ctx context.Context, send func() error) error {
+	for i := 0; i < p.count; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(p.interval):
+			}
+		}
+		if err := send(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// String 返回用于日志展示的发送方式，如 udp 192.168.1.255:9 via eth0 x3
+func (p *MagicPacket) String() string {
+	address := p.address
+	if address == "" {
+		address = defaultBroadcast
+		if p.iface != "" {
+			address = "subnet-broadcast"
+		}
+	}
+	parts := []string{"udp " + net.JoinHostPort(address, strconv.Itoa(p.port))}
+	if p.transport == config.WOLTransportRaw {
+		parts = []string{"raw ethertype 0x0842"}
+	}
+	if p.iface != "" {
+		parts = append(parts, "via "+p.iface)
+	}
+	if p.count > 1 {
+		parts = append(parts, fmt.Sprintf("x%d", p.count))
+	}
+	if len(p.password) > 0 {
+		parts = append(parts, "secureon")
+	}
+	return strings.Join(parts, " ")
+}
+
+// interfaceAddr 获取网卡的 IPv4 地址和所在子网的广播地址
+func interfaceAddr(name string) (local, broadcast net.IP, err error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("网卡不存在: %s", name)
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, nil, fmt.Errorf("获取网卡 %s 的地址失败: %v", name, err)
+	}
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		ip := ipNet.IP.To4()
+		if ip == nil {
+			continue
+		}
+		mask := net.IP(ipNet.Mask).To4()
+		if mask == nil {
+			continue
+		}
+		broadcast = make(net.IP, net.IPv4len)
+		for i := range ip {
+			broadcast[i] = ip[i] | ^mask[i]
+		}
+		return ip, broadcast, nil
+	}
+	return nil, nil, fmt.Errorf("网卡 %s 没有 IPv4 地址", name)
+}
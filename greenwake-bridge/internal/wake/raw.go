@@ -0,0 +1,56 @@
+package wake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// etherTypeWOL Wake-on-LAN 以太网帧的类型
+const etherTypeWOL = 0x0842
+
+var broadcastMAC = net.HardwareAddr{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+
+// errRawUnavailable 当前系统或权限不能发送以太网帧
+var errRawUnavailable = errors.New("不能发送以太网帧")
+
+// frameConn 从网卡发送完整的以太网帧
+type frameConn interface {
+	writeFrame(frame []byte) error
+	Close() error
+}
+
+// openFrameConn 打开网卡的以太网帧发送连接，测试时替换为抓包替身
+var openFrameConn = openPacketSocket
+
+// sendRaw 从配置的网卡广播 EtherType 0x0842 的以太网帧，帧内容为唤醒包
+func (p *MagicPacket) sendRaw(ctx context.Context, payload []byte) error {
+	iface, err := net.InterfaceByName(p.iface)
+	if err != nil {
+		return fmt.Errorf("网卡不存在: %s", p.iface)
+	}
+	conn, err := openFrameConn(iface)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	frame := etherFrame(broadcastMAC, iface.HardwareAddr, payload)
+	return p.repeat(ctx, func() error {
+		if err := conn.writeFrame(frame); err != nil {
+			return fmt.Errorf("发送以太网帧失败: %v", err)
+		}
+		return nil
+	})
+}
+
+// etherFrame 生成以太网帧：目标地址、源地址、类型和数据，网卡没有硬件地址时源地址为全0
+func etherFrame(dst, src net.HardwareAddr, payload []byte) []byte {
+	frame := make([]byte, 14, 14+len(payload))
+	copy(frame[0:6], dst)
+	copy(frame[6:12], src)
+	frame[12] = etherTypeWOL >> 8
+	frame[13] = etherTypeWOL & 0xff
+	return append(frame, payload...)
+}
@@ -0,0 +1,77 @@
+package wake
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+const capNetRaw = 13 // CAP_NET_RAW 的编号
+
+// packetSocket AF_PACKET 原始套接字，发送的帧不经过协议栈
+type packetSocket struct {
+	fd   int
+	addr *syscall.SockaddrLinklayer
+}
+
+func openPacketSocket(iface *net.Interface) (frameConn, error) {
+	if !hasNetRaw() {
+		return nil, fmt.Errorf("%w: 缺少 CAP_NET_RAW 权限", errRawUnavailable)
+	}
+
+	fd, err := syscall.Socket(syscall.AF_PACKET, syscall.SOCK_RAW, int(htons(etherTypeWOL)))
+	if errors.Is(err, syscall.EPERM) || errors.Is(err, syscall.EACCES) || errors.Is(err, syscall.EAFNOSUPPORT) {
+		return nil, fmt.Errorf("%w: %v", errRawUnavailable, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("创建 AF_PACKET 套接字失败: %v", err)
+	}
+
+	addr := &syscall.SockaddrLinklayer{
+		Protocol: htons(etherTypeWOL),
+		Ifindex:  iface.Index,
+		Halen:    6,
+	}
+	copy(addr.Addr[:], broadcastMAC)
+	return &packetSocket{fd: fd, addr: addr}, nil
+}
+
+func (s *packetSocket) writeFrame(frame []byte) error {
+	return syscall.Sendto(s.fd, frame, 0, s.addr)
+}
+
+func (s *packetSocket) Close() error {
+	return syscall.Close(s.fd)
+}
+
+// hasNetRaw 检查进程的有效权限集是否包含 CAP_NET_RAW，读取失败时按有权限处理，由创建套接字的结果判断
+func hasNetRaw() bool {
+	f, err := os.Open("/proc/self/status")
+	if err != nil {
+		return true
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		value, ok := strings.CutPrefix(scanner.Text(), "CapEff:")
+		if !ok {
+			continue
+		}
+		caps, err := strconv.ParseUint(strings.TrimSpace(value), 16, 64)
+		if err != nil {
+			return true
+		}
+		return caps&(1<<capNetRaw) != 0
+	}
+	return true
+}
+
+func htons(v uint16) uint16 {
+	return v<<8 | v>>8
+}
@@ -0,0 +1,213 @@
+package wake
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+
+	"greenwake-bridge/internal/config"
+)
+
+const testMAC = "aa:bb:cc:dd:ee:ff"
+
+// captureConn 抓包替身，记录写入的以太网帧
+type captureConn struct {
+	frames [][]byte
+	closed bool
+}
+
+func (c *captureConn) writeFrame(frame []byte) error {
+	c.frames = append(c.frames, append([]byte(nil), frame...))
+	return nil
+}
+
+func (c *captureConn) Close() error {
+	c.closed = true
+	return nil
+}
+
+// stubFrameConn 在测试期间替换 openFrameConn
+func stubFrameConn(t *testing.T, open func(iface *net.Interface) (frameConn, error)) {
+	orig := openFrameConn
+	openFrameConn = open
+	t.Cleanup(func() { openFrameConn = orig })
+}
+
+func newTestPacket(t *testing.T, wol config.WOLConfig) *MagicPacket {
+	p, err := NewMagicPacket(config.PCHostConfig{Name: "pc", MAC: testMAC, WOL: &wol})
+	if err != nil {
+		t.Fatalf("NewMagicPacket: %v", err)
+	}
+	return p
+}
+
+// expectedPayload 6字节 0xFF、16次重复的 MAC 地址和 SecureOn 密码
+func expectedPayload(password []byte) []byte {
+	mac, _ := net.ParseMAC(testMAC)
+	payload := bytes.Repeat([]byte{0xff}, 6)
+	for i := 0; i < 16; i++ {
+		payload = append(payload, mac...)
+	}
+	return append(payload, password...)
+}
+
+func TestSendRawFrame(t *testing.T) {
+	capture := &captureConn{}
+	stubFrameConn(t, func(iface *net.Interface) (frameConn, error) {
+		if iface.Name != "lo" {
+			t.Errorf("网卡 = %s, 应为 lo", iface.Name)
+		}
+		return capture, nil
+	})
+
+	p := newTestPacket(t, config.WOLConfig{
+		Transport:  config.WOLTransportRaw,
+		Interface:  "lo",
+		Count:      2,
+		IntervalMs: 1,
+		Password:   "01:02:03:04:05:06",
+	})
+	if err := p.Wake(context.Background()); err != nil {
+		t.Fatalf("Wake: %v", err)
+	}
+
+	if len(capture.frames) != 2 {
+		t.Fatalf("发送了 %d 个帧, 应为 2", len(capture.frames))
+	}
+	if !capture.closed {
+		t.Error("发送后未关闭连接")
+	}
+
+	lo, err := net.InterfaceByName("lo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	frame := capture.frames[0]
+	if !bytes.Equal(frame[0:6], broadcastMAC) {
+		t.Errorf("目标地址 = %x, 应为广播地址", frame[0:6])
+	}
+	src := make([]byte, 6)
+	copy(src, lo.HardwareAddr)
+	if !bytes.Equal(frame[6:12], src) {
+		t.Errorf("源地址 = %x, 应为 %x", frame[6:12], src)
+	}
+	if frame[12] != 0x08 || frame[13] != 0x42 {
+		t.Errorf("EtherType = %x, 应为 0842", frame[12:14])
+	}
+	if want := expectedPayload([]byte{1, 2, 3, 4, 5, 6}); !bytes.Equal(frame[14:], want) {
+		t.Errorf("唤醒包内容 = %x, 应为 %x", frame[14:], want)
+	}
+}
+
+func TestSendRawFallsBackToUDP(t *testing.T) {
+	stubFrameConn(t, func(iface *net.Interface) (frameConn, error) {
+		return nil, fmt.Errorf("%w: 缺少 CAP_NET_RAW 权限", errRawUnavailable)
+	})
+
+	listener, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	p := newTestPacket(t, config.WOLConfig{
+		Transport: config.WOLTransportRaw,
+		Interface: "lo",
+		Address:   "127.0.0.1",
+		Port:      listener.LocalAddr().(*net.UDPAddr).Port,
+		Password:  "192.168.1.1",
+	})
+	if err := p.Wake(context.Background()); err != nil {
+		t.Fatalf("Wake: %v", err)
+	}
+
+	listener.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 200)
+	n, _, err := listener.ReadFromUDP(buf)
+	if err != nil {
+		t.Fatalf("未收到UDP唤醒包: %v", err)
+	}
+	if want := expectedPayload([]byte{192, 168, 1, 1}); !bytes.Equal(buf[:n], want) {
+		t.Errorf("唤醒包内容 = %x, 应为 %x", buf[:n], want)
+	}
+}
+
+func TestSendRawError(t *testing.T) {
+	stubFrameConn(t, func(iface *net.Interface) (frameConn, error) {
+		return nil, errors.New("创建 AF_PACKET 套接字失败")
+	})
+
+	// 权限以外的错误不改用UDP
+	p := newTestPacket(t, config.WOLConfig{Transport: config.WOLTransportRaw, Interface: "lo"})
+	if err := p.Wake(context.Background()); err == nil {
+		t.Fatal("Wake 应返回错误")
+	}
+}
+
+// TestSendRawVeth 通过 veth 对发送以太网帧，并在对端用 AF_PACKET 套接字接收
+// 需要 CAP_NET_ADMIN 和 CAP_NET_RAW，否则跳过
+func TestSendRawVeth(t *testing.T) {
+	if !hasNetRaw() {
+		t.Skip("缺少 CAP_NET_RAW 权限")
+	}
+	if _, err := exec.LookPath("ip"); err != nil {
+		t.Skip("缺少 ip 命令")
+	}
+
+	const sender, receiver = "gwwake0", "gwwake1"
+	if out, err := exec.Command("ip", "link", "add", sender, "type", "veth", "peer", "name", receiver).CombinedOutput(); err != nil {
+		t.Skipf("无法创建 veth: %v, %s", err, out)
+	}
+	t.Cleanup(func() { exec.Command("ip", "link", "del", sender).Run() })
+	for _, name := range []string{sender, receiver} {
+		if out, err := exec.Command("ip", "link", "set", name, "up").CombinedOutput(); err != nil {
+			t.Fatalf("启用 %s 失败: %v, %s", name, err, out)
+		}
+	}
+
+	rx, err := net.InterfaceByName(receiver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd, err := syscall.Socket(syscall.AF_PACKET, syscall.SOCK_RAW, int(htons(etherTypeWOL)))
+	if err != nil {
+		t.Fatalf("创建接收套接字失败: %v", err)
+	}
+	defer syscall.Close(fd)
+	if err := syscall.Bind(fd, &syscall.SockaddrLinklayer{Protocol: htons(etherTypeWOL), Ifindex: rx.Index}); err != nil {
+		t.Fatalf("绑定接收网卡失败: %v", err)
+	}
+	tv := syscall.NsecToTimeval((2 * time.Second).Nanoseconds())
+	if err := syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &tv); err != nil {
+		t.Fatal(err)
+	}
+
+	p := newTestPacket(t, config.WOLConfig{Transport: config.WOLTransportRaw, Interface: sender})
+	if err := p.Wake(context.Background()); err != nil {
+		t.Fatalf("Wake: %v", err)
+	}
+
+	buf := make([]byte, 1500)
+	n, _, err := syscall.Recvfrom(fd, buf, 0)
+	if err != nil {
+		t.Fatalf("未收到以太网帧: %v", err)
+	}
+	frame := buf[:n]
+	if len(frame) < 14+102 {
+		t.Fatalf("帧长度 = %d, 过短", len(frame))
+	}
+	if !bytes.Equal(frame[0:6], broadcastMAC) || frame[12] != 0x08 || frame[13] != 0x42 {
+		t.Errorf("帧头 = %x, 应为广播地址和 EtherType 0842", frame[0:14])
+	}
+	// 以太网帧最短60字节，唤醒包之后可能有填充
+	if want := expectedPayload(nil); !bytes.Equal(frame[14:14+len(want)], want) {
+		t.Errorf("唤醒包内容 = %x, 应为 %x", frame[14:14+len(want)], want)
+	}
+	t.Logf("收到 %s -> %s 的 %d 字节以太网帧", sender, receiver, n)
+}
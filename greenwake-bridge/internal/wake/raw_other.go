@@ -0,0 +1,13 @@
+//go:build !linux
+
+package wake
+
+import (
+	"fmt"
+	"net"
+)
+
+// openPacketSocket 以太网帧方式仅支持 Linux
+func openPacketSocket(iface *net.Interface) (frameConn, error) {
+	return nil, fmt.Errorf("%w: 仅支持 Linux", errRawUnavailable)
+}
@@ -0,0 +1,168 @@
+package wake
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"greenwake-bridge/internal/config"
+)
+
+const (
+	redfishSystems      = "/redfish/v1/Systems"
+	redfishResetAction  = "#ComputerSystem.Reset"
+	defaultResetType    = "On"
+	maxRedfishBody      = 1 << 20 // 最多读取1MB的响应
+	redfishPowerStateOn = "On"
+)
+
+// redfishWaker 通过 BMC 的 Redfish 接口执行 ComputerSystem.Reset 开机
+type redfishWaker struct {
+	url       string
+	system    string // 计算机系统路径，为空时使用 Systems 中的第一个
+	resetType string
+	user      string
+	password  string
+	client    *http.Client
+}
+
+func newRedfishWaker(m config.WakeMethod) *redfishWaker {
+	resetType := m.ResetType
+	if resetType == "" {
+		resetType = defaultResetType
+	}
+	return &redfishWaker{
+		url:       strings.TrimRight(m.URL, "/"),
+		system:    m.System,
+		resetType: resetType,
+		user:      m.User,
+		password:  m.Password,
+		client:    newHTTPClient(m.Insecure),
+	}
+}
+
+// redfishSystem ComputerSystem 资源中唤醒需要的字段
+type redfishSystem struct {
+	PowerState string `json:"PowerState"`
+	Actions    map[string]struct {
+		Target string `json:"target"`
+	} `json:"Actions"`
+}
+
+func (w *redfishWaker) Wake(ctx context.Context) error {
+	system := w.system
+	if system == "" {
+		var err error
+		if system, err = w.firstSystem(ctx); err != nil {
+			return err
+		}
+	}
+
+	var sys redfishSystem
+	if err := w.do(ctx, http.MethodGet, system, nil, &sys); err != nil {
+		return err
+	}
+	// 已通电的主机执行 On 会失败，返回错误以便尝试下一种唤醒方式
+	if sys.PowerState == redfishPowerStateOn && w.resetType == defaultResetType {
+		return fmt.Errorf("主机已通电 (PowerState: On)")
+	}
+
+	// 优先使用资源中声明的操作地址
+	target := system + "/Actions/ComputerSystem.Reset"
+	if action, ok := sys.Actions[redfishResetAction]; ok && action.Target != "" {
+		target = action.Target
+	}
+	return w.do(ctx, http.MethodPost, target, map[string]string{"ResetType": w.resetType}, nil)
+}
+
+// firstSystem 获取 Systems 集合中的第一个计算机系统
+func (w *redfishWaker) firstSystem(ctx context.Context) (string, error) {
+	var systems struct {
+		Members []struct {
+			ID string `json:"@odata.id"`
+		} `json:"Members"`
+	}
+	if err := w.do(ctx, http.MethodGet, redfishSystems, nil, &systems); err != nil {
+		return "", err
+	}
+	if len(systems.Members) == 0 || systems.Members[0].ID == "" {
+		return "", fmt.Errorf("BMC 没有计算机系统")
+	}
+	return systems.Members[0].ID, nil
+}
+
+// do 发送 Redfish 请求，out 不为空时解析响应内容
+func (w *redfishWaker) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, w.url+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("OData-Version", "4.0")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if w.user != "" {
+		req.SetBasicAuth(w.user, w.password)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("请求 BMC 失败: %v", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxRedfishBody))
+	if err != nil {
+		return fmt.Errorf("读取 BMC 响应失败: %v", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s %s: %s", method, path, redfishError(resp.StatusCode, data))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("%s %s: 响应格式错误: %v", method, path, err)
+	}
+	return nil
+}
+
+// redfishError 从 Redfish 错误响应中取出错误信息
+func redfishError(status int, data []byte) string {
+	var resp struct {
+		Error struct {
+			Message      string `json:"message"`
+			ExtendedInfo []struct {
+				Message string `json:"Message"`
+			} `json:"@Message.ExtendedInfo"`
+		} `json:"error"`
+	}
+	msg := ""
+	if json.Unmarshal(data, &resp) == nil {
+		msg = resp.Error.Message
+		if len(resp.Error.ExtendedInfo) > 0 && resp.Error.ExtendedInfo[0].Message != "" {
+			msg = resp.Error.ExtendedInfo[0].Message
+		}
+	}
+	if msg == "" {
+		msg = http.StatusText(status)
+	}
+	return fmt.Sprintf("%d %s", status, msg)
+}
+
+func (w *redfishWaker) String() string {
+	return "redfish:" + w.url + w.system
+}
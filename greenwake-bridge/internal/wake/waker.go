@@ -0,0 +1,163 @@
+package wake
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"greenwake-bridge/internal/config"
+)
+
+// Waker 一种唤醒主机的方式
+type Waker interface {
+	// Wake 唤醒主机，返回 nil 只表示请求已发出，主机是否上线由在线检测判断
+	Wake(ctx context.Context) error
+	// String 返回用于日志展示的唤醒方式描述
+	String() string
+}
+
+// New 根据主机配置创建唤醒方式，未配置 wake 时只使用 packet
+// packet 为 wol 方式发送唤醒包的实现，由调用者按是否使用中继提供
+func New(host config.PCHostConfig, packet Waker) (*Chain, error) {
+	methods := host.Wake
+	if len(methods) == 0 {
+		methods = []config.WakeMethod{{Type: config.WakeWOL}}
+	}
+
+	wakers := make([]Waker, 0, len(methods))
+	for i, m := range methods {
+		w, err := newMethod(host, m, packet)
+		if err != nil {
+			return nil, fmt.Errorf("wake[%d]: %v", i, err)
+		}
+		timeout := m.Timeout
+		if timeout <= 0 {
+			timeout = config.DefaultWakeMethodTimeout
+		}
+		wakers = append(wakers, &timeoutWaker{Waker: w, timeout: time.Duration(timeout) * time.Second})
+	}
+	return &Chain{host: host.Name, wakers: wakers}, nil
+}
+
+func newMethod(host config.PCHostConfig, m config.WakeMethod, packet Waker) (Waker, error) {
+	switch m.Type {
+	case config.WakeWOL:
+		if packet == nil {
+			return nil, fmt.Errorf("wol 唤醒不可用")
+		}
+		return packet, nil
+	case config.WakeRedfish:
+		if m.URL == "" {
+			return nil, fmt.Errorf("redfish 唤醒缺少 url")
+		}
+		return newRedfishWaker(m), nil
+	case config.WakeHTTP:
+		if m.URL == "" {
+			return nil, fmt.Errorf("http 唤醒缺少 url")
+		}
+		return newHTTPWaker(m), nil
+	case config.WakeCommand:
+		if len(m.Command) == 0 {
+			return nil, fmt.Errorf("command 唤醒缺少 command")
+		}
+		return &commandWaker{host: host, command: m.Command}, nil
+	default:
+		return nil, fmt.Errorf("未知的唤醒方式: %s", m.Type)
+	}
+}
+
+// newHTTPClient 创建 redfish 和 http 唤醒使用的客户端，超时由 ctx 控制
+func newHTTPClient(insecure bool) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if insecure {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	return &http.Client{Transport: transport}
+}
+
+// timeoutWaker 为单个唤醒方式设置超时时间
+type timeoutWaker struct {
+	Waker
+	timeout time.Duration
+}
+
+func (w *timeoutWaker) Wake(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, w.timeout)
+	defer cancel()
+	return w.Waker.Wake(ctx)
+}
+
+// Chain 按顺序使用的多种唤醒方式
+// 某种方式失败时立即尝试下一种；某种方式成功后主机仍未上线而再次唤醒时，从下一种开始，
+// 例如断电后不响应唤醒包的主机在重试时改用 BMC 开机
+type Chain struct {
+	host   string
+	wakers []Waker
+	mu     sync.Mutex
+	next   int // 下次唤醒开始使用的方式
+}
+
+// Wake 从上次成功的下一种方式开始依次尝试，直到有一种成功，返回成功的方式
+// 已经是最后一种时继续从最后一种开始，全部失败时返回各方式的错误
+func (c *Chain) Wake(ctx context.Context) (Waker, error) {
+	c.mu.Lock()
+	start := c.next
+	c.mu.Unlock()
+	return c.wake(ctx, start, true)
+}
+
+// Keep 主机在线或保持唤醒时重发使用，从第一种方式开始尝试，不改变下次唤醒使用的方式
+func (c *Chain) Keep(ctx context.Context) (Waker, error) {
+	return c.wake(ctx, 0, false)
+}
+
+// wake 从 start 开始依次尝试，advance 为 true 时成功后下次从下一种方式开始
+func (c *Chain) wake(ctx context.Context, start int, advance bool) (Waker, error) {
+	var errs []string
+	for i := 0; i < len(c.wakers); i++ {
+		index := (start + i) % len(c.wakers)
+		w := c.wakers[index]
+		err := w.Wake(ctx)
+		if err == nil {
+			if !advance {
+				return w, nil
+			}
+			c.mu.Lock()
+			c.next = index + 1
+			if c.next >= len(c.wakers) {
+				c.next = len(c.wakers) - 1
+			}
+			c.mu.Unlock()
+			return w, nil
+		}
+		errs = append(errs, fmt.Sprintf("%s: %v", w, err))
+		if ctx.Err() != nil {
+			break
+		}
+		if i+1 < len(c.wakers) {
+			log.Printf("主机 %s 唤醒方式 %s 失败，尝试下一种: %v", c.host, w, err)
+		}
+	}
+	return nil, fmt.Errorf("%s", strings.Join(errs, "; "))
+}
+
+// Reset 主机上线后调用，下次唤醒从第一种方式开始
+func (c *Chain) Reset() {
+	c.mu.Lock()
+	c.next = 0
+	c.mu.Unlock()
+}
+
+// String 返回用于日志展示的唤醒方式，如 udp 255.255.255.255:9 -> redfish:https://10.0.0.5
+func (c *Chain) String() string {
+	names := make([]string, 0, len(c.wakers))
+	for _, w := range c.wakers {
+		names = append(names, w.String())
+	}
+	return strings.Join(names, " -> ")
+}
@@ -0,0 +1,285 @@
+package wake
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+
+	"greenwake-bridge/internal/config"
+)
+
+// fakeWaker 记录调用次数，返回预设的错误
+type fakeWaker struct {
+	name  string
+	err   error
+	calls int
+}
+
+func (w *fakeWaker) Wake(ctx context.Context) error {
+	w.calls++
+	return w.err
+}
+
+func (w *fakeWaker) String() string {
+	return w.name
+}
+
+// fakeBMC Redfish 接口替身，记录收到的开机请求
+type fakeBMC struct {
+	mu         sync.Mutex
+	powerState string
+	resets     []string
+	auth       []string
+}
+
+func (b *fakeBMC) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/redfish/v1/Systems", func(w http.ResponseWriter, r *http.Request) {
+		b.record(r)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"Members": []map[string]string{{"@odata.id": "/redfish/v1/Systems/System.Embedded.1"}},
+		})
+	})
+	mux.HandleFunc("/redfish/v1/Systems/System.Embedded.1", func(w http.ResponseWriter, r *http.Request) {
+		b.record(r)
+		b.mu.Lock()
+		state := b.powerState
+		b.mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"PowerState": state,
+			"Actions": map[string]interface{}{
+				"#ComputerSystem.Reset": map[string]string{
+					"target": "/redfish/v1/Systems/System.Embedded.1/Actions/ComputerSystem.Reset",
+				},
+			},
+		})
+	})
+	mux.HandleFunc("/redfish/v1/Systems/System.Embedded.1/Actions/ComputerSystem.Reset", func(w http.ResponseWriter, r *http.Request) {
+		b.record(r)
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		var req struct {
+			ResetType string `json:"ResetType"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if req.ResetType == "ForceOff" {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, `{"error":{"code":"Base.1.8.GeneralError","message":"A general error has occurred.",`+
+				`"@Message.ExtendedInfo":[{"Message":"The value ForceOff is not allowed."}]}}`)
+			return
+		}
+		b.mu.Lock()
+		b.resets = append(b.resets, req.ResetType)
+		b.powerState = "On"
+		b.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return mux
+}
+
+func (b *fakeBMC) record(r *http.Request) {
+	user, password, _ := r.BasicAuth()
+	b.mu.Lock()
+	b.auth = append(b.auth, user+":"+password)
+	b.mu.Unlock()
+}
+
+func TestRedfishWake(t *testing.T) {
+	bmc := &fakeBMC{powerState: "Off"}
+	srv := httptest.NewTLSServer(bmc.handler())
+	defer srv.Close()
+
+	w := newRedfishWaker(config.WakeMethod{
+		Type:     config.WakeRedfish,
+		URL:      srv.URL + "/",
+		User:     "root",
+		Password: "calvin",
+		Insecure: true,
+	})
+	if err := w.Wake(context.Background()); err != nil {
+		t.Fatalf("Wake: %v", err)
+	}
+	if len(bmc.resets) != 1 || bmc.resets[0] != "On" {
+		t.Fatalf("开机请求 = %v, 应为 [On]", bmc.resets)
+	}
+	for _, auth := range bmc.auth {
+		if auth != "root:calvin" {
+			t.Fatalf("认证信息 = %s, 应为 root:calvin", auth)
+		}
+	}
+
+	// 已通电时不再发送开机请求
+	err := w.Wake(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "PowerState: On") {
+		t.Fatalf("已通电时 Wake = %v, 应返回已通电错误", err)
+	}
+	if len(bmc.resets) != 1 {
+		t.Fatalf("已通电时仍发送了开机请求: %v", bmc.resets)
+	}
+}
+
+func TestRedfishWakeError(t *testing.T) {
+	bmc := &fakeBMC{powerState: "On"}
+	srv := httptest.NewTLSServer(bmc.handler())
+	defer srv.Close()
+
+	w := newRedfishWaker(config.WakeMethod{
+		Type:      config.WakeRedfish,
+		URL:       srv.URL,
+		System:    "/redfish/v1/Systems/System.Embedded.1",
+		ResetType: "ForceOff",
+		Insecure:  true,
+	})
+	err := w.Wake(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "400 The value ForceOff is not allowed.") {
+		t.Fatalf("Wake = %v, 应返回 BMC 的错误信息", err)
+	}
+
+	// 默认校验证书，自签名证书的 BMC 需要 insecure
+	w = newRedfishWaker(config.WakeMethod{Type: config.WakeRedfish, URL: srv.URL})
+	if err := w.Wake(context.Background()); err == nil {
+		t.Fatal("未设置 insecure 时应拒绝自签名证书")
+	}
+}
+
+func TestHTTPWake(t *testing.T) {
+	var gotMethod, gotAuth, gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod = r.Method
+		gotAuth = r.Header.Get("Authorization")
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		if r.URL.Path != "/api/services/switch/turn_on" {
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, "not found")
+			return
+		}
+		w.Write([]byte("[]"))
+	}))
+	defer srv.Close()
+
+	w := newHTTPWaker(config.WakeMethod{
+		Type:    config.WakeHTTP,
+		URL:     srv.URL + "/api/services/switch/turn_on",
+		Headers: map[string]string{"Authorization": "Bearer token"},
+		Body:    `{"entity_id": "switch.office_pc"}`,
+	})
+	if err := w.Wake(context.Background()); err != nil {
+		t.Fatalf("Wake: %v", err)
+	}
+	if gotMethod != http.MethodPost || gotAuth != "Bearer token" || gotBody != `{"entity_id": "switch.office_pc"}` {
+		t.Fatalf("请求 = %s %q %q", gotMethod, gotAuth, gotBody)
+	}
+
+	w = newHTTPWaker(config.WakeMethod{Type: config.WakeHTTP, Method: "get", URL: srv.URL + "/relay/0?turn=on"})
+	err := w.Wake(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "状态码 404: not found") {
+		t.Fatalf("Wake = %v, 应返回状态码错误", err)
+	}
+	if gotMethod != http.MethodGet {
+		t.Fatalf("请求方法 = %s, 应为 GET", gotMethod)
+	}
+}
+
+func TestCommandWake(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("没有 sh")
+	}
+	host := config.PCHostConfig{Name: "pc", MAC: "aa:bb:cc:dd:ee:ff"}
+
+	w := &commandWaker{host: host, command: []string{"sh", "-c", `test "$GREENWAKE_HOST" = pc`}}
+	if err := w.Wake(context.Background()); err != nil {
+		t.Fatalf("Wake: %v", err)
+	}
+
+	w = &commandWaker{host: host, command: []string{"sh", "-c", "echo plug offline >&2; exit 3"}}
+	err := w.Wake(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "plug offline") {
+		t.Fatalf("Wake = %v, 应返回命令输出", err)
+	}
+}
+
+func TestChainFallback(t *testing.T) {
+	first := &fakeWaker{name: "first", err: errors.New("broken")}
+	second := &fakeWaker{name: "second"}
+	chain := &Chain{host: "pc", wakers: []Waker{first, second}}
+
+	used, err := chain.Wake(context.Background())
+	if err != nil || used != second {
+		t.Fatalf("Wake = %v, %v, 应使用 second", used, err)
+	}
+
+	second.err = errors.New("down")
+	_, err = chain.Wake(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "first: broken") || !strings.Contains(err.Error(), "second: down") {
+		t.Fatalf("Wake = %v, 应包含全部方式的错误", err)
+	}
+}
+
+func TestChainEscalates(t *testing.T) {
+	packet := &fakeWaker{name: "wol"}
+	bmc := &fakeWaker{name: "redfish"}
+	chain := &Chain{host: "pc", wakers: []Waker{packet, bmc}}
+
+	// 唤醒包发出后主机仍未上线，再次唤醒时改用下一种，之后一直使用最后一种
+	for i, want := range []Waker{packet, bmc, bmc} {
+		used, err := chain.Wake(context.Background())
+		if err != nil || used != want {
+			t.Fatalf("第%d次 Wake = %v, %v, 应使用 %s", i+1, used, err, want)
+		}
+	}
+
+	chain.Reset()
+	if used, _ := chain.Wake(context.Background()); used != packet {
+		t.Fatalf("Reset 后 Wake 使用 %v, 应使用 wol", used)
+	}
+}
+
+func TestChainKeepDoesNotEscalate(t *testing.T) {
+	packet := &fakeWaker{name: "wol"}
+	bmc := &fakeWaker{name: "redfish"}
+	chain := &Chain{host: "pc", wakers: []Waker{packet, bmc}}
+
+	// 主机在线时重发唤醒总是使用第一种，之后离线唤醒仍从第一种开始
+	for i := 0; i < 3; i++ {
+		if used, err := chain.Keep(context.Background()); err != nil || used != packet {
+			t.Fatalf("第%d次 Keep = %v, %v, 应使用 wol", i+1, used, err)
+		}
+	}
+	if used, _ := chain.Wake(context.Background()); used != packet {
+		t.Fatalf("Keep 后 Wake 使用 %v, 应使用 wol", used)
+	}
+
+	packet.err = errors.New("broken")
+	if used, err := chain.Keep(context.Background()); err != nil || used != bmc {
+		t.Fatalf("Keep = %v, %v, 第一种失败时应使用 redfish", used, err)
+	}
+}
+
+func TestNewDefaultsToPacket(t *testing.T) {
+	packet := &fakeWaker{name: "wol"}
+	chain, err := New(config.PCHostConfig{Name: "pc"}, packet)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if chain.String() != "wol" {
+		t.Fatalf("String = %s, 应为 wol", chain.String())
+	}
+
+	_, err = New(config.PCHostConfig{Name: "pc", Wake: []config.WakeMethod{{Type: "ipmi"}}}, packet)
+	if err == nil || !strings.Contains(err.Error(), "wake[0]") {
+		t.Fatalf("New = %v, 应拒绝未知的唤醒方式", err)
+	}
+}
@@ -1,10 +1,37 @@
-import React from 'react';
+import React, { useEffect, useState } from 'react';
+import { Spin } from 'antd';
 import RemoteControl from './pages/RemoteControl';
+import Login from './pages/Login';
+import { authApi, onUnauthorized } from './services';
 
 const App: React.FC = () => {
+  const [auth, setAuth] = useState<AuthInfo | null>(null);
+  const [checking, setChecking] = useState(true);
+
+  useEffect(() => {
+    onUnauthorized(() => setAuth(null));
+    authApi.me()
+      .then(info => setAuth(info))
+      .catch(() => setAuth(null))
+      .finally(() => setChecking(false));
+  }, []);
+
+  const handleLogout = async () => {
+    await authApi.logout();
+    setAuth(null);
+  };
+
+  if (checking) {
+    return <Spin style={{ display: 'block', marginTop: '120px' }} />;
+  }
+
+  if (!auth) {
+    return <Login onLogin={setAuth} />;
+  }
+
   return (
-    <RemoteControl />
+    <RemoteControl auth={auth} onLogout={handleLogout} />
   );
 };
 
-export default App; 
\ No newline at end of file
+export default App;
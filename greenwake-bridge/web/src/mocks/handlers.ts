@@ -2,9 +2,30 @@ import { http, HttpResponse } from 'msw';
 
 type PathParams = {
   hostName: string;
+  leaseId?: string;
 }
 
 export const handlers = [
+  // 登录相关接口
+  http.get('/api/auth/me', () => {
+    return HttpResponse.json({
+      success: true,
+      data: { user: 'admin', anonymous: false }
+    });
+  }),
+
+  http.post('/api/auth/login', async ({ request }) => {
+    const { user } = await request.json() as { user: string };
+    return HttpResponse.json({
+      success: true,
+      data: { user, anonymous: false }
+    });
+  }),
+
+  http.post('/api/auth/logout', () => {
+    return HttpResponse.json({ success: true });
+  }),
+
   // 主机列表接口
   http.get('/api/pc/hosts', () => {
     return HttpResponse.json({
@@ -27,33 +48,134 @@ export const handlers = [
   }),
 
   // 主机状态查询接口
-  http.get<PathParams>('/api/pc/:hostName/status', ({ params, request }) => {
-    const url = new URL(request.url);
-    const keepAwake = url.searchParams.get('keepAwake') === 'true';
-
+  http.get<PathParams>('/api/pc/:hostName/status', ({ params }) => {
     return HttpResponse.json({
       success: true,
       data: {
         name: params.hostName,
         isOnline: true,
-        keepAwake: keepAwake,
-        lastUpdate: new Date().toISOString(),
-        lastWakeTime: keepAwake ? new Date().toISOString() : undefined
+        state: 'online',
+        stateSince: new Date().toISOString(),
+        keepAwake: false,
+        lastUpdate: new Date().toISOString()
       }
     });
   }),
 
-  // 主机客户端信息接口
-  http.get('/api/pc/:hostName/client_info', ({ }) => {
-    return HttpResponse.json([
-      {
-        id: '1',
-        userAgent: 'Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7)',
-        ip: '192.168.1.10',
-        port: '52100',
-        lastSeen: new Date().toISOString()
+  // 唤醒和保持唤醒接口
+  http.post<PathParams>('/api/pc/:hostName/wake', ({ params }) => {
+    return HttpResponse.json({
+      success: true,
+      data: { name: params.hostName, sentAt: new Date().toISOString() }
+    });
+  }),
+
+  http.post<PathParams>('/api/pc/:hostName/keep-awake', async ({ params, request }) => {
+    const { ttl } = await request.json() as { ttl: number };
+    const now = new Date();
+    return HttpResponse.json({
+      success: true,
+      data: {
+        id: crypto.randomUUID().replace(/-/g, '').slice(0, 16),
+        host: params.hostName,
+        owner: 'user:admin',
+        ttl,
+        renewedAt: now.toISOString(),
+        createdAt: now.toISOString(),
+        expiresAt: new Date(now.getTime() + ttl * 1000).toISOString()
       }
-    ]);
+    });
+  }),
+
+  http.delete('/api/pc/:hostName/keep-awake/:leaseId', () => {
+    return HttpResponse.json({ success: true });
+  }),
+
+  // 主机保持唤醒租约接口
+  http.get<PathParams>('/api/pc/:hostName/leases', ({ params }) => {
+    const now = new Date();
+    return HttpResponse.json({
+      success: true,
+      data: [
+        {
+          id: 'a1b2c3d4e5f60718',
+          host: params.hostName,
+          owner: 'user:admin',
+          reason: '网页保持唤醒',
+          clientIp: '192.168.1.10',
+          userAgent: 'Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7)',
+          ttl: 90,
+          createdAt: now.toISOString(),
+          renewedAt: now.toISOString(),
+          expiresAt: new Date(now.getTime() + 90 * 1000).toISOString()
+        }
+      ]
+    });
+  }),
+
+  http.post<PathParams>('/api/pc/:hostName/keep-awake/:leaseId/renew', ({ params }) => {
+    const now = new Date();
+    return HttpResponse.json({
+      success: true,
+      data: {
+        id: params.leaseId,
+        host: params.hostName,
+        owner: 'user:admin',
+        ttl: 90,
+        createdAt: now.toISOString(),
+        renewedAt: now.toISOString(),
+        expiresAt: new Date(now.getTime() + 90 * 1000).toISOString()
+      }
+    });
+  }),
+
+  // 转发会话记录接口
+  http.get('/api/sessions', ({ request }) => {
+    const host = new URL(request.url).searchParams.get('host') || 'home-pc';
+    const end = new Date();
+    const start = new Date(end.getTime() - 25 * 60 * 1000);
+    return HttpResponse.json({
+      success: true,
+      data: {
+        total: 1,
+        sessions: [
+          {
+            id: 'a1b2c3d4e5f60708',
+            channel: `13389-${host}:3389`,
+            service_port: 13389,
+            target_host: host,
+            target_port: 3389,
+            client_ip: '192.168.1.20',
+            client_port: 52344,
+            start_time: start.toISOString(),
+            end_time: end.toISOString(),
+            wake_needed: true,
+            wake_duration_ms: 8200,
+            bytes_to_target: 52340,
+            bytes_to_client: 7340032,
+            close_reason: 'client_closed'
+          }
+        ]
+      }
+    });
+  }),
+
+  // 事件记录接口
+  http.get('/api/events', ({ request }) => {
+    const host = new URL(request.url).searchParams.get('host') || 'home-pc';
+    const now = Date.now();
+    const at = (minutes: number) => new Date(now - minutes * 60 * 1000).toISOString();
+    return HttpResponse.json({
+      success: true,
+      data: {
+        total: 3,
+        events: [
+          { id: 3, type: 'wake.finished', host, actor: `forward:13389-${host}:3389`, time: at(24), data: { actor: `forward:13389-${host}:3389`, result: 'success', durationMs: 8200 } },
+          { id: 2, type: 'wake.sent', host, actor: `forward:13389-${host}:3389`, time: at(25), data: { actor: `forward:13389-${host}:3389`, method: 'wol' } },
+          { id: 1, type: 'host.state', host, time: at(90), data: { from: 'online', to: 'offline' } }
+        ]
+      }
+    });
   }),
 
   // 主机转发通道接口
@@ -65,7 +187,16 @@ export const handlers = [
         servicePort: hostName === 'home-pc' ? 13389 : 10022,
         targetPort: hostName === 'home-pc' ? 3389 : 22,
         status: 'active',
-        lastActive: new Date().toISOString()
+        lastActive: new Date().toISOString(),
+        active_count: 1,
+        total_sessions: 12,
+        failed_sessions: 1,
+        wake_timeouts: 1,
+        dial_failures: 0,
+        bytes_to_target: 52340,
+        bytes_to_client: 7340032,
+        wakes: 3,
+        avg_wake_latency_ms: 8200
       }
     ]);
   })
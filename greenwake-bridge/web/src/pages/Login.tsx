@@ -0,0 +1,51 @@
+import React, { useState } from 'react';
+import { Button, Card, Form, Input, Typography, message } from 'antd';
+import { LockOutlined, UserOutlined } from '@ant-design/icons';
+import { AxiosError } from 'axios';
+import { authApi, APIError } from '../services';
+
+const { Title } = Typography;
+
+interface LoginProps {
+  onLogin: (info: AuthInfo) => void;
+}
+
+const Login: React.FC<LoginProps> = ({ onLogin }) => {
+  const [submitting, setSubmitting] = useState(false);
+
+  const handleFinish = async (values: { user: string; password: string }) => {
+    setSubmitting(true);
+    try {
+      const info = await authApi.login(values.user, values.password);
+      onLogin(info);
+    } catch (err) {
+      const error = err as AxiosError<APIError>;
+      message.error(`登录失败: ${error.response?.data?.error || error.message}`);
+    } finally {
+      setSubmitting(false);
+    }
+  };
+
+  return (
+    <div style={{ display: 'flex', justifyContent: 'center', paddingTop: '120px' }}>
+      <Card style={{ width: 360 }}>
+        <Title level={3} style={{ textAlign: 'center' }}>远程PC控制面板</Title>
+        <Form onFinish={handleFinish} autoComplete="on">
+          <Form.Item name="user" rules={[{ required: true, message: '请输入用户名' }]}>
+            <Input prefix={<UserOutlined />} placeholder="用户名" autoComplete="username" />
+          </Form.Item>
+          <Form.Item name="password" rules={[{ required: true, message: '请输入密码' }]}>
+            <Input.Password prefix={<LockOutlined />} placeholder="密码" autoComplete="current-password" />
+          </Form.Item>
+          <Form.Item>
+            <Button type="primary" htmlType="submit" loading={submitting} block>
+              登录
+            </Button>
+          </Form.Item>
+        </Form>
+      </Card>
+    </div>
+  );
+};
+
+export default Login;
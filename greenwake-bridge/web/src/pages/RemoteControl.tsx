@@ -1,6 +1,6 @@
-import React, { useEffect, useState } from 'react';
-import { Card, Switch, Table, Tag, Typography, Button, Tooltip, Collapse, message } from 'antd';
-import { SyncOutlined } from '@ant-design/icons';
+import React, { useEffect, useRef, useState } from 'react';
+import { Card, Switch, Table, Tag, Typography, Button, Tooltip, Collapse, Dropdown, Modal, message } from 'antd';
+import { DownOutlined, LogoutOutlined, PoweroffOutlined, SyncOutlined } from '@ant-design/icons';
 import { pcStatusApi, APIError } from '../services';
 import { parseUserAgent } from '../utils/userAgent';
 import { AxiosError } from 'axios';
@@ -32,15 +32,165 @@ const formatTimeAgo = (dateStr: string) => {
   }
 };
 
-const RemoteControl: React.FC = () => {
+const formatBytes = (bytes: number) => {
+  const units = ['B', 'KB', 'MB', 'GB', 'TB'];
+  let value = bytes || 0;
+  let unit = 0;
+  while (value >= 1024 && unit < units.length - 1) {
+    value /= 1024;
+    unit++;
+  }
+  return `${unit === 0 ? value : value.toFixed(1)} ${units[unit]}`;
+};
+
+const hostStateLabels: Record<string, string> = {
+  unknown: '检测中',
+  online: '在线',
+  offline: '离线',
+  waking: '唤醒中',
+};
+
+const hostStateColors: Record<string, string> = {
+  unknown: 'default',
+  online: 'green',
+  offline: 'red',
+  waking: 'orange',
+};
+
+const guardStrategyLabels: Record<string, string> = {
+  external_wake: '外部唤醒',
+  permanent: '永久唤醒',
+  timed: '定时唤醒',
+};
+
+const closeReasonLabels: Record<string, string> = {
+  client_closed: '客户端关闭',
+  target_closed: '主机关闭',
+  wake_timeout: '唤醒超时',
+  dial_failed: '连接失败',
+  host_not_found: '主机不存在',
+};
+
+const EVENT_PAGE_SIZE = 20;
+
+const eventTypeLabels: Record<string, string> = {
+  'host.state': '状态变化',
+  'wake.sent': '发送唤醒',
+  'wake.finished': '唤醒结束',
+  'sleep.sent': '远程睡眠',
+  'guard.state': 'guard 状态',
+  'guard.demand': '使用状态',
+  'session.opened': '会话开始',
+  'session.closed': '会话结束',
+  'lease.acquired': '开始保持唤醒',
+  'lease.released': '结束保持唤醒',
+  'lease.expired': '保持唤醒过期',
+  'config.changed': '修改配置',
+};
+
+// 显示事件的发起者，如 user:admin 显示为 用户 admin，keep-awake:token:ha 显示为 保持唤醒（令牌 ha）
+const formatActor = (actor?: string): string => {
+  if (!actor) {
+    return '-';
+  }
+  const [kind, ...rest] = actor.split(':');
+  const name = rest.join(':');
+  switch (kind) {
+    case 'user':
+      return `用户 ${name}`;
+    case 'token':
+      return `令牌 ${name}`;
+    case 'anonymous':
+      return '匿名';
+    case 'forward':
+      return `转发 ${name}`;
+    case 'proxy':
+      return `反向代理 ${name}`;
+    case 'keep-awake':
+      return `保持唤醒（${formatActor(name)}）`;
+    default:
+      return actor;
+  }
+};
+
+// 事件的详细内容
+const describeEvent = (event: HostEvent): string => {
+  const data = (event.data || {}) as Record<string, unknown>;
+  switch (event.type) {
+    case 'host.state':
+      return `${hostStateLabels[data.from as string] || data.from} → ${hostStateLabels[data.to as string] || data.to}`;
+    case 'wake.sent':
+      return `方式: ${data.method}`;
+    case 'wake.finished':
+      return data.result === 'success'
+        ? `主机上线，耗时 ${((data.durationMs as number) / 1000).toFixed(1)} 秒`
+        : '等待上线超时';
+    case 'sleep.sent':
+      return `操作: ${data.action}`;
+    case 'guard.state':
+      return `${data.guard} ${data.from} → ${data.to}`;
+    case 'guard.demand':
+      return data.inUse ? `使用中，转发会话 ${data.sessions} 个，保持唤醒租约 ${data.leases} 个` : '不再使用';
+    case 'session.opened':
+    case 'session.closed': {
+      const session = data as unknown as ForwardSession;
+      const reason = event.type === 'session.closed' ? `，${closeReasonLabels[session.close_reason] || session.close_reason}` : '';
+      return `${session.client_ip}:${session.client_port} → ${session.service_port}${reason}`;
+    }
+    case 'lease.acquired':
+    case 'lease.released':
+    case 'lease.expired': {
+      const lease = data as unknown as KeepAwakeLease;
+      return lease.reason ? `原因: ${lease.reason}` : '';
+    }
+    case 'config.changed':
+      return `${data.action} ${data.target}`;
+    default:
+      return '';
+  }
+};
+
+interface RemoteControlProps {
+  auth: AuthInfo;
+  onLogout: () => void;
+}
+
+const RemoteControl: React.FC<RemoteControlProps> = ({ auth, onLogout }) => {
   const [hosts, setHosts] = useState<PCHostInfo[]>([]);
   const [hostStatuses, setHostStatuses] = useState<Record<string, PCHostStatus>>({});
-  const [hostClients, setHostClients] = useState<Record<string, ClientInfo[]>>({});
+  const [hostLeases, setHostLeases] = useState<Record<string, KeepAwakeLease[]>>({});
   const [hostChannels, setHostChannels] = useState<Record<string, ForwardChannel[]>>({});
+  const [hostSessions, setHostSessions] = useState<Record<string, ForwardSession[]>>({});
+  const [hostEvents, setHostEvents] = useState<Record<string, { total: number; events: HostEvent[] }>>({});
+  const eventPages = useRef<Record<string, number>>({}); // 每个主机事件记录的当前页，刷新时保持
   const [countdowns, setCountdowns] = useState<Record<string, number>>({});
   const [refreshingHosts, setRefreshingHosts] = useState<Record<string, boolean>>({});
   const [loadingHosts, setLoadingHosts] = useState<Record<string, boolean>>({});
   const [refreshInterval, setRefreshInterval] = useState<number>(30); // 默认30秒
+  const [keepAwakeLeases, setKeepAwakeLeases] = useState<Record<string, string>>(pcStatusApi.getKeepAwakeSettings());
+  const [wakingHosts, setWakingHosts] = useState<Record<string, boolean>>({});
+  const [sleepingHosts, setSleepingHosts] = useState<Record<string, boolean>>({});
+  const [relays, setRelays] = useState<Record<string, RelayStatus>>({});
+
+  // 租约有效期为刷新间隔的3倍，页面关闭后租约自动过期
+  const keepAwakeTTL = () => refreshInterval * 3;
+
+  // 续期本页面持有的保持唤醒租约，租约已失效（如被他人结束）时取消本地记录
+  const renewKeepAwake = async (hostName: string) => {
+    const leaseId = pcStatusApi.getKeepAwakeSettings()[hostName];
+    if (!leaseId) {
+      return;
+    }
+    try {
+      await pcStatusApi.renewKeepAwake(hostName, leaseId);
+    } catch (err) {
+      const error = err as AxiosError<APIError>;
+      if (error.response?.status === 404) {
+        pcStatusApi.setLocalKeepAwake(hostName);
+        setKeepAwakeLeases(pcStatusApi.getKeepAwakeSettings());
+      }
+    }
+  };
 
   // 获取配置信息
   useEffect(() => {
@@ -60,20 +210,31 @@ const RemoteControl: React.FC = () => {
     loadConfig();
   }, []);
 
+  // 获取主机当前页的事件记录
+  const fetchHostEvents = (hostName: string) => {
+    const page = eventPages.current[hostName] || 1;
+    return pcStatusApi.getHostEvents(hostName, (page - 1) * EVENT_PAGE_SIZE, EVENT_PAGE_SIZE)
+      .then(result => {
+        setHostEvents(prev => ({ ...prev, [hostName]: { total: result?.total || 0, events: result?.events || [] } }));
+      });
+  };
+
   // 获取单个主机的状态和相关信息
-  const fetchHostData = async (hostName: string, keepAwake?: boolean) => {
+  const fetchHostData = async (hostName: string) => {
     try {
-      // 分别发送三个请求
-      const statusPromise = pcStatusApi.getHostStatus(hostName, keepAwake)
+      await renewKeepAwake(hostName);
+
+      // 分别发送各项请求
+      const statusPromise = pcStatusApi.getHostStatus(hostName)
         .then(status => {
           if (status) {
             setHostStatuses(prev => ({ ...prev, [hostName]: status }));
           }
         });
 
-      const clientsPromise = pcStatusApi.getHostClients(hostName)
-        .then(clients => {
-          setHostClients(prev => ({ ...prev, [hostName]: clients || [] }));
+      const leasesPromise = pcStatusApi.getHostLeases(hostName)
+        .then(leases => {
+          setHostLeases(prev => ({ ...prev, [hostName]: leases || [] }));
         });
 
       const channelsPromise = pcStatusApi.getHostChannels(hostName)
@@ -81,7 +242,14 @@ const RemoteControl: React.FC = () => {
           setHostChannels(prev => ({ ...prev, [hostName]: channels || [] }));
         });
 
-      await Promise.all([statusPromise, clientsPromise, channelsPromise]);
+      const sessionsPromise = pcStatusApi.getHostSessions(hostName)
+        .then(result => {
+          setHostSessions(prev => ({ ...prev, [hostName]: result?.sessions || [] }));
+        });
+
+      const eventsPromise = fetchHostEvents(hostName);
+
+      await Promise.all([statusPromise, leasesPromise, channelsPromise, sessionsPromise, eventsPromise]);
       setCountdowns(prev => ({ ...prev, [hostName]: refreshInterval }));
     } catch (error) {
       console.error(`获取主机 ${hostName} 数据失败:`, error);
@@ -109,13 +277,10 @@ const RemoteControl: React.FC = () => {
         setCountdowns(initialCountdowns);
         setLoadingHosts(initialLoadingStates);
 
-        // 获取本地存储的唤醒设置
-        const settings = pcStatusApi.getKeepAwakeSettings();
-        
         // 逐个获取主机数据
         hostsData.forEach(host => {
           setRefreshingHosts(prev => ({ ...prev, [host.name]: true }));
-          fetchHostData(host.name, settings[host.name]);
+          fetchHostData(host.name);
         });
       } catch (err) {
         const error = err as AxiosError<APIError>;
@@ -127,6 +292,44 @@ const RemoteControl: React.FC = () => {
     loadHosts();
   }, [refreshInterval]);
 
+  // 按刷新间隔获取唤醒包中继的可达状态
+  useEffect(() => {
+    const loadRelays = async () => {
+      try {
+        const list = await pcStatusApi.getRelays();
+        setRelays(Object.fromEntries(list.map(relay => [relay.name, relay])));
+      } catch (error) {
+        console.error('获取唤醒包中继状态失败:', error);
+      }
+    };
+
+    loadRelays();
+    const timer = setInterval(loadRelays, refreshInterval * 1000);
+    return () => clearInterval(timer);
+  }, [refreshInterval]);
+
+  // 订阅实时事件，主机有变化时立即刷新，定时刷新作为兜底
+  useEffect(() => {
+    const pending: Record<string, number> = {};
+    const unsubscribe = pcStatusApi.subscribeEvents(event => {
+      // 本页面刷新时会续期租约，忽略续期事件以免循环刷新
+      if (!event.host || event.type === 'lease.renewed' || pending[event.host]) {
+        return;
+      }
+      const hostName = event.host;
+      // 合并短时间内的多个事件，如大量连接同时建立
+      pending[hostName] = window.setTimeout(() => {
+        delete pending[hostName];
+        fetchHostData(hostName);
+      }, 500);
+    });
+
+    return () => {
+      unsubscribe();
+      Object.values(pending).forEach(timer => clearTimeout(timer));
+    };
+  }, [refreshInterval]);
+
   // 每个主机的自动刷新倒计时
   useEffect(() => {
     const timer = setInterval(() => {
@@ -139,8 +342,7 @@ const RemoteControl: React.FC = () => {
             newCountdowns[host.name]--;
             if (newCountdowns[host.name] === 0) {
               // 倒计时结束，刷新该主机数据
-              const settings = pcStatusApi.getKeepAwakeSettings();
-              fetchHostData(host.name, settings[host.name]);
+              fetchHostData(host.name);
               newCountdowns[host.name] = refreshInterval;
             }
             needsUpdate = true;
@@ -156,81 +358,167 @@ const RemoteControl: React.FC = () => {
 
   const handleKeepAwakeChange = async (hostName: string, checked: boolean) => {
     try {
-      pcStatusApi.setLocalKeepAwake(hostName, checked);
       if (checked) {
-        await fetchHostData(hostName, true);
+        const lease = await pcStatusApi.startKeepAwake(hostName, keepAwakeTTL(), '网页保持唤醒');
+        pcStatusApi.setLocalKeepAwake(hostName, lease.id);
       } else {
-        setHostStatuses(prev => ({
-          ...prev,
-          [hostName]: { ...prev[hostName], keepAwake: false }
-        }));
+        const leaseId = pcStatusApi.getKeepAwakeSettings()[hostName];
+        pcStatusApi.setLocalKeepAwake(hostName);
+        if (leaseId) {
+          await pcStatusApi.stopKeepAwake(hostName, leaseId);
+        }
       }
+      setKeepAwakeLeases(pcStatusApi.getKeepAwakeSettings());
+      await fetchHostData(hostName);
     } catch (err) {
       const error = err as AxiosError<APIError>;
-      pcStatusApi.setLocalKeepAwake(hostName, !checked);
       console.error('设置唤醒状态失败:', error);
       message.error(`设置唤醒状态失败: ${error.response?.data?.error || error.message}`);
     }
   };
 
+  const handleEndLease = async (hostName: string, leaseId: string) => {
+    try {
+      await pcStatusApi.stopKeepAwake(hostName, leaseId);
+      if (pcStatusApi.getKeepAwakeSettings()[hostName] === leaseId) {
+        pcStatusApi.setLocalKeepAwake(hostName);
+        setKeepAwakeLeases(pcStatusApi.getKeepAwakeSettings());
+      }
+      await fetchHostData(hostName);
+    } catch (err) {
+      const error = err as AxiosError<APIError>;
+      message.error(`结束保持唤醒失败: ${error.response?.data?.error || error.message}`);
+    }
+  };
+
+  const handleWake = async (hostName: string) => {
+    setWakingHosts(prev => ({ ...prev, [hostName]: true }));
+    try {
+      await pcStatusApi.wake(hostName);
+      message.success(`已向 ${hostName} 发送唤醒包`);
+      await fetchHostData(hostName);
+    } catch (err) {
+      const error = err as AxiosError<APIError>;
+      message.error(`发送唤醒包失败: ${error.response?.data?.error || error.message}`);
+    } finally {
+      setWakingHosts(prev => ({ ...prev, [hostName]: false }));
+    }
+  };
+
+  const sleepActionLabels: Record<SleepAction, string> = {
+    sleep: '睡眠',
+    shutdown: '关机',
+    sleep_when_idle: '空闲时睡眠'
+  };
+
+  // 主机正在使用中（409）时确认后强制执行，强制执行会结束保持唤醒租约
+  const handleSleep = async (hostName: string, action: SleepAction, force = false) => {
+    setSleepingHosts(prev => ({ ...prev, [hostName]: true }));
+    try {
+      const result = await pcStatusApi.sleep(hostName, action, force);
+      if (action === 'sleep_when_idle') {
+        message.success(`${hostName} 将在 ${result.delaySecs} 秒内无唤醒事件时睡眠`);
+      } else {
+        message.success(`已请求 ${hostName} ${sleepActionLabels[action]}`);
+      }
+      if (force) {
+        pcStatusApi.setLocalKeepAwake(hostName);
+        setKeepAwakeLeases(pcStatusApi.getKeepAwakeSettings());
+      }
+      await fetchHostData(hostName);
+    } catch (err) {
+      const error = err as AxiosError<APIError>;
+      if (error.response?.status === 409 && !force) {
+        Modal.confirm({
+          title: `${hostName} 正在使用中`,
+          content: `${error.response.data?.error}。仍然${sleepActionLabels[action]}将中断这些连接并结束保持唤醒租约。`,
+          okText: `仍然${sleepActionLabels[action]}`,
+          okButtonProps: { danger: true },
+          cancelText: '取消',
+          onOk: () => handleSleep(hostName, action, true)
+        });
+        return;
+      }
+      message.error(`${sleepActionLabels[action]}失败: ${error.response?.data?.error || error.message}`);
+    } finally {
+      setSleepingHosts(prev => ({ ...prev, [hostName]: false }));
+    }
+  };
+
   const handleRefresh = (hostName: string) => {
-    const settings = pcStatusApi.getKeepAwakeSettings();
     setRefreshingHosts(prev => ({ ...prev, [hostName]: true }));
-    fetchHostData(hostName, settings[hostName]);
+    fetchHostData(hostName);
   };
 
-  const clientInfoColumns = [
-    { 
-      title: '地址',
-      key: 'ipPort',
-      render: (_: unknown, record: ClientInfo) => (
-        <span>{record.ip}:{record.port}</span>
+  const leaseColumns = (hostName: string) => [
+    {
+      title: '持有者',
+      dataIndex: 'owner',
+      key: 'owner',
+      render: (owner: string, record: KeepAwakeLease) => (
+        <span>
+          {owner}
+          {keepAwakeLeases[hostName] === record.id && <Tag color="blue" style={{ marginLeft: 8 }}>本页面</Tag>}
+        </span>
       )
     },
-    { 
-      title: '平台',
-      key: 'platform',
-      render: (_: unknown, record: ClientInfo) => {
-        const { platform } = parseUserAgent(record.userAgent);
-        return platform;
-      }
+    {
+      title: '原因',
+      dataIndex: 'reason',
+      key: 'reason',
+      render: (reason?: string) => reason || '-'
     },
-    { 
-      title: '浏览器',
-      key: 'browser',
-      render: (_: unknown, record: ClientInfo) => {
-        const { browser } = parseUserAgent(record.userAgent);
-        return browser;
+    {
+      title: '来源',
+      key: 'source',
+      render: (_: unknown, record: KeepAwakeLease) => {
+        if (!record.userAgent) {
+          return record.clientIp || '-';
+        }
+        const { platform, browser } = parseUserAgent(record.userAgent);
+        return (
+          <Tooltip title={record.userAgent}>
+            <span>{record.clientIp} ({platform} / {browser})</span>
+          </Tooltip>
+        );
       }
     },
     {
-      title: 'User Agent',
-      dataIndex: 'userAgent',
-      key: 'userAgent',
-      render: (userAgent: string) => (
-        <Tooltip title={userAgent}>
-          <span style={{ 
-            maxWidth: '200px', 
-            overflow: 'hidden', 
-            textOverflow: 'ellipsis', 
-            whiteSpace: 'nowrap',
-            display: 'inline-block'
-          }}>
-            {userAgent}
-          </span>
+      title: '创建时间',
+      dataIndex: 'createdAt',
+      key: 'createdAt',
+      render: (time: string) => formatDate(time)
+    },
+    {
+      title: '到期时间',
+      dataIndex: 'expiresAt',
+      key: 'expiresAt',
+      render: (time: string, record: KeepAwakeLease) => (
+        <Tooltip title={record.endAt ? `最晚结束: ${formatDate(record.endAt)}` : `有效期 ${record.ttl} 秒，可续期`}>
+          <span>{formatDate(time)}</span>
         </Tooltip>
       )
     },
-    { 
-      title: '最后在线时间', 
-      dataIndex: 'lastSeen', 
-      key: 'lastSeen',
-      render: (time: string) => formatDate(time)
+    {
+      title: '操作',
+      key: 'action',
+      render: (_: unknown, record: KeepAwakeLease) => (
+        <Button type="link" danger onClick={() => handleEndLease(hostName, record.id)}>
+          结束
+        </Button>
+      )
     }
   ];
 
   const channelColumns = [
-    { title: '服务端口', dataIndex: 'service_port', key: 'service_port' },
+    {
+      title: '服务端口',
+      dataIndex: 'service_port',
+      key: 'service_port',
+      render: (port: number, record: ForwardChannel) => (
+        <span>{port} <Tag>{(record.protocol || 'tcp').toUpperCase()}</Tag></span>
+      )
+    },
     { title: '目标主机', dataIndex: 'target_host', key: 'target_host' },
     { title: '目标端口', dataIndex: 'target_port', key: 'target_port' },
     { 
@@ -239,6 +527,35 @@ const RemoteControl: React.FC = () => {
       key: 'active_count',
       render: (count: number) => count || 0
     },
+    {
+      title: '连接总数',
+      dataIndex: 'total_sessions',
+      key: 'total_sessions',
+      render: (total: number, record: ForwardChannel) => (
+        <Tooltip title={`唤醒超时 ${record.wake_timeouts || 0} 次，连接目标失败 ${record.dial_failures || 0} 次`}>
+          <span>
+            {total || 0}
+            {record.failed_sessions > 0 && <Tag color="red" style={{ marginLeft: 8 }}>失败 {record.failed_sessions}</Tag>}
+          </span>
+        </Tooltip>
+      )
+    },
+    {
+      title: '流量',
+      key: 'bytes',
+      render: (_: unknown, record: ForwardChannel) => (
+        <Tooltip title="上行: 客户端 -> 目标主机，下行: 目标主机 -> 客户端">
+          <span>↑ {formatBytes(record.bytes_to_target)} / ↓ {formatBytes(record.bytes_to_client)}</span>
+        </Tooltip>
+      )
+    },
+    {
+      title: '平均唤醒耗时',
+      dataIndex: 'avg_wake_latency_ms',
+      key: 'avg_wake_latency_ms',
+      render: (ms: number, record: ForwardChannel) =>
+        record.wakes > 0 ? `${(ms / 1000).toFixed(1)} 秒（${record.wakes} 次）` : '-'
+    },
     {
       title: '状态',
       dataIndex: 'status',
@@ -290,11 +607,146 @@ const RemoteControl: React.FC = () => {
     }
   ];
 
+  const sessionColumns = [
+    {
+      title: '客户端',
+      key: 'client',
+      render: (_: unknown, record: ForwardSession) => `${record.client_ip}:${record.client_port}`
+    },
+    {
+      title: '通道',
+      key: 'channel',
+      render: (_: unknown, record: ForwardSession) =>
+        `${record.service_port} -> ${record.target_port}${record.protocol && record.protocol !== 'tcp' ? ` (${record.protocol.toUpperCase()})` : ''}`
+    },
+    {
+      title: '开始时间',
+      dataIndex: 'start_time',
+      key: 'start_time',
+      render: (time: string) => formatDate(time)
+    },
+    {
+      title: '结束时间',
+      dataIndex: 'end_time',
+      key: 'end_time',
+      render: (time: string) => formatDate(time)
+    },
+    {
+      title: '唤醒',
+      key: 'wake',
+      render: (_: unknown, record: ForwardSession) =>
+        record.wake_needed ? <Tag color="orange">唤醒 {(record.wake_duration_ms / 1000).toFixed(1)} 秒</Tag> : '-'
+    },
+    {
+      title: '流量',
+      key: 'bytes',
+      render: (_: unknown, record: ForwardSession) =>
+        `↑ ${formatBytes(record.bytes_to_target)} / ↓ ${formatBytes(record.bytes_to_client)}`
+    },
+    {
+      title: '关闭原因',
+      dataIndex: 'close_reason',
+      key: 'close_reason',
+      render: (reason: string) => {
+        const failed = reason === 'wake_timeout' || reason === 'dial_failed' || reason === 'host_not_found';
+        return <Tag color={failed ? 'red' : 'default'}>{closeReasonLabels[reason] || reason || '-'}</Tag>;
+      }
+    }
+  ];
+
+  const eventColumns = [
+    {
+      title: '时间',
+      dataIndex: 'time',
+      key: 'time',
+      render: (time: string) => formatDate(time)
+    },
+    {
+      title: '事件',
+      dataIndex: 'type',
+      key: 'type',
+      render: (type: string) => <Tag color={type.startsWith('wake.') ? 'orange' : 'default'}>{eventTypeLabels[type] || type}</Tag>
+    },
+    {
+      title: '发起者',
+      dataIndex: 'actor',
+      key: 'actor',
+      render: (actor?: string) => formatActor(actor)
+    },
+    {
+      title: '详情',
+      key: 'detail',
+      render: (_: unknown, record: HostEvent) => describeEvent(record)
+    }
+  ];
+
+  // 渲染主机使用的唤醒包中继及其可达状态
+  const renderRelayTag = (host: PCHostInfo) => {
+    if (!host.relay) {
+      return null;
+    }
+    const relay = relays[host.relay];
+    if (!relay) {
+      return <Tag>中继 {host.relay}</Tag>;
+    }
+    const tip = relay.reachable
+      ? `${relay.url}（${relay.hostname || '-'}），延迟 ${relay.latencyMs ?? 0} 毫秒${relay.wolRelay ? '' : '，未启用唤醒包中继'}`
+      : `${relay.url} 不可达: ${relay.error || '未知错误'}`;
+    return (
+      <Tooltip title={tip}>
+        <Tag color={relay.reachable && relay.wolRelay ? 'green' : 'red'}>
+          中继 {relay.name}{relay.reachable ? '' : '（不可达）'}
+        </Tag>
+      </Tooltip>
+    );
+  };
+
+  // 渲染主机上 guard 的心跳状态，程序控制睡眠模式下显示睡眠倒计时
+  const renderGuardTag = (guard?: GuardStatus) => {
+    if (!guard) {
+      return null;
+    }
+    const lastWake = guard.lastWake
+      ? `，最近唤醒: ${guard.lastWake.type} ${guard.lastWake.source} ${formatTimeAgo(guard.lastWake.time)}`
+      : '';
+    const tip = `${guard.hostname}（${guard.os}），${guardStrategyLabels[guard.strategy] || guard.strategy}，` +
+      `${guard.sleepMode === 'program' ? '程序控制睡眠' : '系统控制睡眠'}${lastWake}，心跳 ${formatTimeAgo(guard.lastHeartbeat)}`;
+
+    let label = 'guard 在线';
+    let color = 'green';
+    if (!guard.online) {
+      label = 'guard 离线';
+      color = 'default';
+    } else if (guard.state === 'sleeping') {
+      label = '正在睡眠';
+      color = 'orange';
+    } else if (guard.state === 'shutting_down') {
+      label = '正在关机';
+      color = 'orange';
+    } else if (guard.state === 'stopped') {
+      label = 'guard 已退出';
+      color = 'default';
+    } else if (guard.sleepAt) {
+      const seconds = Math.ceil((new Date(guard.sleepAt).getTime() - Date.now()) / 1000);
+      label = seconds > 0 ? `${seconds}秒后睡眠` : '即将睡眠';
+      color = 'orange';
+    } else if (guard.temporaryWake) {
+      label = '临时唤醒中';
+    }
+    return (
+      <Tooltip title={tip}>
+        <Tag color={color}>{label}</Tag>
+      </Tooltip>
+    );
+  };
+
   // 渲染主机卡片
   const renderHostCard = (host: PCHostInfo) => {
     const status = hostStatuses[host.name];
-    const clients = hostClients[host.name] || [];
+    const leases = hostLeases[host.name] || [];
     const channels = hostChannels[host.name] || [];
+    const sessions = hostSessions[host.name] || [];
+    const events = hostEvents[host.name];
     const countdown = countdowns[host.name] || refreshInterval;
 
     return (
@@ -305,31 +757,65 @@ const RemoteControl: React.FC = () => {
         style={{ marginBottom: '24px' }}
       >
         <div style={{ display: 'flex', alignItems: 'center', gap: '16px' }}>
-          <Tag color={status?.isOnline ? 'green' : 'red'}>
-            {status?.isOnline ? '在线' : '离线'}
+          <Tag color={hostStateColors[status?.state ?? 'unknown']}>
+            {hostStateLabels[status?.state ?? 'unknown']}
           </Tag>
+          {status?.stateSince && (
+            <span style={{ color: '#999' }}>
+              自 {formatDate(status.stateSince)}
+            </span>
+          )}
           <Button 
             icon={<SyncOutlined spin={refreshingHosts[host.name]} />} 
             onClick={() => handleRefresh(host.name)}
           >
             刷新
           </Button>
+          <Button
+            icon={<PoweroffOutlined />}
+            loading={wakingHosts[host.name]}
+            onClick={() => handleWake(host.name)}
+          >
+            唤醒
+          </Button>
+          {host.sleep && (
+            <Dropdown
+              disabled={sleepingHosts[host.name]}
+              menu={{
+                items: (Object.keys(sleepActionLabels) as SleepAction[]).map(action => ({
+                  key: action,
+                  label: sleepActionLabels[action],
+                  danger: action === 'shutdown'
+                })),
+                onClick: ({ key }) => handleSleep(host.name, key as SleepAction)
+              }}
+            >
+              <Button loading={sleepingHosts[host.name]}>
+                睡眠 <DownOutlined />
+              </Button>
+            </Dropdown>
+          )}
+          {renderRelayTag(host)}
+          {renderGuardTag(status?.guard)}
           <span>{countdown}秒后自动刷新</span>
+          {status?.keepAwake && !keepAwakeLeases[host.name] && (
+            <Tag color="blue">其他客户端保持唤醒中</Tag>
+          )}
           <span style={{ marginLeft: 'auto' }}>保持唤醒：</span>
           <Switch 
-            checked={status?.keepAwake}
+            checked={Boolean(keepAwakeLeases[host.name])}
             onChange={(checked) => handleKeepAwakeChange(host.name, checked)}
           />
           {status?.lastWakeTime && (
-            <span>最后唤醒: {formatTimeAgo(status.lastWakeTime)}</span>
+            <span>最后唤醒: {formatTimeAgo(status.lastWakeTime)}{status.lastWakeBy && `（${formatActor(status.lastWakeBy)}）`}</span>
           )}
         </div>
 
         <Collapse ghost style={{ marginTop: '16px' }}>
-          <Panel header="网页唤醒客户端" key="clients">
+          <Panel header={`保持唤醒租约 (${leases.length})`} key="leases">
             <Table 
-              columns={clientInfoColumns}
-              dataSource={clients}
+              columns={leaseColumns(host.name)}
+              dataSource={leases}
               rowKey="id"
               pagination={false}
             />
@@ -353,6 +839,33 @@ const RemoteControl: React.FC = () => {
               }}
             />
           </Panel>
+          <Panel header="最近转发会话" key="sessions">
+            <Table
+              columns={sessionColumns}
+              dataSource={sessions}
+              rowKey="id"
+              pagination={false}
+              size="small"
+            />
+          </Panel>
+          <Panel header={`事件记录 (${events?.total ?? 0})`} key="events">
+            <Table
+              columns={eventColumns}
+              dataSource={events?.events || []}
+              rowKey="id"
+              size="small"
+              pagination={{
+                current: eventPages.current[host.name] || 1,
+                pageSize: EVENT_PAGE_SIZE,
+                total: events?.total || 0,
+                showSizeChanger: false,
+                onChange: page => {
+                  eventPages.current[host.name] = page;
+                  fetchHostEvents(host.name);
+                }
+              }}
+            />
+          </Panel>
         </Collapse>
       </Card>
     );
@@ -377,7 +890,17 @@ const RemoteControl: React.FC = () => {
 
   return (
     <div style={{ padding: '24px' }}>
-      <Title level={2}>远程PC控制面板</Title>
+      <div style={{ display: 'flex', alignItems: 'center', justifyContent: 'space-between' }}>
+        <Title level={2}>远程PC控制面板</Title>
+        {!auth.anonymous && (
+          <span>
+            {auth.user}
+            <Button type="link" icon={<LogoutOutlined />} onClick={onLogout}>
+              退出登录
+            </Button>
+          </span>
+        )}
+      </div>
       {sortedHosts.map(renderHostCard)}
     </div>
   );
@@ -5,7 +5,8 @@ const PAGE_ID = crypto.randomUUID();
 const api = axios.create({
   baseURL: '/api',
   headers: {
-    'X-Page-ID': PAGE_ID
+    'X-Page-ID': PAGE_ID,
+    'X-Requested-With': 'XMLHttpRequest'
   }
 });
 
@@ -19,15 +20,52 @@ export interface APIResponse<T> {
   data: T;
 }
 
+let unauthorizedHandler: (() => void) | null = null;
+
+// 注册未登录（401）时的回调，用于切换到登录页
+export const onUnauthorized = (handler: () => void) => {
+  unauthorizedHandler = handler;
+};
+
 // 修改 axios 错误处理
 api.interceptors.response.use(
   response => response,
   (error: AxiosError<APIError>) => {
+    if (error.response?.status === 401 && unauthorizedHandler) {
+      unauthorizedHandler();
+    }
     return Promise.reject(error);
   }
 );
 
-const KEEP_AWAKE_KEY = 'pc-keep-awake-settings';
+export const authApi = {
+  login: (user: string, password: string) =>
+    api.post<APIResponse<AuthInfo>>('/auth/login', { user, password })
+      .then(res => res.data.data),
+
+  logout: () => api.post('/auth/logout'),
+
+  me: () => api.get<APIResponse<AuthInfo>>('/auth/me')
+    .then(res => res.data.data)
+};
+
+const KEEP_AWAKE_KEY = 'pc-keep-awake-leases';
+
+const HOST_EVENT_TYPES: HostEventType[] = [
+  'host.state',
+  'wake.sent',
+  'wake.finished',
+  'sleep.sent',
+  'guard.state',
+  'guard.demand',
+  'session.opened',
+  'session.closed',
+  'lease.acquired',
+  'lease.renewed',
+  'lease.released',
+  'lease.expired',
+  'config.changed'
+];
 
 export const pcStatusApi = {
   getHosts: () => api.get<{ success: boolean; data: PCHostInfo[] }>('/pc/hosts')
@@ -36,23 +74,63 @@ export const pcStatusApi = {
   getConfig: () => api.get<{ success: boolean; data: { refreshInterval: number } }>('/pc/config')
     .then(res => res.data.data),
 
-  getHostStatus: async (hostName: string, keepAwake?: boolean) => {
-    const url = keepAwake ? 
-      `/pc/${hostName}/status?keepAwake=true` : 
-      `/pc/${hostName}/status`;
-    const res = await api.get<{ success: boolean; data: PCHostStatus }>(url);
+  getHostStatus: async (hostName: string) => {
+    const res = await api.get<{ success: boolean; data: PCHostStatus }>(`/pc/${hostName}/status`);
     return res.data.data;
   },
 
-  getHostClients: (hostName: string) => 
-    api.get<{ success: boolean; data: ClientInfo[] }>(`/pc/${hostName}/client_info`)
+  wake: (hostName: string) =>
+    api.post(`/pc/${hostName}/wake`),
+
+  // 有活动会话或保持唤醒租约时返回 409，force 为 true 时仍然执行并结束租约
+  sleep: (hostName: string, action: SleepAction, force = false) =>
+    api.post<APIResponse<{ action: SleepAction; delaySecs: number }>>(`/pc/${hostName}/sleep`, { action, force })
+      .then(res => res.data.data),
+
+  startKeepAwake: (hostName: string, ttl: number, reason?: string) =>
+    api.post<APIResponse<KeepAwakeLease>>(`/pc/${hostName}/keep-awake`, { ttl, reason })
+      .then(res => res.data.data),
+
+  renewKeepAwake: (hostName: string, leaseId: string) =>
+    api.post<APIResponse<KeepAwakeLease>>(`/pc/${hostName}/keep-awake/${leaseId}/renew`)
+      .then(res => res.data.data),
+
+  stopKeepAwake: (hostName: string, leaseId: string) =>
+    api.delete(`/pc/${hostName}/keep-awake/${leaseId}`),
+
+  getHostLeases: (hostName: string) =>
+    api.get<{ success: boolean; data: KeepAwakeLease[] }>(`/pc/${hostName}/leases`)
       .then(res => res.data.data),
 
   getHostChannels: (hostName: string) => 
     api.get<{ success: boolean; data: ForwardChannel[] }>(`/pc/${hostName}/forward_channels`)
       .then(res => res.data.data),
 
-  getKeepAwakeSettings: (): Record<string, boolean> => {
+  getRelays: () => api.get<APIResponse<RelayStatus[]>>('/relays')
+    .then(res => res.data.data || []),
+
+  getGuards: () => api.get<APIResponse<GuardStatus[]>>('/guards')
+    .then(res => res.data.data || []),
+
+  getHostSessions: (hostName: string, limit = 20) =>
+    api.get<APIResponse<{ total: number; sessions: ForwardSession[] }>>('/sessions', { params: { host: hostName, limit } })
+      .then(res => res.data.data),
+
+  getHostEvents: (hostName: string, offset = 0, limit = 20) =>
+    api.get<APIResponse<{ total: number; events: HostEvent[] }>>('/events', { params: { host: hostName, offset, limit } })
+      .then(res => res.data.data),
+
+  // 本页面持有的保持唤醒租约，key 为主机名，value 为租约ID
+  // 订阅实时事件，断线后浏览器自动重连并补发错过的事件，返回取消订阅的函数
+  subscribeEvents: (onEvent: (event: HostEvent) => void) => {
+    const source = new EventSource('/api/events/stream');
+    HOST_EVENT_TYPES.forEach(type => {
+      source.addEventListener(type, e => onEvent(JSON.parse((e as MessageEvent).data)));
+    });
+    return () => source.close();
+  },
+
+  getKeepAwakeSettings: (): Record<string, string> => {
     try {
       return JSON.parse(localStorage.getItem(KEEP_AWAKE_KEY) || '{}');
     } catch {
@@ -60,17 +138,51 @@ export const pcStatusApi = {
     }
   },
 
-  setLocalKeepAwake: (hostName: string, keepAwake: boolean) => {
+  setLocalKeepAwake: (hostName: string, leaseId?: string) => {
     const settings = pcStatusApi.getKeepAwakeSettings();
-    settings[hostName] = keepAwake;
+    if (leaseId) {
+      settings[hostName] = leaseId;
+    } else {
+      delete settings[hostName];
+    }
     localStorage.setItem(KEEP_AWAKE_KEY, JSON.stringify(settings));
   }
 };
 
-export const clientInfoApi = {
-  getInfo: () => api.get<ClientInfo[]>('/client/info').then(res => res.data)
-};
-
 export const forwardChannelApi = {
   getChannels: () => api.get<ForwardChannel[]>('/forward/channels').then(res => res.data)
-};
\ No newline at end of file
+};
+
+export const configApi = {
+  getHosts: () => api.get<APIResponse<HostConfig[]>>('/config/hosts')
+    .then(res => res.data.data),
+
+  createHost: (host: HostConfig) =>
+    api.post<APIResponse<HostConfig>>('/config/hosts', host)
+      .then(res => res.data.data),
+
+  updateHost: (name: string, host: HostConfig) =>
+    api.put<APIResponse<HostConfig>>(`/config/hosts/${name}`, host)
+      .then(res => res.data.data),
+
+  deleteHost: (name: string) =>
+    api.delete(`/config/hosts/${name}`),
+
+  getForwards: () => api.get<APIResponse<ForwardConfig[]>>('/config/forwards')
+    .then(res => res.data.data),
+
+  createForward: (forward: ForwardConfig) =>
+    api.post<APIResponse<ForwardConfig>>('/config/forwards', forward)
+      .then(res => res.data.data),
+
+  updateForward: (port: number, protocol: string, forward: ForwardConfig) =>
+    api.put<APIResponse<ForwardConfig>>(`/config/forwards/${port}`, forward, { params: { protocol } })
+      .then(res => res.data.data),
+
+  deleteForward: (port: number, protocol: string) =>
+    api.delete(`/config/forwards/${port}`, { params: { protocol } }),
+
+  getAudit: (params: { action?: string; target?: string; offset?: number; limit?: number } = {}) =>
+    api.get<APIResponse<{ total: number; entries: AuditEntry[] }>>('/audit', { params })
+      .then(res => res.data.data)
+};
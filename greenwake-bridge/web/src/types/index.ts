@@ -3,23 +3,56 @@ interface PCHostInfo {
   ip: string;
   mac: string;
   monitorPort: number;
+  relay?: string; // 发送唤醒包的中继
+  sleep?: boolean; // 配置了 guard，可以远程睡眠或关机
 }
 
+// 远程睡眠的操作：立即睡眠、关机、结束 guard 的临时唤醒后空闲时睡眠
+type SleepAction = 'sleep' | 'shutdown' | 'sleep_when_idle';
+
 interface PCHostStatus {
   name: string;
   isOnline: boolean;
+  state: 'unknown' | 'online' | 'offline' | 'waking';
+  stateSince: string;
   keepAwake: boolean;
   lastUpdate?: string;
   lastWakeTime?: string;
+  lastWakeBy?: string; // 最后一次唤醒的发起者，如 user:admin、forward:<通道>
+  guard?: GuardStatus; // 主机上的 greenwake-guard 最近一次心跳
+}
+
+// 向 bridge 注册的 greenwake-guard 及其最近一次心跳
+interface GuardStatus {
+  hostname: string;
+  host?: string;
+  os: string;
+  version: number;
+  online: boolean;
+  state: 'awake' | 'sleeping' | 'shutting_down' | 'stopped';
+  strategy: string;
+  sleepMode: string;
+  temporaryWake: boolean;
+  sleepAt?: string; // 程序控制睡眠模式下预计睡眠的时间
+  lastWake?: { type: string; source: string; time: string };
+  interfaces: { name: string; mac: string; ips: string[] }[];
+  address: string;
+  registeredAt: string;
+  lastHeartbeat: string;
 }
 
-interface ClientInfo {
+interface KeepAwakeLease {
   id: string;
-  userAgent: string;
-  ip: string;
-  port: string;
-  lastSeen: string;
-  targetHost: string;
+  host: string;
+  owner: string;
+  reason?: string;
+  clientIp?: string;
+  userAgent?: string;
+  ttl: number;
+  createdAt: string;
+  renewedAt: string;
+  expiresAt: string;
+  endAt?: string;
 }
 
 interface ChannelClient {
@@ -32,12 +65,40 @@ interface ChannelClient {
 
 interface ForwardChannel {
   id: string;
+  protocol: 'tcp' | 'udp' | 'socks5' | 'connect';
   servicePort: number;
   targetHost: string;
   targetPort: number;
   status: 'active' | 'inactive';
   lastActive?: string;
   clients?: ChannelClient[];
+  active_count: number;
+  total_sessions: number;
+  failed_sessions: number;
+  wake_timeouts: number;
+  dial_failures: number;
+  bytes_to_target: number;
+  bytes_to_client: number;
+  wakes: number;
+  avg_wake_latency_ms: number;
+}
+
+interface ForwardSession {
+  id: string;
+  channel: string;
+  protocol: 'tcp' | 'udp' | 'socks5' | 'connect';
+  service_port: number;
+  target_host: string;
+  target_port: number;
+  client_ip: string;
+  client_port: number;
+  start_time: string;
+  end_time: string;
+  wake_needed: boolean;
+  wake_duration_ms: number;
+  bytes_to_target: number;
+  bytes_to_client: number;
+  close_reason: string;
 }
 
 interface ServiceLink {
@@ -46,4 +107,80 @@ interface ServiceLink {
   url: string;
   description?: string;
   targetHost: string;
-} 
\ No newline at end of file
+}
+
+interface AuthInfo {
+  user: string;
+  anonymous: boolean;
+}
+
+
+// 配置文件中的主机条目，字段与 config.yaml 一致
+interface HostConfig {
+  name: string;
+  ip: string;
+  mac: string;
+  monitor_port?: number;
+  allowed_ports?: number[];
+  aliases?: string[];
+  [key: string]: unknown;
+}
+
+// 配置文件中的端口转发条目
+interface ForwardConfig {
+  service_port: number;
+  target_host: string;
+  target_port: number;
+  protocol?: 'tcp' | 'udp';
+  idle_timeout?: number;
+}
+
+interface AuditEntry {
+  id: string;
+  time: string;
+  actor: string;
+  action: string;
+  target: string;
+  before?: unknown;
+  after?: unknown;
+}
+
+type HostEventType =
+  | 'host.state'
+  | 'wake.sent'
+  | 'wake.finished'
+  | 'sleep.sent'
+  | 'guard.state'
+  | 'guard.demand'
+  | 'session.opened'
+  | 'session.closed'
+  | 'lease.acquired'
+  | 'lease.renewed'
+  | 'lease.released'
+  | 'lease.expired'
+  | 'auth.login'
+  | 'config.changed'
+  | 'config.reloaded';
+
+// 通过 /api/events/stream 推送的实时事件，也可以通过 /api/events 查询保存的记录
+interface HostEvent {
+  id?: number; // 只推送不保存的事件（lease.renewed）没有事件ID
+  type: HostEventType;
+  host?: string;
+  actor?: string; // 发起者，如 user:admin、token:ha、forward:<通道>、keep-awake:<租约持有者>
+  time: string;
+  data?: unknown;
+}
+
+// 唤醒包中继（greenwake-guard）的可达状态
+interface RelayStatus {
+  name: string;
+  url: string;
+  reachable: boolean;
+  wolRelay: boolean;
+  hostname?: string;
+  version?: number;
+  latencyMs?: number;
+  lastCheck?: string;
+  error?: string;
+}
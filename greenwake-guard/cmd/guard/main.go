@@ -13,6 +13,7 @@ import (
 
 	"greenwake-guard/pkg/logger"
 	"greenwake-guard/pkg/singleinstance"
+	"greenwake-guard/service/control"
 	"greenwake-guard/service/tray"
 	"greenwake-guard/service/wakeevent"
 	"greenwake-guard/service/wakelock"
@@ -92,6 +93,22 @@ func main() {
 		}
 	}()
 
+	// 启动控制接口，供 greenwake-bridge 调用（如中继唤醒包、远程睡眠）
+	controlSvc := control.NewServer(cfg.Control, wakeLockSvc, wakeLockSvc)
+	if err := controlSvc.Start(); err != nil {
+		logger.Error("启动控制接口失败: %v", err)
+	}
+	defer controlSvc.Stop()
+
+	// 向 greenwake-bridge 注册并发送心跳，睡眠或关机前通知 bridge
+	heartbeatSvc := control.NewHeartbeat(cfg.Bridges, wakeLockSvc)
+	if err := heartbeatSvc.Start(); err != nil {
+		logger.Error("启动心跳服务失败: %v", err)
+	} else {
+		wakeLockSvc.SetSleepCallback(heartbeatSvc.NotifySleep)
+		defer heartbeatSvc.Stop()
+	}
+
 	// 创建并启动设备监控器
 	deviceMonitor := wakeevent.NewDeviceMonitor(wakeLockSvc)
 	wg.Add(1)
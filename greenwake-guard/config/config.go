@@ -29,6 +29,25 @@ type Config struct {
 	ExternalWake      ExternalWake `yaml:"external_wake"`       // 外部唤醒相关配置
 	ProgramSleepDelay int          `yaml:"program_sleep_delay"` // 程序控制睡眠模式下等待睡眠时间
 	LogLevel          string       `yaml:"log_level"`           // 日志级别
+	Control           Control      `yaml:"control"`             // 供 greenwake-bridge 调用的控制接口
+	Bridges           []Bridge     `yaml:"bridges"`             // 注册本机并发送心跳的 greenwake-bridge
+}
+
+// Control 控制接口配置，请求需使用共享密钥签名
+type Control struct {
+	Listen     string `yaml:"listen"`      // 监听地址，如 ":8056"，为空不启用
+	Secret     string `yaml:"secret"`      // 与 bridge 共享的签名密钥，至少16个字符
+	WolRelay   bool   `yaml:"wol_relay"`   // 作为唤醒包中继，代替 bridge 在本网段广播唤醒包
+	AllowSleep bool   `yaml:"allow_sleep"` // 允许 bridge 请求本机睡眠、关机或空闲时睡眠
+	// 接受 bridge 报告的主机使用状态：使用中时保持唤醒，不再使用该时间（秒）后允许睡眠，0 不接受
+	DemandTimeout int `yaml:"demand_timeout"`
+}
+
+// Bridge 接收本机注册和心跳的 greenwake-bridge，请求使用共享密钥签名
+type Bridge struct {
+	URL    string `yaml:"url"`    // bridge 地址，如 http://192.168.1.2:8055
+	Secret string `yaml:"secret"` // 与 bridge 配置 guards.secret 相同，至少16个字符
+	Host   string `yaml:"host"`   // 本机在 bridge 中的主机名，为空时由 bridge 按 MAC 和 IP 匹配
 }
 
 // ExternalWake 外部唤醒相关配置
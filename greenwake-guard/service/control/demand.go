@@ -0,0 +1,57 @@
+package control
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"greenwake-guard/pkg/logger"
+)
+
+// minDemandTimeout bridge 每30秒刷新一次使用状态，超时时间不能短于刷新间隔
+const minDemandTimeout = 60 * time.Second
+
+// demandRequest bridge 报告的主机使用状态
+type demandRequest struct {
+	InUse     bool   `json:"in_use"`
+	IdleSince string `json:"idle_since"` // 不再使用的开始时间（RFC3339）
+	Sessions  int    `json:"sessions"`   // 活跃的转发会话数
+	Leases    int    `json:"leases"`     // 保持唤醒租约数
+}
+
+// handleDemand 将 bridge 报告的使用状态作为唤醒来源，主机不再使用一段时间后允许睡眠
+func (s *Server) handleDemand(w http.ResponseWriter, r *http.Request, body []byte) {
+	if s.cfg.DemandTimeout <= 0 || s.demand == nil {
+		writeError(w, http.StatusForbidden, "未接受 bridge 的使用状态，请设置 control.demand_timeout")
+		return
+	}
+
+	var req demandRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "请求格式错误")
+		return
+	}
+	idleSince := time.Now()
+	if !req.InUse && req.IdleSince != "" {
+		t, err := time.Parse(time.RFC3339, req.IdleSince)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "idle_since 格式错误")
+			return
+		}
+		idleSince = t
+	}
+
+	timeout := time.Duration(s.cfg.DemandTimeout) * time.Second
+	if timeout < minDemandTimeout {
+		timeout = minDemandTimeout
+	}
+	reason := fmt.Sprintf("转发会话 %d 个，保持唤醒租约 %d 个", req.Sessions, req.Leases)
+	logger.Debug("bridge 使用状态: 使用中=%v, %s, 来源: %s", req.InUse, reason, r.RemoteAddr)
+	until := s.demand.SetDemand(req.InUse, idleSince, reason, timeout)
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"in_use": req.InUse,
+		"until":  until.Format(time.RFC3339),
+	})
+}
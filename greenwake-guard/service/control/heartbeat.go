@@ -0,0 +1,395 @@
+package control
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"greenwake-guard/config"
+	"greenwake-guard/pkg/logger"
+	"greenwake-guard/service/wakelock"
+)
+
+// bridge 接收注册和心跳的接口路径，签名时使用该路径
+const (
+	registerPath  = "/api/guard/v1/register"
+	heartbeatPath = "/api/guard/v1/heartbeat"
+)
+
+// 心跳中的主机状态
+const (
+	stateAwake    = "awake"         // 正常运行
+	stateSleeping = "sleeping"      // 即将睡眠
+	stateShutdown = "shutting_down" // 即将关机
+	stateStopped  = "stopped"       // guard 退出
+)
+
+const (
+	defaultHeartbeatInterval = 15 * time.Second // bridge 未返回心跳间隔时使用
+	registerRetryInterval    = 30 * time.Second // 注册或心跳失败后的重试间隔
+	notifyTimeout            = 2 * time.Second  // 睡眠、关机或退出前通知 bridge 的超时时间
+	bridgeRequestTimeout     = 10 * time.Second
+)
+
+// StatusSource 心跳中的唤醒锁状态，由唤醒锁服务实现
+type StatusSource interface {
+	Snapshot() wakelock.Snapshot
+}
+
+// heartbeat 注册和心跳的请求体，与 bridge 的 guard.Heartbeat 一致
+type heartbeat struct {
+	Version       int          `json:"version"`
+	Hostname      string       `json:"hostname"`
+	Host          string       `json:"host,omitempty"`
+	OS            string       `json:"os"`
+	State         string       `json:"state"`
+	Strategy      string       `json:"strategy"`
+	SleepMode     string       `json:"sleep_mode"`
+	TemporaryWake bool         `json:"temporary_wake"`
+	SleepInSecs   *int         `json:"sleep_in_secs,omitempty"`
+	LastWake      *wakeEvent   `json:"last_wake,omitempty"`
+	Interfaces    []netAddress `json:"interfaces"`
+}
+
+type wakeEvent struct {
+	Type   string `json:"type"`
+	Source string `json:"source"`
+	Time   string `json:"time"`
+}
+
+type netAddress struct {
+	Name string   `json:"name"`
+	MAC  string   `json:"mac"`
+	IPs  []string `json:"ips"`
+}
+
+// bridgeResponse bridge 接口的响应格式
+type bridgeResponse struct {
+	Success bool            `json:"success"`
+	Error   string          `json:"error"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// registerResponse bridge 返回的匹配主机和心跳间隔
+type registerResponse struct {
+	Version           int    `json:"version"`
+	Host              string `json:"host"`
+	HeartbeatInterval int    `json:"heartbeat_interval"`
+}
+
+// bridge 一个接收注册和心跳的 bridge
+type bridge struct {
+	cfg        config.Bridge
+	baseURL    string
+	client     *http.Client
+	mu         sync.Mutex
+	registered bool
+	interval   time.Duration
+	failing    bool // 上一次请求失败，恢复时记录日志
+}
+
+// Heartbeat 向 bridge 注册本机并定期发送心跳，睡眠、关机或退出前立即通知 bridge
+type Heartbeat struct {
+	bridges []*bridge
+	status  StatusSource
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewHeartbeat 创建心跳服务
+func NewHeartbeat(bridges []config.Bridge, status StatusSource) *Heartbeat {
+	h := &Heartbeat{
+		status: status,
+		done:   make(chan struct{}),
+	}
+	for _, cfg := range bridges {
+		h.bridges = append(h.bridges, &bridge{
+			cfg:      cfg,
+			baseURL:  strings.TrimRight(cfg.URL, "/"),
+			client:   &http.Client{Timeout: bridgeRequestTimeout},
+			interval: defaultHeartbeatInterval,
+		})
+	}
+	return h
+}
+
+// Start 检查配置并开始注册，未配置 bridge 时不启用
+func (h *Heartbeat) Start() error {
+	if len(h.bridges) == 0 {
+		logger.Debug("未配置 bridges，不向 bridge 注册")
+		return nil
+	}
+	for _, b := range h.bridges {
+		u, err := url.Parse(b.cfg.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("bridge 地址格式错误: %s", b.cfg.URL)
+		}
+		if len(b.cfg.Secret) < minSecretLen {
+			return fmt.Errorf("bridge %s 的签名密钥至少 %d 个字符", b.cfg.URL, minSecretLen)
+		}
+	}
+
+	for _, b := range h.bridges {
+		h.wg.Add(1)
+		go h.run(b)
+	}
+	return nil
+}
+
+// Stop 停止发送心跳，并通知已注册的 bridge 本机的 guard 已退出
+func (h *Heartbeat) Stop() {
+	if len(h.bridges) == 0 {
+		return
+	}
+	close(h.done)
+	h.wg.Wait()
+	h.Notify(stateStopped)
+	logger.Debug("心跳服务已停止")
+}
+
+// NotifySleep 睡眠或关机前通知 bridge，bridge 收到后立即将主机标记为离线
+func (h *Heartbeat) NotifySleep(shutdown bool) {
+	if shutdown {
+		h.Notify(stateShutdown)
+	} else {
+		h.Notify(stateSleeping)
+	}
+}
+
+// Notify 立即向已注册的 bridge 发送指定状态的心跳，最多等待 notifyTimeout
+func (h *Heartbeat) Notify(state string) {
+	hb := h.heartbeat(state)
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, b := range h.bridges {
+		b.mu.Lock()
+		registered := b.registered
+		b.mu.Unlock()
+		if !registered {
+			continue
+		}
+		wg.Add(1)
+		go func(b *bridge) {
+			defer wg.Done()
+			if _, err := b.post(ctx, heartbeatPath, h.withHost(hb, b), nil); err != nil {
+				logger.Error("通知 bridge %s 失败: %v", b.cfg.URL, err)
+			}
+		}(b)
+	}
+	wg.Wait()
+}
+
+// run 注册后按 bridge 返回的间隔发送心跳，失败时重试，bridge 重启后重新注册
+func (h *Heartbeat) run(b *bridge) {
+	defer h.wg.Done()
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-h.done:
+			return
+		case <-timer.C:
+		}
+		timer.Reset(h.beat(b))
+	}
+}
+
+// beat 未注册时注册，否则发送心跳，返回下一次发送的等待时间
+func (h *Heartbeat) beat(b *bridge) time.Duration {
+	ctx, cancel := context.WithTimeout(context.Background(), bridgeRequestTimeout)
+	defer cancel()
+	hb := h.withHost(h.heartbeat(stateAwake), b)
+
+	b.mu.Lock()
+	registered := b.registered
+	b.mu.Unlock()
+
+	if !registered {
+		var resp registerResponse
+		if _, err := b.post(ctx, registerPath, hb, &resp); err != nil {
+			b.fail("注册失败", err)
+			return registerRetryInterval
+		}
+		b.mu.Lock()
+		b.registered = true
+		b.failing = false
+		if resp.HeartbeatInterval > 0 {
+			b.interval = time.Duration(resp.HeartbeatInterval) * time.Second
+		}
+		interval := b.interval
+		b.mu.Unlock()
+
+		host := resp.Host
+		if host == "" {
+			host = "未匹配"
+		}
+		logger.Info("已注册到 bridge: %s, 主机: %s, 心跳间隔: %v", b.cfg.URL, host, interval)
+		return interval
+	}
+
+	status, err := b.post(ctx, heartbeatPath, hb, nil)
+	if status == http.StatusNotFound {
+		// bridge 重启后不再记录本机，立即重新注册
+		logger.Info("bridge %s 要求重新注册", b.cfg.URL)
+		b.mu.Lock()
+		b.registered = false
+		b.mu.Unlock()
+		return 0
+	}
+	if err != nil {
+		b.fail("发送心跳失败", err)
+		return registerRetryInterval
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failing {
+		logger.Info("bridge %s 心跳恢复", b.cfg.URL)
+		b.failing = false
+	}
+	return b.interval
+}
+
+// fail 记录请求失败，连续失败时只在第一次记录错误日志
+func (b *bridge) fail(action string, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failing {
+		logger.Debug("bridge %s %s: %v", b.cfg.URL, action, err)
+		return
+	}
+	b.failing = true
+	logger.Error("bridge %s %s: %v", b.cfg.URL, action, err)
+}
+
+// heartbeat 按当前的唤醒锁状态和网卡生成心跳
+func (h *Heartbeat) heartbeat(state string) heartbeat {
+	hostname, _ := os.Hostname()
+	snapshot := h.status.Snapshot()
+	hb := heartbeat{
+		Version:       ProtocolVersion,
+		Hostname:      hostname,
+		OS:            runtime.GOOS,
+		State:         state,
+		Strategy:      string(snapshot.Strategy),
+		SleepMode:     string(snapshot.SleepMode),
+		TemporaryWake: snapshot.TemporaryWake,
+		Interfaces:    interfaces(),
+	}
+	if snapshot.SleepIn >= 0 && state == stateAwake {
+		sleepIn := snapshot.SleepIn
+		hb.SleepInSecs = &sleepIn
+	}
+	if snapshot.LastWake != nil {
+		hb.LastWake = &wakeEvent{
+			Type:   string(snapshot.LastWake.Type),
+			Source: snapshot.LastWake.Source,
+			Time:   snapshot.LastWake.Timestamp.Format(time.RFC3339),
+		}
+	}
+	return hb
+}
+
+// withHost 设置 bridge 配置中本机的主机名
+func (h *Heartbeat) withHost(hb heartbeat, b *bridge) heartbeat {
+	hb.Host = b.cfg.Host
+	return hb
+}
+
+// interfaces 返回已启用的网卡的 MAC 和 IP 地址，忽略回环和没有 MAC 的网卡
+func interfaces() []netAddress {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		logger.Error("获取网卡失败: %v", err)
+		return nil
+	}
+
+	result := make([]netAddress, 0, len(ifaces))
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 || len(iface.HardwareAddr) != 6 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		ips := make([]string, 0, len(addrs))
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok || ipNet.IP.IsLinkLocalUnicast() {
+				continue
+			}
+			ips = append(ips, ipNet.IP.String())
+		}
+		if len(ips) == 0 {
+			continue
+		}
+		result = append(result, netAddress{Name: iface.Name, MAC: iface.HardwareAddr.String(), IPs: ips})
+	}
+	return result
+}
+
+// post 发送签名的请求，返回状态码，out 不为空时解析响应内容
+func (b *bridge) post(ctx context.Context, path string, in, out interface{}) (int, error) {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set(headerTimestamp, timestamp)
+	req.Header.Set(headerNonce, hex.EncodeToString(nonce))
+	req.Header.Set(headerSignature, hex.EncodeToString(sign(b.cfg.Secret, http.MethodPost, path, timestamp, hex.EncodeToString(nonce), body)))
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxRequestBody))
+	if err != nil {
+		return resp.StatusCode, err
+	}
+	var r bridgeResponse
+	if resp.StatusCode != http.StatusOK {
+		if json.Unmarshal(data, &r) == nil && r.Error != "" {
+			return resp.StatusCode, fmt.Errorf("%s (%d)", r.Error, resp.StatusCode)
+		}
+		return resp.StatusCode, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, &r); err != nil {
+			return resp.StatusCode, fmt.Errorf("解析响应失败: %v", err)
+		}
+		if err := json.Unmarshal(r.Data, out); err != nil {
+			return resp.StatusCode, fmt.Errorf("解析响应失败: %v", err)
+		}
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,121 @@
+package control
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"greenwake-guard/pkg/logger"
+)
+
+const (
+	maxRelayCount    = 10          // 单次请求最多发送的唤醒包数
+	maxRelayInterval = time.Second // 连续发送的最大间隔，避免单个请求长时间占用连接
+)
+
+// wakeRequest bridge 请求中继发送的唤醒包
+type wakeRequest struct {
+	MAC        string `json:"mac"`
+	Address    string `json:"address"`     // 目标地址，默认 255.255.255.255
+	Port       int    `json:"port"`        // UDP端口，默认9
+	Count      int    `json:"count"`       // 连续发送的包数，默认1
+	IntervalMs int    `json:"interval_ms"` // 连续发送的间隔（毫秒），默认100，最大1000
+	Password   string `json:"password"`    // 十六进制的 SecureOn 密码
+}
+
+// handleWake 代替 bridge 在本网段广播唤醒包
+func (s *Server) handleWake(w http.ResponseWriter, r *http.Request, body []byte) {
+	if !s.cfg.WolRelay {
+		writeError(w, http.StatusForbidden, "未启用唤醒包中继")
+		return
+	}
+
+	var req wakeRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "请求格式错误")
+		return
+	}
+	packet, err := req.packet()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	address := req.Address
+	if address == "" {
+		address = "255.255.255.255"
+	}
+	port := req.Port
+	if port == 0 {
+		port = 9
+	}
+	count := req.Count
+	if count <= 0 {
+		count = 1
+	}
+	if count > maxRelayCount {
+		count = maxRelayCount
+	}
+	interval := time.Duration(req.IntervalMs) * time.Millisecond
+	if interval <= 0 {
+		interval = 100 * time.Millisecond
+	}
+	if interval > maxRelayInterval {
+		interval = maxRelayInterval
+	}
+
+	logger.Info("中继唤醒包: MAC=%s, 目标=%s:%d, 次数=%d, 来源: %s", req.MAC, address, port, count, r.RemoteAddr)
+	if err := broadcast(net.JoinHostPort(address, strconv.Itoa(port)), packet, count, interval); err != nil {
+		logger.Error("中继唤醒包发送失败: %v", err)
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"sent": true})
+}
+
+// packet 生成唤醒包：6字节 0xFF、16次重复的 MAC 地址和可选的 SecureOn 密码
+func (req *wakeRequest) packet() ([]byte, error) {
+	mac, err := net.ParseMAC(req.MAC)
+	if err != nil || len(mac) != 6 {
+		return nil, fmt.Errorf("MAC 地址格式错误: %s", req.MAC)
+	}
+	password, err := hex.DecodeString(req.Password)
+	if err != nil || (len(password) != 0 && len(password) != 4 && len(password) != 6) {
+		return nil, fmt.Errorf("SecureOn 密码格式错误")
+	}
+	if req.Port < 0 || req.Port > 65535 {
+		return nil, fmt.Errorf("端口无效: %d", req.Port)
+	}
+
+	packet := make([]byte, 0, 102+len(password))
+	for i := 0; i < 6; i++ {
+		packet = append(packet, 0xFF)
+	}
+	for i := 0; i < 16; i++ {
+		packet = append(packet, mac...)
+	}
+	return append(packet, password...), nil
+}
+
+// broadcast 按次数和间隔发送唤醒包
+func broadcast(address string, packet []byte, count int, interval time.Duration) error {
+	conn, err := net.Dial("udp4", address)
+	if err != nil {
+		return fmt.Errorf("创建UDP连接失败: %v", err)
+	}
+	defer conn.Close()
+
+	for i := 0; i < count; i++ {
+		if i > 0 {
+			time.Sleep(interval)
+		}
+		if _, err := conn.Write(packet); err != nil {
+			return fmt.Errorf("发送唤醒包失败: %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package control
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"greenwake-guard/config"
+	"greenwake-guard/pkg/logger"
+)
+
+// ProtocolVersion 控制接口协议版本，与 bridge 的 guard 客户端一致
+const ProtocolVersion = 1
+
+// 签名相关的请求头，签名为 HMAC-SHA256(密钥, 方法\n路径\n时间戳\n随机数\n请求体)
+const (
+	headerTimestamp = "X-Greenwake-Timestamp"
+	headerNonce     = "X-Greenwake-Nonce"
+	headerSignature = "X-Greenwake-Signature"
+)
+
+const (
+	maxClockSkew   = 5 * time.Minute // 允许的请求时间偏差
+	maxRequestBody = 64 << 10
+	minSecretLen   = 16
+)
+
+// Power 睡眠和关机控制，由唤醒锁服务实现
+type Power interface {
+	ForceSleep() error
+	Shutdown() error
+	SleepWhenIdle() (int, error)
+}
+
+// Demand bridge 报告的主机使用状态，由唤醒锁服务实现
+type Demand interface {
+	SetDemand(inUse bool, idleSince time.Time, reason string, timeout time.Duration) time.Time
+}
+
+// Server 供 greenwake-bridge 调用的控制接口
+type Server struct {
+	cfg    config.Control
+	power  Power
+	demand Demand
+	mu     sync.Mutex
+	nonces map[string]time.Time // 最近使用过的随机数，防止重放
+	srv    *http.Server
+}
+
+// NewServer 创建控制接口服务
+func NewServer(cfg config.Control, power Power, demand Demand) *Server {
+	s := &Server{
+		cfg:    cfg,
+		power:  power,
+		demand: demand,
+		nonces: make(map[string]time.Time),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/ping", s.verify(http.MethodGet, s.handlePing))
+	mux.HandleFunc("/v1/wake", s.verify(http.MethodPost, s.handleWake))
+	mux.HandleFunc("/v1/sleep", s.verify(http.MethodPost, s.handleSleep))
+	mux.HandleFunc("/v1/demand", s.verify(http.MethodPost, s.handleDemand))
+	s.srv = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return s
+}
+
+// Start 开始监听，未配置监听地址时不启用
+func (s *Server) Start() error {
+	if s.cfg.Listen == "" {
+		logger.Debug("未配置控制接口监听地址，不启用控制接口")
+		return nil
+	}
+	if len(s.cfg.Secret) < minSecretLen {
+		return fmt.Errorf("控制接口签名密钥至少 %d 个字符", minSecretLen)
+	}
+
+	ln, err := net.Listen("tcp", s.cfg.Listen)
+	if err != nil {
+		return err
+	}
+	logger.Info("控制接口已启动，监听: %s, 唤醒包中继: %v, 远程睡眠: %v, 使用状态超时: %d秒",
+		s.cfg.Listen, s.cfg.WolRelay, s.cfg.AllowSleep, s.cfg.DemandTimeout)
+
+	go func() {
+		if err := s.srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+			logger.Error("控制接口异常退出: %v", err)
+		}
+	}()
+	return nil
+}
+
+// Stop 停止控制接口
+func (s *Server) Stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	s.srv.Shutdown(ctx)
+	logger.Debug("控制接口已停止")
+}
+
+// verify 校验请求方法、时间戳、随机数和签名，通过后调用 next
+func (s *Server) verify(method string, next func(w http.ResponseWriter, r *http.Request, body []byte)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestBody))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "读取请求失败")
+			return
+		}
+
+		timestamp := r.Header.Get(headerTimestamp)
+		nonce := r.Header.Get(headerNonce)
+		ts, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil || nonce == "" {
+			s.reject(w, r, "缺少时间戳或随机数")
+			return
+		}
+		if skew := time.Since(time.Unix(ts, 0)); skew > maxClockSkew || skew < -maxClockSkew {
+			s.reject(w, r, "请求时间偏差过大，请检查两台机器的时钟")
+			return
+		}
+
+		want := sign(s.cfg.Secret, r.Method, r.URL.Path, timestamp, nonce, body)
+		got, err := hex.DecodeString(r.Header.Get(headerSignature))
+		if err != nil || !hmac.Equal(got, want) {
+			s.reject(w, r, "签名错误")
+			return
+		}
+		if !s.useNonce(nonce) {
+			s.reject(w, r, "重复的请求")
+			return
+		}
+
+		next(w, r, body)
+	}
+}
+
+func (s *Server) reject(w http.ResponseWriter, r *http.Request, reason string) {
+	logger.Info("拒绝控制请求: %s %s, 来源: %s, 原因: %s", r.Method, r.URL.Path, r.RemoteAddr, reason)
+	writeError(w, http.StatusUnauthorized, reason)
+}
+
+// useNonce 记录随机数，已使用过时返回 false，超过时间偏差的记录会被清理
+func (s *Server) useNonce(nonce string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for n, at := range s.nonces {
+		if now.Sub(at) > 2*maxClockSkew {
+			delete(s.nonces, n)
+		}
+	}
+	if _, used := s.nonces[nonce]; used {
+		return false
+	}
+	s.nonces[nonce] = now
+	return true
+}
+
+// sign 计算请求签名
+func sign(secret, method, path, timestamp, nonce string, body []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strings.Join([]string{method, path, timestamp, nonce}, "\n")))
+	mac.Write([]byte("\n"))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+// handlePing 返回协议版本和主机信息，bridge 用于检查是否可达
+func (s *Server) handlePing(w http.ResponseWriter, r *http.Request, body []byte) {
+	hostname, _ := os.Hostname()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"version":   ProtocolVersion,
+		"hostname":  hostname,
+		"wol_relay": s.cfg.WolRelay,
+		"sleep":     s.cfg.AllowSleep,
+		"demand":    s.cfg.DemandTimeout > 0,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
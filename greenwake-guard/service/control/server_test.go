@@ -0,0 +1,118 @@
+package control
+
+import (
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"greenwake-guard/config"
+)
+
+const testSecret = "0123456789abcdef"
+
+// signedRequest 生成带签名的控制请求
+func signedRequest(secret, method, path string, at time.Time, nonce, body string) *http.Request {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	r.Header.Set(headerTimestamp, timestamp)
+	r.Header.Set(headerNonce, nonce)
+	r.Header.Set(headerSignature, hex.EncodeToString(sign(secret, method, path, timestamp, nonce, []byte(body))))
+	return r
+}
+
+func newTestServer() *Server {
+	return NewServer(config.Control{Secret: testSecret}, nil, nil)
+}
+
+func TestSign(t *testing.T) {
+	sig := sign(testSecret, http.MethodPost, "/v1/wake", "1700000000", "n1", []byte(`{"mac":"00:11:22:33:44:55"}`))
+	// 与 bridge 的签名方式一致: HMAC-SHA256(密钥, 方法\n路径\n时间戳\n随机数\n请求体)
+	want := "d6d7f399ea417d0667c499b8e0ddda3fa6be2e0bcdac117a6bfef5b54fe7bcfb"
+	if got := hex.EncodeToString(sig); got != want {
+		t.Fatalf("签名为 %s，期望 %s", got, want)
+	}
+
+	changed := map[string][]byte{
+		"密钥":  sign("fedcba9876543210", http.MethodPost, "/v1/wake", "1700000000", "n1", []byte(`{"mac":"00:11:22:33:44:55"}`)),
+		"方法":  sign(testSecret, http.MethodGet, "/v1/wake", "1700000000", "n1", []byte(`{"mac":"00:11:22:33:44:55"}`)),
+		"路径":  sign(testSecret, http.MethodPost, "/v1/sleep", "1700000000", "n1", []byte(`{"mac":"00:11:22:33:44:55"}`)),
+		"时间戳": sign(testSecret, http.MethodPost, "/v1/wake", "1700000001", "n1", []byte(`{"mac":"00:11:22:33:44:55"}`)),
+		"随机数": sign(testSecret, http.MethodPost, "/v1/wake", "1700000000", "n2", []byte(`{"mac":"00:11:22:33:44:55"}`)),
+		"请求体": sign(testSecret, http.MethodPost, "/v1/wake", "1700000000", "n1", []byte(`{"mac":"00:11:22:33:44:56"}`)),
+	}
+	for name, other := range changed {
+		if string(other) == string(sig) {
+			t.Errorf("%s变化后签名未变化", name)
+		}
+	}
+}
+
+func TestServerVerify(t *testing.T) {
+	now := time.Now()
+	// 为 /v1/wake 签名的请求不能用于 /v1/ping
+	otherPath := signedRequest(testSecret, http.MethodGet, "/v1/wake", now, "path", "")
+	otherPath.URL.Path = "/v1/ping"
+	tests := []struct {
+		name    string
+		req     *http.Request
+		status  int
+		wantErr string
+	}{
+		{"正确签名", signedRequest(testSecret, http.MethodGet, "/v1/ping", now, "ok", ""), http.StatusOK, ""},
+		{"允许时间偏差内的请求", signedRequest(testSecret, http.MethodGet, "/v1/ping", now.Add(-4*time.Minute), "past", ""), http.StatusOK, ""},
+		{"请求方法错误", signedRequest(testSecret, http.MethodPost, "/v1/ping", now, "method", ""), http.StatusMethodNotAllowed, "method not allowed"},
+		{"缺少时间戳", httptest.NewRequest(http.MethodGet, "/v1/ping", nil), http.StatusUnauthorized, "缺少时间戳或随机数"},
+		{"缺少随机数", signedRequest(testSecret, http.MethodGet, "/v1/ping", now, "", ""), http.StatusUnauthorized, "缺少时间戳或随机数"},
+		{"时间戳过旧", signedRequest(testSecret, http.MethodGet, "/v1/ping", now.Add(-6*time.Minute), "old", ""), http.StatusUnauthorized, "时间偏差过大"},
+		{"时间戳超前", signedRequest(testSecret, http.MethodGet, "/v1/ping", now.Add(6*time.Minute), "new", ""), http.StatusUnauthorized, "时间偏差过大"},
+		{"密钥错误", signedRequest("fedcba9876543210", http.MethodGet, "/v1/ping", now, "key", ""), http.StatusUnauthorized, "签名错误"},
+		{"签名的路径不同", otherPath, http.StatusUnauthorized, "签名错误"},
+	}
+
+	s := newTestServer()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			s.srv.Handler.ServeHTTP(w, tt.req)
+			if w.Code != tt.status {
+				t.Fatalf("状态码为 %d，期望 %d，响应: %s", w.Code, tt.status, w.Body.String())
+			}
+			if tt.wantErr != "" && !strings.Contains(w.Body.String(), tt.wantErr) {
+				t.Errorf("响应为 %s，期望包含 %q", w.Body.String(), tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestServerRejectsReplay(t *testing.T) {
+	s := newTestServer()
+	req := signedRequest(testSecret, http.MethodGet, "/v1/ping", time.Now(), "replay", "")
+
+	w := httptest.NewRecorder()
+	s.srv.Handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("首次请求状态码为 %d，期望 200", w.Code)
+	}
+
+	replay := signedRequest(testSecret, http.MethodGet, "/v1/ping", time.Now(), "replay", "")
+	w = httptest.NewRecorder()
+	s.srv.Handler.ServeHTTP(w, replay)
+	if w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), "重复的请求") {
+		t.Errorf("重放的请求状态码为 %d，响应 %s，期望 401 重复的请求", w.Code, w.Body.String())
+	}
+
+	// 超过两倍时间偏差的随机数被清理
+	s.mu.Lock()
+	s.nonces["replay"] = time.Now().Add(-2*maxClockSkew - time.Second)
+	s.mu.Unlock()
+	if !s.useNonce("other") {
+		t.Fatal("新的随机数应可使用")
+	}
+	if !s.useNonce("replay") {
+		t.Error("过期的随机数未被清理")
+	}
+}
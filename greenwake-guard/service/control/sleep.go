@@ -0,0 +1,63 @@
+package control
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"greenwake-guard/pkg/logger"
+)
+
+// 睡眠请求的操作
+const (
+	actionSleep         = "sleep"           // 立即睡眠
+	actionShutdown      = "shutdown"        // 关机
+	actionSleepWhenIdle = "sleep_when_idle" // 结束临时唤醒，空闲时睡眠
+)
+
+// powerDelay 返回响应后再睡眠或关机，保证 bridge 能收到响应
+const powerDelay = time.Second
+
+// sleepRequest bridge 的睡眠请求
+type sleepRequest struct {
+	Action      string `json:"action"`       // sleep, shutdown, sleep_when_idle
+	RequestedBy string `json:"requested_by"` // bridge 上的发起者，如 user:admin
+}
+
+// handleSleep 按 bridge 的请求睡眠、关机或空闲时睡眠
+func (s *Server) handleSleep(w http.ResponseWriter, r *http.Request, body []byte) {
+	if !s.cfg.AllowSleep || s.power == nil {
+		writeError(w, http.StatusForbidden, "未允许远程睡眠")
+		return
+	}
+
+	var req sleepRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "请求格式错误")
+		return
+	}
+	logger.Info("收到睡眠请求: 操作=%s, 发起者=%s, 来源=%s", req.Action, req.RequestedBy, r.RemoteAddr)
+
+	switch req.Action {
+	case actionSleep, actionShutdown:
+		action := s.power.ForceSleep
+		if req.Action == actionShutdown {
+			action = s.power.Shutdown
+		}
+		time.AfterFunc(powerDelay, func() {
+			if err := action(); err != nil {
+				logger.Error("执行 %s 失败: %v", req.Action, err)
+			}
+		})
+		writeJSON(w, http.StatusOK, map[string]interface{}{"action": req.Action})
+	case actionSleepWhenIdle:
+		delay, err := s.power.SleepWhenIdle()
+		if err != nil {
+			writeError(w, http.StatusConflict, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"action": req.Action, "delay_secs": delay})
+	default:
+		writeError(w, http.StatusBadRequest, "未知的操作: "+req.Action)
+	}
+}
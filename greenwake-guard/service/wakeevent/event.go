@@ -8,6 +8,7 @@ type EventType string
 const (
 	EventTypeWOL    EventType = "wol"    // 网络唤醒包
 	EventTypeDevice EventType = "device" // 外部设备活动（键盘、鼠标等）
+	EventTypeBridge EventType = "bridge" // greenwake-bridge 报告主机使用中（转发会话、保持唤醒租约）
 )
 
 // Event 唤醒事件
@@ -24,6 +25,8 @@ func (t EventType) String() string {
 		return "网络唤醒包"
 	case EventTypeDevice:
 		return "外部设备活动"
+	case EventTypeBridge:
+		return "bridge 使用中"
 	default:
 		return "未知事件"
 	}
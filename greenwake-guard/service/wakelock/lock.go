@@ -10,4 +10,7 @@ type Lock interface {
 
 	// ForceSleep 强制系统进入睡眠状态
 	ForceSleep() error
+
+	// Shutdown 关闭系统
+	Shutdown() error
 }
@@ -60,3 +60,16 @@ func (l *darwinLock) ForceSleep() error {
 	cmd := exec.Command("pmset", "sleepnow")
 	return cmd.Run()
 }
+
+func (l *darwinLock) Shutdown() error {
+	// 先释放唤醒锁
+	l.Release()
+
+	// 通过 System Events 关机，不需要 root 权限
+	if err := exec.Command("osascript", "-e", `tell application "System Events" to shut down`).Run(); err == nil {
+		return nil
+	}
+
+	// 以 root 运行时直接关机
+	return exec.Command("shutdown", "-h", "now").Run()
+}
@@ -84,3 +84,21 @@ func (l *linuxLock) ForceSleep() error {
 		"boolean:true")
 	return cmd.Run()
 }
+
+func (l *linuxLock) Shutdown() error {
+	// 先释放唤醒锁
+	l.Release()
+
+	// 尝试使用 systemctl 命令
+	if err := exec.Command("systemctl", "poweroff").Run(); err == nil {
+		return nil
+	}
+
+	// 如果 systemctl 失败，尝试使用 dbus-send
+	cmd := exec.Command("dbus-send", "--system", "--print-reply",
+		"--dest=org.freedesktop.login1",
+		"/org/freedesktop/login1",
+		"org.freedesktop.login1.Manager.PowerOff",
+		"boolean:true")
+	return cmd.Run()
+}
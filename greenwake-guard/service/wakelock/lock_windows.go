@@ -4,6 +4,8 @@
 package wakelock
 
 import (
+	"os/exec"
+
 	"greenwake-guard/pkg/logger"
 
 	"golang.org/x/sys/windows"
@@ -79,3 +81,11 @@ func (l *windowsLock) ForceSleep() error {
 	}
 	return nil
 }
+
+func (l *windowsLock) Shutdown() error {
+	// 先释放唤醒锁
+	l.Release()
+
+	// /s: 关机，/t 0: 立即执行
+	return exec.Command("shutdown", "/s", "/t", "0").Run()
+}
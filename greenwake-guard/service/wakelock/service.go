@@ -3,6 +3,7 @@ package wakelock
 import (
 	"fmt"
 	"greenwake-guard/config"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -29,15 +30,22 @@ type Service struct {
 	programSleepDelay       int                 // 程序控制睡眠模式下等待睡眠时间（秒）
 	externalWakeTimeoutSecs int                 // 外部唤醒超时时间（秒）
 	validEvents             []string            // 有效的唤醒事件类型
+	mu                      sync.Mutex          // 保护睡眠定时器、最后一次唤醒事件和 bridge 唤醒状态，控制接口和定时器回调会并发访问
 	sleepTimer              *time.Timer         // 睡眠定时器
 	timerStartTime          time.Time           // 定时器启动时间
 	done                    chan struct{}       // 用于停止检查定时器
 	lastWakeEvent           wakeevent.EventType // 最后一次唤醒事件类型
+	lastWakeSource          string              // 最后一次唤醒事件来源
+	lastWakeTime            time.Time           // 最后一次唤醒事件时间
+	demandInUse             bool                // bridge 报告主机使用中
+	demandUntil             time.Time           // bridge 唤醒的截止时间，之前不释放唤醒锁
+	demandTimer             *time.Timer         // bridge 唤醒到期定时器
 	remainingTime           int                 // 剩余时间（秒）
 	duration                time.Duration       // 持续时间
 	updateCallback          func()              // 状态更新回调函数
 	strategyChangeCallback  func(Strategy)      // 策略变更回调函数
 	saveConfigCallback      func() error        // 配置保存回调函数
+	sleepCallback           func(bool)          // 睡眠或关机前的回调函数，参数为是否关机
 	lock                    Lock                // 系统唤醒锁
 }
 
@@ -89,6 +97,8 @@ func (s *Service) runCheckTimer() {
 
 // checkStatus 检查并打印状态
 func (s *Service) checkStatus() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
 	// 计算剩余时间
 	var remainingStr string
@@ -118,11 +128,13 @@ func (s *Service) checkStatus() {
 	// 检查是否需要启动睡眠定时器：
 	// 1. 策略是外部唤醒
 	// 2. 不在临时唤醒状态（说明已经超过超时时间）
-	// 3. 是程序控制模式
-	// 4. 没有运行中的睡眠定时器
-	// 5. 不是永久唤醒或计时唤醒模式
+	// 3. bridge 没有报告主机使用中
+	// 4. 是程序控制模式
+	// 5. 没有运行中的睡眠定时器
+	// 6. 不是永久唤醒或计时唤醒模式
 	if s.strategy == StrategyExternalWake &&
 		atomic.LoadInt32(&s.isTemporaryWake) == 0 &&
+		!s.demandActive() &&
 		s.sleepMode == SleepModeProgram &&
 		s.sleepTimer == nil {
 		logger.Info("满足启动睡眠定时器的条件，启动定时器，延迟时间：%d秒", s.programSleepDelay)
@@ -153,6 +165,7 @@ func (s *Service) HandleWakeEvent(event wakeevent.Event) {
 	logger.Info("收到唤醒事件 - 类型：%s，来源：%s，时间：%v", event.Type.String(), event.Source, event.Timestamp)
 	logger.Debug("唤醒事件详情 - 当前策略：%s，睡眠模式：%s，临时唤醒：%v", s.strategy, s.sleepMode, atomic.LoadInt32(&s.isTemporaryWake) == 1)
 
+	s.mu.Lock()
 	// 取消运行中的睡眠定时器
 	if s.sleepTimer != nil {
 		logger.Debug("取消正在运行的睡眠定时器")
@@ -165,6 +178,12 @@ func (s *Service) HandleWakeEvent(event wakeevent.Event) {
 	// 设置临时唤醒状态和事件类型
 	atomic.StoreInt32(&s.isTemporaryWake, 1)
 	s.lastWakeEvent = event.Type
+	s.lastWakeSource = event.Source
+	s.lastWakeTime = event.Timestamp
+	if s.lastWakeTime.IsZero() {
+		s.lastWakeTime = time.Now()
+	}
+	s.mu.Unlock()
 
 	// 启动外部唤醒超时定时器
 	externalWakeTimeoutSecs := s.externalWakeTimeoutSecs
@@ -174,8 +193,12 @@ func (s *Service) HandleWakeEvent(event wakeevent.Event) {
 		if atomic.LoadInt32(&s.isTemporaryWake) == 1 {
 			logger.Debug("外部唤醒超时，重置临时唤醒状态")
 			atomic.StoreInt32(&s.isTemporaryWake, 0)
-			// 释放唤醒锁，允许系统睡眠
-			s.lock.Release()
+			// 释放唤醒锁，允许系统睡眠，bridge 报告主机使用中时由 bridge 唤醒到期后释放
+			s.mu.Lock()
+			if !s.demandActive() {
+				s.lock.Release()
+			}
+			s.mu.Unlock()
 
 			// 如果是程序控制模式，检查定时器会自动启动睡眠定时器
 			if s.sleepMode == SleepModeProgram {
@@ -190,7 +213,7 @@ func (s *Service) HandleWakeEvent(event wakeevent.Event) {
 	}
 }
 
-// startSleepTimer 启动睡眠定时器
+// startSleepTimer 启动睡眠定时器，调用方需持有 mu
 func (s *Service) startSleepTimer() {
 	// 如果已经有定时器在运行，先取消它
 	s.cancelSleepTimer()
@@ -207,7 +230,7 @@ func (s *Service) startSleepTimer() {
 	})
 }
 
-// cancelSleepTimer 取消睡眠定时器
+// cancelSleepTimer 取消睡眠定时器，调用方需持有 mu
 func (s *Service) cancelSleepTimer() {
 	if s.sleepTimer != nil {
 		s.sleepTimer.Stop()
@@ -219,7 +242,9 @@ func (s *Service) cancelSleepTimer() {
 // Stop 停止服务
 func (s *Service) Stop() {
 	close(s.done)
+	s.mu.Lock()
 	s.cancelSleepTimer()
+	s.mu.Unlock()
 	// 停止服务时释放唤醒锁
 	s.lock.Release()
 }
@@ -227,12 +252,175 @@ func (s *Service) Stop() {
 // forceSystemSleep 强制系统进入睡眠状态
 func (s *Service) forceSystemSleep() error {
 	logger.Info("强制系统睡眠")
+	if s.sleepCallback != nil {
+		s.sleepCallback(false)
+	}
 	// 释放唤醒锁
 	s.lock.Release()
 	// 强制系统睡眠
 	return s.lock.ForceSleep()
 }
 
+// ForceSleep 立即睡眠，供 bridge 通过控制接口调用
+func (s *Service) ForceSleep() error {
+	s.mu.Lock()
+	s.cancelSleepTimer()
+	s.mu.Unlock()
+	return s.forceSystemSleep()
+}
+
+// Shutdown 关闭系统，供 bridge 通过控制接口调用
+func (s *Service) Shutdown() error {
+	logger.Info("关闭系统")
+	s.mu.Lock()
+	s.cancelSleepTimer()
+	s.mu.Unlock()
+	if s.sleepCallback != nil {
+		s.sleepCallback(true)
+	}
+	s.lock.Release()
+	return s.lock.Shutdown()
+}
+
+// SleepWhenIdle 结束临时唤醒状态并释放唤醒锁，返回预计睡眠前的等待时间（秒）
+// 程序控制模式下等待 programSleepDelay 后睡眠，期间收到唤醒事件会取消；系统控制模式下由系统按空闲时间睡眠
+func (s *Service) SleepWhenIdle() (int, error) {
+	if s.strategy == StrategyPermanent || s.strategy == StrategyTimed {
+		return 0, fmt.Errorf("当前策略为 %s，不会进入睡眠", s.strategy)
+	}
+
+	logger.Info("空闲时睡眠 - 结束临时唤醒状态，睡眠模式：%v", s.sleepMode)
+	atomic.StoreInt32(&s.isTemporaryWake, 0)
+	s.lock.Release()
+
+	delay := 0
+	if s.sleepMode == SleepModeProgram {
+		s.mu.Lock()
+		s.startSleepTimer()
+		s.mu.Unlock()
+		delay = s.programSleepDelay
+	}
+	if s.updateCallback != nil {
+		s.updateCallback()
+	}
+	return delay, nil
+}
+
+// SetDemand 记录 bridge 报告的主机使用状态，作为与唤醒事件并列的唤醒来源，返回 bridge 唤醒的截止时间
+// 使用中时保持唤醒到 timeout 之后，bridge 定期刷新；不再使用时保持唤醒到 idleSince 之后 timeout，
+// 并结束由唤醒包引起的临时唤醒（唤醒包通常由 bridge 发送），设备活动引起的临时唤醒不受影响
+func (s *Service) SetDemand(inUse bool, idleSince time.Time, reason string, timeout time.Duration) time.Time {
+	s.mu.Lock()
+	now := time.Now()
+	until := now.Add(timeout)
+	if !inUse {
+		until = idleSince.Add(timeout)
+		if until.Before(now) {
+			until = now
+		}
+		if atomic.LoadInt32(&s.isTemporaryWake) == 1 && s.lastWakeEvent == wakeevent.EventTypeWOL {
+			logger.Info("bridge 报告主机不再使用，结束唤醒包引起的临时唤醒")
+			atomic.StoreInt32(&s.isTemporaryWake, 0)
+		}
+		// bridge 唤醒已经结束，定期刷新的不再使用状态无需处理
+		if !s.demandInUse && !s.demandActive() && !until.After(now) {
+			s.releaseIfIdle()
+			s.mu.Unlock()
+			return until
+		}
+		if s.demandInUse {
+			logger.Info("bridge 报告主机自 %s 起不再使用，%v 后结束 bridge 唤醒", idleSince.Format(time.RFC3339), until.Sub(now).Round(time.Second))
+		}
+	} else {
+		if !s.demandInUse {
+			logger.Info("bridge 报告主机使用中: %s", reason)
+			s.lastWakeEvent = wakeevent.EventTypeBridge
+			s.lastWakeSource = reason
+			s.lastWakeTime = now
+		}
+		s.cancelSleepTimer()
+		s.lock.Acquire()
+	}
+
+	s.demandInUse = inUse
+	s.demandUntil = until
+	if s.demandTimer != nil {
+		s.demandTimer.Stop()
+	}
+	s.demandTimer = time.AfterFunc(until.Sub(now), s.demandExpired)
+	s.mu.Unlock()
+
+	if s.updateCallback != nil {
+		s.updateCallback()
+	}
+	return until
+}
+
+// demandExpired bridge 唤醒到期（主机不再使用或 bridge 停止刷新）
+func (s *Service) demandExpired() {
+	s.mu.Lock()
+	if s.demandActive() {
+		s.mu.Unlock()
+		return
+	}
+	logger.Info("bridge 唤醒结束")
+	s.demandInUse = false
+	s.releaseIfIdle()
+	s.mu.Unlock()
+	if s.updateCallback != nil {
+		s.updateCallback()
+	}
+}
+
+// releaseIfIdle 外部唤醒策略下没有其他唤醒来源时释放唤醒锁，程序控制模式下检查定时器会启动睡眠定时器，调用方需持有 mu
+func (s *Service) releaseIfIdle() {
+	if s.strategy == StrategyExternalWake && atomic.LoadInt32(&s.isTemporaryWake) == 0 && !s.demandActive() {
+		s.lock.Release()
+	}
+}
+
+// demandActive bridge 唤醒是否未到期，调用方需持有 mu
+func (s *Service) demandActive() bool {
+	return time.Now().Before(s.demandUntil)
+}
+
+// Snapshot 唤醒锁状态快照，用于向 bridge 发送心跳
+type Snapshot struct {
+	Strategy      Strategy
+	SleepMode     SleepMode
+	TemporaryWake bool
+	SleepIn       int              // 程序控制睡眠模式下距离睡眠的时间（秒），没有睡眠定时器时为 -1
+	LastWake      *wakeevent.Event // 最后一次唤醒事件，没有时为 nil
+}
+
+// Snapshot 获取当前的唤醒锁状态
+func (s *Service) Snapshot() Snapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot := Snapshot{
+		Strategy:      s.strategy,
+		SleepMode:     s.sleepMode,
+		TemporaryWake: atomic.LoadInt32(&s.isTemporaryWake) == 1,
+		SleepIn:       -1,
+	}
+	if s.sleepTimer != nil && !s.timerStartTime.IsZero() {
+		snapshot.SleepIn = s.programSleepDelay - int(time.Since(s.timerStartTime).Seconds())
+		if snapshot.SleepIn < 0 {
+			snapshot.SleepIn = 0
+		}
+	}
+	if s.lastWakeEvent != "" {
+		snapshot.LastWake = &wakeevent.Event{Type: s.lastWakeEvent, Source: s.lastWakeSource, Timestamp: s.lastWakeTime}
+	}
+	return snapshot
+}
+
+// SetSleepCallback 设置睡眠或关机前的回调函数，用于通知 bridge，参数为是否关机
+func (s *Service) SetSleepCallback(callback func(shutdown bool)) {
+	s.sleepCallback = callback
+}
+
 // GetStrategy 获取当前策略
 func (s *Service) GetStrategy() Strategy {
 	return s.strategy
@@ -242,7 +430,9 @@ func (s *Service) GetStrategy() Strategy {
 func (s *Service) SetStrategy(strategy Strategy, duration time.Duration) {
 	// 如果切换到永久唤醒或计时唤醒，取消睡眠定时器
 	if strategy == StrategyPermanent || strategy == StrategyTimed {
+		s.mu.Lock()
 		s.cancelSleepTimer()
+		s.mu.Unlock()
 	}
 
 	s.strategy = strategy
@@ -256,7 +446,9 @@ func (s *Service) SetStrategy(strategy Strategy, duration time.Duration) {
 			s.lock.Release()
 			// 如果是程序控制模式，启动睡眠定时器
 			if s.sleepMode == SleepModeProgram {
+				s.mu.Lock()
 				s.startSleepTimer()
+				s.mu.Unlock()
 			}
 		})
 	} else if strategy == StrategyPermanent {
@@ -334,7 +526,9 @@ func (s *Service) SetSleepMode(mode SleepMode) {
 	// 如果切换到系统控制模式，取消已有的睡眠定时器和检查定时器
 	if mode == SleepModeSystem {
 		logger.Debug("切换到系统控制模式，取消睡眠定时器和检查定时器")
+		s.mu.Lock()
 		s.cancelSleepTimer()
+		s.mu.Unlock()
 		close(s.done)
 		s.done = make(chan struct{}) // 重新创建 done channel 以备后用
 	} else {
@@ -370,7 +564,9 @@ func (s *Service) InitializeState(strategy Strategy, sleepMode SleepMode, durati
 	s.duration = duration
 
 	// 取消所有定时器
+	s.mu.Lock()
 	s.cancelSleepTimer()
+	s.mu.Unlock()
 
 	// 根据策略设置唤醒锁状态
 	if strategy == StrategyPermanent || strategy == StrategyTimed {